@@ -0,0 +1,168 @@
+package xmlquery
+
+import (
+	"math"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// Count returns the number of nodes selected by the XPath expr.
+func Count(top *Node, expr string) (int, error) {
+	r, err := Evaluate(top, expr)
+	if err != nil {
+		return 0, err
+	}
+	if r.Type == NodeSetResult {
+		return len(r.nodes), nil
+	}
+	return int(r.Number()), nil
+}
+
+// SumFloat returns the sum of the numeric values selected by the XPath expr.
+// Values are parsed with ParseNumber, so text such as "1,234.50" or
+// "€ 12,5" is accepted; values that are not numbers are ignored.
+func SumFloat(top *Node, expr string) (float64, error) {
+	values, err := numbers(top, expr)
+	if err != nil {
+		return 0, err
+	}
+	var sum float64
+	for _, v := range values {
+		sum += v
+	}
+	return sum, nil
+}
+
+// Avg returns the mean of the numeric values selected by the XPath expr, or
+// NaN if there are none. See SumFloat for how values are parsed.
+func Avg(top *Node, expr string) (float64, error) {
+	values, err := numbers(top, expr)
+	if err != nil || len(values) == 0 {
+		return math.NaN(), err
+	}
+	var sum float64
+	for _, v := range values {
+		sum += v
+	}
+	return sum / float64(len(values)), nil
+}
+
+// Min returns the smallest numeric value selected by the XPath expr, or NaN
+// if there are none. See SumFloat for how values are parsed.
+func Min(top *Node, expr string) (float64, error) {
+	return extreme(top, expr, func(a, b float64) bool { return a < b })
+}
+
+// Max returns the largest numeric value selected by the XPath expr, or NaN
+// if there are none. See SumFloat for how values are parsed.
+func Max(top *Node, expr string) (float64, error) {
+	return extreme(top, expr, func(a, b float64) bool { return a > b })
+}
+
+func extreme(top *Node, expr string, better func(a, b float64) bool) (float64, error) {
+	values, err := numbers(top, expr)
+	if err != nil || len(values) == 0 {
+		return math.NaN(), err
+	}
+	m := values[0]
+	for _, v := range values[1:] {
+		if better(v, m) {
+			m = v
+		}
+	}
+	return m, nil
+}
+
+// numbers evaluates expr and returns the numeric values it yields.
+func numbers(top *Node, expr string) ([]float64, error) {
+	r, err := Evaluate(top, expr)
+	if err != nil {
+		return nil, err
+	}
+	if r.Type != NodeSetResult {
+		if f, ok := ParseNumber(r.String()); ok {
+			return []float64{f}, nil
+		}
+		return nil, nil
+	}
+	values := make([]float64, 0, len(r.nodes))
+	for _, n := range r.nodes {
+		if f, ok := ParseNumber(n.InnerText()); ok {
+			values = append(values, f)
+		}
+	}
+	return values, nil
+}
+
+// ParseNumber parses a number written in the text of a document, tolerating
+// the common locale conventions: surrounding whitespace, currency symbols
+// and units, grouping separators (commas, dots, apostrophes, underscores
+// and spaces) and either '.' or ',' as the decimal separator.
+//
+// '.' and ',' are treated alike. When both appear, the last one is the
+// decimal separator, as in "1,234.5" and "1.234,5". When only one of them
+// appears, it is the decimal separator if it appears once, so "1.234" and
+// "1,234" are both 1.234, and a grouping separator if it is repeated, as in
+// "1.234.567". Use ParseNumberDecimal when the decimal separator is known.
+func ParseNumber(s string) (float64, bool) {
+	return ParseNumberDecimal(s, 0)
+}
+
+// ParseNumberDecimal is like ParseNumber, but with the decimal separator
+// given, '.' or ','. The other one is a grouping separator wherever it
+// appears, so ParseNumberDecimal("1,234", '.') is 1234. A decimal of 0
+// chooses it as ParseNumber does.
+func ParseNumberDecimal(s string, decimal byte) (float64, bool) {
+	s = strings.TrimFunc(s, func(r rune) bool {
+		return !unicode.IsDigit(r) && r != '-' && r != '+' && r != '.' && r != ','
+	})
+	if s == "" {
+		return 0, false
+	}
+	if decimal != ',' {
+		if f, err := strconv.ParseFloat(s, 64); err == nil {
+			return f, true
+		}
+	}
+	var b strings.Builder
+	for _, r := range s {
+		switch {
+		case r == '\'' || r == '_' || unicode.IsSpace(r):
+		default:
+			b.WriteRune(r)
+		}
+	}
+	s = b.String()
+
+	if decimal == 0 {
+		dots, commas := strings.Count(s, "."), strings.Count(s, ",")
+		switch {
+		case dots > 0 && commas > 0:
+			if strings.LastIndexByte(s, '.') > strings.LastIndexByte(s, ',') {
+				decimal = '.'
+			} else {
+				decimal = ','
+			}
+		case dots == 1:
+			decimal = '.'
+		case commas == 1:
+			decimal = ','
+		}
+	}
+	b.Reset()
+	for i := 0; i < len(s); i++ {
+		switch c := s[i]; {
+		case c == decimal:
+			b.WriteByte('.')
+		case c == '.' || c == ',':
+		default:
+			b.WriteByte(c)
+		}
+	}
+	f, err := strconv.ParseFloat(b.String(), 64)
+	if err != nil {
+		return 0, false
+	}
+	return f, true
+}
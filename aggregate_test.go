@@ -0,0 +1,105 @@
+package xmlquery
+
+import (
+	"math"
+	"strings"
+	"testing"
+)
+
+func TestAggregates(t *testing.T) {
+	doc, err := Parse(strings.NewReader(`<orders>
+		<order><total>1,234.50</total></order>
+		<order><total>€ 10</total></order>
+		<order><total>n/a</total></order>
+		<order><total> 5.5 </total></order>
+	</orders>`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	n, err := Count(doc, "//order")
+	if err != nil {
+		t.Fatal(err)
+	}
+	testValue(t, n, 4)
+
+	sum, err := SumFloat(doc, "//total")
+	if err != nil {
+		t.Fatal(err)
+	}
+	testValue(t, sum, 1250.0)
+
+	avg, err := Avg(doc, "//total")
+	if err != nil {
+		t.Fatal(err)
+	}
+	testValue(t, avg, 1250.0/3)
+
+	min, err := Min(doc, "//total")
+	if err != nil {
+		t.Fatal(err)
+	}
+	testValue(t, min, 5.5)
+
+	max, err := Max(doc, "//total")
+	if err != nil {
+		t.Fatal(err)
+	}
+	testValue(t, max, 1234.5)
+
+	avg, err = Avg(doc, "//missing")
+	if err != nil {
+		t.Fatal(err)
+	}
+	testTrue(t, math.IsNaN(avg))
+
+	if _, err := SumFloat(doc, "//total["); err == nil {
+		t.Fatal("expected error for invalid expression")
+	}
+}
+
+func TestParseNumber(t *testing.T) {
+	for s, want := range map[string]float64{
+		"42":           42,
+		" -3.25 ":      -3.25,
+		"1,234":        1.234,
+		"1.234":        1.234,
+		"1,234.5":      1234.5,
+		"1.234,5":      1234.5,
+		"12,5":         12.5,
+		"1.234.567":    1234567,
+		"1 234 567,89": 1234567.89,
+		"1'000":        1000,
+		"$19.99":       19.99,
+		"12 kg":        12,
+		"1e3":          1000,
+	} {
+		got, ok := ParseNumber(s)
+		if !ok || got != want {
+			t.Errorf("ParseNumber(%q) = %v, %v; want %v", s, got, ok, want)
+		}
+	}
+	for _, s := range []string{"", "abc", "n/a", "1-2"} {
+		if _, ok := ParseNumber(s); ok {
+			t.Errorf("ParseNumber(%q) should fail", s)
+		}
+	}
+
+	for _, test := range []struct {
+		s       string
+		decimal byte
+		want    float64
+	}{
+		{"1,234", '.', 1234},
+		{"1.234", ',', 1234},
+		{"1.234", '.', 1.234},
+		{"1,234", ',', 1.234},
+		{"1.234,5", ',', 1234.5},
+		{"1,234.5", '.', 1234.5},
+		{"12 kg", ',', 12},
+	} {
+		got, ok := ParseNumberDecimal(test.s, test.decimal)
+		if !ok || got != test.want {
+			t.Errorf("ParseNumberDecimal(%q, %q) = %v, %v; want %v", test.s, test.decimal, got, ok, test.want)
+		}
+	}
+}
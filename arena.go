@@ -0,0 +1,106 @@
+package xmlquery
+
+import (
+	"sync"
+)
+
+// arenaChunkSize is the number of nodes or attributes in one arena slab.
+const arenaChunkSize = 1024
+
+var (
+	nodeSlabPool = sync.Pool{New: func() interface{} { return make([]Node, arenaChunkSize) }}
+	attrSlabPool = sync.Pool{New: func() interface{} { return make([]Attr, arenaChunkSize) }}
+)
+
+// nodeArena hands out nodes and attribute slices carved from large slabs.
+// Slabs are recycled through a sync.Pool when the document is freed.
+type nodeArena struct {
+	nodeSlabs [][]Node
+	attrSlabs [][]Attr
+	nodes     []Node // unused part of the current node slab
+	attrs     []Attr // unused part of the current attribute slab
+}
+
+func (a *nodeArena) newNode() *Node {
+	if len(a.nodes) == 0 {
+		slab := nodeSlabPool.Get().([]Node)
+		a.nodeSlabs = append(a.nodeSlabs, slab)
+		a.nodes = slab
+	}
+	n := &a.nodes[0]
+	a.nodes = a.nodes[1:]
+	return n
+}
+
+func (a *nodeArena) newAttrs(n int) []Attr {
+	if n > arenaChunkSize/4 {
+		return make([]Attr, n)
+	}
+	if len(a.attrs) < n {
+		slab := attrSlabPool.Get().([]Attr)
+		a.attrSlabs = append(a.attrSlabs, slab)
+		a.attrs = slab
+	}
+	// Limit the capacity so that appending to the slice reallocates instead
+	// of overwriting the attributes of the next node.
+	attrs := a.attrs[:n:n]
+	a.attrs = a.attrs[n:]
+	return attrs
+}
+
+func (a *nodeArena) free() {
+	for _, slab := range a.nodeSlabs {
+		for i := range slab {
+			slab[i] = Node{}
+		}
+		nodeSlabPool.Put(slab)
+	}
+	for _, slab := range a.attrSlabs {
+		for i := range slab {
+			slab[i] = Attr{}
+		}
+		attrSlabPool.Put(slab)
+	}
+	*a = nodeArena{}
+}
+
+// allocNode returns a new node of the given type, data and level, taken
+// from the arena if the parser has one, or from the nodes released by
+// StreamParser.Release when streaming. Nodes hold an atomic value, so they
+// are filled in place rather than copied.
+func (p *parser) allocNode(typ NodeType, data string, level int) *Node {
+	p.nodes++
+	var node *Node
+	switch {
+	case p.arena != nil:
+		node = p.arena.newNode()
+	case p.streamElementXPath != nil:
+		node = releasedNodes.Get().(*Node)
+	default:
+		node = new(Node)
+	}
+	node.Type, node.Data, node.level = typ, data, level
+	return node
+}
+
+// allocAttrs returns a slice of n attributes, taken from the arena if the
+// parser has one.
+func (p *parser) allocAttrs(n int) []Attr {
+	if p.arena == nil {
+		return make([]Attr, n)
+	}
+	return p.arena.newAttrs(n)
+}
+
+// Free releases the memory of a document parsed with ParserOptions.UseArena
+// so it can be reused by later parses. After Free returns, neither n nor any
+// node of its tree may be used. Free does nothing for other trees.
+func (n *Node) Free() {
+	if n.doc == nil || n.doc.arena == nil {
+		return
+	}
+	n.doc.arena.free()
+	n.doc = nil
+	n.FirstChild = nil
+	n.LastChild = nil
+}
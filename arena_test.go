@@ -0,0 +1,48 @@
+package xmlquery
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParseWithArena(t *testing.T) {
+	var b strings.Builder
+	b.WriteString(`<?xml version="1.0"?><list>`)
+	for i := 0; i < 3000; i++ {
+		b.WriteString(`<item id="x" kind="y">text<!--c--></item>`)
+	}
+	b.WriteString(`</list>`)
+	s := b.String()
+
+	expected, err := Parse(strings.NewReader(s))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 2; i++ {
+		doc, err := ParseWithOptions(strings.NewReader(s), ParserOptions{UseArena: true})
+		if err != nil {
+			t.Fatal(err)
+		}
+		verifyNodePointers(t, doc)
+		testValue(t, doc.OutputXML(false), expected.OutputXML(false))
+		testValue(t, len(Find(doc, "//item[@kind='y']")), 3000)
+
+		// Appending an attribute must not clobber the next node's attributes.
+		first := FindOne(doc, "//item[1]")
+		AddAttr(first, "extra", "z")
+		testValue(t, FindOne(doc, "//item[2]").SelectAttr("id"), "x")
+		testValue(t, first.SelectAttr("extra"), "z")
+
+		doc.Free()
+		testTrue(t, doc.FirstChild == nil)
+	}
+}
+
+func TestFreeWithoutArena(t *testing.T) {
+	doc, err := Parse(strings.NewReader(`<a>b</a>`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	doc.Free()
+	testValue(t, FindOne(doc, "//a").InnerText(), "b")
+}
@@ -0,0 +1,56 @@
+package xmlquery
+
+import (
+	"github.com/suifengpiao14/xmlquery/xml"
+)
+
+// attrIndexMin is the number of attributes from which an element looks
+// attribute names up in an index instead of scanning Attr.
+const attrIndexMin = 16
+
+// attrIndex maps the attribute names of an element to their position in
+// its Attr slice.
+type attrIndex struct {
+	first *Attr // &Attr[0] when the index was built
+	n     int   // len(Attr) when the index was built
+	pos   map[xml.Name]int
+}
+
+// attrPos returns the position in n.Attr of the first attribute named
+// name, or -1. Elements with many attributes keep an index, built on the
+// first lookup. It is shared by the goroutines reading the tree, so it is
+// accessed atomically. The functions that add, remove or rename attributes
+// discard it with resetAttrIndex. Changes made to Attr directly are noticed
+// when they move or resize the slice.
+func (n *Node) attrPos(name xml.Name) int {
+	if len(n.Attr) < attrIndexMin {
+		for i := range n.Attr {
+			if n.Attr[i].Name == name {
+				return i
+			}
+		}
+		return -1
+	}
+	idx := n.attrIndex.Load()
+	if idx == nil || idx.first != &n.Attr[0] || idx.n != len(n.Attr) {
+		idx = &attrIndex{first: &n.Attr[0], n: len(n.Attr), pos: make(map[xml.Name]int, len(n.Attr))}
+		for i := range n.Attr {
+			if _, dup := idx.pos[n.Attr[i].Name]; !dup {
+				idx.pos[n.Attr[i].Name] = i
+			}
+		}
+		n.attrIndex.Store(idx)
+	}
+	if i, ok := idx.pos[name]; ok {
+		return i
+	}
+	return -1
+}
+
+// resetAttrIndex discards the attribute index of n, after attributes have
+// been added, removed or renamed.
+func (n *Node) resetAttrIndex() {
+	if n.attrIndex.Load() != nil {
+		n.attrIndex.Store(nil)
+	}
+}
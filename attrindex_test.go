@@ -0,0 +1,90 @@
+package xmlquery
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func manyAttrsDoc(t testing.TB, n int) *Node {
+	var b strings.Builder
+	b.WriteString(`<r xmlns:p="urn:p"><e`)
+	for i := 0; i < n; i++ {
+		fmt.Fprintf(&b, ` a%d="%d"`, i, i)
+	}
+	b.WriteString(` p:a0="p" a5="dup"/></r>`)
+	doc, err := Parse(strings.NewReader(b.String()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return doc
+}
+
+func TestAttrIndex(t *testing.T) {
+	doc := manyAttrsDoc(t, 40)
+	e := FindOne(doc, "//e")
+	testValue(t, e.SelectAttr("a39"), "39")
+	testValue(t, e.SelectAttr("p:a0"), "p")
+	// The first of duplicate attributes is found.
+	testValue(t, e.SelectAttr("a5"), "5")
+	testValue(t, e.SelectAttr("missing"), "")
+
+	e.SetAttr("a1", "one")
+	testValue(t, e.SelectAttr("a1"), "one")
+	e.SetAttr("added", "x")
+	testValue(t, e.SelectAttr("added"), "x")
+	e.RemoveAttr("a0")
+	testValue(t, e.SelectAttr("a0"), "")
+	testValue(t, e.SelectAttr("a1"), "one")
+	testValue(t, e.SelectAttrNode("a2").Value, "2")
+
+	RewritePrefixes(e, map[string]string{"p": "q"})
+	testValue(t, e.SelectAttr("p:a0"), "")
+	testValue(t, e.SelectAttr("q:a0"), "p")
+	StripNamespaces(e)
+	testValue(t, e.SelectAttr("q:a0"), "")
+
+	testValue(t, len(Find(doc, "//e[@a39='39']")), 1)
+}
+
+func TestAttrIndexRemoveAdd(t *testing.T) {
+	// Removing and adding an attribute keeps both the length of Attr and,
+	// with spare capacity, its first element.
+	e := &Node{Type: ElementNode, Data: "e"}
+	for i := 0; i <= 16; i++ {
+		AddAttr(e, fmt.Sprintf("a%d", i), fmt.Sprint(i))
+	}
+	testValue(t, e.SelectAttr("a5"), "5")
+	e.RemoveAttr("a0")
+	AddAttr(e, "z", "zz")
+	testValue(t, e.SelectAttr("a5"), "5")
+	testValue(t, e.SelectAttr("z"), "zz")
+	testValue(t, e.SelectAttr("a0"), "")
+}
+
+func TestAttrIndexConcurrent(t *testing.T) {
+	doc := manyAttrsDoc(t, 40)
+	e := FindOne(doc, "//e")
+	var wg sync.WaitGroup
+	for g := 0; g < 4; g++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < 40; i++ {
+				if got := e.SelectAttr(fmt.Sprintf("a%d", i)); got != fmt.Sprint(i) {
+					t.Errorf("a%d = %q", i, got)
+				}
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+func BenchmarkSelectAttrMany(b *testing.B) {
+	e := FindOne(manyAttrsDoc(b, 40), "//e")
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		e.SelectAttr("a39")
+	}
+}
@@ -0,0 +1,65 @@
+package xmlquery
+
+import "sort"
+
+// An AttrOrder selects the order in which attributes of elements are
+// written, see WithAttrOrder.
+type AttrOrder int
+
+const (
+	// AttrOrderDocument keeps attributes in the order they are stored in
+	// the node.
+	AttrOrderDocument AttrOrder = iota
+	// AttrOrderAlphabetical sorts attributes by their qualified name, as in
+	// "prefix:local".
+	AttrOrderAlphabetical
+	// AttrOrderNamespacesFirst writes the default namespace declaration,
+	// then the prefixed namespace declarations, then the other attributes,
+	// each group sorted by qualified name.
+	AttrOrderNamespacesFirst
+)
+
+// WithAttrOrder sets the order in which the attributes of elements are
+// written, so that documents built from maps serialize the same way every
+// time. Processing instructions and the XML declaration keep their order.
+func WithAttrOrder(order AttrOrder) OutputOption {
+	return func(oc *outputConfiguration) {
+		oc.attrOrder = order
+	}
+}
+
+// orderedAttrs returns the attributes of element n in the given order.
+func orderedAttrs(n *Node, order AttrOrder) []Attr {
+	if order == AttrOrderDocument || n.Type != ElementNode || len(n.Attr) < 2 {
+		return n.Attr
+	}
+	attrs := make([]Attr, len(n.Attr))
+	copy(attrs, n.Attr)
+	group := func(attr *Attr) int {
+		switch {
+		case order != AttrOrderNamespacesFirst:
+			return 0
+		case attr.Name.Space == "" && attr.Name.Local == "xmlns":
+			return 0
+		case attr.Name.Space == "xmlns":
+			return 1
+		}
+		return 2
+	}
+	sort.SliceStable(attrs, func(i, j int) bool {
+		a, b := &attrs[i], &attrs[j]
+		if ga, gb := group(a), group(b); ga != gb {
+			return ga < gb
+		}
+		return qualifiedAttrName(a) < qualifiedAttrName(b)
+	})
+	return attrs
+}
+
+// qualifiedAttrName returns the name of attr as written, "prefix:local".
+func qualifiedAttrName(attr *Attr) string {
+	if attr.Name.Space == "" {
+		return attr.Name.Local
+	}
+	return attr.Name.Space + ":" + attr.Name.Local
+}
@@ -0,0 +1,26 @@
+package xmlquery
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestWithAttrOrder(t *testing.T) {
+	s := `<?xml version="1.0" encoding="UTF-8"?><root z="1" xmlns:b="urn:b" b:y="2" xmlns="urn:d" a="3" xmlns:a="urn:a" ab="4"><c d="1" d="0"/></root>`
+	doc, err := Parse(strings.NewReader(s))
+	if err != nil {
+		t.Fatal(err)
+	}
+	testValue(t, doc.OutputXMLWithOptions(WithAttrOrder(AttrOrderAlphabetical)),
+		`<?xml version="1.0" encoding="UTF-8"?><root a="3" ab="4" b:y="2" xmlns="urn:d" xmlns:a="urn:a" xmlns:b="urn:b" z="1"><c d="1" d="0"></c></root>`)
+	testValue(t, doc.OutputXMLWithOptions(WithAttrOrder(AttrOrderNamespacesFirst)),
+		`<?xml version="1.0" encoding="UTF-8"?><root xmlns="urn:d" xmlns:a="urn:a" xmlns:b="urn:b" a="3" ab="4" b:y="2" z="1"><c d="1" d="0"></c></root>`)
+	testValue(t, doc.OutputXMLWithOptions(WithEmptyTagSupport()), s)
+	testValue(t, doc.SelectElement("root").Attr[0].Name.Local, "z")
+
+	doc, err = ParseWithOptions(strings.NewReader(`<root b="1" a="2"/>`), ParserOptions{RoundTrip: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	testValue(t, doc.OutputXMLWithOptions(WithOutDeclarationNode(), WithAttrOrder(AttrOrderAlphabetical)), `<root a="2" b="1"></root>`)
+}
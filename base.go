@@ -0,0 +1,81 @@
+package xmlquery
+
+import (
+	"net/url"
+)
+
+// SetDocumentURI sets the URI the document rooted at n was loaded from. It
+// is the base against which xml:base attributes and relative references
+// are resolved. LoadURL and ParseFile set it automatically.
+func (n *Node) SetDocumentURI(uri string) {
+	n.docData().uri = uri
+}
+
+// DocumentURI returns the URI of the document n belongs to, or "" if it is
+// unknown.
+func (n *Node) DocumentURI() string {
+	for n.Parent != nil {
+		n = n.Parent
+	}
+	if n.doc == nil {
+		return ""
+	}
+	return n.doc.uri
+}
+
+// BaseURI returns the base URI of n as defined by the XML Base
+// specification: the xml:base attributes of n and its ancestors, resolved
+// against each other and against the document URI. It returns "" if no
+// base URI is known.
+func (n *Node) BaseURI() string {
+	var bases []string
+	for p := n; p != nil; p = p.Parent {
+		if p.Type != ElementNode {
+			continue
+		}
+		if b := p.SelectAttrNode("xml:base"); b != nil {
+			bases = append(bases, b.Value)
+		}
+	}
+	base := n.DocumentURI()
+	for i := len(bases) - 1; i >= 0; i-- {
+		base = resolveURI(base, bases[i])
+	}
+	return base
+}
+
+// ResolveURI resolves the URI reference rel, such as the value of an href
+// attribute, against the base URI of n. If n has no base URI, rel is
+// returned unchanged.
+func (n *Node) ResolveURI(rel string) (string, error) {
+	ref, err := url.Parse(rel)
+	if err != nil {
+		return "", err
+	}
+	base := n.BaseURI()
+	if base == "" {
+		return rel, nil
+	}
+	u, err := url.Parse(base)
+	if err != nil {
+		return "", err
+	}
+	return u.ResolveReference(ref).String(), nil
+}
+
+// resolveURI resolves ref against base, keeping ref as is if either can't be
+// parsed.
+func resolveURI(base, ref string) string {
+	if base == "" {
+		return ref
+	}
+	b, err := url.Parse(base)
+	if err != nil {
+		return ref
+	}
+	r, err := url.Parse(ref)
+	if err != nil {
+		return ref
+	}
+	return b.ResolveReference(r).String()
+}
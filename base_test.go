@@ -0,0 +1,50 @@
+package xmlquery
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestBaseURI(t *testing.T) {
+	s := `<feed xml:base="http://example.org/blog/">
+		<entry xml:base="2024/">
+			<link href="post.html"/>
+			<content xml:base="/static/"><img src="a.png"/></content>
+		</entry>
+		<entry><link href="../about"/></entry>
+	</feed>`
+	doc, err := Parse(strings.NewReader(s))
+	if err != nil {
+		t.Fatal(err)
+	}
+	links := Find(doc, "//link")
+	testValue(t, links[0].BaseURI(), "http://example.org/blog/2024/")
+	u, err := links[0].ResolveURI(links[0].SelectAttr("href"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	testValue(t, u, "http://example.org/blog/2024/post.html")
+	u, _ = links[1].ResolveURI(links[1].SelectAttr("href"))
+	testValue(t, u, "http://example.org/about")
+	u, _ = FindOne(doc, "//img").ResolveURI("a.png")
+	testValue(t, u, "http://example.org/static/a.png")
+}
+
+func TestBaseURIDocument(t *testing.T) {
+	doc, err := Parse(strings.NewReader(`<a><b xml:base="sub/"/></a>`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	b := FindOne(doc, "//b")
+	testValue(t, b.BaseURI(), "sub/")
+	u, _ := FindOne(doc, "//a").ResolveURI("x.xml")
+	testValue(t, u, "x.xml")
+
+	doc.SetDocumentURI("file:///data/doc.xml")
+	testValue(t, b.DocumentURI(), "file:///data/doc.xml")
+	u, _ = b.ResolveURI("x.xml")
+	testValue(t, u, "file:///data/sub/x.xml")
+	if _, err := b.ResolveURI("%zz"); err == nil {
+		t.Fatal("expected error for invalid reference")
+	}
+}
@@ -0,0 +1,162 @@
+package xmlquery
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// BindQuery returns expr with each ? placeholder replaced by the next of
+// args, written as an XPath literal, so that values from user input can't
+// change the meaning of the expression:
+//
+//	expr, err := xmlquery.BindQuery("//user[@name=? and @age>?]", name, 18)
+//
+// Strings and fmt.Stringers become string literals, even if they contain
+// both kinds of quotes, integers and floats become numbers and booleans
+// true() or false(). A ? inside a string literal of expr is not a
+// placeholder. An error is returned if the number of args doesn't match the
+// number of placeholders, or for an argument of another type.
+func BindQuery(expr string, args ...interface{}) (string, error) {
+	var b strings.Builder
+	next := 0
+	for i := 0; i < len(expr); i++ {
+		switch c := expr[i]; c {
+		case '\'', '"':
+			end := strings.IndexByte(expr[i+1:], c)
+			if end < 0 {
+				return "", errors.New("xmlquery: unterminated string literal in " + expr)
+			}
+			b.WriteString(expr[i : i+end+2])
+			i += end + 1
+		case '?':
+			if next == len(args) {
+				return "", fmt.Errorf("xmlquery: %d arguments for more placeholders in %s", len(args), expr)
+			}
+			lit, err := xpathLiteral(args[next])
+			if err != nil {
+				return "", fmt.Errorf("xmlquery: argument %d: %v", next+1, err)
+			}
+			b.WriteString(lit)
+			next++
+		default:
+			b.WriteByte(c)
+		}
+	}
+	if next != len(args) {
+		return "", fmt.Errorf("xmlquery: %d arguments for %d placeholders in %s", len(args), next, expr)
+	}
+	return b.String(), nil
+}
+
+// QueryAllWithArgs is like QueryAll, with the placeholders of expr bound to
+// args, see BindQuery.
+func QueryAllWithArgs(top *Node, expr string, args ...interface{}) ([]*Node, error) {
+	bound, err := BindQuery(expr, args...)
+	if err != nil {
+		return nil, err
+	}
+	return QueryAll(top, bound)
+}
+
+// QueryWithArgs is like Query, with the placeholders of expr bound to args,
+// see BindQuery.
+func QueryWithArgs(top *Node, expr string, args ...interface{}) (*Node, error) {
+	bound, err := BindQuery(expr, args...)
+	if err != nil {
+		return nil, err
+	}
+	return Query(top, bound)
+}
+
+// xpathLiteral returns v written as an XPath expression.
+func xpathLiteral(v interface{}) (string, error) {
+	switch v := v.(type) {
+	case string:
+		return quoteXPathString(v), nil
+	case fmt.Stringer:
+		return quoteXPathString(v.String()), nil
+	case bool:
+		if v {
+			return "true()", nil
+		}
+		return "false()", nil
+	case int:
+		return xpathNumber(float64(v), strconv.FormatInt(int64(v), 10)), nil
+	case int8:
+		return xpathNumber(float64(v), strconv.FormatInt(int64(v), 10)), nil
+	case int16:
+		return xpathNumber(float64(v), strconv.FormatInt(int64(v), 10)), nil
+	case int32:
+		return xpathNumber(float64(v), strconv.FormatInt(int64(v), 10)), nil
+	case int64:
+		return xpathNumber(float64(v), strconv.FormatInt(v, 10)), nil
+	case uint:
+		return strconv.FormatUint(uint64(v), 10), nil
+	case uint8:
+		return strconv.FormatUint(uint64(v), 10), nil
+	case uint16:
+		return strconv.FormatUint(uint64(v), 10), nil
+	case uint32:
+		return strconv.FormatUint(uint64(v), 10), nil
+	case uint64:
+		return strconv.FormatUint(v, 10), nil
+	case float32:
+		return xpathFloat(float64(v)), nil
+	case float64:
+		return xpathFloat(v), nil
+	}
+	return "", fmt.Errorf("unsupported type %T", v)
+}
+
+// xpathNumber returns s, the decimal form of f, parenthesized if negative
+// so that it binds as a single operand.
+func xpathNumber(f float64, s string) string {
+	if f < 0 {
+		return "(" + s + ")"
+	}
+	return s
+}
+
+// xpathFloat returns f as an XPath expression. XPath 1.0 numbers have no
+// exponent, and the special values are written as divisions.
+func xpathFloat(f float64) string {
+	switch {
+	case math.IsNaN(f):
+		return "(0 div 0)"
+	case math.IsInf(f, 1):
+		return "(1 div 0)"
+	case math.IsInf(f, -1):
+		return "(-1 div 0)"
+	}
+	return xpathNumber(f, strconv.FormatFloat(f, 'f', -1, 64))
+}
+
+// quoteXPathString returns s as an XPath string literal. XPath 1.0 has no
+// escapes, so a string with both kinds of quotes is built with concat().
+func quoteXPathString(s string) string {
+	if !strings.Contains(s, "'") {
+		return "'" + s + "'"
+	}
+	if !strings.Contains(s, `"`) {
+		return `"` + s + `"`
+	}
+	var parts []string
+	for _, part := range strings.SplitAfter(s, "'") {
+		if part == "" {
+			continue
+		}
+		// Each part but the last ends with ', which is quoted on its own.
+		if strings.HasSuffix(part, "'") {
+			if part != "'" {
+				parts = append(parts, quoteXPathString(part[:len(part)-1]))
+			}
+			parts = append(parts, `"'"`)
+		} else {
+			parts = append(parts, "'"+part+"'")
+		}
+	}
+	return "concat(" + strings.Join(parts, ", ") + ")"
+}
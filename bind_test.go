@@ -0,0 +1,69 @@
+package xmlquery
+
+import (
+	"math"
+	"strings"
+	"testing"
+)
+
+func TestBindQuery(t *testing.T) {
+	tests := []struct {
+		expr string
+		args []interface{}
+		want string
+	}{
+		{"//user[@name=?]", []interface{}{"bob"}, "//user[@name='bob']"},
+		{"//user[@name=?]", []interface{}{"o'hara"}, `//user[@name="o'hara"]`},
+		{"//user[@name=?]", []interface{}{`a'b"c'`}, `//user[@name=concat('a', "'", 'b"c', "'")]`},
+		{"//user[@age>? and @score<?]", []interface{}{18, -1.5}, "//user[@age>18 and @score<(-1.5)]"},
+		{"//user[@active=?][@name='?']", []interface{}{true}, "//user[@active=true()][@name='?']"},
+		{"//x[. = ?]", []interface{}{math.NaN()}, "//x[. = (0 div 0)]"},
+		{"//x[. = ?]", []interface{}{1e21}, "//x[. = 1000000000000000000000]"},
+	}
+	for _, test := range tests {
+		got, err := BindQuery(test.expr, test.args...)
+		if err != nil {
+			t.Fatal(err)
+		}
+		testValue(t, got, test.want)
+	}
+
+	for _, test := range []struct {
+		expr string
+		args []interface{}
+	}{
+		{"//user[@name=?]", nil},
+		{"//user", []interface{}{"x"}},
+		{"//user[@name=?]", []interface{}{[]string{"x"}}},
+		{"//user[@name='?]", []interface{}{"x"}},
+	} {
+		if _, err := BindQuery(test.expr, test.args...); err == nil {
+			t.Errorf("BindQuery(%q, %v): no error", test.expr, test.args)
+		}
+	}
+}
+
+func TestQueryWithArgs(t *testing.T) {
+	doc, err := Parse(strings.NewReader(`<users><user name="o'hara" age="40"/><user name="bob" age="12"/><user name="a'b&quot;c"/></users>`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// An injection attempt is compared as a plain string.
+	nodes, err := QueryAllWithArgs(doc, "//user[@name=?]", "x' or '1'='1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	testValue(t, len(nodes), 0)
+
+	n, err := QueryWithArgs(doc, "//user[@name=? and @age>?]", "o'hara", 18)
+	if err != nil {
+		t.Fatal(err)
+	}
+	testValue(t, n.SelectAttr("age"), "40")
+
+	n, err = QueryWithArgs(doc, "//user[@name=?]", `a'b"c`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	testTrue(t, n != nil)
+}
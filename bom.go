@@ -0,0 +1,52 @@
+package xmlquery
+
+import (
+	"bufio"
+	"bytes"
+
+	"golang.org/x/text/encoding/unicode"
+	"golang.org/x/text/transform"
+)
+
+var utf8BOM = []byte("\uFEFF")
+
+// decodeBOM looks for a byte order mark at the start of r and returns the
+// encoding it names, "UTF-8", "UTF-16LE" or "UTF-16BE", or "" if there is
+// none. UTF-16 input is returned converted to UTF-8, without the mark; a
+// UTF-8 mark is left in the input, so that offsets keep referring to it.
+func decodeBOM(r *bufio.Reader) (string, *bufio.Reader) {
+	b, _ := r.Peek(len(utf8BOM))
+	switch {
+	case bytes.HasPrefix(b, utf8BOM):
+		return "UTF-8", r
+	case bytes.HasPrefix(b, []byte{0xFF, 0xFE}):
+		return "UTF-16LE", decodeUTF16(r, unicode.LittleEndian)
+	case bytes.HasPrefix(b, []byte{0xFE, 0xFF}):
+		return "UTF-16BE", decodeUTF16(r, unicode.BigEndian)
+	}
+	return "", r
+}
+
+func decodeUTF16(r *bufio.Reader, e unicode.Endianness) *bufio.Reader {
+	decoder := unicode.UTF16(e, unicode.ExpectBOM).NewDecoder()
+	return bufio.NewReader(transform.NewReader(r, decoder))
+}
+
+// BOM returns the encoding named by the byte order mark the parsed input of
+// n's document started with: "UTF-8", "UTF-16LE" or "UTF-16BE", or "" if
+// the input had none. Such a mark is not part of the tree; write one with
+// WithBOM.
+func (n *Node) BOM() string {
+	if n.doc == nil {
+		return ""
+	}
+	return n.doc.bom
+}
+
+// WithBOM starts the output with a UTF-8 byte order mark, for consumers
+// that need one to recognize the encoding.
+func WithBOM() OutputOption {
+	return func(oc *outputConfiguration) {
+		oc.bom = true
+	}
+}
@@ -0,0 +1,99 @@
+package xmlquery
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"golang.org/x/text/encoding/unicode"
+)
+
+func TestParseBOM(t *testing.T) {
+	const s = `<?xml version="1.0" encoding="UTF-16"?><a x="é">text</a>`
+	utf16 := func(e unicode.Endianness) string {
+		b, err := unicode.UTF16(e, unicode.UseBOM).NewEncoder().String(s)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return b
+	}
+	for _, test := range []struct {
+		input, bom string
+	}{
+		{s, ""},
+		{"\uFEFF" + strings.Replace(s, "UTF-16", "UTF-8", 1), "UTF-8"},
+		{utf16(unicode.LittleEndian), "UTF-16LE"},
+		{utf16(unicode.BigEndian), "UTF-16BE"},
+	} {
+		input := test.input
+		if test.bom == "" {
+			input = strings.Replace(input, ` encoding="UTF-16"`, "", 1)
+		}
+		doc, err := Parse(strings.NewReader(input))
+		if err != nil {
+			t.Fatalf("%s: %v", test.bom, err)
+		}
+		testValue(t, doc.BOM(), test.bom)
+		a := FindOne(doc, "/a")
+		if a == nil {
+			t.Fatalf("%s: no root element", test.bom)
+		}
+		testValue(t, a.SelectAttr("x"), "é")
+		testValue(t, a.InnerText(), "text")
+		for c := doc.FirstChild; c != nil; c = c.NextSibling {
+			if c.Type == TextNode {
+				t.Errorf("%s: unexpected text %q before the root element", test.bom, c.Data)
+			}
+		}
+
+		var got []string
+		err = MatchStream(strings.NewReader(input), "/a", func(n *Node) error {
+			got = append(got, n.SelectAttr("x"))
+			return nil
+		})
+		if err != nil {
+			t.Fatalf("%s: %v", test.bom, err)
+		}
+		testValue(t, strings.Join(got, ","), "é")
+	}
+}
+
+func TestParseBOMRoundTrip(t *testing.T) {
+	const s = "\uFEFF<a>b</a>"
+	doc, err := ParseWithOptions(strings.NewReader(s), ParserOptions{RoundTrip: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	testValue(t, doc.OutputXMLWithOptions(WithBOM()), s)
+	testValue(t, doc.OutputXML(true), "<a>b</a>")
+}
+
+func TestOutputXMLWithBOM(t *testing.T) {
+	doc, err := Parse(strings.NewReader("<a>b</a>"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var b bytes.Buffer
+	doc.WriteWithOptions(&b, WithBOM(), WithOutDeclarationNode())
+	testValue(t, b.String(), "\uFEFF<a>b</a>")
+}
+
+func TestQueryAndWriteWithBOM(t *testing.T) {
+	doc, err := Parse(strings.NewReader(`<list><item>1</item><item>2</item><item>3</item></list>`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var b bytes.Buffer
+	count, err := QueryAndWrite(doc, "//item", &b, "\n", WithBOM())
+	if err != nil {
+		t.Fatal(err)
+	}
+	testValue(t, count, 3)
+	testValue(t, b.String(), "\uFEFF<item>1</item>\n<item>2</item>\n<item>3</item>")
+
+	b.Reset()
+	if _, err := QueryAndWrite(doc, "//none", &b, "\n", WithBOM()); err != nil {
+		t.Fatal(err)
+	}
+	testValue(t, b.String(), "")
+}
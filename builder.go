@@ -0,0 +1,336 @@
+package xmlquery
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/antchfx/xpath"
+)
+
+// A Path is an XPath location path built step by step, as an alternative to
+// writing the expression by hand. Names are checked and values are written
+// as literals, so that a Path always compiles, and the steps of a query can
+// be shared and composed as Go values:
+//
+//	price := xmlquery.Desc("item").Where(xmlquery.Attribute("id").Eq(id)).Child("price")
+//	n, err := price.Query(doc) // //item[@id='7']/price
+//
+// A Path is immutable: each method returns a new one. A Path starting with
+// Root or Desc is absolute; one starting with Child, Attribute, Self or Text is
+// relative to the node it is evaluated from or, in Where, to the node being
+// filtered. An invalid name or value is reported by Compile and the
+// functions evaluating the path.
+type Path struct {
+	expr string
+	err  error
+}
+
+// A Cond is a condition of Path.Where, made with the comparison methods of
+// Path, And, Or and Not.
+type Cond struct {
+	expr string
+	err  error
+}
+
+// Root returns the path of the document node, "/".
+func Root() Path { return Path{expr: "/"} }
+
+// Self returns the path of the context node, ".".
+func Self() Path { return Path{expr: "."} }
+
+// Desc returns the path of the elements named name in the document, as in
+// "//name". name is a name as in "prefix:local", or "*" for any element.
+func Desc(name string) Path { return Root().Desc(name) }
+
+// Child returns the relative path of the child elements named name, see
+// Desc.
+func Child(name string) Path { return Path{}.step("", name) }
+
+// Attribute returns the relative path of the attribute named name, "@name".
+func Attribute(name string) Path { return Path{}.step("@", name) }
+
+// Text returns the relative path of the child text nodes, "text()".
+func Text() Path { return Path{expr: "text()"} }
+
+// step appends a step with a name test to p.
+func (p Path) step(axis, name string) Path {
+	if p.err != nil {
+		return p
+	}
+	if !isXPathNameTest(name) {
+		return Path{err: fmt.Errorf("xmlquery: invalid name %q in query", name)}
+	}
+	return Path{expr: p.join("/") + axis + name}
+}
+
+// join returns p followed by sep, if p isn't empty, so that another step
+// can be appended.
+func (p Path) join(sep string) string {
+	switch p.expr {
+	case "":
+		return ""
+	case "/":
+		return sep
+	}
+	return p.expr + sep
+}
+
+// Child returns the path of the child elements of p named name, see Desc.
+func (p Path) Child(name string) Path { return p.step("", name) }
+
+// Desc returns the path of the descendant elements of p named name, as in
+// "p//name", see Desc.
+func (p Path) Desc(name string) Path {
+	base := p.expr
+	switch base {
+	case "/":
+		base = ""
+	case "":
+		base = "."
+	}
+	step := p.step("", name)
+	if step.err != nil {
+		return step
+	}
+	return Path{expr: base + "//" + name}
+}
+
+// Attribute returns the path of the attributes of p named name.
+func (p Path) Attribute(name string) Path { return p.step("@", name) }
+
+// Text returns the path of the child text nodes of p.
+func (p Path) Text() Path { return p.then("text()") }
+
+// Parent returns the path of the parents of p.
+func (p Path) Parent() Path { return p.then("..") }
+
+func (p Path) then(step string) Path {
+	if p.err != nil {
+		return p
+	}
+	return Path{expr: p.join("/") + step}
+}
+
+// Where returns the nodes of p that meet all the conditions.
+func (p Path) Where(conds ...Cond) Path {
+	return p.predicate(And(conds...))
+}
+
+// At returns the node of p at position i, counted from 1 among the nodes
+// of its last step with the same parent, as in "p[i]".
+func (p Path) At(i int) Path {
+	return p.predicate(Cond{expr: strconv.Itoa(i)})
+}
+
+// Last returns the last node of p among the nodes of its last step with the
+// same parent, as in "p[last()]".
+func (p Path) Last() Path {
+	return p.predicate(Cond{expr: "last()"})
+}
+
+func (p Path) predicate(c Cond) Path {
+	switch {
+	case p.err != nil:
+		return p
+	case c.err != nil:
+		return Path{err: c.err}
+	case p.expr == "" || p.expr == "/":
+		return Path{err: fmt.Errorf("xmlquery: predicate [%s] without a step to apply it to", c.expr)}
+	case c.expr == "true()":
+		return p
+	}
+	return Path{expr: p.expr + "[" + c.expr + "]"}
+}
+
+// Union returns the nodes of p and of the other paths, in document order.
+func (p Path) Union(paths ...Path) Path {
+	exprs := make([]string, 0, len(paths)+1)
+	for _, q := range append([]Path{p}, paths...) {
+		if err := q.Err(); err != nil {
+			return Path{err: err}
+		}
+		exprs = append(exprs, q.expr)
+	}
+	return Path{expr: "(" + strings.Join(exprs, " | ") + ")"}
+}
+
+// Eq returns the condition that p has a node whose value is v, as in
+// "p = v". v is another Path, or a value written as a literal as by
+// BindQuery.
+func (p Path) Eq(v interface{}) Cond { return p.compare("=", v) }
+
+// Ne returns the condition that p has a node whose value isn't v, see Eq.
+func (p Path) Ne(v interface{}) Cond { return p.compare("!=", v) }
+
+// Lt returns the condition that p has a node whose value is less than v,
+// see Eq.
+func (p Path) Lt(v interface{}) Cond { return p.compare("<", v) }
+
+// Le returns the condition that p has a node whose value is less than or
+// equal to v, see Eq.
+func (p Path) Le(v interface{}) Cond { return p.compare("<=", v) }
+
+// Gt returns the condition that p has a node whose value is greater than
+// v, see Eq.
+func (p Path) Gt(v interface{}) Cond { return p.compare(">", v) }
+
+// Ge returns the condition that p has a node whose value is greater than or
+// equal to v, see Eq.
+func (p Path) Ge(v interface{}) Cond { return p.compare(">=", v) }
+
+func (p Path) compare(op string, v interface{}) Cond {
+	if p.err != nil {
+		return Cond{err: p.err}
+	}
+	var operand string
+	switch v := v.(type) {
+	case Path:
+		if err := v.Err(); err != nil {
+			return Cond{err: err}
+		}
+		operand = v.expr
+	default:
+		lit, err := xpathLiteral(v)
+		if err != nil {
+			return Cond{err: fmt.Errorf("xmlquery: comparing %s: %v", p.expr, err)}
+		}
+		operand = lit
+	}
+	return Cond{expr: p.expr + op + operand}
+}
+
+// Contains returns the condition that the value of the first node of p
+// contains s.
+func (p Path) Contains(s string) Cond { return p.call("contains", s) }
+
+// StartsWith returns the condition that the value of the first node of p
+// starts with s.
+func (p Path) StartsWith(s string) Cond { return p.call("starts-with", s) }
+
+func (p Path) call(fn, s string) Cond {
+	if p.err != nil {
+		return Cond{err: p.err}
+	}
+	return Cond{expr: fn + "(" + p.expr + ", " + quoteXPathString(s) + ")"}
+}
+
+// Exists returns the condition that p has a node.
+func (p Path) Exists() Cond {
+	if p.err != nil {
+		return Cond{err: p.err}
+	}
+	return Cond{expr: p.expr}
+}
+
+// And returns the condition that all of conds are met, which is always the
+// case if there are none.
+func And(conds ...Cond) Cond { return combine(" and ", "true()", conds) }
+
+// Or returns the condition that any of conds is met, which is never the
+// case if there are none.
+func Or(conds ...Cond) Cond {
+	c := combine(" or ", "false()", conds)
+	if len(conds) > 1 && c.err == nil {
+		c.expr = "(" + c.expr + ")"
+	}
+	return c
+}
+
+// Not returns the condition that c is not met.
+func Not(c Cond) Cond {
+	if c.err != nil {
+		return c
+	}
+	return Cond{expr: "not(" + c.expr + ")"}
+}
+
+func combine(op, empty string, conds []Cond) Cond {
+	if len(conds) == 0 {
+		return Cond{expr: empty}
+	}
+	exprs := make([]string, len(conds))
+	for i, c := range conds {
+		if c.err != nil {
+			return c
+		}
+		exprs[i] = c.expr
+	}
+	return Cond{expr: strings.Join(exprs, op)}
+}
+
+// String returns the XPath expression of p, or an empty string if p is
+// invalid.
+func (p Path) String() string { return p.expr }
+
+// String returns the XPath expression of c, or an empty string if c is
+// invalid.
+func (c Cond) String() string { return c.expr }
+
+// Err returns the error found while building p, if any.
+func (p Path) Err() error {
+	if p.err == nil && p.expr == "" {
+		return fmt.Errorf("xmlquery: empty path")
+	}
+	return p.err
+}
+
+// Compile returns the compiled expression of p.
+func (p Path) Compile() (*xpath.Expr, error) {
+	if err := p.Err(); err != nil {
+		return nil, err
+	}
+	return getQuery(p.expr)
+}
+
+// CompileFor is like Compile, for an expression to evaluate against the
+// tree of top, whose names may match case-insensitively.
+func (p Path) CompileFor(top *Node) (*xpath.Expr, error) {
+	if err := p.Err(); err != nil {
+		return nil, err
+	}
+	return getQueryFor(top, p.expr)
+}
+
+// QueryAll returns the nodes of p, evaluated from top, see QueryAll.
+func (p Path) QueryAll(top *Node) ([]*Node, error) {
+	if err := p.Err(); err != nil {
+		return nil, err
+	}
+	return QueryAll(top, p.expr)
+}
+
+// Query returns the first node of p, evaluated from top, see Query.
+func (p Path) Query(top *Node) (*Node, error) {
+	if err := p.Err(); err != nil {
+		return nil, err
+	}
+	return Query(top, p.expr)
+}
+
+// isXPathNameTest reports whether name is a name test of XPath: a
+// qualified name, "*" or "prefix:*".
+func isXPathNameTest(name string) bool {
+	if name == "*" {
+		return true
+	}
+	prefix, local, ok := strings.Cut(name, ":")
+	if !ok {
+		return isNCName(name)
+	}
+	return isNCName(prefix) && (local == "*" || isNCName(local))
+}
+
+// isNCName reports whether s is a name without colon.
+func isNCName(s string) bool {
+	for i, r := range s {
+		switch {
+		case unicode.IsLetter(r) || r == '_':
+		case i > 0 && (unicode.IsDigit(r) || r == '-' || r == '.' || unicode.Is(unicode.Mn, r) || r == '\u00b7'):
+		default:
+			return false
+		}
+	}
+	return s != ""
+}
@@ -0,0 +1,63 @@
+package xmlquery
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestPathBuilder(t *testing.T) {
+	tests := []struct {
+		path Path
+		want string
+	}{
+		{Desc("item"), "//item"},
+		{Root().Child("shop").Child("item"), "/shop/item"},
+		{Child("item").Desc("price"), "item//price"},
+		{Self().Desc("*"), ".//*"},
+		{Desc("item").Where(Attribute("id").Eq(7)).Child("price"), "//item[@id=7]/price"},
+		{Desc("item").Where(Child("name").Eq(`it's "x"`)), `//item[name=concat('it', "'", 's "x"')]`},
+		{Desc("item").Where(Attribute("id").Ne("1"), Or(Child("price").Gt(3.5), Not(Child("price").Exists()))), "//item[@id!='1' and (price>3.5 or not(price))]"},
+		{Desc("item").Where(Child("name").StartsWith("Co"), Attribute("sku").Eq(Child("code"))), "//item[starts-with(name, 'Co') and @sku=code]"},
+		{Desc("item").Where(), "//item"},
+		{Desc("item").At(2).Attribute("id"), "//item[2]/@id"},
+		{Desc("p:price").Parent().Text(), "//p:price/../text()"},
+		{Desc("name").Union(Desc("p:price")).Last(), "(//name | //p:price)[last()]"},
+	}
+	for _, test := range tests {
+		testValue(t, test.path.String(), test.want)
+		if _, err := test.path.Compile(); err != nil {
+			t.Errorf("%s: %v", test.want, err)
+		}
+	}
+
+	doc, err := Parse(strings.NewReader(`<shop xmlns:p="urn:price">
+  <item id="1"><name>Tea</name><p:price>3</p:price></item>
+  <item id="2"><name>Coffee</name><p:price>4.5</p:price></item>
+</shop>`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	n, err := Desc("item").Where(Child("p:price").Gt(4)).Child("name").Query(doc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	testValue(t, n.InnerText(), "Coffee")
+	nodes, err := Child("item").Attribute("id").QueryAll(FindOne(doc, "/shop"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	testValue(t, len(nodes), 2)
+
+	for _, p := range []Path{
+		Desc("bad name"),
+		Child("1st"),
+		Desc("item").Where(Attribute("x").Eq([]int{1})),
+		Root().Where(Attribute("id").Eq(1)),
+		Desc("a:b:c"),
+		{},
+	} {
+		if _, err := p.QueryAll(doc); err == nil {
+			t.Errorf("%q: expected an error", p.String())
+		}
+	}
+}
@@ -0,0 +1,189 @@
+package xmlquery
+
+import (
+	"errors"
+
+	"github.com/antchfx/xpath"
+	"github.com/suifengpiao14/xmlquery/xml"
+)
+
+// RemoveAll removes every node below top that matches the XPath expression
+// expr from the tree, and returns the number of nodes removed. Attribute
+// matches are removed from their element. A match inside another match is
+// removed together with it and not counted on its own, and the document
+// node itself is never removed:
+//
+//	// Drop credentials before logging a request.
+//	xmlquery.RemoveAll(doc, "//password | //@token")
+func RemoveAll(top *Node, expr string) (int, error) {
+	exp, err := getQueryFor(top, expr)
+	if err != nil {
+		return 0, err
+	}
+	type attrMatch struct {
+		elem *Node
+		name xml.Name
+	}
+	var (
+		nodes   []*Node
+		attrs   []attrMatch
+		matched = make(map[*Node]bool)
+	)
+	t := exp.Select(selectorNavigator(top, exp))
+	for t.MoveNext() {
+		nav := t.Current().(*NodeNavigator)
+		if nav.onKey() {
+			continue
+		}
+		if nav.NodeType() == xpath.AttributeNode {
+			attrs = append(attrs, attrMatch{nav.curr, nav.curr.Attr[nav.attr].Name})
+		} else if nav.curr.Parent != nil {
+			nodes = append(nodes, nav.curr)
+			matched[nav.curr] = true
+		}
+	}
+	// Matches are collected first, as removing nodes while the query is
+	// evaluated would change its result.
+	removedWith := func(n *Node) bool {
+		for p := n; p != nil; p = p.Parent {
+			if matched[p] {
+				return true
+			}
+		}
+		return false
+	}
+	count := 0
+	for _, a := range attrs {
+		if removedWith(a.elem) {
+			continue
+		}
+		if removeAttrByName(a.elem, a.name) {
+			count++
+		}
+	}
+	for _, n := range nodes {
+		if !removedWith(n.Parent) {
+			RemoveFromTree(n)
+			count++
+		}
+	}
+	return count, nil
+}
+
+// removeAttrByName removes the attribute of elem with the given name and
+// reports whether there was one.
+func removeAttrByName(elem *Node, name xml.Name) bool {
+	for i, attr := range elem.Attr {
+		if attr.Name == name {
+			elem.Attr = append(elem.Attr[:i], elem.Attr[i+1:]...)
+			elem.resetAttrIndex()
+			notify(elem, Mutation{Type: AttrRemoved, Target: elem, Name: qualifiedAttrName(&attr), OldValue: attr.Value})
+			return true
+		}
+	}
+	return false
+}
+
+// setAttrByName sets the value of the attribute of elem with the given
+// name.
+func setAttrByName(elem *Node, name xml.Name, value string) {
+	for i, attr := range elem.Attr {
+		if attr.Name == name {
+			elem.Attr[i].Value = value
+			notify(elem, Mutation{Type: AttrSet, Target: elem, Name: qualifiedAttrName(&attr), OldValue: attr.Value})
+			return
+		}
+	}
+}
+
+// DeleteNode is returned by a ForEach callback to remove the node it was
+// called with from the tree.
+var DeleteNode = errors.New("xmlquery: delete node")
+
+// ForEach calls fn with each node below top that matches the XPath
+// expression expr, in document order, and applies what fn returns:
+//
+//   - nil or the node itself leaves the node in place;
+//   - another node replaces it, which is first removed from its own tree,
+//     or, for a document, its root element does;
+//   - the error DeleteNode removes it;
+//   - any other error stops ForEach, which returns it.
+//
+// For an attribute match, fn is called with an AttributeNode; returning
+// another node sets the value of the attribute to the text of that node.
+// Matches are collected before fn is first called, and a match that is no
+// longer below top because an ancestor was replaced or deleted is skipped.
+//
+//	err := xmlquery.ForEach(doc, "//price", func(n *xmlquery.Node) (*xmlquery.Node, error) {
+//		if n.InnerText() == "" {
+//			return nil, xmlquery.DeleteNode
+//		}
+//		n.SetAttr("currency", "EUR")
+//		return nil, nil
+//	})
+func ForEach(top *Node, expr string, fn func(*Node) (*Node, error)) error {
+	exp, err := getQueryFor(top, expr)
+	if err != nil {
+		return err
+	}
+	var (
+		nodes     []*Node
+		attrNames = make(map[*Node]xml.Name)
+	)
+	t := exp.Select(selectorNavigator(top, exp))
+	for t.MoveNext() {
+		nav := t.Current().(*NodeNavigator)
+		if nav.onKey() {
+			continue
+		}
+		n := navigatorNode(nav)
+		if n.Type == AttributeNode {
+			attrNames[n] = nav.curr.Attr[nav.attr].Name
+		}
+		nodes = append(nodes, n)
+	}
+	for _, n := range sortDocumentOrder(nodes) {
+		owner := n
+		if n.Type == AttributeNode {
+			owner = n.Parent
+		}
+		if !isDescendantOrSelf(owner, top) {
+			continue
+		}
+		repl, err := fn(n)
+		switch {
+		case err == DeleteNode:
+			if n.Type == AttributeNode {
+				removeAttrByName(owner, attrNames[n])
+			} else {
+				RemoveFromTree(n)
+			}
+		case err != nil:
+			return err
+		case repl == nil || repl == n:
+		case n.Type == AttributeNode:
+			setAttrByName(owner, attrNames[n], repl.InnerText())
+		case n.Parent != nil:
+			if repl.Type == DocumentNode {
+				if repl = firstChildElement(repl); repl == nil {
+					RemoveFromTree(n)
+					continue
+				}
+			}
+			RemoveFromTree(repl)
+			setLevel(repl, n.level)
+			replaceNode(n, repl)
+		}
+	}
+	return nil
+}
+
+// isDescendantOrSelf reports whether n is top or one of its descendants.
+func isDescendantOrSelf(n, top *Node) bool {
+	for ; n != nil; n = n.Parent {
+		if n == top {
+			return true
+		}
+	}
+	return false
+}
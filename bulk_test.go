@@ -0,0 +1,122 @@
+package xmlquery
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestRemoveAll(t *testing.T) {
+	doc, err := Parse(strings.NewReader(`<r token="t"><user id="1"><password>x</password><secret><password>y</password></secret></user><secret token="u"/><password/></r>`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var mutations []MutationType
+	doc.Observe(func(m *Mutation) {
+		mutations = append(mutations, m.Type)
+	})
+	n, err := RemoveAll(doc, "//secret | //password | //@token")
+	if err != nil {
+		t.Fatal(err)
+	}
+	testValue(t, n, 5)
+	testValue(t, len(mutations), 5)
+	testValue(t, doc.OutputXML(false), `<?xml version="1.0"?><r><user id="1"></user></r>`)
+	verifyNodePointers(t, doc)
+
+	n, err = RemoveAll(doc, "//password")
+	testValue(t, n, 0)
+	testTrue(t, err == nil)
+
+	if _, err := RemoveAll(doc, "//["); err == nil {
+		t.Fatal("expected error for invalid expression")
+	}
+
+	// The document itself is not removed.
+	n, _ = RemoveAll(doc, "/ | /r")
+	testValue(t, n, 1)
+	testTrue(t, doc.FirstChild != nil)
+}
+
+func TestForEach(t *testing.T) {
+	doc, err := Parse(strings.NewReader(`<r><item p:c="usd" xmlns:p="urn:p"><price>1</price></item><item><price/></item><old><price>3</price></old><note/></r>`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var seen []string
+	err = ForEach(doc, "//price | //old | //@p:c | //note", func(n *Node) (*Node, error) {
+		seen = append(seen, n.Data)
+		switch {
+		case n.Type == AttributeNode:
+			return &Node{Type: TextNode, Data: strings.ToUpper(n.InnerText())}, nil
+		case n.Data == "old":
+			repl, _ := Parse(strings.NewReader(`<new><price>3</price></new>`))
+			return repl, nil
+		case n.Data == "note":
+			return n, nil
+		case n.InnerText() == "":
+			return nil, DeleteNode
+		}
+		amount := &Node{Type: ElementNode, Data: "amount"}
+		AddChild(amount, &Node{Type: TextNode, Data: n.InnerText()})
+		return amount, nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The price inside <old> went away with it.
+	testValue(t, strings.Join(seen, ","), "c,price,price,old,note")
+	testValue(t, doc.OutputXML(false), `<?xml version="1.0"?><r><item p:c="USD" xmlns:p="urn:p"><amount>1</amount></item><item></item><new><price>3</price></new><note></note></r>`)
+	testValue(t, FindOne(doc, "//new/price").level, 3)
+	verifyNodePointers(t, doc)
+
+	stop := ForEach(doc, "//item", func(n *Node) (*Node, error) {
+		return nil, DeleteNode
+	})
+	testTrue(t, stop == nil)
+	testValue(t, len(Find(doc, "//item")), 0)
+
+	errStop := ForEach(doc, "//*", func(n *Node) (*Node, error) {
+		return nil, errTest
+	})
+	testTrue(t, errStop == errTest)
+}
+
+func TestForEachDocumentOrder(t *testing.T) {
+	// The xpath engine returns the nested matches after the outer ones.
+	doc, err := Parse(strings.NewReader(`<r><n id="1"><n id="2"/></n><n id="3"><n id="4"/></n></r>`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var ids []string
+	err = ForEach(doc, "//n[@id]", func(n *Node) (*Node, error) {
+		ids = append(ids, n.SelectAttr("id"))
+		return nil, nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	testValue(t, strings.Join(ids, ","), "1,2,3,4")
+}
+
+func BenchmarkForEach(b *testing.B) {
+	var s strings.Builder
+	s.WriteString("<r>")
+	for i := 0; i < 20000; i++ {
+		fmt.Fprintf(&s, `<item id="%d"/>`, i)
+	}
+	s.WriteString("</r>")
+	doc, err := Parse(strings.NewReader(s.String()))
+	if err != nil {
+		b.Fatal(err)
+	}
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		ForEach(doc, "//item[@id]", func(n *Node) (*Node, error) {
+			return nil, nil
+		})
+	}
+}
+
+var errTest = errors.New("test")
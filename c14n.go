@@ -0,0 +1,332 @@
+package xmlquery
+
+import (
+	"bytes"
+	"errors"
+	"sort"
+	"strings"
+)
+
+// Canonicalization methods supported by Canonicalize.
+const (
+	C14N10                    = "http://www.w3.org/TR/2001/REC-xml-c14n-20010315"
+	C14N10WithComments        = "http://www.w3.org/TR/2001/REC-xml-c14n-20010315#WithComments"
+	C14N11                    = "http://www.w3.org/2006/12/xml-c14n11"
+	C14N11WithComments        = "http://www.w3.org/2006/12/xml-c14n11#WithComments"
+	ExclusiveC14N             = "http://www.w3.org/2001/10/xml-exc-c14n#"
+	ExclusiveC14NWithComments = "http://www.w3.org/2001/10/xml-exc-c14n#WithComments"
+)
+
+// ErrUnsupportedC14N is returned by Canonicalize for unknown methods.
+var ErrUnsupportedC14N = errors.New("xmlquery: unsupported canonicalization method")
+
+// Canonicalize returns the canonical form of the subtree rooted at n, as
+// defined by Canonical XML 1.0 or 1.1 or Exclusive XML Canonicalization
+// 1.0, selected by method. inclusivePrefixes is the InclusiveNamespaces
+// PrefixList of the exclusive methods, with "#default" for the default
+// namespace; it is ignored by the other methods.
+//
+// The canonical form is what XML signatures are computed over: namespace
+// declarations and attributes are sorted, empty elements are written with
+// an end tag, text and attribute values are escaped in a fixed way and the
+// XML declaration and DOCTYPE are dropped.
+func Canonicalize(n *Node, method string, inclusivePrefixes ...string) ([]byte, error) {
+	c, err := newCanonicalizer(method, inclusivePrefixes)
+	if err != nil {
+		return nil, err
+	}
+	var b bytes.Buffer
+	c.write(&b, n)
+	return b.Bytes(), nil
+}
+
+type canonicalizer struct {
+	exclusive bool
+	comments  bool
+	v11       bool            // Canonical XML 1.1
+	inclusive map[string]bool // InclusiveNamespaces PrefixList, for exclusive methods
+	exclude   *Node           // left out of the output, such as an enveloped signature
+}
+
+func newCanonicalizer(method string, inclusivePrefixes []string) (*canonicalizer, error) {
+	c := &canonicalizer{}
+	switch method {
+	case C14N10:
+	case C14N10WithComments:
+		c.comments = true
+	case C14N11:
+		c.v11 = true
+	case C14N11WithComments:
+		c.v11, c.comments = true, true
+	case ExclusiveC14N:
+		c.exclusive = true
+	case ExclusiveC14NWithComments:
+		c.exclusive, c.comments = true, true
+	default:
+		return nil, ErrUnsupportedC14N
+	}
+	if c.exclusive && len(inclusivePrefixes) > 0 {
+		c.inclusive = make(map[string]bool)
+		for _, p := range inclusivePrefixes {
+			if p == "#default" {
+				p = ""
+			}
+			c.inclusive[p] = true
+		}
+	}
+	return c, nil
+}
+
+func (c *canonicalizer) write(b *bytes.Buffer, n *Node) {
+	n.Materialize()
+	if n.Type != DocumentNode {
+		var scope map[string]string
+		if n.Parent != nil {
+			scope = namespacesInScope(n.Parent)
+		} else {
+			scope = namespacesInScope(nil)
+		}
+		c.writeNode(b, n, scope, map[string]string{"": ""}, true)
+		return
+	}
+	// Document: nodes outside the document element are separated from it by
+	// line feeds.
+	seenRoot := false
+	for child := n.FirstChild; child != nil; child = child.NextSibling {
+		switch child.Type {
+		case ElementNode:
+			c.writeNode(b, child, namespacesInScope(nil), map[string]string{"": ""}, true)
+			seenRoot = true
+		case CommentNode, DeclarationNode:
+			if child.Type == CommentNode && !c.comments || child.Type == DeclarationNode && child.Data == "xml" || child == c.exclude {
+				continue
+			}
+			if seenRoot {
+				b.WriteByte('\n')
+			}
+			c.writeNode(b, child, nil, nil, false)
+			if !seenRoot {
+				b.WriteByte('\n')
+			}
+		}
+	}
+}
+
+// writeNode writes n. scope holds the namespace declarations in scope at
+// the parent of n and rendered those written by output ancestors.
+func (c *canonicalizer) writeNode(b *bytes.Buffer, n *Node, scope, rendered map[string]string, apex bool) {
+	if n == c.exclude {
+		return
+	}
+	switch n.Type {
+	case TextNode, CharDataNode:
+		writeC14NText(b, n.Data)
+		return
+	case CommentNode:
+		if c.comments {
+			b.WriteString("<!--")
+			b.WriteString(n.Data)
+			b.WriteString("-->")
+		}
+		return
+	case DeclarationNode:
+		if n.Data == "xml" {
+			return
+		}
+		b.WriteString("<?")
+		b.WriteString(n.Data)
+		if data := n.ProcInstData(); data != "" {
+			b.WriteByte(' ')
+			b.WriteString(data)
+		}
+		b.WriteString("?>")
+		for child := n.FirstChild; child != nil; child = child.NextSibling {
+			c.writeNode(b, child, scope, rendered, false)
+		}
+		return
+	case ElementNode:
+	default:
+		return
+	}
+	n.Materialize()
+
+	local := make(map[string]string, len(scope)+2)
+	for k, v := range scope {
+		local[k] = v
+	}
+	declareNamespaces(n, local)
+	// The element's own namespace is authoritative for its prefix.
+	local[n.Prefix] = n.NamespaceURI
+
+	// Namespace declarations to render, by prefix.
+	decls := make(map[string]string)
+	consider := func(prefix string) {
+		if prefix == "xml" || prefix == "xmlns" {
+			return
+		}
+		uri, ok := local[prefix]
+		if !ok {
+			return
+		}
+		if r, ok := rendered[prefix]; ok && r == uri {
+			return
+		}
+		if prefix != "" && uri == "" {
+			return
+		}
+		decls[prefix] = uri
+	}
+	if c.exclusive {
+		consider(n.Prefix)
+		for _, attr := range n.Attr {
+			if attr.Name.Space != "" && attr.Name.Space != "xmlns" {
+				consider(attr.Name.Space)
+			}
+		}
+		for p := range c.inclusive {
+			consider(p)
+		}
+	} else {
+		for p := range local {
+			consider(p)
+		}
+	}
+
+	var attrs []Attr
+	for _, attr := range n.Attr {
+		if attr.Name.Space == "xmlns" || attr.Name.Space == "" && attr.Name.Local == "xmlns" {
+			continue
+		}
+		if attr.Name.Space == "xml" {
+			attr.NamespaceURI = "http://www.w3.org/XML/1998/namespace"
+		} else if attr.Name.Space != "" {
+			attr.NamespaceURI = local[attr.Name.Space]
+		} else {
+			attr.NamespaceURI = ""
+		}
+		attrs = append(attrs, attr)
+	}
+	if apex && !c.exclusive {
+		// Inherit xml:* attributes of ancestors that are left out. Version
+		// 1.1 doesn't inherit xml:id and combines xml:base values instead.
+		if c.v11 {
+			var bases []string
+			for p := n; p != nil; p = p.Parent {
+				if b := p.SelectAttrNode("xml:base"); b != nil && p.Type == ElementNode {
+					bases = append(bases, b.Value)
+				}
+			}
+			if len(bases) > 1 || len(bases) == 1 && n.SelectAttrNode("xml:base") == nil {
+				base := ""
+				for i := len(bases) - 1; i >= 0; i-- {
+					base = resolveURI(base, bases[i])
+				}
+				attrs = setXMLAttr(attrs, "base", base)
+			}
+		}
+		for p := n.Parent; p != nil; p = p.Parent {
+			for _, attr := range p.Attr {
+				if attr.Name.Space != "xml" || c.v11 && (attr.Name.Local == "id" || attr.Name.Local == "base") {
+					continue
+				}
+				found := false
+				for _, a := range attrs {
+					if a.Name == attr.Name {
+						found = true
+						break
+					}
+				}
+				if !found {
+					attr.NamespaceURI = "http://www.w3.org/XML/1998/namespace"
+					attrs = append(attrs, attr)
+				}
+			}
+		}
+	}
+
+	b.WriteByte('<')
+	b.WriteString(qualifiedName(n))
+	prefixes := make([]string, 0, len(decls))
+	for p := range decls {
+		prefixes = append(prefixes, p)
+	}
+	sort.Strings(prefixes)
+	childRendered := rendered
+	if len(decls) > 0 {
+		childRendered = make(map[string]string, len(rendered)+len(decls))
+		for k, v := range rendered {
+			childRendered[k] = v
+		}
+	}
+	for _, p := range prefixes {
+		if p == "" {
+			b.WriteString(` xmlns="`)
+		} else {
+			b.WriteString(" xmlns:")
+			b.WriteString(p)
+			b.WriteString(`="`)
+		}
+		writeC14NAttrValue(b, decls[p])
+		b.WriteByte('"')
+		childRendered[p] = decls[p]
+	}
+	sort.SliceStable(attrs, func(i, j int) bool {
+		if attrs[i].NamespaceURI != attrs[j].NamespaceURI {
+			return attrs[i].NamespaceURI < attrs[j].NamespaceURI
+		}
+		return attrs[i].Name.Local < attrs[j].Name.Local
+	})
+	for _, attr := range attrs {
+		b.WriteByte(' ')
+		if attr.Name.Space != "" {
+			b.WriteString(attr.Name.Space)
+			b.WriteByte(':')
+		}
+		b.WriteString(attr.Name.Local)
+		b.WriteString(`="`)
+		writeC14NAttrValue(b, attr.Value)
+		b.WriteByte('"')
+	}
+	b.WriteByte('>')
+	for child := n.FirstChild; child != nil; child = child.NextSibling {
+		c.writeNode(b, child, local, childRendered, false)
+	}
+	b.WriteString("</")
+	b.WriteString(qualifiedName(n))
+	b.WriteByte('>')
+}
+
+// setXMLAttr sets the xml:local attribute in attrs.
+func setXMLAttr(attrs []Attr, local, value string) []Attr {
+	for i := range attrs {
+		if attrs[i].Name.Space == "xml" && attrs[i].Name.Local == local {
+			attrs[i].Value = value
+			return attrs
+		}
+	}
+	return append(attrs, Attr{Name: newXMLName("xml:" + local), Value: value, NamespaceURI: "http://www.w3.org/XML/1998/namespace"})
+}
+
+var c14nTextEscaper = strings.NewReplacer(
+	"&", "&amp;",
+	"<", "&lt;",
+	">", "&gt;",
+	"\r", "&#xD;",
+)
+
+var c14nAttrEscaper = strings.NewReplacer(
+	"&", "&amp;",
+	"<", "&lt;",
+	`"`, "&quot;",
+	"\t", "&#x9;",
+	"\n", "&#xA;",
+	"\r", "&#xD;",
+)
+
+func writeC14NText(b *bytes.Buffer, s string) {
+	c14nTextEscaper.WriteString(b, s)
+}
+
+func writeC14NAttrValue(b *bytes.Buffer, s string) {
+	c14nAttrEscaper.WriteString(b, s)
+}
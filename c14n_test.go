@@ -0,0 +1,85 @@
+package xmlquery
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestCanonicalize(t *testing.T) {
+	// Example 3.3 of the Canonical XML 1.0 specification.
+	s := `<doc>
+   <e1   />
+   <e2   ></e2>
+   <e3   name = "elem3"   id="elem3"   />
+   <e4   name="elem4"   id="elem4"   ></e4>
+   <e5 a:attr="out" b:attr="sorted" attr2="all" attr="I'm"
+      xmlns:b="http://www.ietf.org"
+      xmlns:a="http://www.w3.org"
+      xmlns="http://example.org"/>
+   <e6 xmlns="" xmlns:a="http://www.w3.org">
+      <e7 xmlns="http://www.ietf.org">
+         <e8 xmlns="" xmlns:a="http://www.w3.org">
+            <e9 xmlns="" xmlns:a="http://www.ietf.org"/>
+         </e8>
+      </e7>
+   </e6>
+</doc>`
+	expected := `<doc>
+   <e1></e1>
+   <e2></e2>
+   <e3 id="elem3" name="elem3"></e3>
+   <e4 id="elem4" name="elem4"></e4>
+   <e5 xmlns="http://example.org" xmlns:a="http://www.w3.org" xmlns:b="http://www.ietf.org" attr="I'm" attr2="all" b:attr="sorted" a:attr="out"></e5>
+   <e6 xmlns:a="http://www.w3.org">
+      <e7 xmlns="http://www.ietf.org">
+         <e8 xmlns="">
+            <e9 xmlns:a="http://www.ietf.org"></e9>
+         </e8>
+      </e7>
+   </e6>
+</doc>`
+	doc, err := Parse(strings.NewReader(s))
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := Canonicalize(doc, C14N10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	testValue(t, string(b), expected)
+
+	if _, err := Canonicalize(doc, "urn:unknown"); err != ErrUnsupportedC14N {
+		t.Fatalf("expected ErrUnsupportedC14N, got %v", err)
+	}
+}
+
+func TestCanonicalizeEscaping(t *testing.T) {
+	s := "<?xml version=\"1.0\"?>\n<?pi  a=\"1\" ?><!--c--><a t=\"&#9;&#10;&#13;&lt;&amp;&quot;'&gt;\"><![CDATA[<x> & y]]>&#13;</a><!--d-->"
+	doc, err := Parse(strings.NewReader(s))
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, _ := Canonicalize(doc, C14N10)
+	testValue(t, string(b), "<?pi a=\"1\"?>\n<a t=\"&#x9;&#xA;&#xD;&lt;&amp;&quot;'>\">&lt;x&gt; &amp; y&#xD;</a>")
+	b, _ = Canonicalize(doc, C14N10WithComments)
+	testValue(t, string(b), "<?pi a=\"1\"?>\n<!--c-->\n<a t=\"&#x9;&#xA;&#xD;&lt;&amp;&quot;'>\">&lt;x&gt; &amp; y&#xD;</a>\n<!--d-->")
+}
+
+func TestCanonicalizeSubtree(t *testing.T) {
+	s := `<r xmlns="urn:r" xmlns:a="urn:a" xmlns:u="urn:unused" xml:lang="en" xml:id="r"><a:x b="1"><y a:c="2"/></a:x></r>`
+	doc, err := Parse(strings.NewReader(s))
+	if err != nil {
+		t.Fatal(err)
+	}
+	x := FindOne(doc, "//a:x")
+
+	b, _ := Canonicalize(x, C14N10)
+	testValue(t, string(b), `<a:x xmlns="urn:r" xmlns:a="urn:a" xmlns:u="urn:unused" b="1" xml:id="r" xml:lang="en"><y a:c="2"></y></a:x>`)
+	b, _ = Canonicalize(x, C14N11)
+	testValue(t, string(b), `<a:x xmlns="urn:r" xmlns:a="urn:a" xmlns:u="urn:unused" b="1" xml:lang="en"><y a:c="2"></y></a:x>`)
+
+	b, _ = Canonicalize(x, ExclusiveC14N)
+	testValue(t, string(b), `<a:x xmlns:a="urn:a" b="1"><y xmlns="urn:r" a:c="2"></y></a:x>`)
+	b, _ = Canonicalize(x, ExclusiveC14N, "u", "#default")
+	testValue(t, string(b), `<a:x xmlns="urn:r" xmlns:a="urn:a" xmlns:u="urn:unused" b="1"><y a:c="2"></y></a:x>`)
+}
@@ -23,7 +23,7 @@ var (
 
 func getQuery(expr string) (*xpath.Expr, error) {
 	if DisableSelectorCache || SelectorCacheMaxEntries <= 0 {
-		return xpath.Compile(expr)
+		return compileQuery(expr)
 	}
 	cacheOnce.Do(func() {
 		cache = lru.New(SelectorCacheMaxEntries)
@@ -33,7 +33,7 @@ func getQuery(expr string) (*xpath.Expr, error) {
 	if v, ok := cache.Get(expr); ok {
 		return v.(*xpath.Expr), nil
 	}
-	v, err := xpath.Compile(expr)
+	v, err := compileQuery(expr)
 	if err != nil {
 		return nil, err
 	}
@@ -41,3 +41,17 @@ func getQuery(expr string) (*xpath.Expr, error) {
 	return v, nil
 
 }
+
+// compileQuery compiles expr with the rewrites that make up for what the
+// xpath package lacks.
+func compileQuery(expr string) (*xpath.Expr, error) {
+	expr, err := rewriteIDCalls(rewriteProcInstTests(expr))
+	if err != nil {
+		return nil, err
+	}
+	expr, err = rewriteKeyCalls(expr)
+	if err != nil {
+		return nil, err
+	}
+	return xpath.Compile(expr)
+}
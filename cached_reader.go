@@ -10,6 +10,9 @@ type cachedReader struct {
 	cacheCap int
 	cacheLen int
 	caching bool
+	recording bool   // if set, every byte read is appended to recorded
+	recorded  []byte // bytes read since offset recordedBase, see parser.rawSource
+	recordedBase int64
 }
 
 func newCachedReader(r *bufio.Reader) *cachedReader {
@@ -28,13 +31,19 @@ func (c *cachedReader) StartCaching() {
 }
 
 func (c *cachedReader) ReadByte() (byte, error) {
-	if !c.caching {
+	if !c.caching && !c.recording {
 		return c.buffer.ReadByte()
 	}
 	b, err := c.buffer.ReadByte()
 	if err != nil {
 		return b, err
 	}
+	if c.recording {
+		c.recorded = append(c.recorded, b)
+	}
+	if !c.caching {
+		return b, err
+	}
 	if c.cacheLen < c.cacheCap {
 		c.cache[c.cacheLen] = b
 		c.cacheLen++
@@ -55,6 +64,9 @@ func (c *cachedReader) Read(p []byte) (int, error) {
 	if err != nil {
 		return n, err
 	}
+	if c.recording {
+		c.recorded = append(c.recorded, p[:n]...)
+	}
 	if c.caching && c.cacheLen < c.cacheCap {
 		for i := 0; i < n; i++ {
 			c.cache[c.cacheLen] = p[i]
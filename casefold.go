@@ -0,0 +1,77 @@
+package xmlquery
+
+import (
+	"strings"
+
+	"github.com/antchfx/xpath"
+)
+
+// SetCaseInsensitiveNames sets whether the element and attribute names of
+// the tree of n match whatever their case, see
+// ParserOptions.CaseInsensitiveNames. The names are kept as written, and
+// the output is unchanged.
+//
+// Queries given as strings, such as to Find, QueryAll and Evaluate, have
+// their names lowered, and the navigators report the names of elements and
+// attributes in lower case, so name() and local-name() return lower-case
+// names. Expressions compiled beforehand, for QuerySelectorAll, must use
+// lower-case names, or be compiled with Path.CompileFor. Values, such as
+// those of attributes, still compare case-sensitively.
+func (n *Node) SetCaseInsensitiveNames(on bool) {
+	for n.Parent != nil {
+		n = n.Parent
+	}
+	n.docData().foldCase = on
+}
+
+// foldsCase reports whether names match case-insensitively in the tree of
+// n.
+func (n *Node) foldsCase() bool {
+	for n.Parent != nil {
+		n = n.Parent
+	}
+	return n.doc != nil && n.doc.foldCase
+}
+
+// foldName returns name in lower case if x folds names.
+func (x *NodeNavigator) foldName(name string) string {
+	if x.fold {
+		return strings.ToLower(name)
+	}
+	return name
+}
+
+// getQueryFor is like getQuery, for an expression to evaluate against the
+// tree of top, whose names may match case-insensitively.
+func getQueryFor(top *Node, expr string) (*xpath.Expr, error) {
+	if top.foldsCase() {
+		expr = lowerQueryNames(expr)
+	}
+	return getQuery(expr)
+}
+
+// lowerQueryNames returns expr in lower case, except for its string
+// literals. Function names, axes and operators are in lower case already.
+func lowerQueryNames(expr string) string {
+	var b strings.Builder
+	for i := 0; i < len(expr); {
+		c := expr[i]
+		if c != '\'' && c != '"' {
+			j := strings.IndexAny(expr[i:], `'"`)
+			if j < 0 {
+				j = len(expr) - i
+			}
+			b.WriteString(strings.ToLower(expr[i : i+j]))
+			i += j
+			continue
+		}
+		end := strings.IndexByte(expr[i+1:], c)
+		if end < 0 {
+			b.WriteString(expr[i:])
+			break
+		}
+		b.WriteString(expr[i : i+end+2])
+		i += end + 2
+	}
+	return b.String()
+}
@@ -0,0 +1,141 @@
+package xmlquery
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCaseInsensitiveNames(t *testing.T) {
+	s := `<Catalog><ITEM ID="1" Name="Tea"/><item id="2" name="Coffee"/><Item Id="3" NAME="o'Clock"/></Catalog>`
+	doc, err := ParseWithOptions(strings.NewReader(s), ParserOptions{CaseInsensitiveNames: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	testValue(t, len(Find(doc, "/catalog/Item")), 3)
+	testValue(t, len(Find(doc, "//ITEM[@name='Coffee']")), 1)
+	// Values still compare case-sensitively.
+	testValue(t, len(Find(doc, "//item[@name='coffee']")), 0)
+	testValue(t, FindOne(doc, `//item[@NAME="o'Clock"]`).SelectAttr("id"), "3")
+	testValue(t, FindOne(doc, "//item").SelectAttr("name"), "Tea")
+	testValue(t, len(doc.SelectElement("CATALOG").SelectElements("item")), 3)
+	r, err := Evaluate(doc, "count(//Item)")
+	if err != nil {
+		t.Fatal(err)
+	}
+	testValue(t, r.Number(), float64(3))
+	r, err = Evaluate(doc, "name(/*)")
+	if err != nil {
+		t.Fatal(err)
+	}
+	testValue(t, r.String(), "catalog")
+	var b strings.Builder
+	count, err := QueryAndWrite(doc, "//item/@ID", &b, ",")
+	if err != nil {
+		t.Fatal(err)
+	}
+	testValue(t, count, 3)
+	testValue(t, b.String(), "1,2,3")
+	var names []string
+	ForEach(doc, "//ITEM", func(n *Node) (*Node, error) {
+		names = append(names, n.Data)
+		return nil, nil
+	})
+	testValue(t, strings.Join(names, ","), "ITEM,item,Item")
+	// The names are kept as written.
+	testValue(t, FindOne(doc, "//item").OutputXML(true), `<ITEM ID="1" Name="Tea"></ITEM>`)
+
+	doc.SetCaseInsensitiveNames(false)
+	testValue(t, len(Find(doc, "//item")), 1)
+	testValue(t, FindOne(doc, "//item").SelectAttr("ID"), "")
+
+	doc, err = Parse(strings.NewReader(s))
+	if err != nil {
+		t.Fatal(err)
+	}
+	testValue(t, len(Find(doc, "//item")), 1)
+	FindOne(doc, "//item").SetCaseInsensitiveNames(true)
+	testValue(t, len(Find(doc, "//item")), 3)
+}
+
+func TestCaseInsensitiveNamesEntryPoints(t *testing.T) {
+	const s = `<Catalog><ITEM ID="1"/><Item Id="2"/></Catalog>`
+	parse := func() *Node {
+		doc, err := ParseWithOptions(strings.NewReader(s), ParserOptions{CaseInsensitiveNames: true})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return doc
+	}
+	doc := parse()
+
+	nodes, err := QueryAllWithOptions(doc, "//Item", QueryOptions{Timeout: time.Minute})
+	if err != nil {
+		t.Fatal(err)
+	}
+	testValue(t, len(nodes), 2)
+
+	docs, err := Split(doc, "//Item")
+	if err != nil {
+		t.Fatal(err)
+	}
+	testValue(t, len(docs), 2)
+
+	var b strings.Builder
+	rows, err := ExtractCSV(doc, "//Item", []ColumnSpec{{Name: "id", XPath: "@id"}}, &b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	testValue(t, rows, 2)
+	testValue(t, b.String(), "id\n1\n2\n")
+
+	nodes, exp, err := Explain(doc, "/CATALOG/Item")
+	if err != nil {
+		t.Fatal(err)
+	}
+	testValue(t, len(nodes), 2)
+	testValue(t, exp.Steps[len(exp.Steps)-1].Matches, 2)
+
+	p, err := Desc("Item").CompileFor(doc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	testValue(t, len(QuerySelectorAll(doc, p)), 2)
+
+	set := NewDocumentSet()
+	set.Add("other.xml", parse())
+	nodes, err = set.QueryAll(doc, "document('other.xml')//Item")
+	if err != nil {
+		t.Fatal(err)
+	}
+	testValue(t, len(nodes), 2)
+
+	idx, err := doc.CreateIndex("Item", "@ID")
+	if err != nil {
+		t.Fatal(err)
+	}
+	testValue(t, len(idx.Lookup("2")), 1)
+
+	sp, err := CreateStreamParserWithOptions(strings.NewReader(s), ParserOptions{CaseInsensitiveNames: true}, "//Item")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 2; i++ {
+		if _, err := sp.Read(); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	pp := NewPullParser(strings.NewReader(s), ParserOptions{CaseInsensitiveNames: true})
+	n, err := pp.NextElement("Item")
+	if err != nil {
+		t.Fatal(err)
+	}
+	testValue(t, n.Data, "ITEM")
+
+	removed, err := RemoveAll(doc, "//Item")
+	if err != nil {
+		t.Fatal(err)
+	}
+	testValue(t, removed, 2)
+}
@@ -0,0 +1,269 @@
+package xmlquery
+
+import (
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+)
+
+// CatalogNamespace is the namespace of OASIS XML Catalog files.
+const CatalogNamespace = "urn:oasis:names:tc:entity:xmlns:xml:catalog"
+
+// A Catalog maps the public and system identifiers of DTDs and entities,
+// and URIs such as schema locations, to local copies, following the OASIS
+// XML Catalogs specification. It is an EntityResolver, so that documents
+// referring to remote DTDs can be parsed offline:
+//
+//	catalog, err := xmlquery.LoadCatalog("/etc/xml/catalog")
+//	doc, err := xmlquery.ParseWithOptions(r, xmlquery.ParserOptions{EntityResolver: catalog})
+//
+// The public, system, rewriteSystem, systemSuffix, uri, rewriteURI,
+// uriSuffix, group and nextCatalog entries are supported, as well as the
+// prefer and xml:base attributes. Delegate entries are ignored.
+type Catalog struct {
+	// Fallback, if set, resolves the entities the catalog has no entry
+	// for. By default they are skipped.
+	Fallback EntityResolver
+
+	entries []catalogEntry
+	mu      sync.Mutex
+	next    []string // URIs of the nextCatalog entries not loaded yet
+	loaded  []*Catalog
+}
+
+// catalogEntry is an entry of a catalog file. key is the identifier, URI,
+// prefix or suffix it matches, and uri the absolute URI it maps to.
+type catalogEntry struct {
+	kind   string // local name of the entry element
+	key    string
+	uri    string
+	public bool // prefer="public" applies
+}
+
+// LoadCatalog reads the catalog file at path. Catalogs referred to by its
+// nextCatalog entries are read when they are first needed.
+func LoadCatalog(path string) (*Catalog, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return ParseCatalog(f, path)
+}
+
+// ParseCatalog reads a catalog file from r. Relative URIs of the catalog
+// are resolved against base, the path or URI it was read from.
+func ParseCatalog(r io.Reader, base string) (*Catalog, error) {
+	doc, err := Parse(r)
+	if err != nil {
+		return nil, err
+	}
+	root := doc.SelectElement("*")
+	if root == nil || root.Data != "catalog" || root.NamespaceURI != CatalogNamespace {
+		return nil, fmt.Errorf("xmlquery: %s is not an XML catalog", base)
+	}
+	c := &Catalog{}
+	c.readEntries(root, base, true)
+	return c, nil
+}
+
+// readEntries adds the entries of the catalog or group element n.
+func (c *Catalog) readEntries(n *Node, base string, public bool) {
+	base, public = catalogScope(n, base, public)
+	for child := n.FirstChild; child != nil; child = child.NextSibling {
+		if child.Type != ElementNode || child.NamespaceURI != CatalogNamespace {
+			continue
+		}
+		entryBase, entryPublic := catalogScope(child, base, public)
+		entry := catalogEntry{kind: child.Data, public: entryPublic}
+		switch child.Data {
+		case "group":
+			c.readEntries(child, base, public)
+			continue
+		case "nextCatalog":
+			c.next = append(c.next, resolveURI(entryBase, child.SelectAttr("catalog")))
+			continue
+		case "public":
+			entry.key = normalizePublicID(child.SelectAttr("publicId"))
+			entry.uri = child.SelectAttr("uri")
+		case "system":
+			entry.key = child.SelectAttr("systemId")
+			entry.uri = child.SelectAttr("uri")
+		case "rewriteSystem":
+			entry.key = child.SelectAttr("systemIdStartString")
+			entry.uri = child.SelectAttr("rewritePrefix")
+		case "systemSuffix":
+			entry.key = child.SelectAttr("systemIdSuffix")
+			entry.uri = child.SelectAttr("uri")
+		case "uri":
+			entry.key = child.SelectAttr("name")
+			entry.uri = child.SelectAttr("uri")
+		case "rewriteURI":
+			entry.key = child.SelectAttr("uriStartString")
+			entry.uri = child.SelectAttr("rewritePrefix")
+		case "uriSuffix":
+			entry.key = child.SelectAttr("uriSuffix")
+			entry.uri = child.SelectAttr("uri")
+		default:
+			continue
+		}
+		if entry.key == "" || entry.uri == "" {
+			continue
+		}
+		entry.uri = resolveURI(entryBase, entry.uri)
+		c.entries = append(c.entries, entry)
+	}
+}
+
+// catalogScope returns the base URI and prefer setting of the catalog
+// element n, which inherits base and public from its parent.
+func catalogScope(n *Node, base string, public bool) (string, bool) {
+	for _, attr := range n.Attr {
+		switch {
+		case attr.Name.Local == "base" && attr.Name.Space == "xml":
+			base = resolveURI(base, attr.Value)
+		case attr.Name.Local == "prefer" && attr.Name.Space == "":
+			public = attr.Value != "system"
+		}
+	}
+	return base, public
+}
+
+// normalizePublicID collapses the white space of a public identifier.
+func normalizePublicID(id string) string {
+	return strings.Join(strings.Fields(id), " ")
+}
+
+// ResolveExternalID returns the URI of the local copy of the resource with
+// the given public and system identifiers, either of which may be empty.
+// System entries are tried first, then public entries, which are only used
+// for a resource with a system identifier if they were declared with
+// prefer="public", the default. ok is false if no entry matches.
+func (c *Catalog) ResolveExternalID(publicID, systemID string) (uri string, ok bool) {
+	publicID = normalizePublicID(publicID)
+	if systemID != "" {
+		if uri, ok := c.match(systemID, "system", "rewriteSystem", "systemSuffix"); ok {
+			return uri, true
+		}
+	}
+	if publicID != "" {
+		for _, e := range c.entries {
+			if e.kind == "public" && e.key == publicID && (e.public || systemID == "") {
+				return e.uri, true
+			}
+		}
+	}
+	for _, next := range c.nextCatalogs() {
+		if uri, ok := next.ResolveExternalID(publicID, systemID); ok {
+			return uri, true
+		}
+	}
+	return "", false
+}
+
+// ResolveURI returns the URI of the local copy of the resource at uri, such
+// as an XML Schema location, using the uri, rewriteURI and uriSuffix
+// entries. ok is false if no entry matches.
+func (c *Catalog) ResolveURI(uri string) (string, bool) {
+	if resolved, ok := c.match(uri, "uri", "rewriteURI", "uriSuffix"); ok {
+		return resolved, true
+	}
+	for _, next := range c.nextCatalogs() {
+		if resolved, ok := next.ResolveURI(uri); ok {
+			return resolved, true
+		}
+	}
+	return "", false
+}
+
+// match looks s up in the entries of the given kinds: an exact match first,
+// then the longest matching prefix, then the longest matching suffix.
+func (c *Catalog) match(s, exact, prefix, suffix string) (string, bool) {
+	for _, e := range c.entries {
+		if e.kind == exact && e.key == s {
+			return e.uri, true
+		}
+	}
+	var best *catalogEntry
+	for i := range c.entries {
+		e := &c.entries[i]
+		if e.kind == prefix && strings.HasPrefix(s, e.key) && (best == nil || len(e.key) > len(best.key)) {
+			best = e
+		}
+	}
+	if best != nil {
+		return best.uri + s[len(best.key):], true
+	}
+	for i := range c.entries {
+		e := &c.entries[i]
+		if e.kind == suffix && strings.HasSuffix(s, e.key) && (best == nil || len(e.key) > len(best.key)) {
+			best = e
+		}
+	}
+	if best != nil {
+		return best.uri, true
+	}
+	return "", false
+}
+
+// nextCatalogs returns the catalogs of the nextCatalog entries, loading
+// them the first time. Catalogs that can't be read are skipped, as the
+// specification requires.
+func (c *Catalog) nextCatalogs() []*Catalog {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if len(c.next) > 0 {
+		for _, uri := range c.next {
+			r, err := openLocalURI(uri)
+			if err != nil {
+				continue
+			}
+			next, err := ParseCatalog(r, uri)
+			r.Close()
+			if err == nil {
+				c.loaded = append(c.loaded, next)
+			}
+		}
+		c.next = nil
+	}
+	return c.loaded
+}
+
+// ResolveEntity implements EntityResolver by opening the local copy of the
+// resource. Resources the catalog has no entry for are passed to Fallback.
+func (c *Catalog) ResolveEntity(publicID, systemID string) (io.ReadCloser, error) {
+	uri, ok := c.ResolveExternalID(publicID, systemID)
+	if !ok {
+		if c.Fallback != nil {
+			return c.Fallback.ResolveEntity(publicID, systemID)
+		}
+		return nil, nil
+	}
+	return openLocalURI(uri)
+}
+
+// Open opens the local copy of the resource at uri, see ResolveURI. It
+// returns an error wrapping os.ErrNotExist if the catalog has no entry for
+// it.
+func (c *Catalog) Open(uri string) (io.ReadCloser, error) {
+	resolved, ok := c.ResolveURI(uri)
+	if !ok {
+		return nil, fmt.Errorf("xmlquery: no catalog entry for %q: %w", uri, os.ErrNotExist)
+	}
+	return openLocalURI(resolved)
+}
+
+// openLocalURI opens a file given by path or file URI.
+func openLocalURI(uri string) (io.ReadCloser, error) {
+	u, err := url.Parse(uri)
+	if err != nil || u.Scheme == "" || len(u.Scheme) == 1 { // path, maybe with a drive letter
+		return os.Open(uri)
+	}
+	if u.Scheme != "file" {
+		return nil, fmt.Errorf("xmlquery: %q is not a local file", uri)
+	}
+	return os.Open(u.Path)
+}
@@ -0,0 +1,99 @@
+package xmlquery
+
+import (
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestCatalog(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"catalog.xml": `<catalog xmlns="urn:oasis:names:tc:entity:xmlns:xml:catalog">
+			<public publicId="-//Test//DTD   Doc//EN" uri="dtd/doc.dtd"/>
+			<system systemId="http://example.com/chapter.txt" uri="text/chapter.txt"/>
+			<rewriteSystem systemIdStartString="http://example.com/dtd/" rewritePrefix="dtd/"/>
+			<group prefer="system" xml:base="dtd/">
+				<public publicId="-//Test//DTD Other//EN" uri="other.dtd"/>
+			</group>
+			<uri name="http://example.com/schema.xsd" uri="xsd/schema.xsd"/>
+			<uriSuffix uriSuffix="/common.xsd" uri="xsd/common.xsd"/>
+			<nextCatalog catalog="next/catalog.xml"/>
+			<nextCatalog catalog="missing.xml"/>
+		</catalog>`,
+		"next/catalog.xml": `<catalog xmlns="urn:oasis:names:tc:entity:xmlns:xml:catalog">
+			<rewriteURI uriStartString="http://example.com/types/" rewritePrefix="../xsd/"/>
+		</catalog>`,
+		"dtd/doc.dtd":      `<!ENTITY dtd "from dtd">`,
+		"text/chapter.txt": `chapter text`,
+		"xsd/schema.xsd":   `<schema/>`,
+		"xsd/types.xsd":    `<types/>`,
+	}
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	catalog, err := LoadCatalog(filepath.Join(dir, "catalog.xml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	resolve := func(publicID, systemID string) string {
+		uri, ok := catalog.ResolveExternalID(publicID, systemID)
+		if !ok {
+			return "-"
+		}
+		return strings.TrimPrefix(uri, dir+"/")
+	}
+	testValue(t, resolve("-//Test//DTD Doc//EN", "http://example.com/doc.dtd"), "dtd/doc.dtd")
+	testValue(t, resolve("", "http://example.com/chapter.txt"), "text/chapter.txt")
+	testValue(t, resolve("", "http://example.com/dtd/sub/a.dtd"), "dtd/sub/a.dtd")
+	testValue(t, resolve("-//Test//DTD Other//EN", ""), "dtd/other.dtd")
+	testValue(t, resolve("-//Test//DTD Other//EN", "http://example.com/other.dtd"), "-")
+	testValue(t, resolve("-//Test//DTD Unknown//EN", "unknown.dtd"), "-")
+
+	resolveURI := func(uri string) string {
+		resolved, ok := catalog.ResolveURI(uri)
+		if !ok {
+			return "-"
+		}
+		return strings.TrimPrefix(resolved, dir+"/")
+	}
+	testValue(t, resolveURI("http://example.com/schema.xsd"), "xsd/schema.xsd")
+	testValue(t, resolveURI("http://other.org/x/common.xsd"), "xsd/common.xsd")
+	testValue(t, resolveURI("http://example.com/types/types.xsd"), "xsd/types.xsd")
+	testValue(t, resolveURI("http://example.com/none.xsd"), "-")
+
+	r, err := catalog.Open("http://example.com/types/types.xsd")
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, _ := io.ReadAll(r)
+	r.Close()
+	testValue(t, string(b), "<types/>")
+	if _, err := catalog.Open("http://example.com/none.xsd"); !errors.Is(err, os.ErrNotExist) {
+		t.Fatalf("expected a not exist error, got %v", err)
+	}
+
+	s := `<!DOCTYPE doc PUBLIC "-//Test//DTD Doc//EN" "http://example.com/doc.dtd" [
+		<!ENTITY chapter SYSTEM "http://example.com/chapter.txt">
+		<!ENTITY unknown SYSTEM "http://example.com/unknown.txt">
+	]><doc>&dtd; &chapter;</doc>`
+	doc, err := ParseWithOptions(strings.NewReader(s), ParserOptions{EntityResolver: catalog})
+	if err != nil {
+		t.Fatal(err)
+	}
+	testValue(t, FindOne(doc, "/doc").InnerText(), "from dtd chapter text")
+
+	if _, err := ParseCatalog(strings.NewReader(`<catalog/>`), "x.xml"); err == nil {
+		t.Fatal("expected an error for a document that is not a catalog")
+	}
+}
@@ -0,0 +1,311 @@
+// Command xmlquery runs XPath expressions against an XML document and
+// prints the matches, which is handy for trying out expressions before
+// using them in code.
+//
+// Usage:
+//
+//	xmlquery [flags] expr [file ...]
+//	xmlquery [flags] -e expr [-e expr ...] [file ...]
+//	xmlquery gostruct [-package name] [file ...]
+//
+// The document is read from the files, or from standard input if there are
+// none or a file is "-". Each match is printed as XML, as its text value or
+// as JSON, depending on -o. Expressions that don't return nodes, such as
+// count(//item), print their value.
+//
+// Flags:
+//
+//	-e expr         XPath expression to run; may be repeated
+//	-o format       output format: xml (default), text or json
+//	-ns prefix=uri  bind a namespace prefix used in the expressions; may be repeated
+//	-indent         pretty-print XML output
+//
+// The exit status is 0 if any expression matched, 1 if none did and 2 if
+// an error occurred.
+//
+// The gostruct subcommand prints Go structs with xml tags inferred from the
+// documents taken as samples, see xmlquery.GenerateGoStructs, with a
+// package clause if -package is given. To query elements named gostruct,
+// use -e.
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"strings"
+
+	"github.com/antchfx/xpath"
+	"github.com/suifengpiao14/xmlquery"
+)
+
+func main() {
+	os.Exit(run(os.Args[1:], os.Stdin, os.Stdout, os.Stderr))
+}
+
+// listFlag is a flag that may be given more than once.
+type listFlag []string
+
+func (l *listFlag) String() string { return strings.Join(*l, ", ") }
+
+func (l *listFlag) Set(s string) error {
+	*l = append(*l, s)
+	return nil
+}
+
+func run(args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	if len(args) > 0 && args[0] == "gostruct" {
+		return runGoStruct(args[1:], stdin, stdout, stderr)
+	}
+	flags := flag.NewFlagSet("xmlquery", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	var exprs, namespaces listFlag
+	flags.Var(&exprs, "e", "XPath `expr`ession to run; may be repeated")
+	flags.Var(&namespaces, "ns", "bind a namespace `prefix=uri`; may be repeated")
+	format := flags.String("o", "xml", "output `format`: xml, text or json")
+	indent := flags.Bool("indent", false, "pretty-print XML output")
+	flags.Usage = func() {
+		fmt.Fprintln(stderr, "usage: xmlquery [flags] expr [file ...]")
+		fmt.Fprintln(stderr, "       xmlquery [flags] -e expr [-e expr ...] [file ...]")
+		flags.PrintDefaults()
+	}
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+	files := flags.Args()
+	if len(exprs) == 0 {
+		if len(files) == 0 {
+			flags.Usage()
+			return 2
+		}
+		exprs, files = listFlag{files[0]}, files[1:]
+	}
+	if *format != "xml" && *format != "text" && *format != "json" {
+		fmt.Fprintf(stderr, "xmlquery: unknown output format %q\n", *format)
+		return 2
+	}
+
+	var ns map[string]string // nil keeps prefixes matching literally
+	for _, binding := range namespaces {
+		prefix, uri, ok := strings.Cut(binding, "=")
+		if !ok || prefix == "" {
+			fmt.Fprintf(stderr, "xmlquery: invalid namespace binding %q, want prefix=uri\n", binding)
+			return 2
+		}
+		if ns == nil {
+			ns = make(map[string]string)
+		}
+		ns[prefix] = uri
+	}
+	selectors := make([]*xpath.Expr, len(exprs))
+	for i, expr := range exprs {
+		var err error
+		if selectors[i], err = xmlquery.Compile(expr, ns); err != nil {
+			fmt.Fprintf(stderr, "xmlquery: %s: %v\n", expr, err)
+			return 2
+		}
+	}
+
+	if len(files) == 0 {
+		files = []string{"-"}
+	}
+	w := bufio.NewWriter(stdout)
+	defer w.Flush()
+	p := &printer{w: w, format: *format, indent: *indent, multi: len(exprs) > 1}
+	matched := false
+	for _, file := range files {
+		doc, err := load(file, stdin)
+		if err != nil {
+			fmt.Fprintf(stderr, "xmlquery: %v\n", err)
+			return 2
+		}
+		for i, selector := range selectors {
+			if p.print(exprs[i], xmlquery.EvaluateSelector(doc, selector)) {
+				matched = true
+			}
+		}
+	}
+	if err := p.finish(); err != nil {
+		fmt.Fprintf(stderr, "xmlquery: %v\n", err)
+		return 2
+	}
+	if !matched {
+		return 1
+	}
+	return 0
+}
+
+// runGoStruct runs the gostruct subcommand.
+func runGoStruct(args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	flags := flag.NewFlagSet("xmlquery gostruct", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	pkg := flags.String("package", "", "write a package clause for package `name`")
+	flags.Usage = func() {
+		fmt.Fprintln(stderr, "usage: xmlquery gostruct [-package name] [file ...]")
+		flags.PrintDefaults()
+	}
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+	files := flags.Args()
+	if len(files) == 0 {
+		files = []string{"-"}
+	}
+	var samples []*xmlquery.Node
+	for _, file := range files {
+		doc, err := load(file, stdin)
+		if err != nil {
+			fmt.Fprintf(stderr, "xmlquery: %v\n", err)
+			return 2
+		}
+		samples = append(samples, doc)
+	}
+	src, err := xmlquery.GenerateGoStructs(xmlquery.GoStructOptions{Package: *pkg}, samples...)
+	if err != nil {
+		fmt.Fprintf(stderr, "xmlquery: %v\n", err)
+		return 2
+	}
+	if _, err := stdout.Write(src); err != nil {
+		fmt.Fprintf(stderr, "xmlquery: %v\n", err)
+		return 2
+	}
+	return 0
+}
+
+func load(file string, stdin io.Reader) (*xmlquery.Node, error) {
+	if file == "-" {
+		return xmlquery.Parse(stdin)
+	}
+	doc, err := xmlquery.LoadFile(file)
+	if err != nil {
+		var perr *xmlquery.ParseError
+		if errors.As(err, &perr) {
+			return nil, fmt.Errorf("%s: %v", file, err)
+		}
+		return nil, err
+	}
+	return doc, nil
+}
+
+type printer struct {
+	w      *bufio.Writer
+	format string
+	indent bool
+	multi  bool // more than one expression, so JSON results are grouped
+
+	results []jsonResult
+}
+
+type jsonResult struct {
+	Expr    string        `json:"expr"`
+	Matches []interface{} `json:"matches"`
+}
+
+// jsonNode is the JSON form of a matched node.
+type jsonNode struct {
+	Type       string            `json:"type"`
+	Name       string            `json:"name,omitempty"`
+	Path       string            `json:"path"`
+	Value      string            `json:"value"`
+	Attributes map[string]string `json:"attributes,omitempty"`
+}
+
+// print prints the result of expr and reports whether it matched anything.
+func (p *printer) print(expr string, r *xmlquery.Result) bool {
+	if p.format == "json" {
+		matches := []interface{}{}
+		switch r.Type {
+		case xmlquery.NodeSetResult:
+			for _, n := range r.Nodes() {
+				matches = append(matches, newJSONNode(n))
+			}
+		case xmlquery.NumberResult:
+			if f := r.Number(); math.IsNaN(f) || math.IsInf(f, 0) {
+				matches = append(matches, nil)
+			} else {
+				matches = append(matches, f)
+			}
+		case xmlquery.BooleanResult:
+			matches = append(matches, r.Bool())
+		default:
+			matches = append(matches, r.String())
+		}
+		p.results = append(p.results, jsonResult{Expr: expr, Matches: matches})
+		return len(matches) > 0
+	}
+	if r.Type != xmlquery.NodeSetResult {
+		fmt.Fprintln(p.w, r.String())
+		return true
+	}
+	for _, n := range r.Nodes() {
+		if p.format == "text" || n.Type == xmlquery.AttributeNode {
+			fmt.Fprintln(p.w, n.InnerText())
+			continue
+		}
+		opts := []xmlquery.OutputOption{xmlquery.WithOutputSelf(), xmlquery.WithPreserveSpace()}
+		if p.indent {
+			opts = []xmlquery.OutputOption{xmlquery.WithOutputSelf(), xmlquery.WithIndentation("  ")}
+		}
+		// Indented output starts with a line break.
+		fmt.Fprintln(p.w, strings.TrimPrefix(n.OutputXMLWithOptions(opts...), "\n"))
+	}
+	return len(r.Nodes()) > 0
+}
+
+func (p *printer) finish() error {
+	if p.format != "json" {
+		return nil
+	}
+	enc := json.NewEncoder(p.w)
+	enc.SetIndent("", "  ")
+	if p.multi {
+		return enc.Encode(p.results)
+	}
+	var matches []interface{}
+	for _, r := range p.results {
+		matches = append(matches, r.Matches...)
+	}
+	if matches == nil {
+		matches = []interface{}{}
+	}
+	return enc.Encode(matches)
+}
+
+func newJSONNode(n *xmlquery.Node) *jsonNode {
+	j := &jsonNode{Path: n.Path(), Value: n.InnerText()}
+	switch n.Type {
+	case xmlquery.ElementNode:
+		j.Type = "element"
+		j.Name = qualifiedName(n.Prefix, n.Data)
+		if len(n.Attr) > 0 {
+			j.Attributes = make(map[string]string, len(n.Attr))
+			for _, attr := range n.Attr {
+				j.Attributes[qualifiedName(attr.Name.Space, attr.Name.Local)] = attr.Value
+			}
+		}
+	case xmlquery.AttributeNode:
+		j.Type = "attribute"
+		j.Name = n.Data
+	case xmlquery.TextNode, xmlquery.CharDataNode:
+		j.Type = "text"
+	case xmlquery.CommentNode:
+		j.Type = "comment"
+	case xmlquery.DocumentNode:
+		j.Type = "document"
+	default:
+		j.Type = "other"
+	}
+	return j
+}
+
+func qualifiedName(prefix, local string) string {
+	if prefix == "" {
+		return local
+	}
+	return prefix + ":" + local
+}
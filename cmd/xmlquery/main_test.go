@@ -0,0 +1,120 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const testDoc = `<?xml version="1.0"?>
+<shop xmlns:p="urn:price">
+  <item id="1"><name>Tea</name><p:price>3</p:price></item>
+  <item id="2"><name>Coffee</name><p:price>4.5</p:price></item>
+</shop>`
+
+func runWith(t *testing.T, stdin string, args ...string) (string, string, int) {
+	t.Helper()
+	var stdout, stderr bytes.Buffer
+	code := run(args, strings.NewReader(stdin), &stdout, &stderr)
+	return stdout.String(), stderr.String(), code
+}
+
+func TestRun(t *testing.T) {
+	tests := []struct {
+		args []string
+		out  string
+		code int
+	}{
+		{[]string{"//name"}, "<name>Tea</name>\n<name>Coffee</name>\n", 0},
+		{[]string{"-o", "text", "//item/@id"}, "1\n2\n", 0},
+		{[]string{"-o", "text", "-e", "//name", "-e", "sum(//p:price)"}, "Tea\nCoffee\n7.5\n", 0},
+		{[]string{"-ns", "x=urn:price", "-o", "text", "//x:price"}, "3\n4.5\n", 0},
+		{[]string{"-indent", "//item[1]"}, "<item id=\"1\" xmlns:p=\"urn:price\">\n  <name>Tea</name>\n  <p:price>3</p:price>\n</item>\n", 0},
+		{[]string{"//missing"}, "", 1},
+		{[]string{"-o", "json", "//item[2]/name"}, `[
+  {
+    "type": "element",
+    "name": "name",
+    "path": "/shop[1]/item[2]/name[1]",
+    "value": "Coffee"
+  }
+]
+`, 0},
+		{[]string{"-o", "json", "-e", "count(//item)", "-e", "//item[1]/@id"}, `[
+  {
+    "expr": "count(//item)",
+    "matches": [
+      2
+    ]
+  },
+  {
+    "expr": "//item[1]/@id",
+    "matches": [
+      {
+        "type": "attribute",
+        "name": "id",
+        "path": "/shop[1]/item[1]/@id",
+        "value": "1"
+      }
+    ]
+  }
+]
+`, 0},
+	}
+	for _, test := range tests {
+		out, stderr, code := runWith(t, testDoc, test.args...)
+		if out != test.out || code != test.code {
+			t.Errorf("%v: got %q (exit %d, %s), want %q (exit %d)", test.args, out, code, stderr, test.out, test.code)
+		}
+	}
+}
+
+func TestRunFiles(t *testing.T) {
+	dir := t.TempDir()
+	a := filepath.Join(dir, "a.xml")
+	os.WriteFile(a, []byte(`<r><v>a</v></r>`), 0o644)
+	bad := filepath.Join(dir, "bad.xml")
+	os.WriteFile(bad, []byte(`<r><v>`), 0o644)
+
+	out, _, code := runWith(t, `<r><v>stdin</v></r>`, "-o", "text", "//v", a, "-")
+	testOutput(t, out, "a\nstdin\n", code, 0)
+
+	_, stderr, code := runWith(t, "", "//v", bad)
+	testOutput(t, "", "", code, 2)
+	if !strings.Contains(stderr, "bad.xml") {
+		t.Errorf("expected file name in error, got %q", stderr)
+	}
+
+	for _, args := range [][]string{{}, {"//v["}, {"-o", "yaml", "//v"}, {"-ns", "x", "//v"}} {
+		if _, _, code := runWith(t, `<r/>`, args...); code != 2 {
+			t.Errorf("%v: expected exit 2, got %d", args, code)
+		}
+	}
+}
+
+func testOutput(t *testing.T, out, want string, code, wantCode int) {
+	t.Helper()
+	if out != want || code != wantCode {
+		t.Errorf("got %q (exit %d), want %q (exit %d)", out, code, want, wantCode)
+	}
+}
+
+func TestRunGoStruct(t *testing.T) {
+	out, _, code := runWith(t, testDoc, "gostruct", "-package", "shop")
+	testOutput(t, out, "package shop\n\nimport \"encoding/xml\"\n\n"+
+		"type Shop struct {\n"+
+		"\tXMLName xml.Name `xml:\"shop\"`\n"+
+		"\tItem    []Item   `xml:\"item\"`\n"+
+		"}\n\n"+
+		"type Item struct {\n"+
+		"\tID    int     `xml:\"id,attr\"`\n"+
+		"\tName  string  `xml:\"name\"`\n"+
+		"\tPrice float64 `xml:\"urn:price price\"`\n"+
+		"}\n", code, 0)
+
+	if _, _, code := runWith(t, `<r>`, "gostruct"); code != 2 {
+		t.Errorf("expected exit 2 for an invalid sample, got %d", code)
+	}
+}
@@ -0,0 +1,67 @@
+package xmlquery
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/antchfx/xpath"
+)
+
+// A ColumnSpec describes one column of the output of ExtractCSV.
+type ColumnSpec struct {
+	// Name is written to the header row.
+	Name string
+	// XPath is evaluated relative to each row node. A node-set result uses
+	// the value of its first node, other results are formatted as strings.
+	XPath string
+	// Join, if not empty, joins the values of all nodes of a node-set
+	// result instead of only using the first one.
+	Join string
+}
+
+// ExtractCSV writes one CSV record to w for every node matched by rowXPath,
+// with one field per column evaluated relative to that row. A header row
+// with the column names is written first. It returns the number of rows
+// written, not counting the header.
+func ExtractCSV(doc *Node, rowXPath string, columns []ColumnSpec, w io.Writer) (int, error) {
+	rowExpr, err := getQueryFor(doc, rowXPath)
+	if err != nil {
+		return 0, fmt.Errorf("xmlquery: invalid row expression '%s': %s", rowXPath, err.Error())
+	}
+	exprs := make([]*xpath.Expr, len(columns))
+	header := make([]string, len(columns))
+	for i, col := range columns {
+		if exprs[i], err = getQueryFor(doc, col.XPath); err != nil {
+			return 0, fmt.Errorf("xmlquery: invalid expression '%s' for column %s: %s", col.XPath, col.Name, err.Error())
+		}
+		header[i] = col.Name
+	}
+
+	cw := csv.NewWriter(w)
+	if err := cw.Write(header); err != nil {
+		return 0, err
+	}
+	rows := 0
+	record := make([]string, len(columns))
+	for _, row := range QuerySelectorAll(doc, rowExpr) {
+		for i, col := range columns {
+			values := evalStrings(exprs[i], row)
+			switch {
+			case len(values) == 0:
+				record[i] = ""
+			case col.Join != "":
+				record[i] = strings.Join(values, col.Join)
+			default:
+				record[i] = values[0]
+			}
+		}
+		if err := cw.Write(record); err != nil {
+			return rows, err
+		}
+		rows++
+	}
+	cw.Flush()
+	return rows, cw.Error()
+}
@@ -0,0 +1,39 @@
+package xmlquery
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestExtractCSV(t *testing.T) {
+	s := `<report>
+		<order id="1"><customer>Smith, Jane</customer><line sku="a">2</line><line sku="b">3</line></order>
+		<order id="2"><customer>Doe "JD"</customer><line sku="c">1</line></order>
+	</report>`
+	doc, err := Parse(strings.NewReader(s))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var b strings.Builder
+	rows, err := ExtractCSV(doc, "//order", []ColumnSpec{
+		{Name: "id", XPath: "@id"},
+		{Name: "customer", XPath: "customer"},
+		{Name: "skus", XPath: "line/@sku", Join: ";"},
+		{Name: "quantity", XPath: "sum(line)"},
+		{Name: "missing", XPath: "note"},
+	}, &b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	testValue(t, rows, 2)
+	testValue(t, b.String(), "id,customer,skus,quantity,missing\n"+
+		"1,\"Smith, Jane\",a;b,5,\n"+
+		"2,\"Doe \"\"JD\"\"\",c,1,\n")
+
+	if _, err := ExtractCSV(doc, "//order[", nil, &b); err == nil {
+		t.Fatal("expected error for invalid row expression")
+	}
+	if _, err := ExtractCSV(doc, "//order", []ColumnSpec{{Name: "x", XPath: "@id["}}, &b); err == nil {
+		t.Fatal("expected error for invalid column expression")
+	}
+}
@@ -0,0 +1,411 @@
+package xmlquery
+
+import (
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/antchfx/xpath"
+)
+
+// A DocumentSet is a collection of documents, keyed by URI, that queries
+// can reach with the XPath document() function, to join a data file
+// against a lookup table kept in another file:
+//
+//	set := xmlquery.NewDocumentSet()
+//	items, err := set.QueryAll(orders, "//item[@code = document('codes.xml')//code[@active]/@id]")
+//
+// Documents are loaded the first time they are referred to and kept for
+// later queries. A DocumentSet may be used by several goroutines at once,
+// as long as its documents aren't modified.
+type DocumentSet struct {
+	// Loader loads the document at an absolute or relative URI. By default,
+	// http and https URIs are fetched with LoadURL and other URIs are read
+	// with LoadFile.
+	Loader func(uri string) (*Node, error)
+
+	mu   sync.Mutex
+	docs map[string]*Node
+	uris []string
+}
+
+// NewDocumentSet returns an empty DocumentSet.
+func NewDocumentSet() *DocumentSet {
+	return &DocumentSet{docs: make(map[string]*Node)}
+}
+
+// Add adds doc to s under uri, replacing any document with that URI. The
+// document URI of doc is set to uri if it has none.
+func (s *DocumentSet) Add(uri string, doc *Node) {
+	if doc.DocumentURI() == "" {
+		doc.SetDocumentURI(uri)
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.docs[uri]; !ok {
+		s.uris = append(s.uris, uri)
+	}
+	s.docs[uri] = doc
+}
+
+// Document returns the document of s with the given URI, or nil.
+func (s *DocumentSet) Document(uri string) *Node {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.docs[uri]
+}
+
+// Documents returns the documents of s in the order they were added or
+// loaded.
+func (s *DocumentSet) Documents() []*Node {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	docs := make([]*Node, len(s.uris))
+	for i, uri := range s.uris {
+		docs[i] = s.docs[uri]
+	}
+	return docs
+}
+
+// Load returns the document of s with the given URI, loading and adding it
+// first if needed.
+func (s *DocumentSet) Load(uri string) (*Node, error) {
+	if doc := s.Document(uri); doc != nil {
+		return doc, nil
+	}
+	load := s.Loader
+	if load == nil {
+		load = loadDocument
+	}
+	doc, err := load(uri)
+	if err != nil {
+		return nil, err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if loaded, ok := s.docs[uri]; ok {
+		return loaded, nil // loaded by another goroutine meanwhile
+	}
+	if doc.DocumentURI() == "" {
+		doc.SetDocumentURI(uri)
+	}
+	s.docs[uri] = doc
+	s.uris = append(s.uris, uri)
+	return doc, nil
+}
+
+// loadDocument is the default DocumentSet loader.
+func loadDocument(uri string) (*Node, error) {
+	u, err := url.Parse(uri)
+	if err != nil {
+		return nil, err
+	}
+	switch u.Scheme {
+	case "http", "https":
+		return LoadURL(uri)
+	case "file":
+		return LoadFile(u.Path)
+	}
+	return LoadFile(uri)
+}
+
+// QueryAll is like the QueryAll function, but expr may call document() with
+// a string literal, the URI of a document of s, which is loaded if needed.
+// A relative URI is resolved against the base URI of top, see BaseURI, and
+// then looked up as written if no document has the resolved URI. The
+// function returns the document node, from which paths continue as usual:
+//
+//	document('codes.xml')/codes/code[@id = 'A1']
+//	//item[@code = document('codes.xml')//code/@id]
+//
+// Absolute paths always start at top, also inside predicates applied to
+// nodes of other documents. Union results are not sorted across documents.
+func (s *DocumentSet) QueryAll(top *Node, expr string) ([]*Node, error) {
+	nav, exp, err := s.compile(top, expr)
+	if err != nil {
+		return nil, err
+	}
+	t := exp.Select(nav)
+	var nodes []*Node
+	for t.MoveNext() {
+		nodes = append(nodes, t.Current().(*documentSetNavigator).node())
+	}
+	return nodes, nil
+}
+
+// Query is like QueryAll, but returns the first match only.
+func (s *DocumentSet) Query(top *Node, expr string) (*Node, error) {
+	nav, exp, err := s.compile(top, expr)
+	if err != nil {
+		return nil, err
+	}
+	t := exp.Select(nav)
+	if t.MoveNext() {
+		return t.Current().(*documentSetNavigator).node(), nil
+	}
+	return nil, nil
+}
+
+// QueryDocuments evaluates expr against every document of s, in the order
+// of Documents, and returns all the matches, for queries across a
+// collection such as "//invoice[total > 1000]".
+func (s *DocumentSet) QueryDocuments(expr string) ([]*Node, error) {
+	var nodes []*Node
+	for _, doc := range s.Documents() {
+		matches, err := s.QueryAll(doc, expr)
+		if err != nil {
+			return nil, err
+		}
+		nodes = append(nodes, matches...)
+	}
+	return nodes, nil
+}
+
+// compile replaces the document() calls of expr with references to
+// the documents they load, see documentSetNavigator, and compiles it.
+func (s *DocumentSet) compile(top *Node, expr string) (*documentSetNavigator, *xpath.Expr, error) {
+	nav := &documentSetNavigator{top: top, doc: -1}
+	nav.NodeNavigator = NodeNavigator{root: top, curr: top, attr: -1, fold: top.foldsCase()}
+	var b strings.Builder
+	for i := 0; i < len(expr); {
+		c := expr[i]
+		switch {
+		case c == '"' || c == '\'':
+			end := strings.IndexByte(expr[i+1:], c)
+			if end < 0 {
+				b.WriteString(expr[i:])
+				i = len(expr)
+				continue
+			}
+			b.WriteString(expr[i : i+end+2])
+			i += end + 2
+			continue
+		case strings.HasPrefix(expr[i:], "document") && !isXPathNameEnd(expr[:i]):
+			uri, n, ok := parseDocumentCall(expr[i+len("document"):])
+			if !ok {
+				break
+			}
+			doc, err := s.resolve(top, uri)
+			if err != nil {
+				return nil, nil, fmt.Errorf("xmlquery: document(%q): %w", uri, err)
+			}
+			index := -1
+			for j, d := range nav.docs {
+				if d == doc {
+					index = j
+				}
+			}
+			if index < 0 {
+				index = len(nav.docs)
+				nav.docs = append(nav.docs, doc)
+			}
+			b.WriteString("(/@" + documentAttrName(index) + ")")
+			i += len("document") + n
+			continue
+		}
+		b.WriteByte(c)
+		i++
+	}
+	exp, err := getQueryFor(top, b.String())
+	if err != nil {
+		return nil, nil, err
+	}
+	return nav, exp, nil
+}
+
+// resolve returns the document a document() call refers to.
+func (s *DocumentSet) resolve(top *Node, uri string) (*Node, error) {
+	if abs, err := top.ResolveURI(uri); err == nil && abs != uri {
+		if doc := s.Document(abs); doc != nil {
+			return doc, nil
+		}
+		if doc := s.Document(uri); doc != nil {
+			return doc, nil
+		}
+		return s.Load(abs)
+	}
+	return s.Load(uri)
+}
+
+// parseDocumentCall parses the rest of a document('uri') call, after the
+// function name, and returns the URI and the length of what it parsed.
+func parseDocumentCall(s string) (uri string, n int, ok bool) {
+	rest := strings.TrimLeft(s, " \t\r\n")
+	if !strings.HasPrefix(rest, "(") {
+		return "", 0, false
+	}
+	rest = strings.TrimLeft(rest[1:], " \t\r\n")
+	if rest == "" || (rest[0] != '"' && rest[0] != '\'') {
+		return "", 0, false
+	}
+	end := strings.IndexByte(rest[1:], rest[0])
+	if end < 0 {
+		return "", 0, false
+	}
+	uri = rest[1 : end+1]
+	after := strings.TrimLeft(rest[end+2:], " \t\r\n")
+	if !strings.HasPrefix(after, ")") {
+		return "", 0, false
+	}
+	return uri, len(s) - len(after) + 1, true
+}
+
+// documentAttrName is the name of the attribute standing for the document
+// with the given index.
+func documentAttrName(index int) string {
+	return "xmlquery-document-" + strconv.Itoa(index)
+}
+
+// documentSetNavigator navigates the tree of top, whose root has extra
+// attributes standing for the documents loaded by the document() calls of
+// a query. The children of such an attribute are those of its document,
+// so that paths continue into it.
+type documentSetNavigator struct {
+	NodeNavigator
+	top  *Node
+	docs []*Node
+	doc  int  // index in docs of the document being navigated, or -1 for top
+	virt bool // positioned on the attribute standing for docs[doc]
+}
+
+func (x *documentSetNavigator) NodeType() xpath.NodeType {
+	if x.virt {
+		return xpath.AttributeNode
+	}
+	return x.NodeNavigator.NodeType()
+}
+
+func (x *documentSetNavigator) LocalName() string {
+	if x.virt {
+		return documentAttrName(x.doc)
+	}
+	return x.NodeNavigator.LocalName()
+}
+
+func (x *documentSetNavigator) Prefix() string {
+	if x.virt {
+		return ""
+	}
+	return x.NodeNavigator.Prefix()
+}
+
+func (x *documentSetNavigator) NamespaceURL() string {
+	if x.virt {
+		return ""
+	}
+	return x.NodeNavigator.NamespaceURL()
+}
+
+func (x *documentSetNavigator) Value() string {
+	if x.virt {
+		return x.docs[x.doc].InnerText()
+	}
+	return x.NodeNavigator.Value()
+}
+
+func (x *documentSetNavigator) String() string {
+	return x.Value()
+}
+
+func (x *documentSetNavigator) Copy() xpath.NodeNavigator {
+	n := *x
+	return &n
+}
+
+func (x *documentSetNavigator) MoveToRoot() {
+	x.NodeNavigator = NodeNavigator{root: x.top, curr: x.top, attr: -1, fold: x.fold}
+	x.doc, x.virt = -1, false
+}
+
+func (x *documentSetNavigator) MoveToParent() bool {
+	switch {
+	case x.virt:
+		x.MoveToRoot()
+		return true
+	case x.doc >= 0 && x.attr == -1 && x.curr.Parent == x.docs[x.doc]:
+		x.virt = true
+		return true
+	}
+	return x.NodeNavigator.MoveToParent()
+}
+
+func (x *documentSetNavigator) MoveToNextAttribute() bool {
+	if x.virt {
+		if x.doc < len(x.docs)-1 {
+			x.doc++
+			return true
+		}
+		return false
+	}
+	if x.NodeNavigator.MoveToNextAttribute() {
+		return true
+	}
+	if x.doc == -1 && x.curr == x.top && len(x.docs) > 0 {
+		x.attr = -1
+		x.doc, x.virt = 0, true
+		return true
+	}
+	return false
+}
+
+func (x *documentSetNavigator) MoveToChild() bool {
+	if !x.virt {
+		return x.NodeNavigator.MoveToChild()
+	}
+	doc := x.docs[x.doc]
+	// The names of the other documents match as those of top do, for
+	// which the query was compiled.
+	nav := NodeNavigator{root: doc, curr: doc, attr: -1, fold: x.fold}
+	if !nav.MoveToChild() {
+		return false
+	}
+	x.NodeNavigator = nav
+	x.virt = false
+	return true
+}
+
+func (x *documentSetNavigator) MoveToFirst() bool {
+	if x.virt {
+		return false
+	}
+	return x.NodeNavigator.MoveToFirst()
+}
+
+func (x *documentSetNavigator) MoveToNext() bool {
+	if x.virt {
+		return false
+	}
+	return x.NodeNavigator.MoveToNext()
+}
+
+// MoveToPrevious moves between the document attributes too, which gives
+// the nodes of each document distinct positions for the xpath package.
+func (x *documentSetNavigator) MoveToPrevious() bool {
+	if x.virt {
+		if x.doc > 0 {
+			x.doc--
+			return true
+		}
+		return false
+	}
+	return x.NodeNavigator.MoveToPrevious()
+}
+
+func (x *documentSetNavigator) MoveTo(other xpath.NodeNavigator) bool {
+	node, ok := other.(*documentSetNavigator)
+	if !ok || node.top != x.top {
+		return false
+	}
+	*x = *node
+	return true
+}
+
+// node returns the node x is positioned at, see navigatorNode.
+func (x *documentSetNavigator) node() *Node {
+	if x.virt {
+		return x.docs[x.doc]
+	}
+	return navigatorNode(&x.NodeNavigator)
+}
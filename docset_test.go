@@ -0,0 +1,80 @@
+package xmlquery
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestDocumentSet(t *testing.T) {
+	dir := t.TempDir()
+	codes := `<codes><code id="A1" active="1">Apple</code><code id="B2">Banana</code><code id="C3" active="1">Cherry</code></codes>`
+	if err := os.WriteFile(filepath.Join(dir, "codes.xml"), []byte(codes), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	orders, err := Parse(strings.NewReader(`<orders code="C3"><item code="A1">1</item><item code="B2">2</item><item code="C3">3</item><item code="Z9">4</item></orders>`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	orders.SetDocumentURI("file://" + filepath.ToSlash(dir) + "/orders.xml")
+
+	set := NewDocumentSet()
+	items, err := set.QueryAll(orders, "//item[@code = document('codes.xml')//code[@active]/@id]")
+	if err != nil {
+		t.Fatal(err)
+	}
+	testValue(t, joinText(items), "1,3")
+	if len(set.Documents()) != 1 || set.Document("file://"+filepath.ToSlash(dir)+"/codes.xml") == nil {
+		t.Fatalf("documents = %v", set.Documents())
+	}
+
+	n, err := set.Query(orders, `document("codes.xml")/codes/code[@id = /orders/@code]`)
+	if err != nil || n == nil || n.InnerText() != "Cherry" {
+		t.Fatalf("Query = %v, %v", n, err)
+	}
+	n, err = set.Query(orders, "document('codes.xml')")
+	if err != nil || n == nil || n.Type != DocumentNode {
+		t.Fatalf("document() = %v, %v", n, err)
+	}
+	nodes, err := set.QueryAll(orders, "document('codes.xml')//code/@id")
+	if err != nil || len(nodes) != 3 || nodes[0].Type != AttributeNode || nodes[0].InnerText() != "A1" || nodes[0].Parent.InnerText() != "Apple" {
+		t.Fatalf("attributes = %v, %v", nodes, err)
+	}
+
+	// Documents added by hand, with the same structure: union keeps both.
+	a, _ := Parse(strings.NewReader(`<r><v>a</v></r>`))
+	b, _ := Parse(strings.NewReader(`<r><v>b</v></r>`))
+	set.Add("urn:a", a)
+	set.Add("urn:b", b)
+	nodes, err = set.QueryAll(orders, "document('urn:a')//v | document('urn:b')//v | //item[1]")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := joinText(nodes); got != "1,a,b" && got != "a,b,1" {
+		t.Errorf("union = %s", got)
+	}
+	nodes, err = set.QueryDocuments("//v")
+	if err != nil {
+		t.Fatal(err)
+	}
+	testValue(t, joinText(nodes), "a,b")
+	nodes, err = set.QueryAll(orders, "//item[@code = 'document(\"x\")']")
+	if err != nil || len(nodes) != 0 {
+		t.Errorf("literal = %v, %v", nodes, err)
+	}
+
+	set.Loader = func(uri string) (*Node, error) { return nil, errors.New("offline") }
+	if _, err := set.QueryAll(orders, "document('http://example.com/x.xml')"); err == nil || !strings.Contains(err.Error(), "offline") {
+		t.Errorf("error = %v", err)
+	}
+}
+
+func joinText(nodes []*Node) string {
+	texts := make([]string, len(nodes))
+	for i, n := range nodes {
+		texts[i] = n.InnerText()
+	}
+	return strings.Join(texts, ",")
+}
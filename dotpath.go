@@ -0,0 +1,116 @@
+package xmlquery
+
+import (
+	"strconv"
+	"strings"
+)
+
+// Get returns the node at the dot path below n, or nil if there is none.
+// A dot path is a lightweight alternative to XPath for digging a single
+// value out of a document, and is evaluated without compiling anything:
+//
+//	order.shipping.city       the first city in a shipping of order
+//	order.item.1.sku          the sku of the second item of order
+//	order.item.1@id           the id attribute of that item
+//	@version                  the version attribute of n itself
+//
+// Each name selects the child elements with that local name, whatever their
+// prefix or namespace, unless it is written with a prefix, as in "soap:Body".
+// A number selects one of the elements selected so far, counting from 0,
+// and "*" selects all child elements. When n is a document, the first name
+// selects its root element. A trailing @name selects an attribute, which is
+// returned as an AttributeNode like those of attribute queries.
+func Get(n *Node, path string) *Node {
+	path, attr, hasAttr := strings.Cut(path, "@")
+	nodes := []*Node{n}
+	if path != "" {
+		for _, name := range strings.Split(path, ".") {
+			if nodes = dotPathStep(nodes, name); len(nodes) == 0 {
+				return nil
+			}
+		}
+	}
+	if !hasAttr {
+		return nodes[0]
+	}
+	elem := nodes[0]
+	for i := range elem.Attr {
+		a := &elem.Attr[i]
+		if a.Name.Local == attr || (a.Name.Space != "" && a.Name.Space+":"+a.Name.Local == attr) {
+			text := &Node{Type: TextNode, Data: a.Value}
+			return &Node{Parent: elem, Type: AttributeNode, Data: a.Name.Local, Prefix: a.Name.Space, NamespaceURI: a.NamespaceURI, FirstChild: text, LastChild: text}
+		}
+	}
+	return nil
+}
+
+// dotPathStep applies one name or index of a dot path to nodes.
+func dotPathStep(nodes []*Node, name string) []*Node {
+	if name != "" && name[0] >= '0' && name[0] <= '9' {
+		i := 0
+		for _, c := range name {
+			if c < '0' || c > '9' {
+				return nil
+			}
+			i = i*10 + int(c-'0')
+			if i >= len(nodes) {
+				return nil
+			}
+		}
+		return nodes[i : i+1]
+	}
+	prefix, local, prefixed := strings.Cut(name, ":")
+	if !prefixed {
+		local = prefix
+	}
+	var next []*Node
+	for _, n := range nodes {
+		n.Materialize()
+		for child := n.FirstChild; child != nil; child = child.NextSibling {
+			if child.Type != ElementNode {
+				continue
+			}
+			if local == "*" || (child.Data == local && (!prefixed || child.Prefix == prefix)) {
+				next = append(next, child)
+			}
+		}
+	}
+	return next
+}
+
+// GetString returns the text of the node at the dot path below n, or "" if
+// there is none. See Get.
+func GetString(n *Node, path string) string {
+	if n = Get(n, path); n == nil {
+		return ""
+	}
+	return n.InnerText()
+}
+
+// GetInt returns the text at the dot path below n as an int, or 0 if there
+// is none or it is not an integer. See Get.
+func GetInt(n *Node, path string) int {
+	v, err := strconv.Atoi(strings.TrimSpace(GetString(n, path)))
+	if err != nil {
+		return 0
+	}
+	return v
+}
+
+// GetFloat returns the text at the dot path below n as a float64, or 0 if
+// there is none or it is not a number. See Get.
+func GetFloat(n *Node, path string) float64 {
+	v, err := strconv.ParseFloat(strings.TrimSpace(GetString(n, path)), 64)
+	if err != nil {
+		return 0
+	}
+	return v
+}
+
+// GetBool returns the text at the dot path below n as a bool, or false if
+// there is none or it is not a boolean such as "true", "1" or "false".
+// See Get.
+func GetBool(n *Node, path string) bool {
+	v, _ := strconv.ParseBool(strings.TrimSpace(GetString(n, path)))
+	return v
+}
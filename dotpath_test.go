@@ -0,0 +1,47 @@
+package xmlquery
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestGet(t *testing.T) {
+	doc, err := Parse(strings.NewReader(`<soap:Envelope xmlns:soap="urn:soap" xmlns="urn:shop">
+	<soap:Body>
+		<order id="7" paid="true" xml:lang="de">
+			<total> 12.50 </total>
+			<item sku="a"><qty>1</qty></item>
+			<item sku="b"><qty>3</qty></item>
+			<x:item xmlns:x="urn:x" sku="c"/>
+		</order>
+	</soap:Body>
+</soap:Envelope>`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	testValue(t, GetString(doc, "Envelope.Body.order@id"), "7")
+	testValue(t, GetString(doc, "soap:Envelope.soap:Body.order.item.1@sku"), "b")
+	testValue(t, GetString(doc, "Envelope.Body.order.item.2@sku"), "c")
+	testValue(t, GetString(doc, "Envelope.Body.order.x:item@sku"), "c")
+	testValue(t, GetString(doc, "Envelope.Body.order@xml:lang"), "de")
+	testValue(t, GetInt(doc, "Envelope.Body.order.item.1.qty"), 3)
+	testValue(t, GetFloat(doc, "Envelope.Body.order.total"), 12.5)
+	testValue(t, GetBool(doc, "Envelope.Body.order@paid"), true)
+	testValue(t, GetString(doc, "Envelope.*.*.item.qty"), "1")
+
+	order := Get(doc, "Envelope.Body.order")
+	testValue(t, order.Data, "order")
+	testValue(t, GetString(order, "@id"), "7")
+	testValue(t, GetInt(order, "item.qty"), 1)
+	attr := Get(order, "@id")
+	testValue(t, attr.Type, AttributeNode)
+	testTrue(t, attr.Parent == order)
+
+	for _, path := range []string{"order", "Envelope.Body.order.item.3", "Envelope.Body.order@missing", "Envelope.Body.order.item.x1", "soap:Envelope.x:Body"} {
+		testTrue(t, Get(doc, path) == nil)
+		testValue(t, GetString(doc, path), "")
+	}
+	testValue(t, GetInt(doc, "Envelope.Body.order.total"), 0)
+	testValue(t, GetFloat(doc, "Envelope.Body.missing"), 0.0)
+	testValue(t, GetBool(doc, "Envelope.Body.order.total"), false)
+}
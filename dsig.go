@@ -0,0 +1,328 @@
+package xmlquery
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/subtle"
+	"crypto/x509"
+	"encoding/asn1"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+)
+
+// Identifiers used in XML signatures.
+const (
+	// DSigNS is the namespace of XML Signature elements.
+	DSigNS = "http://www.w3.org/2000/09/xmldsig#"
+
+	SignatureRSASHA256   = "http://www.w3.org/2001/04/xmldsig-more#rsa-sha256"
+	SignatureECDSASHA256 = "http://www.w3.org/2001/04/xmldsig-more#ecdsa-sha256"
+	DigestSHA256         = "http://www.w3.org/2001/04/xmlenc#sha256"
+	TransformEnveloped   = "http://www.w3.org/2000/09/xmldsig#enveloped-signature"
+)
+
+var (
+	// ErrNoSignature is returned by VerifyEnveloped when the element has no
+	// Signature child.
+	ErrNoSignature = errors.New("xmlquery: no signature found")
+	// ErrInvalidSignature is returned, possibly wrapped, by VerifyEnveloped
+	// when the signature doesn't verify.
+	ErrInvalidSignature = errors.New("xmlquery: invalid signature")
+)
+
+// SignEnveloped signs n with key and adds the resulting ds:Signature element
+// as the last child of n, which it returns. If n is a document, its document
+// element is signed.
+//
+// The signature references n by its ID, Id or id attribute, or by the empty
+// URI if n is the document element and has none of them, and uses the
+// enveloped-signature and exclusive canonicalization transforms with a
+// SHA-256 digest. key must be an RSA or ECDSA key; certs, if any, are added
+// to the KeyInfo.
+//
+// n must not be modified after signing; even changes in whitespace break
+// the signature, so write the document with WithPreserveSpace.
+func SignEnveloped(n *Node, key crypto.Signer, certs ...*x509.Certificate) (*Node, error) {
+	if n.Type == DocumentNode {
+		n = firstChildElement(n)
+	}
+	if n == nil || n.Type != ElementNode {
+		return nil, errors.New("xmlquery: nothing to sign")
+	}
+	var method string
+	switch key.Public().(type) {
+	case *rsa.PublicKey:
+		method = SignatureRSASHA256
+	case *ecdsa.PublicKey:
+		method = SignatureECDSASHA256
+	default:
+		return nil, fmt.Errorf("xmlquery: unsupported signing key %T", key.Public())
+	}
+	uri := ""
+	if id := signatureID(n); id != "" {
+		uri = "#" + id
+	} else if n.Parent != nil && n.Parent.Type != DocumentNode {
+		return nil, errors.New("xmlquery: signed element has no ID attribute")
+	}
+
+	c, _ := newCanonicalizer(ExclusiveC14N, nil)
+	var b bytes.Buffer
+	c.write(&b, signedContent(n, uri))
+	digest := sha256.Sum256(b.Bytes())
+
+	newElement := func(parent *Node, name string) *Node {
+		elem := &Node{Type: ElementNode, Data: name, Prefix: "ds", NamespaceURI: DSigNS}
+		if parent != nil {
+			AddChild(parent, elem)
+		}
+		return elem
+	}
+	newText := func(parent *Node, name, text string) {
+		AddChild(newElement(parent, name), &Node{Type: TextNode, Data: text})
+	}
+	sig := newElement(nil, "Signature")
+	sig.SetAttr("xmlns:ds", DSigNS)
+	signedInfo := newElement(sig, "SignedInfo")
+	newElement(signedInfo, "CanonicalizationMethod").SetAttr("Algorithm", ExclusiveC14N)
+	newElement(signedInfo, "SignatureMethod").SetAttr("Algorithm", method)
+	ref := newElement(signedInfo, "Reference")
+	ref.SetAttr("URI", uri)
+	transforms := newElement(ref, "Transforms")
+	newElement(transforms, "Transform").SetAttr("Algorithm", TransformEnveloped)
+	newElement(transforms, "Transform").SetAttr("Algorithm", ExclusiveC14N)
+	newElement(ref, "DigestMethod").SetAttr("Algorithm", DigestSHA256)
+	newText(ref, "DigestValue", base64.StdEncoding.EncodeToString(digest[:]))
+
+	b.Reset()
+	c.write(&b, signedInfo)
+	value, err := signBytes(key, b.Bytes())
+	if err != nil {
+		return nil, err
+	}
+	newText(sig, "SignatureValue", base64.StdEncoding.EncodeToString(value))
+	if len(certs) > 0 {
+		data := newElement(newElement(sig, "KeyInfo"), "X509Data")
+		for _, cert := range certs {
+			newText(data, "X509Certificate", base64.StdEncoding.EncodeToString(cert.Raw))
+		}
+	}
+	setLevel(sig, n.level+1)
+	AddChild(n, sig)
+	return sig, nil
+}
+
+// VerifyEnveloped checks the enveloped signature of n, a ds:Signature child
+// element, against the trusted public key. If n is a document, the
+// signature of its document element is checked. The signature must
+// reference n itself, so a nil error means that n and its descendants,
+// except for the signature, are exactly as signed; content outside n is not
+// covered.
+//
+// The signature must use RSA or ECDSA with SHA-256, a SHA-256 digest, and
+// Canonical XML 1.0 or 1.1 or exclusive canonicalization.
+func VerifyEnveloped(n *Node, key crypto.PublicKey) error {
+	if n.Type == DocumentNode {
+		n = firstChildElement(n)
+	}
+	if n == nil {
+		return ErrNoSignature
+	}
+	sig := childElementNS(n, DSigNS, "Signature")
+	if sig == nil {
+		return ErrNoSignature
+	}
+	invalid := func(format string, args ...interface{}) error {
+		return fmt.Errorf("%w: %s", ErrInvalidSignature, fmt.Sprintf(format, args...))
+	}
+	signedInfo := childElementNS(sig, DSigNS, "SignedInfo")
+	if signedInfo == nil {
+		return invalid("missing SignedInfo")
+	}
+	signatureValue := childElementNS(sig, DSigNS, "SignatureValue")
+	if signatureValue == nil {
+		return invalid("missing SignatureValue")
+	}
+	var refs []*Node
+	for c := signedInfo.FirstChild; c != nil; c = c.NextSibling {
+		if c.Type == ElementNode && c.NamespaceURI == DSigNS && c.Data == "Reference" {
+			refs = append(refs, c)
+		}
+	}
+	if len(refs) != 1 {
+		return invalid("expected one Reference, found %d", len(refs))
+	}
+	ref := refs[0]
+
+	// The reference must point at n, not at some other element that
+	// happens to carry the same ID.
+	uri := ref.SelectAttr("URI")
+	switch {
+	case uri == "":
+		if n.Parent != nil && n.Parent.Type != DocumentNode {
+			return invalid("empty reference URI on a nested element")
+		}
+	case uri[0] == '#':
+		if signatureID(n) != uri[1:] {
+			return invalid("reference %q doesn't match the signed element", uri)
+		}
+	default:
+		return invalid("unsupported reference URI %q", uri)
+	}
+
+	// Digest of the referenced content.
+	enveloped := false
+	c, _ := newCanonicalizer(C14N10, nil)
+	if transforms := childElementNS(ref, DSigNS, "Transforms"); transforms != nil {
+		for t := transforms.FirstChild; t != nil; t = t.NextSibling {
+			if t.Type != ElementNode {
+				continue
+			}
+			alg := t.SelectAttr("Algorithm")
+			if alg == TransformEnveloped {
+				enveloped = true
+				continue
+			}
+			var err error
+			if c, err = newCanonicalizer(alg, inclusiveNamespaces(t)); err != nil {
+				return invalid("unsupported transform %q", alg)
+			}
+		}
+	}
+	if !enveloped {
+		return invalid("missing enveloped-signature transform")
+	}
+	// Same-document references never include comments.
+	c.comments = false
+	c.exclude = sig
+	if m := childElementNS(ref, DSigNS, "DigestMethod"); m == nil || m.SelectAttr("Algorithm") != DigestSHA256 {
+		return invalid("unsupported digest method")
+	}
+	want, err := decodeBase64Text(childElementNS(ref, DSigNS, "DigestValue"))
+	if err != nil {
+		return invalid("bad DigestValue: %v", err)
+	}
+	var b bytes.Buffer
+	c.write(&b, signedContent(n, uri))
+	got := sha256.Sum256(b.Bytes())
+	if subtle.ConstantTimeCompare(got[:], want) != 1 {
+		return invalid("digest mismatch")
+	}
+
+	// Signature over SignedInfo.
+	m := childElementNS(signedInfo, DSigNS, "CanonicalizationMethod")
+	if m == nil {
+		return invalid("missing CanonicalizationMethod")
+	}
+	if c, err = newCanonicalizer(m.SelectAttr("Algorithm"), inclusiveNamespaces(m)); err != nil {
+		return invalid("unsupported canonicalization %q", m.SelectAttr("Algorithm"))
+	}
+	b.Reset()
+	c.write(&b, signedInfo)
+	value, err := decodeBase64Text(signatureValue)
+	if err != nil {
+		return invalid("bad SignatureValue: %v", err)
+	}
+	method := ""
+	if m := childElementNS(signedInfo, DSigNS, "SignatureMethod"); m != nil {
+		method = m.SelectAttr("Algorithm")
+	}
+	hashed := sha256.Sum256(b.Bytes())
+	switch method {
+	case SignatureRSASHA256:
+		pub, ok := key.(*rsa.PublicKey)
+		if !ok {
+			return invalid("%s needs an RSA key", method)
+		}
+		if rsa.VerifyPKCS1v15(pub, crypto.SHA256, hashed[:], value) != nil {
+			return invalid("signature mismatch")
+		}
+	case SignatureECDSASHA256:
+		pub, ok := key.(*ecdsa.PublicKey)
+		if !ok {
+			return invalid("%s needs an ECDSA key", method)
+		}
+		size := (pub.Curve.Params().BitSize + 7) / 8
+		if len(value) != 2*size {
+			// Some signers use ASN.1 like in X.509.
+			if !ecdsa.VerifyASN1(pub, hashed[:], value) {
+				return invalid("signature mismatch")
+			}
+			break
+		}
+		r := new(big.Int).SetBytes(value[:size])
+		s := new(big.Int).SetBytes(value[size:])
+		if !ecdsa.Verify(pub, hashed[:], r, s) {
+			return invalid("signature mismatch")
+		}
+	default:
+		return invalid("unsupported signature method %q", method)
+	}
+	return nil
+}
+
+// signatureID returns the value of the attribute that identifies n in a
+// signature reference.
+func signatureID(n *Node) string {
+	for _, name := range []string{"ID", "Id", "id"} {
+		if v := n.SelectAttr(name); v != "" {
+			return v
+		}
+	}
+	return ""
+}
+
+// signedContent returns the node a reference with the given URI covers when
+// it points at n: the whole document for the empty URI.
+func signedContent(n *Node, uri string) *Node {
+	if uri == "" && n.Parent != nil {
+		return n.Parent
+	}
+	return n
+}
+
+// inclusiveNamespaces returns the PrefixList of an InclusiveNamespaces
+// child of an exclusive canonicalization method or transform.
+func inclusiveNamespaces(n *Node) []string {
+	in := childElementNS(n, ExclusiveC14N, "InclusiveNamespaces")
+	if in == nil {
+		return nil
+	}
+	return strings.Fields(in.SelectAttr("PrefixList"))
+}
+
+func decodeBase64Text(n *Node) ([]byte, error) {
+	if n == nil {
+		return nil, errors.New("missing")
+	}
+	s := strings.Join(strings.Fields(n.InnerText()), "")
+	return base64.StdEncoding.DecodeString(s)
+}
+
+func signBytes(key crypto.Signer, data []byte) ([]byte, error) {
+	hashed := sha256.Sum256(data)
+	value, err := key.Sign(rand.Reader, hashed[:], crypto.SHA256)
+	if err != nil {
+		return nil, err
+	}
+	pub, ok := key.Public().(*ecdsa.PublicKey)
+	if !ok {
+		return value, nil
+	}
+	// XML signatures use the fixed-size r || s form instead of ASN.1.
+	var parsed struct{ R, S *big.Int }
+	if _, err := asn1.Unmarshal(value, &parsed); err != nil {
+		return nil, err
+	}
+	size := (pub.Curve.Params().BitSize + 7) / 8
+	out := make([]byte, 2*size)
+	parsed.R.FillBytes(out[:size])
+	parsed.S.FillBytes(out[size:])
+	return out, nil
+}
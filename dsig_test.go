@@ -0,0 +1,137 @@
+package xmlquery
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestSignEnveloped(t *testing.T) {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := `<?xml version="1.0"?>
+<r:response xmlns:r="urn:r" ID="resp">
+  <r:assertion ID="a1" xmlns:u="urn:unused">
+    <r:subject>alice</r:subject>
+  </r:assertion>
+</r:response>`
+	for _, test := range []struct {
+		name string
+		key  crypto.Signer
+		expr string
+	}{
+		{"rsa document", rsaKey, "/"},
+		{"ecdsa element", ecKey, "//r:assertion"},
+	} {
+		doc, err := Parse(strings.NewReader(s))
+		if err != nil {
+			t.Fatal(err)
+		}
+		n := FindOne(doc, test.expr)
+		sig, err := SignEnveloped(n, test.key)
+		if err != nil {
+			t.Fatalf("%s: %v", test.name, err)
+		}
+		testValue(t, sig.Prefix, "ds")
+		testValue(t, sig.FirstChild.Level(), sig.Parent.Level()+2)
+		pub := test.key.Public()
+		if err := VerifyEnveloped(n, pub); err != nil {
+			t.Fatalf("%s: %v", test.name, err)
+		}
+		verifyNodePointers(t, doc)
+
+		// The signature survives writing and parsing the document.
+		again, err := Parse(strings.NewReader(doc.OutputXMLWithOptions(WithPreserveSpace())))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := VerifyEnveloped(FindOne(again, test.expr), pub); err != nil {
+			t.Fatalf("%s: after round trip: %v", test.name, err)
+		}
+
+		// Any change to the content is detected.
+		FindOne(again, "//r:subject").FirstChild.Data = "mallory"
+		if err := VerifyEnveloped(FindOne(again, test.expr), pub); !errors.Is(err, ErrInvalidSignature) {
+			t.Fatalf("%s: expected ErrInvalidSignature, got %v", test.name, err)
+		}
+	}
+}
+
+func TestVerifyEnvelopedReference(t *testing.T) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	doc, err := Parse(strings.NewReader(`<r><a ID="1">x</a><b ID="2">y</b></r>`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	a := FindOne(doc, "//a")
+	if _, err := SignEnveloped(a, key); err != nil {
+		t.Fatal(err)
+	}
+	// Moving the signature to another element must not verify it.
+	sig := childElementNS(a, DSigNS, "Signature")
+	RemoveFromTree(sig)
+	b := FindOne(doc, "//b")
+	AddChild(b, sig)
+	if err := VerifyEnveloped(b, &key.PublicKey); !errors.Is(err, ErrInvalidSignature) {
+		t.Fatalf("expected ErrInvalidSignature, got %v", err)
+	}
+	if err := VerifyEnveloped(a, &key.PublicKey); err != ErrNoSignature {
+		t.Fatalf("expected ErrNoSignature, got %v", err)
+	}
+
+	// Nested elements need an ID to be referenced.
+	if _, err := SignEnveloped(FindOne(doc, "//a"), key); err != nil {
+		t.Fatal(err)
+	}
+	RemoveFromTree(childElementNS(a, DSigNS, "Signature"))
+	a.Attr = nil
+	if _, err := SignEnveloped(a, key); err == nil {
+		t.Fatal("expected error for element without ID")
+	}
+}
+
+// Signed with an independent implementation, using exclusive
+// canonicalization and an empty reference URI.
+const signedOrder = `<order xmlns="urn:shop" id="o1"><item sku="a&amp;b">Widget</item><note xml:lang="en">Leave at door</note><ds:Signature xmlns:ds="http://www.w3.org/2000/09/xmldsig#"><ds:SignedInfo><ds:CanonicalizationMethod Algorithm="http://www.w3.org/2001/10/xml-exc-c14n#"/><ds:SignatureMethod Algorithm="http://www.w3.org/2001/04/xmldsig-more#rsa-sha256"/><ds:Reference URI=""><ds:Transforms><ds:Transform Algorithm="http://www.w3.org/2000/09/xmldsig#enveloped-signature"/><ds:Transform Algorithm="http://www.w3.org/2001/10/xml-exc-c14n#"/></ds:Transforms><ds:DigestMethod Algorithm="http://www.w3.org/2001/04/xmlenc#sha256"/><ds:DigestValue>AYEG/4HpYBQxWgGEpSuhZBO9hHFS0I7nW12jMPsUvdg=</ds:DigestValue></ds:Reference></ds:SignedInfo><ds:SignatureValue>f+wc0PfFAdiUH5SpeJ154ywIPxXP+50G9rM1M0ZsjCdZa4BMCFcof11ouWZqfYUADG24L34cMWQSwxGJYAm+kH/rfa/N3Bu6bZFhGSxEZ/tMKa+i4qfW0lE7BDE5v8KfyEGiRM7SaLSgb6Tgjlt7BSemzpL+96W6Xs8Vtf1W5Yo=</ds:SignatureValue></ds:Signature></order>`
+
+const signedOrderKey = `-----BEGIN PUBLIC KEY-----
+MIGfMA0GCSqGSIb3DQEBAQUAA4GNADCBiQKBgQCleO2wZd6mP91qPTx/ZatudL/V
+d2/EVnIYkvLAMHHL70JstJ1Fvg+5CZccZ5k5H+qEgxf+3u7SPzybLXALPKvAeUdM
+b7UPs1DDlCPkOz7gl35IUmsH++qtY7u0rV0OLaTJq4sUh2ZDJ0JYGarYwP1JlVyc
+DgRrCD6gjS0N72hqAQIDAQAB
+-----END PUBLIC KEY-----`
+
+func TestVerifyEnvelopedInterop(t *testing.T) {
+	block, _ := pem.Decode([]byte(signedOrderKey))
+	pub, err := x509.ParsePKIXPublicKey(block.Bytes)
+	if err != nil {
+		t.Fatal(err)
+	}
+	doc, err := Parse(strings.NewReader(signedOrder))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := VerifyEnveloped(doc, pub); err != nil {
+		t.Fatal(err)
+	}
+	FindOne(doc, "//item").SetAttr("sku", "a")
+	if err := VerifyEnveloped(doc, pub); !errors.Is(err, ErrInvalidSignature) {
+		t.Fatalf("expected ErrInvalidSignature, got %v", err)
+	}
+}
@@ -0,0 +1,116 @@
+package xmlquery
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// dumpTextLimit is the number of characters of text Dump shows.
+const dumpTextLimit = 40
+
+var nodeTypeNames = [...]string{
+	DocumentNode:    "DocumentNode",
+	DeclarationNode: "DeclarationNode",
+	ElementNode:     "ElementNode",
+	TextNode:        "TextNode",
+	CharDataNode:    "CharDataNode",
+	CommentNode:     "CommentNode",
+	AttributeNode:   "AttributeNode",
+	NotationNode:    "NotationNode",
+}
+
+// String returns the name of the node type, such as "ElementNode".
+func (t NodeType) String() string {
+	if int(t) < len(nodeTypeNames) {
+		return nodeTypeNames[t]
+	}
+	return "NodeType(" + strconv.Itoa(int(t)) + ")"
+}
+
+// Dump returns an indented view of the subtree rooted at n for debugging,
+// with one line per node giving its type, name, namespace URI, attributes
+// and the beginning of its text:
+//
+//	DocumentNode
+//	  DeclarationNode xml version="1.0"
+//	  ElementNode x:order {urn:x}
+//	    @id="7"
+//	    @xmlns:x="urn:x"
+//	    TextNode "Lorem ipsum dolor sit amet, consectetur…" (57 bytes)
+//
+// Elements whose content is not parsed yet, see ParseLazy, are marked as
+// lazy and are not parsed by Dump.
+func (n *Node) Dump() string {
+	var b strings.Builder
+	dumpNode(&b, n, 0)
+	return b.String()
+}
+
+// GoString returns n.Dump(), so that the %#v verb prints the tree.
+func (n *Node) GoString() string {
+	if n == nil {
+		return "(*xmlquery.Node)(nil)"
+	}
+	return n.Dump()
+}
+
+func dumpNode(b *strings.Builder, n *Node, depth int) {
+	indent := strings.Repeat("  ", depth)
+	b.WriteString(indent)
+	b.WriteString(n.Type.String())
+	switch n.Type {
+	case ElementNode, AttributeNode:
+		b.WriteByte(' ')
+		b.WriteString(qualifiedName(n))
+		if n.NamespaceURI != "" {
+			fmt.Fprintf(b, " {%s}", n.NamespaceURI)
+		}
+		if n.lazy != nil && n.FirstChild == nil {
+			b.WriteString(" (lazy)")
+		}
+	case DeclarationNode:
+		b.WriteByte(' ')
+		b.WriteString(n.Data)
+		if data := n.ProcInstData(); data != "" {
+			b.WriteByte(' ')
+			b.WriteString(data)
+		}
+	case TextNode, CharDataNode, CommentNode, NotationNode:
+		b.WriteByte(' ')
+		b.WriteString(truncateDumpText(n.Data))
+	}
+	b.WriteByte('\n')
+	if n.Type == ElementNode {
+		for _, attr := range n.Attr {
+			b.WriteString(indent)
+			b.WriteString("  @")
+			b.WriteString(qualifiedAttrName(&attr))
+			b.WriteByte('=')
+			b.WriteString(strconv.Quote(attr.Value))
+			if attr.NamespaceURI != "" && attr.NamespaceURI != "xmlns" {
+				fmt.Fprintf(b, " {%s}", attr.NamespaceURI)
+			}
+			b.WriteByte('\n')
+		}
+	}
+	for child := n.FirstChild; child != nil; child = child.NextSibling {
+		dumpNode(b, child, depth+1)
+	}
+}
+
+// truncateDumpText quotes s, shortened to dumpTextLimit characters.
+func truncateDumpText(s string) string {
+	if utf8.RuneCountInString(s) <= dumpTextLimit {
+		return strconv.Quote(s)
+	}
+	i, count := 0, 0
+	for i = range s {
+		if count == dumpTextLimit {
+			break
+		}
+		count++
+	}
+	return strconv.Quote(s[:i]+"…") + fmt.Sprintf(" (%d bytes)", len(s))
+}
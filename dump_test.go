@@ -0,0 +1,31 @@
+package xmlquery
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestDump(t *testing.T) {
+	doc, err := Parse(strings.NewReader(`<?xml version="1.0"?><x:order xmlns:x="urn:x" id="7" x:ref="r"><!--note--><line><![CDATA[<b>]]>Lorem ipsum dolor sit amet, consectetur adipiscing elit</line><?pi a="1"?></x:order>`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := `DocumentNode
+  DeclarationNode xml version="1.0"
+  ElementNode x:order {urn:x}
+    @xmlns:x="urn:x"
+    @id="7"
+    @x:ref="r" {urn:x}
+    CommentNode "note"
+    ElementNode line
+      CharDataNode "<b>"
+      TextNode "Lorem ipsum dolor sit amet, consectetur …" (55 bytes)
+    DeclarationNode pi a="1"
+`
+	testValue(t, doc.Dump(), expected)
+	testValue(t, fmt.Sprintf("%#v", doc), expected)
+	testValue(t, fmt.Sprintf("%#v", (*Node)(nil)), "(*xmlquery.Node)(nil)")
+	testValue(t, FindOne(doc, "//@id").Dump(), "AttributeNode id\n  TextNode \"7\"\n")
+	testValue(t, NodeType(42).String(), "NodeType(42)")
+}
@@ -0,0 +1,78 @@
+package xmlquery
+
+import (
+	"github.com/suifengpiao14/xmlquery/xml"
+)
+
+// DuplicateAttrPolicy is the handling of an element that repeats an
+// attribute, see ParserOptions.DuplicateAttrs. Attributes are the same if
+// they have the same name and namespace, whatever prefix they are written
+// with.
+type DuplicateAttrPolicy int
+
+const (
+	// DuplicateAttrKeepAll keeps every occurrence, as encoding/xml does.
+	DuplicateAttrKeepAll DuplicateAttrPolicy = iota
+	// DuplicateAttrError fails parsing with a ParseError. With
+	// ParseRecover, the error is recorded and the first occurrence kept.
+	DuplicateAttrError
+	// DuplicateAttrKeepFirst drops all occurrences but the first.
+	DuplicateAttrKeepFirst
+	// DuplicateAttrKeepLast drops all occurrences but the last.
+	DuplicateAttrKeepLast
+)
+
+// removeDuplicateAttrs applies p.duplicateAttrs to the attributes of a
+// start element.
+func (p *parser) removeDuplicateAttrs(attrs []xml.Attr) ([]xml.Attr, error) {
+	var out []xml.Attr
+	for i, attr := range attrs {
+		dup := false
+		switch p.duplicateAttrs {
+		case DuplicateAttrKeepLast:
+			for _, other := range attrs[i+1:] {
+				if other.Name == attr.Name {
+					dup = true
+					break
+				}
+			}
+		default:
+			for _, other := range attrs[:i] {
+				if other.Name == attr.Name {
+					dup = true
+					break
+				}
+			}
+		}
+		if !dup {
+			if out != nil {
+				out = append(out, attr)
+			}
+			continue
+		}
+		if p.duplicateAttrs == DuplicateAttrError {
+			if err := p.violation("duplicate attribute " + p.rawAttrName(attr.Name)); err != nil {
+				return nil, err
+			}
+		}
+		if out == nil {
+			out = append(make([]xml.Attr, 0, len(attrs)-1), attrs[:i]...)
+		}
+	}
+	if out == nil {
+		return attrs, nil
+	}
+	return out, nil
+}
+
+// rawAttrName returns name, whose space is a namespace URI, with the prefix
+// bound to that URI.
+func (p *parser) rawAttrName(name xml.Name) string {
+	if name.Space == "" {
+		return name.Local
+	}
+	if prefix, ok := p.space2prefix[name.Space]; ok && prefix.name != "" {
+		return prefix.name + ":" + name.Local
+	}
+	return name.Space + ":" + name.Local
+}
@@ -0,0 +1,49 @@
+package xmlquery
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestDuplicateAttrs(t *testing.T) {
+	const s = `<a xmlns:p="urn:p" xmlns:q="urn:p" x="1" p:y="2" x="3" q:y="4" z="5"/>`
+	attrs := func(doc *Node) string {
+		var b strings.Builder
+		for _, attr := range FindOne(doc, "/a").Attr {
+			if !isNamespaceDecl(attr) {
+				b.WriteString(attr.Name.Local + "=" + attr.Value + " ")
+			}
+		}
+		return b.String()
+	}
+	for _, test := range []struct {
+		policy DuplicateAttrPolicy
+		want   string
+	}{
+		{DuplicateAttrKeepAll, "x=1 y=2 x=3 y=4 z=5 "},
+		{DuplicateAttrKeepFirst, "x=1 y=2 z=5 "},
+		{DuplicateAttrKeepLast, "x=3 y=4 z=5 "},
+	} {
+		doc, err := ParseWithOptions(strings.NewReader(s), ParserOptions{DuplicateAttrs: test.policy})
+		if err != nil {
+			t.Fatal(err)
+		}
+		testValue(t, attrs(doc), test.want)
+	}
+
+	_, err := ParseWithOptions(strings.NewReader("<r>\n  <a x='1' x='2'/>\n</r>"), ParserOptions{DuplicateAttrs: DuplicateAttrError})
+	var perr *ParseError
+	if !errors.As(err, &perr) {
+		t.Fatalf("got %v, want a *ParseError", err)
+	}
+	testValue(t, perr.Line, 2)
+	testValue(t, err.Error(), `xmlquery: line 2, column 3: duplicate attribute x (near "a x='1' x='2'/>")`)
+
+	doc, diags, err := ParseRecover(strings.NewReader(s), ParserOptions{DuplicateAttrs: DuplicateAttrError})
+	if err != nil {
+		t.Fatal(err)
+	}
+	testValue(t, len(diags), 2)
+	testValue(t, attrs(doc), "x=1 y=2 z=5 ")
+}
@@ -0,0 +1,64 @@
+package xmlquery
+
+import (
+	"strings"
+)
+
+// EncodingDeclPolicy chooses how the encoding pseudo-attribute of the XML
+// declaration is written, see WithEncodingDecl. Documents are held in
+// memory as UTF-8 whatever encoding they were read from, so writing the
+// declaration of a document read from ISO-8859-1 as it is would declare an
+// encoding other than the one of the bytes written.
+type EncodingDeclPolicy int
+
+const (
+	// EncodingDeclRewrite declares UTF-8 instead of any other encoding.
+	// This is the default.
+	EncodingDeclRewrite EncodingDeclPolicy = iota
+	// EncodingDeclStrip removes an encoding other than UTF-8 from the
+	// declaration, which makes parsers assume UTF-8.
+	EncodingDeclStrip
+	// EncodingDeclKeep writes the declaration as it is, for output that is
+	// transcoded to the declared encoding afterwards.
+	EncodingDeclKeep
+)
+
+// WithEncodingDecl sets how the encoding declared by the XML declaration
+// is written, see EncodingDeclPolicy. SaveFile with SaveOptions.Encoding
+// declares the encoding it writes instead.
+func WithEncodingDecl(policy EncodingDeclPolicy) OutputOption {
+	return func(oc *outputConfiguration) {
+		oc.encodingDecl = policy
+	}
+}
+
+// declarationAttrs returns the attributes to write for the XML declaration,
+// which has attributes attrs.
+func declarationAttrs(attrs []Attr, policy EncodingDeclPolicy) []Attr {
+	i := foreignEncoding(attrs, policy)
+	if i < 0 {
+		return attrs
+	}
+	fixed := make([]Attr, 0, len(attrs))
+	fixed = append(fixed, attrs[:i]...)
+	if policy == EncodingDeclRewrite {
+		attr := attrs[i]
+		attr.Value = "UTF-8"
+		fixed = append(fixed, attr)
+	}
+	return append(fixed, attrs[i+1:]...)
+}
+
+// foreignEncoding returns the index in attrs of the encoding pseudo-attribute
+// to rewrite or strip according to policy, or -1.
+func foreignEncoding(attrs []Attr, policy EncodingDeclPolicy) int {
+	if policy == EncodingDeclKeep {
+		return -1
+	}
+	for i, attr := range attrs {
+		if attr.Name.Space == "" && attr.Name.Local == "encoding" && !strings.EqualFold(attr.Value, "utf-8") {
+			return i
+		}
+	}
+	return -1
+}
@@ -0,0 +1,33 @@
+package xmlquery
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestEncodingDecl(t *testing.T) {
+	s := `<?xml version="1.0" encoding="ISO-8859-1" standalone="yes"?><r>caf` + "\xe9" + `</r>`
+
+	for _, roundTrip := range []bool{false, true} {
+		doc, err := ParseWithOptions(strings.NewReader(s), ParserOptions{RoundTrip: roundTrip})
+		if err != nil {
+			t.Fatal(err)
+		}
+		testValue(t, doc.OutputXML(false),
+			`<?xml version="1.0" encoding="UTF-8" standalone="yes"?><r>caf`+"\u00e9"+`</r>`)
+		testValue(t, doc.OutputXMLWithOptions(WithEncodingDecl(EncodingDeclStrip)),
+			`<?xml version="1.0" standalone="yes"?><r>caf`+"\u00e9"+`</r>`)
+		testValue(t, doc.OutputXMLWithOptions(WithEncodingDecl(EncodingDeclKeep)),
+			`<?xml version="1.0" encoding="ISO-8859-1" standalone="yes"?><r>caf`+"\u00e9"+`</r>`)
+		// The declaration itself is left as it is.
+		testValue(t, doc.FirstChild.SelectAttr("encoding"), "ISO-8859-1")
+	}
+
+	// A UTF-8 declaration is written as it is, whatever its case.
+	doc, err := Parse(strings.NewReader(`<?xml version="1.0" encoding="utf-8"?><r/>`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	testValue(t, doc.OutputXMLWithOptions(WithEncodingDecl(EncodingDeclStrip)),
+		`<?xml version="1.0" encoding="utf-8"?><r></r>`)
+}
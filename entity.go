@@ -0,0 +1,229 @@
+package xmlquery
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// An EntityResolver provides the content of the external DTD subset and
+// the external parsed entities a document refers to, see
+// ParserOptions.EntityResolver. It can serve them from a local cache or
+// catalog, fetch them, or deny access.
+type EntityResolver interface {
+	// ResolveEntity returns the content of the external resource with the
+	// given public identifier, which may be empty, and system identifier,
+	// as written in the document. Returning a nil reader and a nil error
+	// skips the resource; returning an error fails parsing with it.
+	ResolveEntity(publicID, systemID string) (io.ReadCloser, error)
+}
+
+// EntityResolverFunc is an adapter to use a function as an EntityResolver.
+type EntityResolverFunc func(publicID, systemID string) (io.ReadCloser, error)
+
+// ResolveEntity calls f(publicID, systemID).
+func (f EntityResolverFunc) ResolveEntity(publicID, systemID string) (io.ReadCloser, error) {
+	return f(publicID, systemID)
+}
+
+// entityDecl is a general entity declaration of a DTD.
+type entityDecl struct {
+	name             string
+	value            string // replacement text of an internal entity
+	publicID, system string // identifiers of an external entity
+	external         bool
+}
+
+// declareEntities makes the general entities declared by the DOCTYPE
+// directive known to the decoder, so that references to them are replaced
+// by their content. Declarations of the internal subset take precedence
+// over those of the external subset, and external resources are only read
+// through p.entityResolver. Unparsed and parameter entities are ignored.
+// The replacement text of an entity is inserted as text: markup it
+// contains is not parsed. It is only used with ParserOptions.DTDEntities.
+func (p *parser) declareEntities(directive string) error {
+	if !strings.HasPrefix(directive, "DOCTYPE") {
+		return nil
+	}
+	header, subset := directive, ""
+	if i := strings.IndexByte(directive, '['); i >= 0 {
+		header, subset = directive[:i], directive[i+1:]
+	}
+	decls := parseEntityDecls(subset)
+	if fields := dtdFields(header); p.entityResolver != nil && len(fields) > 2 {
+		// DOCTYPE name ExternalID
+		if publicID, system, ok := externalID(fields[2:]); ok {
+			dtd, err := p.resolveEntity(publicID, system)
+			if err != nil {
+				return err
+			}
+			decls = append(decls, parseEntityDecls(dtd)...)
+			parseDTDIDAttrs(dtd, p.idAttrs)
+		}
+	}
+	for _, decl := range decls {
+		if _, found := p.decoder.Entity[decl.name]; found {
+			continue // the first declaration is binding
+		}
+		value := decl.value
+		if decl.external {
+			if p.entityResolver == nil {
+				continue
+			}
+			content, err := p.resolveEntity(decl.publicID, decl.system)
+			if err != nil {
+				return err
+			}
+			value = stripTextDecl(content)
+		}
+		if !p.ownEntities {
+			// Don't modify the map of the caller.
+			entities := make(map[string]string, len(p.decoder.Entity)+len(decls))
+			for k, v := range p.decoder.Entity {
+				entities[k] = v
+			}
+			p.decoder.Entity = entities
+			p.ownEntities = true
+		}
+		p.decoder.Entity[decl.name] = value
+	}
+	return nil
+}
+
+// resolveEntity reads the external resource with the given identifiers. It
+// returns "" if the resolver skipped it.
+func (p *parser) resolveEntity(publicID, system string) (string, error) {
+	r, err := p.entityResolver.ResolveEntity(publicID, system)
+	if err != nil {
+		return "", fmt.Errorf("xmlquery: resolving %q: %w", system, err)
+	}
+	if r == nil {
+		return "", nil
+	}
+	defer r.Close()
+	b, err := io.ReadAll(r)
+	if err != nil {
+		return "", fmt.Errorf("xmlquery: reading %q: %w", system, err)
+	}
+	return string(b), nil
+}
+
+// stripTextDecl removes the byte order mark and text declaration an
+// external parsed entity may start with.
+func stripTextDecl(s string) string {
+	s = strings.TrimPrefix(s, string(utf8BOM))
+	if strings.HasPrefix(s, "<?xml") {
+		if end := strings.Index(s, "?>"); end >= 0 {
+			s = s[end+2:]
+		}
+	}
+	return s
+}
+
+// parseEntityDecls returns the general entity declarations of a DTD.
+func parseEntityDecls(dtd string) []entityDecl {
+	var decls []entityDecl
+	for {
+		i := strings.Index(dtd, "<!ENTITY")
+		if i < 0 {
+			return decls
+		}
+		dtd = dtd[i+len("<!ENTITY"):]
+		end := declEnd(dtd)
+		fields := dtdFields(dtd[:end])
+		dtd = dtd[end:]
+		if len(fields) < 2 || fields[0] == "%" {
+			continue // parameter entity
+		}
+		decl := entityDecl{name: fields[0]}
+		if value, ok := unquote(fields[1]); ok {
+			decl.value = expandCharRefs(value)
+		} else if decl.publicID, decl.system, ok = externalID(fields[1:]); ok {
+			if n := len(fields); n >= 2 && fields[n-2] == "NDATA" {
+				continue // unparsed entity
+			}
+			decl.external = true
+		} else {
+			continue
+		}
+		decls = append(decls, decl)
+	}
+}
+
+// declEnd returns the index of the '>' that ends the markup declaration at
+// the start of s, skipping quoted literals, or len(s).
+func declEnd(s string) int {
+	var quote byte
+	for i := 0; i < len(s); i++ {
+		switch c := s[i]; {
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			}
+		case c == '"' || c == '\'':
+			quote = c
+		case c == '>':
+			return i
+		}
+	}
+	return len(s)
+}
+
+// externalID parses SYSTEM "uri" or PUBLIC "id" "uri" from the start of
+// fields.
+func externalID(fields []string) (publicID, system string, ok bool) {
+	switch {
+	case len(fields) >= 2 && fields[0] == "SYSTEM":
+		system, ok = unquote(fields[1])
+	case len(fields) >= 3 && fields[0] == "PUBLIC":
+		if publicID, ok = unquote(fields[1]); ok {
+			system, ok = unquote(fields[2])
+		}
+	}
+	return publicID, system, ok
+}
+
+// unquote returns the content of a quoted literal.
+func unquote(s string) (string, bool) {
+	if len(s) < 2 || (s[0] != '"' && s[0] != '\'') || s[len(s)-1] != s[0] {
+		return "", false
+	}
+	return s[1 : len(s)-1], true
+}
+
+// expandCharRefs replaces the character references in an entity value,
+// which are expanded when the entity is declared.
+func expandCharRefs(s string) string {
+	if !strings.Contains(s, "&#") {
+		return s
+	}
+	var b strings.Builder
+	for {
+		i := strings.Index(s, "&#")
+		if i < 0 {
+			b.WriteString(s)
+			return b.String()
+		}
+		end := strings.IndexByte(s[i:], ';')
+		if end < 0 {
+			b.WriteString(s)
+			return b.String()
+		}
+		ref := s[i+2 : i+end]
+		var r uint64
+		var err error
+		if strings.HasPrefix(ref, "x") {
+			r, err = strconv.ParseUint(ref[1:], 16, 32)
+		} else {
+			r, err = strconv.ParseUint(ref, 10, 32)
+		}
+		b.WriteString(s[:i])
+		if err != nil {
+			b.WriteString(s[i : i+end+1])
+		} else {
+			b.WriteRune(rune(r))
+		}
+		s = s[i+end+1:]
+	}
+}
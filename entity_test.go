@@ -0,0 +1,108 @@
+package xmlquery
+
+import (
+	"errors"
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestInternalEntities(t *testing.T) {
+	s := `<!DOCTYPE doc [
+		<!ENTITY company "Acme &#x26; Co">
+		<!ENTITY gt2 '>>'>
+		<!ENTITY % param "ignored">
+		<!ENTITY logo SYSTEM "logo.gif" NDATA gif>
+	]><doc a="&gt2;">&company; &amp; &gt2;</doc>`
+	// The declarations are only used when asked for.
+	if _, err := Parse(strings.NewReader(s)); err == nil {
+		t.Fatal("expected an error for entities declared without DTDEntities")
+	}
+	doc, err := ParseWithOptions(strings.NewReader(s), ParserOptions{DTDEntities: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	root := FindOne(doc, "/doc")
+	testValue(t, root.InnerText(), "Acme & Co & >>")
+	testValue(t, root.SelectAttr("a"), ">>")
+
+	// The entities of the caller are kept, and take precedence.
+	entities := map[string]string{"company": "Other", "x": "y"}
+	doc, err = ParseWithOptions(strings.NewReader(strings.Replace(s, "&amp;", "&x;", 1)), ParserOptions{DTDEntities: true, Decoder: &DecoderOptions{Strict: true, Entity: entities}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	testValue(t, FindOne(doc, "/doc").InnerText(), "Other y >>")
+	testValue(t, len(entities), 2)
+}
+
+func TestEntityResolver(t *testing.T) {
+	s := `<!DOCTYPE doc PUBLIC "-//Test//DTD Doc//EN" "doc.dtd" [
+		<!ENTITY local "internal">
+		<!ENTITY chapter SYSTEM "chapter.txt">
+	]><doc id="d1">&local; &dtd; &chapter;</doc>`
+	var requests []string
+	resolver := EntityResolverFunc(func(publicID, systemID string) (io.ReadCloser, error) {
+		requests = append(requests, publicID+"|"+systemID)
+		switch systemID {
+		case "doc.dtd":
+			return io.NopCloser(strings.NewReader(`<!ENTITY local "external"><!ENTITY dtd "from dtd"><!ATTLIST doc id ID #IMPLIED>`)), nil
+		case "chapter.txt":
+			return io.NopCloser(strings.NewReader(`<?xml encoding="UTF-8"?>chapter text`)), nil
+		}
+		return nil, errors.New("denied")
+	})
+	doc, err := ParseWithOptions(strings.NewReader(s), ParserOptions{EntityResolver: resolver})
+	if err != nil {
+		t.Fatal(err)
+	}
+	testValue(t, FindOne(doc, "/doc").InnerText(), "internal from dtd chapter text")
+	testValue(t, strings.Join(requests, ","), "-//Test//DTD Doc//EN|doc.dtd,|chapter.txt")
+	testTrue(t, doc.GetElementByID("d1") != nil)
+
+	// Without a resolver, external entities are unknown.
+	if _, err := Parse(strings.NewReader(s)); err == nil {
+		t.Fatal("expected error for undefined entity")
+	}
+
+	// A resolver can skip a resource or deny it.
+	skip := EntityResolverFunc(func(publicID, systemID string) (io.ReadCloser, error) {
+		return nil, nil
+	})
+	_, err = ParseWithOptions(strings.NewReader(`<!DOCTYPE doc SYSTEM "doc.dtd"><doc/>`), ParserOptions{EntityResolver: skip})
+	testTrue(t, err == nil)
+	_, err = ParseWithOptions(strings.NewReader(`<!DOCTYPE doc SYSTEM "secret.dtd"><doc/>`), ParserOptions{EntityResolver: resolver})
+	var perr *ParseError
+	if !errors.As(err, &perr) || !strings.Contains(err.Error(), `resolving "secret.dtd": denied`) {
+		t.Fatalf("got %v, want the error of the resolver", err)
+	}
+}
+
+func TestHTMLEntities(t *testing.T) {
+	s := `<!DOCTYPE p [<!ENTITY copy "(c)">]><p title="caf&eacute;">a&nbsp;&mdash;&nbsp;b &copy; &fjlig; &lt;</p>`
+	if _, err := ParseWithOptions(strings.NewReader(s), ParserOptions{Decoder: &DecoderOptions{Strict: true}}); err == nil {
+		t.Fatal("expected an error for undeclared entities")
+	}
+	doc, err := ParseWithOptions(strings.NewReader(s), ParserOptions{HTMLEntities: true, DTDEntities: true, Decoder: &DecoderOptions{Strict: true}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	p := FindOne(doc, "/p")
+	testValue(t, p.SelectAttr("title"), "caf\u00e9")
+	testValue(t, p.InnerText(), "a\u00a0\u2014\u00a0b (c) fj <")
+
+	// Entities of the caller take precedence.
+	doc, err = ParseWithOptions(strings.NewReader(`<p>&nbsp;</p>`), ParserOptions{HTMLEntities: true, Decoder: &DecoderOptions{Strict: true, Entity: map[string]string{"nbsp": "[nbsp]"}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	testValue(t, FindOne(doc, "/p").InnerText(), "[nbsp]")
+
+	for name, text := range map[string]string{"not": "\u00ac", "semi": ";", "NewLine": "\n", "notin": "\u2209", "notit": "", "nbsp2": "", "x:y": ""} {
+		value, ok := htmlEntity(name)
+		testValue(t, ok, text != "")
+		testValue(t, value, text)
+	}
+	_, err = ParseWithOptions(strings.NewReader(`<p>&notit;</p>`), ParserOptions{HTMLEntities: true, Decoder: &DecoderOptions{Strict: true}})
+	testTrue(t, err != nil)
+}
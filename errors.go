@@ -0,0 +1,111 @@
+package xmlquery
+
+import (
+	"errors"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/suifengpiao14/xmlquery/xml"
+)
+
+// A ParseError is returned by Parse and the other parsing functions when the
+// input is not a well-formed document. It records where in the input the
+// problem was found; use errors.As to retrieve it:
+//
+//	var perr *xmlquery.ParseError
+//	if errors.As(err, &perr) {
+//		log.Printf("line %d, column %d: %v", perr.Line, perr.Column, perr.Err)
+//	}
+type ParseError struct {
+	Line   int    // 1-based line of the start of the offending token
+	Column int    // 1-based byte column of the start of the token
+	Offset int64  // byte offset of the start of the token in the input
+	Token  string // the input read for the offending token, possibly truncated
+	Err    error  // the underlying error, such as an *xml.SyntaxError
+}
+
+func (e *ParseError) Error() string {
+	msg := e.Err.Error()
+	var syntax *xml.SyntaxError
+	if errors.As(e.Err, &syntax) {
+		msg = syntax.Msg
+	}
+	s := "xmlquery: line " + strconv.Itoa(e.Line) + ", column " + strconv.Itoa(e.Column) + ": " + msg
+	if e.Token != "" {
+		s += " (near " + strconv.Quote(e.Token) + ")"
+	}
+	return s
+}
+
+func (e *ParseError) Unwrap() error {
+	return e.Err
+}
+
+// recoverFrom records the syntax error err as a diagnostic and prepares the
+// decoder to continue after it. It returns nil if parsing can continue,
+// io.EOF if it should stop with the tree built so far, or the error to fail
+// with.
+func (p *parser) recoverFrom(err error) error {
+	if err == io.EOF {
+		return err
+	}
+	perr := p.parseError(err).(*ParseError)
+	var syntax *xml.SyntaxError
+	if !errors.As(err, &syntax) {
+		return perr
+	}
+	if p.recoveredAt == perr.Offset {
+		// No input was consumed since the last error, typically because
+		// the input ended inside an element.
+		return io.EOF
+	}
+	p.recoveredAt = perr.Offset
+	p.diagnostics = append(p.diagnostics, perr)
+	p.decoder.ClearError()
+	return nil
+}
+
+// undefinedEntity records a diagnostic for the entity reference ref, which
+// the decoder has just read and kept as text since it isn't defined.
+func (p *parser) undefinedEntity(ref string) {
+	msg := "invalid character entity " + ref
+	if !strings.HasSuffix(ref, ";") {
+		msg += " (no semicolon)"
+	}
+	line, column := p.decoder.InputPos()
+	p.diagnostics = append(p.diagnostics, &ParseError{
+		Line:   line,
+		Column: column - len(ref),
+		Offset: p.decoder.InputOffset() - int64(len(ref)),
+		Token:  ref,
+		Err:    &xml.SyntaxError{Msg: msg, Line: line},
+	})
+}
+
+// maxErrorToken is the length the Token of a ParseError is truncated to.
+const maxErrorToken = 40
+
+// parseError wraps err, which occurred while reading a token, in a
+// ParseError reporting the start of the token. io.EOF is returned
+// unchanged.
+func (p *parser) parseError(err error) error {
+	if err == io.EOF {
+		return err
+	}
+	var perr *ParseError
+	if errors.As(err, &perr) {
+		return err
+	}
+	token := p.reader.Cache()
+	if len(token) > maxErrorToken {
+		token = token[:maxErrorToken]
+	}
+	return &ParseError{
+		Line:   p.tokenLine,
+		Column: p.tokenColumn,
+		Offset: p.tokenOffset,
+		Token:  string(token),
+		Err:    err,
+	}
+}
@@ -0,0 +1,102 @@
+package xmlquery
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/suifengpiao14/xmlquery/xml"
+)
+
+func TestParseError(t *testing.T) {
+	_, err := Parse(strings.NewReader("<root>\n  <a>text</b>\n</root>"))
+	var perr *ParseError
+	if !errors.As(err, &perr) {
+		t.Fatalf("got %v, want a *ParseError", err)
+	}
+	// The position is that of the start of the offending token.
+	testValue(t, perr.Line, 2)
+	testValue(t, perr.Column, 10)
+	testValue(t, perr.Offset, int64(16))
+	testValue(t, perr.Token, "/b>")
+	var syntax *xml.SyntaxError
+	testTrue(t, errors.As(err, &syntax))
+	testValue(t, err.Error(), `xmlquery: line 2, column 10: element <a> closed by </b> (near "/b>")`)
+}
+
+func TestParseErrorColumn(t *testing.T) {
+	for s, column := range map[string]int{
+		"<r><ab></c></ab></r>": 8,
+		"<r>\n<a></c>":         4,
+		"<r><a b=1/></r>":      4,
+		"<r>&bogus;</r>":       4,
+	} {
+		_, err := Parse(strings.NewReader(s))
+		var perr *ParseError
+		if !errors.As(err, &perr) {
+			t.Fatalf("%q: got %v, want a *ParseError", s, err)
+		}
+		testValue(t, perr.Column, column)
+	}
+	_, diags, err := ParseRecover(strings.NewReader("<r><ab></c></ab></r>"), ParserOptions{WellFormed: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	testValue(t, len(diags), 1)
+	testValue(t, diags[0].Column, 8)
+}
+
+func TestParseErrorNamespace(t *testing.T) {
+	_, err := ParseWithOptions(strings.NewReader("<root>\n<x:a/></root>"), ParserOptions{Decoder: &DecoderOptions{Strict: true}})
+	var perr *ParseError
+	if !errors.As(err, &perr) {
+		t.Fatalf("got %v, want a *ParseError", err)
+	}
+	testValue(t, perr.Line, 2)
+	testValue(t, err.Error(), `xmlquery: line 2, column 1: invalid XML document, namespace x is missing (near "x:a/>")`)
+}
+
+func TestParseRecover(t *testing.T) {
+	s := "<root>\n<a>1 < 2</a>\n<b>x</c></b>\n</d>\n<e>\x01</e>\n<f><g>y</f>z</root>"
+	doc, diags, err := ParseRecover(strings.NewReader(s), ParserOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	testValue(t, len(diags), 4)
+	testValue(t, diags[0].Line, 2)
+	testValue(t, diags[1].Err.Error(), "XML syntax error on line 3: unexpected end element </c>")
+	testValue(t, diags[2].Line, 4)
+	testValue(t, diags[3].Line, 5)
+	testValue(t, FindOne(doc, "//a").InnerText(), "1  2")
+	testValue(t, FindOne(doc, "//b").InnerText(), "x")
+	testValue(t, FindOne(doc, "/root/f/g").InnerText(), "y")
+	testValue(t, FindOne(doc, "/root").LastChild.Data, "z")
+}
+
+func TestParseRecoverUnexpectedEOF(t *testing.T) {
+	doc, diags, err := ParseRecover(strings.NewReader("<root><a>text</a><b>"), ParserOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	testValue(t, len(diags), 1)
+	testValue(t, diags[0].Err.Error(), "XML syntax error on line 1: unexpected EOF")
+	testValue(t, FindOne(doc, "//a").InnerText(), "text")
+	testTrue(t, FindOne(doc, "/root/b") != nil)
+}
+
+func TestParseRecoverUndefinedEntity(t *testing.T) {
+	doc, diags, err := ParseRecover(strings.NewReader("<root>\n<a t=\"&x;\">1 &bogus; 2 &amp; 3 & 4</a></root>"), ParserOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	testValue(t, len(diags), 3)
+	testValue(t, diags[0].Token, "&x;")
+	testValue(t, diags[0].Line, 2)
+	testValue(t, diags[0].Column, 7)
+	testValue(t, diags[1].Error(), `xmlquery: line 2, column 14: invalid character entity &bogus; (near "&bogus;")`)
+	testValue(t, diags[1].Offset, int64(20))
+	testValue(t, diags[2].Error(), `xmlquery: line 2, column 32: invalid character entity & (no semicolon) (near "&")`)
+	// The references are kept as text.
+	testValue(t, FindOne(doc, "//a").InnerText(), "1 &bogus; 2 & 3 & 4")
+	testValue(t, FindOne(doc, "//a").SelectAttr("t"), "&x;")
+}
@@ -0,0 +1,117 @@
+package xmlquery
+
+import (
+	"strconv"
+	"unicode/utf8"
+)
+
+// An EscapeMode selects how special characters in text and attribute values
+// are written, see WithEscapeMode.
+type EscapeMode int
+
+const (
+	// EscapeDefault writes &amp;, &lt; and &gt; and numeric references for
+	// quotes, like html.EscapeString.
+	EscapeDefault EscapeMode = iota
+	// EscapeNamed uses the predefined entities &amp;, &lt;, &gt;, &quot;
+	// and &apos;.
+	EscapeNamed
+	// EscapeNumeric uses character references, such as &#38; and &#60;.
+	EscapeNumeric
+	// EscapeMinimal escapes only what is required to stay well-formed: '&'
+	// and '<', '>' after "]]" in text, and the quote character delimiting
+	// an attribute value.
+	EscapeMinimal
+)
+
+// WithEscapeMode sets how special characters are escaped in text and
+// attribute values. Unlike the default output, attribute values are escaped
+// as well.
+func WithEscapeMode(mode EscapeMode) OutputOption {
+	return func(oc *outputConfiguration) {
+		oc.escapeMode = mode
+		oc.escapeAttrs = true
+	}
+}
+
+// WithASCIIOnly writes every non-ASCII character of text and attribute
+// values as a numeric character reference, such as &#xe9;, for consumers
+// that only handle ASCII. Attribute values are escaped as well.
+func WithASCIIOnly() OutputOption {
+	return func(oc *outputConfiguration) {
+		oc.asciiOnly = true
+		oc.escapeAttrs = true
+	}
+}
+
+// WithEscapeAttrWhitespace writes tabs, newlines and carriage returns in
+// attribute values as &#9;, &#10; and &#13;, so that they survive the
+// attribute-value normalization of XML parsers, which would otherwise turn
+// them into spaces. Attribute values are escaped as well.
+func WithEscapeAttrWhitespace() OutputOption {
+	return func(oc *outputConfiguration) {
+		oc.escapeAttrWhitespace = true
+		oc.escapeAttrs = true
+	}
+}
+
+// entityForms holds the replacement of each special character per mode.
+var entityForms = [...][5]string{
+	//                 &        <       >       "        '
+	EscapeDefault: {"&amp;", "&lt;", "&gt;", "&#34;", "&#39;"},
+	EscapeNamed:   {"&amp;", "&lt;", "&gt;", "&quot;", "&apos;"},
+	EscapeNumeric: {"&#38;", "&#60;", "&#62;", "&#34;", "&#39;"},
+	EscapeMinimal: {"&amp;", "&lt;", "&gt;", "&quot;", "&apos;"},
+}
+
+// writeEscaped writes s with special characters escaped according to
+// config. For attribute values, quote is the delimiting quote character;
+// for text it is 0.
+func writeEscaped(w xmlWriter, s string, quote byte, config *outputConfiguration) {
+	mode := config.escapeMode
+	if mode < EscapeDefault || mode > EscapeMinimal {
+		mode = EscapeDefault
+	}
+	forms := &entityForms[mode]
+	last := 0
+	for i := 0; i < len(s); {
+		c := s[i]
+		var esc string
+		width := 1
+		switch {
+		case c == '&':
+			esc = forms[0]
+		case c == '<':
+			esc = forms[1]
+		case c == '>':
+			if mode != EscapeMinimal || (quote == 0 && i >= 2 && s[i-2:i] == "]]") {
+				esc = forms[2]
+			}
+		case c == '"':
+			if mode != EscapeMinimal || quote == '"' {
+				esc = forms[3]
+			}
+		case c == '\'':
+			if mode != EscapeMinimal || quote == '\'' {
+				esc = forms[4]
+			}
+		case quote != 0 && config.escapeAttrWhitespace && (c == '\t' || c == '\n' || c == '\r'):
+			esc = "&#" + strconv.Itoa(int(c)) + ";"
+		case c >= utf8.RuneSelf && config.asciiOnly:
+			r, size := utf8.DecodeRuneInString(s[i:])
+			width = size
+			if r != utf8.RuneError || size > 1 {
+				esc = "&#x" + strconv.FormatInt(int64(r), 16) + ";"
+			}
+		}
+		if esc == "" {
+			i += width
+			continue
+		}
+		w.WriteString(s[last:i])
+		w.WriteString(esc)
+		i += width
+		last = i
+	}
+	w.WriteString(s[last:])
+}
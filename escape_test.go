@@ -0,0 +1,71 @@
+package xmlquery
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestEscapeMode(t *testing.T) {
+	doc, err := Parse(strings.NewReader(`<a title="x &lt; &quot;y&quot; &amp; 'z'">Tom &amp; "Jerry" &lt;3 ]]&gt; café</a>`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	a := doc.SelectElement("a")
+	for _, test := range []struct {
+		opts     []OutputOption
+		expected string
+	}{
+		{
+			expected: `<a title="x < "y" & 'z'">Tom &amp; &#34;Jerry&#34; &lt;3 ]]&gt; café</a>`,
+		},
+		{
+			opts:     []OutputOption{WithEscapeMode(EscapeDefault)},
+			expected: `<a title="x &lt; &#34;y&#34; &amp; &#39;z&#39;">Tom &amp; &#34;Jerry&#34; &lt;3 ]]&gt; café</a>`,
+		},
+		{
+			opts:     []OutputOption{WithEscapeMode(EscapeNamed)},
+			expected: `<a title="x &lt; &quot;y&quot; &amp; &apos;z&apos;">Tom &amp; &quot;Jerry&quot; &lt;3 ]]&gt; café</a>`,
+		},
+		{
+			opts:     []OutputOption{WithEscapeMode(EscapeNumeric)},
+			expected: `<a title="x &#60; &#34;y&#34; &#38; &#39;z&#39;">Tom &#38; &#34;Jerry&#34; &#60;3 ]]&#62; café</a>`,
+		},
+		{
+			opts:     []OutputOption{WithEscapeMode(EscapeMinimal)},
+			expected: `<a title="x &lt; &quot;y&quot; &amp; 'z'">Tom &amp; "Jerry" &lt;3 ]]&gt; café</a>`,
+		},
+		{
+			opts:     []OutputOption{WithEscapeMode(EscapeMinimal), WithASCIIOnly()},
+			expected: `<a title="x &lt; &quot;y&quot; &amp; 'z'">Tom &amp; "Jerry" &lt;3 ]]&gt; caf&#xe9;</a>`,
+		},
+	} {
+		opts := append([]OutputOption{WithOutputSelf()}, test.opts...)
+		testValue(t, a.OutputXMLWithOptions(opts...), test.expected)
+	}
+
+	a.SetAttr("title", `it's`)
+	testValue(t, a.OutputXMLWithOptions(WithOutputSelf(), WithEscapeMode(EscapeMinimal)), `<a title="it's">Tom &amp; "Jerry" &lt;3 ]]&gt; café</a>`)
+	a.SetAttr("title", `"a" b`)
+	testValue(t, a.OutputXMLWithOptions(WithOutputSelf(), WithEscapeMode(EscapeMinimal)), `<a title='"a" b'>Tom &amp; "Jerry" &lt;3 ]]&gt; café</a>`)
+}
+
+func TestASCIIOnly(t *testing.T) {
+	doc, err := Parse(strings.NewReader(`<a name="日本">€ 5 😀</a>`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	testValue(t, doc.SelectElement("a").OutputXMLWithOptions(WithOutputSelf(), WithASCIIOnly()), `<a name="&#x65e5;&#x672c;">&#x20ac; 5 &#x1f600;</a>`)
+}
+
+func TestEscapeAttrWhitespace(t *testing.T) {
+	a := &Node{Type: ElementNode, Data: "a"}
+	a.SetAttr("v", "line1\nline2\tx\r\n&")
+	AddChild(a, &Node{Type: TextNode, Data: "t\tt"})
+	testValue(t, a.OutputXMLWithOptions(WithOutputSelf(), WithEscapeAttrWhitespace()), `<a v="line1&#10;line2&#9;x&#13;&#10;&amp;">t	t</a>`)
+
+	doc, err := Parse(strings.NewReader(a.OutputXMLWithOptions(WithOutputSelf(), WithEscapeAttrWhitespace())))
+	if err != nil {
+		t.Fatal(err)
+	}
+	testValue(t, doc.SelectElement("a").SelectAttr("v"), "line1\nline2\tx\r\n&")
+}
@@ -0,0 +1,144 @@
+// Package etreeconv converts between xmlquery documents and the documents
+// of github.com/beevik/etree, without writing and parsing them again. It is
+// a module of its own, so that xmlquery doesn't depend on etree.
+package etreeconv
+
+import (
+	"strings"
+
+	"github.com/beevik/etree"
+	"github.com/suifengpiao14/xmlquery"
+	"github.com/suifengpiao14/xmlquery/xml"
+)
+
+// FromEtree returns a copy of the etree document d as a document node.
+// Prefixes are kept as they are and resolved with the namespace
+// declarations of the document.
+func FromEtree(d *etree.Document) *xmlquery.Node {
+	doc := &xmlquery.Node{Type: xmlquery.DocumentNode}
+	fromTokens(doc, d.Child, map[string]string{"xml": "http://www.w3.org/XML/1998/namespace"})
+	return doc
+}
+
+func fromTokens(parent *xmlquery.Node, tokens []etree.Token, scope map[string]string) {
+	for _, tok := range tokens {
+		var n *xmlquery.Node
+		switch tok := tok.(type) {
+		case *etree.Element:
+			n = &xmlquery.Node{Type: xmlquery.ElementNode, Data: tok.Tag, Prefix: tok.Space}
+			local, copied := scope, false
+			for _, a := range tok.Attr {
+				attr := xmlquery.Attr{Name: xml.Name{Space: a.Space, Local: a.Key}, Value: a.Value}
+				if a.Space == "xmlns" || a.Space == "" && a.Key == "xmlns" {
+					if a.Space == "xmlns" {
+						attr.NamespaceURI = "xmlns"
+					}
+					if !copied {
+						local, copied = copyScope(scope), true
+					}
+					local[declaredPrefix(a)] = a.Value
+				}
+				n.Attr = append(n.Attr, attr)
+			}
+			n.NamespaceURI = local[n.Prefix]
+			for i := range n.Attr {
+				if space := n.Attr[i].Name.Space; space != "" && space != "xmlns" {
+					n.Attr[i].NamespaceURI = local[space]
+				}
+			}
+			// Reparent sets the level of the node, before its children are
+			// added.
+			_ = xmlquery.Reparent(n, parent)
+			fromTokens(n, tok.Child, local)
+			continue
+		case *etree.CharData:
+			n = &xmlquery.Node{Type: xmlquery.TextNode, Data: tok.Data}
+			if tok.IsCData() {
+				n.Type = xmlquery.CharDataNode
+			}
+		case *etree.Comment:
+			n = &xmlquery.Node{Type: xmlquery.CommentNode, Data: tok.Data}
+		case *etree.Directive:
+			n = &xmlquery.Node{Type: xmlquery.NotationNode, Data: tok.Data}
+		case *etree.ProcInst:
+			n = &xmlquery.Node{Type: xmlquery.DeclarationNode, Data: tok.Target}
+			if strings.TrimSpace(tok.Inst) != "" {
+				n.SetProcInstData(tok.Inst)
+			}
+		default:
+			continue
+		}
+		_ = xmlquery.Reparent(n, parent)
+	}
+}
+
+// declaredPrefix returns the prefix declared by the xmlns attribute a, or
+// the empty prefix for the default namespace.
+func declaredPrefix(a etree.Attr) string {
+	if a.Space == "" {
+		return ""
+	}
+	return a.Key
+}
+
+func copyScope(scope map[string]string) map[string]string {
+	local := make(map[string]string, len(scope)+1)
+	for k, v := range scope {
+		local[k] = v
+	}
+	return local
+}
+
+// ToEtree returns a copy of the subtree rooted at n as an etree document.
+// If n is an element, it becomes the root of the document, with the
+// namespace declarations it relies on from its ancestors.
+func ToEtree(n *xmlquery.Node) *etree.Document {
+	d := etree.NewDocument()
+	switch n.Type {
+	case xmlquery.DocumentNode:
+		n.Materialize()
+		for child := n.FirstChild; child != nil; child = child.NextSibling {
+			toEtree(&d.Element, child)
+		}
+	case xmlquery.ElementNode:
+		// ImportNode copies the declarations along.
+		root := xmlquery.ImportNode(&xmlquery.Node{Type: xmlquery.DocumentNode}, n)
+		toEtree(&d.Element, root)
+	default:
+		toEtree(&d.Element, n)
+	}
+	return d
+}
+
+func toEtree(parent *etree.Element, n *xmlquery.Node) {
+	switch n.Type {
+	case xmlquery.ElementNode:
+		n.Materialize()
+		elem := parent.CreateElement(qualifiedName(n.Prefix, n.Data))
+		for _, attr := range n.Attr {
+			elem.CreateAttr(qualifiedName(attr.Name.Space, attr.Name.Local), attr.Value)
+		}
+		for child := n.FirstChild; child != nil; child = child.NextSibling {
+			toEtree(elem, child)
+		}
+	case xmlquery.TextNode:
+		parent.CreateText(n.Data)
+	case xmlquery.CharDataNode:
+		parent.CreateCData(n.Data)
+	case xmlquery.CommentNode:
+		parent.CreateComment(n.Data)
+	case xmlquery.DeclarationNode:
+		parent.CreateProcInst(n.Data, n.ProcInstData())
+	case xmlquery.NotationNode:
+		parent.CreateDirective(n.Data)
+	case xmlquery.AttributeNode:
+		parent.CreateText(n.InnerText())
+	}
+}
+
+func qualifiedName(prefix, local string) string {
+	if prefix == "" {
+		return local
+	}
+	return prefix + ":" + local
+}
@@ -0,0 +1,56 @@
+package etreeconv
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/beevik/etree"
+	"github.com/suifengpiao14/xmlquery"
+)
+
+func TestEtree(t *testing.T) {
+	const s = `<?xml version="1.0"?><r xmlns="urn:d" xmlns:x="urn:x"><x:a x:id="1" k="v">t<![CDATA[<c>]]></x:a><!--n--><b/></r>`
+	d := etree.NewDocument()
+	d.ReadSettings.PreserveCData = true
+	if err := d.ReadFromString(s); err != nil {
+		t.Fatal(err)
+	}
+	doc := FromEtree(d)
+	parsed, err := xmlquery.Parse(strings.NewReader(s))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := doc.OutputXML(false), parsed.OutputXML(false); got != want {
+		t.Errorf("FromEtree = %s, want %s", got, want)
+	}
+	a := xmlquery.FindOne(doc, "//x:a")
+	if a == nil || a.NamespaceURI != "urn:x" || a.Level() != 2 || a.Parent.LastChild.NextSibling != nil {
+		t.Fatalf("//x:a = %v", a)
+	}
+	if len(a.Attr) != 2 || a.Attr[0].NamespaceURI != "urn:x" || a.Attr[1].NamespaceURI != "" {
+		t.Errorf("attributes = %v", a.Attr)
+	}
+	if b := xmlquery.FindOne(doc, "//b"); b == nil || b.NamespaceURI != "urn:d" || b.PrevSibling.Type != xmlquery.CommentNode {
+		t.Errorf("//b = %v", b)
+	}
+
+	out, err := ToEtree(doc).WriteToString()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if out != s {
+		t.Errorf("ToEtree = %s, want %s", out, s)
+	}
+
+	// An element carries the declarations of its ancestors along.
+	out, err = ToEtree(a).WriteToString()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := `<x:a x:id="1" k="v" xmlns:x="urn:x">t<![CDATA[<c>]]></x:a>`; out != want {
+		t.Errorf("ToEtree = %s, want %s", out, want)
+	}
+	if a.Parent == nil || len(a.Attr) != 2 {
+		t.Error("ToEtree modified the element")
+	}
+}
@@ -0,0 +1,17 @@
+module github.com/suifengpiao14/xmlquery/etreeconv
+
+go 1.20
+
+require (
+	github.com/beevik/etree v1.2.0
+	github.com/suifengpiao14/xmlquery v0.0.0
+)
+
+require (
+	github.com/antchfx/xpath v1.3.3 // indirect
+	github.com/golang/groupcache v0.0.0-20210331224755-41bb18bfe9da // indirect
+	golang.org/x/net v0.33.0 // indirect
+	golang.org/x/text v0.21.0 // indirect
+)
+
+replace github.com/suifengpiao14/xmlquery => ../
@@ -0,0 +1,157 @@
+package xmlquery
+
+import (
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/antchfx/xpath"
+)
+
+// ResultType is the type of the result of an XPath expression.
+type ResultType int
+
+const (
+	// NodeSetResult is a node-set, as returned by location paths.
+	NodeSetResult ResultType = iota
+	// StringResult is a string, as returned by string functions.
+	StringResult
+	// NumberResult is a number, as returned by count(), sum() or arithmetic.
+	NumberResult
+	// BooleanResult is a boolean, as returned by comparisons.
+	BooleanResult
+)
+
+func (t ResultType) String() string {
+	switch t {
+	case NodeSetResult:
+		return "node-set"
+	case StringResult:
+		return "string"
+	case NumberResult:
+		return "number"
+	case BooleanResult:
+		return "boolean"
+	}
+	return "ResultType(" + strconv.Itoa(int(t)) + ")"
+}
+
+// A Result is the typed result of an XPath expression. The accessors
+// convert the result to the requested type following the XPath 1.0 rules
+// of the string(), number() and boolean() functions.
+type Result struct {
+	Type ResultType
+
+	nodes []*Node
+	str   string
+	num   float64
+	b     bool
+}
+
+// Evaluate evaluates the XPath expr against top and returns its typed
+// result, so expressions like `count(//item)` or `sum(//price)` can be used
+// directly. Returns an error if the expression cannot be parsed.
+func Evaluate(top *Node, expr string) (*Result, error) {
+	exp, err := getQueryFor(top, expr)
+	if err != nil {
+		return nil, err
+	}
+	return EvaluateSelector(top, exp), nil
+}
+
+// EvaluateSelector is like Evaluate, but takes a compiled selector.
+func EvaluateSelector(top *Node, selector *xpath.Expr) *Result {
+	prof := startProfile(selector)
+	defer prof.end()
+	nav := selectorNavigator(top, selector)
+	nav.prof = prof
+	switch v := selector.Evaluate(nav).(type) {
+	case *xpath.NodeIterator:
+		r := &Result{Type: NodeSetResult}
+		for v.MoveNext() {
+			r.nodes = append(r.nodes, getCurrentNode(v))
+		}
+		return r
+	case string:
+		return &Result{Type: StringResult, str: v}
+	case float64:
+		return &Result{Type: NumberResult, num: v}
+	case bool:
+		return &Result{Type: BooleanResult, b: v}
+	}
+	return &Result{Type: NodeSetResult}
+}
+
+// Nodes returns the nodes of a node-set result, or nil for other types.
+func (r *Result) Nodes() []*Node {
+	return r.nodes
+}
+
+// String returns the result as a string. For a node-set, this is the text
+// of its first node.
+func (r *Result) String() string {
+	switch r.Type {
+	case NodeSetResult:
+		if len(r.nodes) == 0 {
+			return ""
+		}
+		return r.nodes[0].InnerText()
+	case StringResult:
+		return r.str
+	case NumberResult:
+		return formatXPathNumber(r.num)
+	case BooleanResult:
+		return strconv.FormatBool(r.b)
+	}
+	return ""
+}
+
+// Number returns the result as a number. Strings and node-sets that don't
+// contain a number give NaN.
+func (r *Result) Number() float64 {
+	switch r.Type {
+	case NumberResult:
+		return r.num
+	case BooleanResult:
+		if r.b {
+			return 1
+		}
+		return 0
+	}
+	return parseXPathNumber(r.String())
+}
+
+// Bool returns the result as a boolean: a node-set is true if it is not
+// empty, a string if it is not empty and a number if it is neither zero nor
+// NaN.
+func (r *Result) Bool() bool {
+	switch r.Type {
+	case NodeSetResult:
+		return len(r.nodes) > 0
+	case StringResult:
+		return r.str != ""
+	case NumberResult:
+		return r.num != 0 && !math.IsNaN(r.num)
+	}
+	return r.b
+}
+
+func formatXPathNumber(f float64) string {
+	switch {
+	case math.IsNaN(f):
+		return "NaN"
+	case math.IsInf(f, 1):
+		return "Infinity"
+	case math.IsInf(f, -1):
+		return "-Infinity"
+	}
+	return strconv.FormatFloat(f, 'f', -1, 64)
+}
+
+func parseXPathNumber(s string) float64 {
+	f, err := strconv.ParseFloat(strings.TrimSpace(s), 64)
+	if err != nil {
+		return math.NaN()
+	}
+	return f
+}
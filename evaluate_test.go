@@ -0,0 +1,58 @@
+package xmlquery
+
+import (
+	"math"
+	"strings"
+	"testing"
+)
+
+func TestEvaluate(t *testing.T) {
+	doc, err := Parse(strings.NewReader(`<shop><item><price>1.5</price></item><item><price>2</price></item><name>Corner</name></shop>`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	eval := func(expr string) *Result {
+		r, err := Evaluate(doc, expr)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return r
+	}
+
+	r := eval("count(//item)")
+	testValue(t, r.Type, NumberResult)
+	testValue(t, r.Number(), float64(2))
+	testValue(t, r.String(), "2")
+	testValue(t, r.Bool(), true)
+
+	r = eval("sum(//price)")
+	testValue(t, r.Number(), 3.5)
+	testValue(t, r.String(), "3.5")
+
+	r = eval("//price")
+	testValue(t, r.Type, NodeSetResult)
+	testValue(t, len(r.Nodes()), 2)
+	testValue(t, r.String(), "1.5")
+	testValue(t, r.Number(), 1.5)
+	testValue(t, r.Bool(), true)
+
+	r = eval("//missing")
+	testValue(t, r.Bool(), false)
+	testValue(t, r.String(), "")
+	testTrue(t, math.IsNaN(r.Number()))
+
+	r = eval("concat(//name, '!')")
+	testValue(t, r.Type, StringResult)
+	testValue(t, r.String(), "Corner!")
+	testTrue(t, math.IsNaN(r.Number()))
+
+	r = eval("count(//item) > 1")
+	testValue(t, r.Type, BooleanResult)
+	testValue(t, r.Bool(), true)
+	testValue(t, r.Number(), float64(1))
+	testValue(t, r.String(), "true")
+
+	if _, err := Evaluate(doc, "count(//item"); err == nil {
+		t.Fatal("expected error for invalid expression")
+	}
+}
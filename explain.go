@@ -0,0 +1,229 @@
+package xmlquery
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/antchfx/xpath"
+)
+
+// An Explanation describes how an XPath expression was evaluated, see
+// Explain.
+type Explanation struct {
+	Expr string
+	// Steps lists the result of each prefix of the location path, with
+	// predicates applied one at a time, so that the step at which the
+	// number of matches drops to zero is easy to spot. For expressions that
+	// aren't location paths, it only has the whole expression.
+	Steps []ExplainStep
+	// Moves counts the navigator moves made while evaluating the whole
+	// expression, by direction: "child", "parent", "next", "previous",
+	// "first", "attribute" and "root". Failed moves are counted under the
+	// direction followed by " (none)".
+	Moves map[string]int
+	// Visited is the number of distinct nodes the evaluation visited.
+	Visited int
+}
+
+// An ExplainStep is the result of a prefix of an expression.
+type ExplainStep struct {
+	Expr    string
+	Type    ResultType
+	Matches int    // number of nodes selected, for node-sets
+	Value   string // the value, for results that aren't node-sets
+}
+
+// Explain evaluates the XPath expr like QueryAll and also returns an
+// Explanation of the evaluation, for finding out why a query doesn't match
+// what it should.
+//
+//	nodes, exp, err := xmlquery.Explain(doc, "//book[@lang='en']/title")
+//	fmt.Print(exp)
+//
+// prints
+//
+//	//book[@lang='en']/title
+//	  //book                    2 nodes
+//	  //book[@lang='en']        0 nodes
+//	  //book[@lang='en']/title  0 nodes
+//	visited 9 nodes; attribute=2 attribute (none)=2 child=12 ...
+func Explain(top *Node, expr string) ([]*Node, *Explanation, error) {
+	exp, err := getQueryFor(top, expr)
+	if err != nil {
+		return nil, nil, err
+	}
+	t := &queryTrace{moves: make(map[string]int), visited: make(map[*Node]struct{})}
+	nav := &traceNavigator{NodeNavigator: selectorNavigator(top, exp), t: t}
+	var nodes []*Node
+	if it, ok := exp.Evaluate(nav).(*xpath.NodeIterator); ok {
+		for it.MoveNext() {
+			nodes = append(nodes, navigatorNode(it.Current().(*traceNavigator).NodeNavigator))
+		}
+	}
+	e := &Explanation{Expr: expr, Moves: t.moves, Visited: len(t.visited)}
+	for _, prefix := range pathPrefixes(expr) {
+		sub, err := getQueryFor(top, prefix)
+		if err != nil {
+			continue
+		}
+		r := EvaluateSelector(top, sub)
+		step := ExplainStep{Expr: prefix, Type: r.Type}
+		if r.Type == NodeSetResult {
+			step.Matches = len(r.Nodes())
+		} else {
+			step.Value = r.String()
+		}
+		e.Steps = append(e.Steps, step)
+	}
+	return nodes, e, nil
+}
+
+// String formats the explanation as a report.
+func (e *Explanation) String() string {
+	var b strings.Builder
+	width := 0
+	for _, s := range e.Steps {
+		if len(s.Expr) > width {
+			width = len(s.Expr)
+		}
+	}
+	for _, s := range e.Steps {
+		if s.Type != NodeSetResult {
+			fmt.Fprintf(&b, "  %-*s  = %q\n", width, s.Expr, s.Value)
+		} else {
+			fmt.Fprintf(&b, "  %-*s  %d nodes\n", width, s.Expr, s.Matches)
+		}
+	}
+	moves := make([]string, 0, len(e.Moves))
+	for m := range e.Moves {
+		moves = append(moves, m)
+	}
+	sort.Strings(moves)
+	fmt.Fprintf(&b, "visited %d nodes;", e.Visited)
+	for _, m := range moves {
+		fmt.Fprintf(&b, " %s=%d", m, e.Moves[m])
+	}
+	b.WriteByte('\n')
+	return e.Expr + "\n" + b.String()
+}
+
+// pathPrefixes splits a location path into its steps and returns the path
+// up to each step and each predicate of a step. Other expressions are
+// returned whole.
+func pathPrefixes(expr string) []string {
+	var prefixes []string
+	depth := 0
+	var quote byte
+	stepStart := true
+	for i := 0; i < len(expr); i++ {
+		c := expr[i]
+		switch {
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			}
+		case c == '"' || c == '\'':
+			quote = c
+		case c == '[' || c == '(':
+			if c == '[' && depth == 0 && i > 0 && !stepStart {
+				prefixes = append(prefixes, expr[:i])
+			}
+			depth++
+		case c == ']' || c == ')':
+			depth--
+		case depth == 0 && c == '|':
+			return []string{expr}
+		case depth == 0 && c == '/':
+			if i > 0 && !stepStart {
+				prefixes = append(prefixes, expr[:i])
+			}
+			stepStart = true
+			continue
+		}
+		if c != ' ' {
+			stepStart = false
+		}
+	}
+	prefixes = append(prefixes, expr)
+	// Drop duplicates, as in "a[1][2]" ending with its last predicate.
+	out := prefixes[:0]
+	for i, p := range prefixes {
+		if i == 0 || p != prefixes[i-1] {
+			out = append(out, strings.TrimSpace(p))
+		}
+	}
+	return out
+}
+
+// queryTrace collects the moves of a traceNavigator.
+type queryTrace struct {
+	moves   map[string]int
+	visited map[*Node]struct{}
+}
+
+func (t *queryTrace) record(move string, ok bool, n *Node) {
+	if !ok {
+		move += " (none)"
+	}
+	t.moves[move]++
+	t.visited[n] = struct{}{}
+}
+
+// traceNavigator is a NodeNavigator that records its moves.
+type traceNavigator struct {
+	*NodeNavigator
+	t *queryTrace
+}
+
+func (x *traceNavigator) Copy() xpath.NodeNavigator {
+	return &traceNavigator{NodeNavigator: x.NodeNavigator.Copy().(*NodeNavigator), t: x.t}
+}
+
+func (x *traceNavigator) MoveTo(other xpath.NodeNavigator) bool {
+	if o, ok := other.(*traceNavigator); ok {
+		other = o.NodeNavigator
+	}
+	return x.NodeNavigator.MoveTo(other)
+}
+
+func (x *traceNavigator) MoveToRoot() {
+	x.NodeNavigator.MoveToRoot()
+	x.t.record("root", true, x.curr)
+}
+
+func (x *traceNavigator) MoveToParent() bool {
+	ok := x.NodeNavigator.MoveToParent()
+	x.t.record("parent", ok, x.curr)
+	return ok
+}
+
+func (x *traceNavigator) MoveToNextAttribute() bool {
+	ok := x.NodeNavigator.MoveToNextAttribute()
+	x.t.record("attribute", ok, x.curr)
+	return ok
+}
+
+func (x *traceNavigator) MoveToChild() bool {
+	ok := x.NodeNavigator.MoveToChild()
+	x.t.record("child", ok, x.curr)
+	return ok
+}
+
+func (x *traceNavigator) MoveToFirst() bool {
+	ok := x.NodeNavigator.MoveToFirst()
+	x.t.record("first", ok, x.curr)
+	return ok
+}
+
+func (x *traceNavigator) MoveToNext() bool {
+	ok := x.NodeNavigator.MoveToNext()
+	x.t.record("next", ok, x.curr)
+	return ok
+}
+
+func (x *traceNavigator) MoveToPrevious() bool {
+	ok := x.NodeNavigator.MoveToPrevious()
+	x.t.record("previous", ok, x.curr)
+	return ok
+}
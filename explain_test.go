@@ -0,0 +1,58 @@
+package xmlquery
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestExplain(t *testing.T) {
+	doc, err := Parse(strings.NewReader(`<books><book lang="de"><title>A</title></book><book lang="fr"><title>B</title></book></books>`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	nodes, exp, err := Explain(doc, "//book[@lang='en']/title")
+	if err != nil {
+		t.Fatal(err)
+	}
+	testValue(t, len(nodes), 0)
+	testDeepEqual(t, exp.Steps, []ExplainStep{
+		{Expr: "//book", Matches: 2},
+		{Expr: "//book[@lang='en']", Matches: 0},
+		{Expr: "//book[@lang='en']/title", Matches: 0},
+	})
+	testTrue(t, exp.Visited > 0)
+	testTrue(t, exp.Moves["child"] > 0)
+	testTrue(t, strings.Contains(exp.String(), "//book[@lang='en']        0 nodes"))
+
+	nodes, exp, err = Explain(doc, "/books/book[@lang='fr'][1]/title")
+	if err != nil {
+		t.Fatal(err)
+	}
+	testValue(t, len(nodes), 1)
+	testValue(t, nodes[0].InnerText(), "B")
+	testValue(t, len(exp.Steps), 5)
+	testValue(t, exp.Steps[3], ExplainStep{Expr: "/books/book[@lang='fr'][1]", Matches: 1})
+
+	nodes, exp, err = Explain(doc, "//book/@lang")
+	if err != nil {
+		t.Fatal(err)
+	}
+	testValue(t, len(nodes), 2)
+	testValue(t, nodes[1].InnerText(), "fr")
+
+	_, exp, err = Explain(doc, "count(//title)")
+	if err != nil {
+		t.Fatal(err)
+	}
+	testDeepEqual(t, exp.Steps, []ExplainStep{{Expr: "count(//title)", Type: NumberResult, Value: "2"}})
+
+	if _, _, err := Explain(doc, "//book["); err == nil {
+		t.Fatal("expected error for invalid expression")
+	}
+}
+
+func TestPathPrefixes(t *testing.T) {
+	testDeepEqual(t, pathPrefixes("/a/b[c/d='x]'][2]//e"), []string{"/a", "/a/b", "/a/b[c/d='x]']", "/a/b[c/d='x]'][2]", "/a/b[c/d='x]'][2]//e"})
+	testDeepEqual(t, pathPrefixes("a | b/c"), []string{"a | b/c"})
+	testDeepEqual(t, pathPrefixes("sum(//a/b)"), []string{"sum(//a/b)"})
+}
@@ -0,0 +1,229 @@
+package xmlquery
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// AtomNS is the namespace of Atom 1.0 feeds.
+const AtomNS = "http://www.w3.org/2005/Atom"
+
+// FeedType is the format of a feed.
+type FeedType int
+
+const (
+	// RSSFeed is an RSS 2.0 feed.
+	RSSFeed FeedType = iota + 1
+	// AtomFeed is an Atom 1.0 feed.
+	AtomFeed
+)
+
+// ErrNotFeed is returned when a document is neither an RSS nor an Atom feed.
+var ErrNotFeed = errors.New("xmlquery: not an RSS or Atom feed")
+
+// A Feed gives typed access to an RSS 2.0 or Atom 1.0 feed. Node is the
+// <channel> element of an RSS feed or the <feed> element of an Atom feed, so
+// anything the accessors don't cover, such as extension elements, can still
+// be queried with XPath.
+type Feed struct {
+	Type FeedType
+	Node *Node
+}
+
+// A FeedEntry is an <item> of an RSS feed or an <entry> of an Atom feed.
+type FeedEntry struct {
+	Type FeedType
+	Node *Node
+}
+
+// A FeedLink is a link of a feed or entry. RSS links have the rel
+// "alternate", RSS enclosures the rel "enclosure".
+type FeedLink struct {
+	Href string
+	Rel  string
+	Type string
+}
+
+// NewFeed detects the format of the feed in doc and returns a Feed for it.
+func NewFeed(doc *Node) (*Feed, error) {
+	root := doc
+	if root.Type == DocumentNode {
+		root = firstChildElement(doc)
+	}
+	if root == nil {
+		return nil, ErrNotFeed
+	}
+	switch {
+	case root.Data == "rss" && root.NamespaceURI == "":
+		channel := childElementNS(root, "", "channel")
+		if channel == nil {
+			return nil, fmt.Errorf("xmlquery: RSS feed has no channel")
+		}
+		return &Feed{Type: RSSFeed, Node: channel}, nil
+	case root.Data == "feed" && root.NamespaceURI == AtomNS:
+		return &Feed{Type: AtomFeed, Node: root}, nil
+	}
+	return nil, ErrNotFeed
+}
+
+// Title returns the title of the feed.
+func (f *Feed) Title() string {
+	return feedText(f.Node, f.Type, "title")
+}
+
+// Links returns the links of the feed.
+func (f *Feed) Links() []FeedLink {
+	return feedLinks(f.Node, f.Type)
+}
+
+// Link returns the address of the feed's web site, that is the first link
+// with rel "alternate".
+func (f *Feed) Link() string {
+	return alternateLink(f.Links())
+}
+
+// Updated returns the time the feed was last updated: lastBuildDate (or
+// pubDate) for RSS, updated for Atom.
+func (f *Feed) Updated() (time.Time, error) {
+	if f.Type == RSSFeed {
+		if s := feedText(f.Node, f.Type, "lastBuildDate"); s != "" {
+			return parseFeedTime(s)
+		}
+		return parseFeedTime(feedText(f.Node, f.Type, "pubDate"))
+	}
+	return parseFeedTime(feedText(f.Node, f.Type, "updated"))
+}
+
+// Entries returns the items or entries of the feed, in document order.
+func (f *Feed) Entries() []*FeedEntry {
+	name, ns := "item", ""
+	if f.Type == AtomFeed {
+		name, ns = "entry", AtomNS
+	}
+	var entries []*FeedEntry
+	for c := f.Node.FirstChild; c != nil; c = c.NextSibling {
+		if c.Type == ElementNode && c.Data == name && c.NamespaceURI == ns {
+			entries = append(entries, &FeedEntry{Type: f.Type, Node: c})
+		}
+	}
+	return entries
+}
+
+// Title returns the title of the entry.
+func (e *FeedEntry) Title() string {
+	return feedText(e.Node, e.Type, "title")
+}
+
+// ID returns the guid of an RSS item or the id of an Atom entry.
+func (e *FeedEntry) ID() string {
+	if e.Type == RSSFeed {
+		return feedText(e.Node, e.Type, "guid")
+	}
+	return feedText(e.Node, e.Type, "id")
+}
+
+// Summary returns the description of an RSS item or the summary (or, if
+// missing, the content) of an Atom entry.
+func (e *FeedEntry) Summary() string {
+	if e.Type == RSSFeed {
+		return feedText(e.Node, e.Type, "description")
+	}
+	if s := feedText(e.Node, e.Type, "summary"); s != "" {
+		return s
+	}
+	return feedText(e.Node, e.Type, "content")
+}
+
+// Links returns the links of the entry.
+func (e *FeedEntry) Links() []FeedLink {
+	return feedLinks(e.Node, e.Type)
+}
+
+// Link returns the first link of the entry with rel "alternate".
+func (e *FeedEntry) Link() string {
+	return alternateLink(e.Links())
+}
+
+// Published returns the publication time of the entry: pubDate for RSS,
+// published (or, if missing, updated) for Atom.
+func (e *FeedEntry) Published() (time.Time, error) {
+	if e.Type == RSSFeed {
+		return parseFeedTime(feedText(e.Node, e.Type, "pubDate"))
+	}
+	if s := feedText(e.Node, e.Type, "published"); s != "" {
+		return parseFeedTime(s)
+	}
+	return parseFeedTime(feedText(e.Node, e.Type, "updated"))
+}
+
+func feedText(n *Node, typ FeedType, name string) string {
+	ns := ""
+	if typ == AtomFeed {
+		ns = AtomNS
+	}
+	if c := childElementNS(n, ns, name); c != nil {
+		return strings.TrimSpace(c.InnerText())
+	}
+	return ""
+}
+
+func feedLinks(n *Node, typ FeedType) []FeedLink {
+	var links []FeedLink
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		if c.Type != ElementNode {
+			continue
+		}
+		switch {
+		case typ == AtomFeed && c.NamespaceURI == AtomNS && c.Data == "link":
+			rel := c.SelectAttr("rel")
+			if rel == "" {
+				rel = "alternate"
+			}
+			links = append(links, FeedLink{Href: c.SelectAttr("href"), Rel: rel, Type: c.SelectAttr("type")})
+		case typ == RSSFeed && c.NamespaceURI == "" && c.Data == "link":
+			links = append(links, FeedLink{Href: strings.TrimSpace(c.InnerText()), Rel: "alternate"})
+		case typ == RSSFeed && c.NamespaceURI == "" && c.Data == "enclosure":
+			links = append(links, FeedLink{Href: c.SelectAttr("url"), Rel: "enclosure", Type: c.SelectAttr("type")})
+		}
+	}
+	return links
+}
+
+func alternateLink(links []FeedLink) string {
+	for _, l := range links {
+		if l.Rel == "alternate" {
+			return l.Href
+		}
+	}
+	return ""
+}
+
+// feedTimeLayouts are the date formats found in RSS and Atom feeds.
+var feedTimeLayouts = []string{
+	time.RFC3339,
+	time.RFC3339Nano,
+	time.RFC1123Z,
+	time.RFC1123,
+	"Mon, 2 Jan 2006 15:04:05 -0700",
+	"Mon, 2 Jan 2006 15:04:05 MST",
+	"Mon, 02 Jan 2006 15:04 -0700",
+	"Mon, 2 Jan 2006 15:04 MST",
+	"02 Jan 2006 15:04:05 -0700",
+	"2 Jan 2006 15:04:05 MST",
+	time.RFC822Z,
+	time.RFC822,
+}
+
+func parseFeedTime(s string) (time.Time, error) {
+	if s == "" {
+		return time.Time{}, errors.New("xmlquery: no date in feed")
+	}
+	for _, layout := range feedTimeLayouts {
+		if t, err := time.Parse(layout, s); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("xmlquery: unrecognized feed date %q", s)
+}
@@ -0,0 +1,114 @@
+package xmlquery
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRSSFeed(t *testing.T) {
+	s := `<?xml version="1.0"?>
+<rss version="2.0" xmlns:dc="http://purl.org/dc/elements/1.1/">
+<channel>
+  <title>W3Schools Home Page</title>
+  <link>https://www.w3schools.com</link>
+  <lastBuildDate>Mon, 06 Sep 2021 16:45:00 +0000</lastBuildDate>
+  <item>
+    <title>RSS Tutorial</title>
+    <link>https://www.w3schools.com/xml/xml_rss.asp</link>
+    <guid>rss-1</guid>
+    <description>New RSS tutorial</description>
+    <pubDate>Sun, 5 Sep 2021 08:00:00 GMT</pubDate>
+    <enclosure url="https://example.com/a.mp3" type="audio/mpeg" length="1"></enclosure>
+    <dc:creator>Jane</dc:creator>
+  </item>
+  <item><title>XML Tutorial</title></item>
+</channel>
+</rss>`
+	doc, err := Parse(strings.NewReader(s))
+	if err != nil {
+		t.Fatal(err)
+	}
+	feed, err := NewFeed(doc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	testValue(t, feed.Type, RSSFeed)
+	testValue(t, feed.Title(), "W3Schools Home Page")
+	testValue(t, feed.Link(), "https://www.w3schools.com")
+	updated, err := feed.Updated()
+	if err != nil {
+		t.Fatal(err)
+	}
+	testTrue(t, updated.Equal(time.Date(2021, 9, 6, 16, 45, 0, 0, time.UTC)))
+
+	entries := feed.Entries()
+	testValue(t, len(entries), 2)
+	e := entries[0]
+	testValue(t, e.Title(), "RSS Tutorial")
+	testValue(t, e.ID(), "rss-1")
+	testValue(t, e.Summary(), "New RSS tutorial")
+	testValue(t, e.Link(), "https://www.w3schools.com/xml/xml_rss.asp")
+	testValue(t, e.Links()[1], FeedLink{Href: "https://example.com/a.mp3", Rel: "enclosure", Type: "audio/mpeg"})
+	published, err := e.Published()
+	if err != nil {
+		t.Fatal(err)
+	}
+	testValue(t, published.Day(), 5)
+	testValue(t, FindOne(e.Node, "dc:creator").InnerText(), "Jane")
+
+	if _, err := entries[1].Published(); err == nil {
+		t.Fatal("expected error for missing pubDate")
+	}
+}
+
+func TestAtomFeed(t *testing.T) {
+	s := `<?xml version="1.0" encoding="utf-8"?>
+<feed xmlns="http://www.w3.org/2005/Atom">
+  <title>Example Feed</title>
+  <link href="http://example.org/feed" rel="self"></link>
+  <link href="http://example.org/"></link>
+  <updated>2003-12-13T18:30:02Z</updated>
+  <entry>
+    <title>Atom-Powered Robots Run Amok</title>
+    <link href="http://example.org/2003/12/13/atom03"></link>
+    <id>urn:uuid:1225c695-cfb8-4ebb-aaaa-80da344efa6a</id>
+    <updated>2003-12-13T18:30:02+01:00</updated>
+    <content>Some text.</content>
+  </entry>
+</feed>`
+	doc, err := Parse(strings.NewReader(s))
+	if err != nil {
+		t.Fatal(err)
+	}
+	feed, err := NewFeed(doc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	testValue(t, feed.Type, AtomFeed)
+	testValue(t, feed.Title(), "Example Feed")
+	testValue(t, feed.Link(), "http://example.org/")
+	testValue(t, len(feed.Links()), 2)
+
+	entries := feed.Entries()
+	testValue(t, len(entries), 1)
+	e := entries[0]
+	testValue(t, e.ID(), "urn:uuid:1225c695-cfb8-4ebb-aaaa-80da344efa6a")
+	testValue(t, e.Summary(), "Some text.")
+	testValue(t, e.Link(), "http://example.org/2003/12/13/atom03")
+	published, err := e.Published()
+	if err != nil {
+		t.Fatal(err)
+	}
+	testTrue(t, published.Equal(time.Date(2003, 12, 13, 17, 30, 2, 0, time.UTC)))
+}
+
+func TestNewFeedNotFeed(t *testing.T) {
+	doc, err := Parse(strings.NewReader(`<feed></feed>`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := NewFeed(doc); err != ErrNotFeed {
+		t.Fatalf("expected ErrNotFeed, got %v", err)
+	}
+}
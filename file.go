@@ -0,0 +1,227 @@
+package xmlquery
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"io"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"golang.org/x/net/html/charset"
+	"golang.org/x/text/encoding"
+	"golang.org/x/text/encoding/ianaindex"
+)
+
+// LoadFile reads and parses the XML file at path. Unlike ParseFile, the
+// file is read into memory and closed before LoadFile returns, so the
+// document doesn't need to be closed.
+func LoadFile(path string) (*Node, error) {
+	return LoadFileWithOptions(path, ParserOptions{})
+}
+
+// LoadFileWithOptions is like LoadFile, but with custom options.
+func LoadFileWithOptions(path string, options ParserOptions) (*Node, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return parseFileData(path, data, options)
+}
+
+// parseFileData parses data, the content of the file at path.
+func parseFileData(path string, data []byte, options ParserOptions) (*Node, error) {
+	doc, err := ParseWithOptions(bytes.NewReader(data), options)
+	if err != nil {
+		return nil, err
+	}
+	if abs, err := filepath.Abs(path); err == nil {
+		doc.SetDocumentURI((&url.URL{Scheme: "file", Path: filepath.ToSlash(abs)}).String())
+	}
+	return doc, nil
+}
+
+// SaveOptions control how SaveFile writes a document.
+type SaveOptions struct {
+	// Output are the options used to serialize the document.
+	Output []OutputOption
+	// Encoding is the character encoding of the file, such as "ISO-8859-1".
+	// The default is UTF-8. The encoding declaration is written accordingly,
+	// and characters the encoding can't represent are written as character
+	// references.
+	Encoding string
+	// BOM writes a byte order mark at the start of the file, as does
+	// WithBOM among the Output options. It requires a Unicode encoding. Files in "UTF-16" (as opposed to UTF-16LE or
+	// UTF-16BE) always start with one.
+	BOM bool
+	// Backup keeps the previous content of the file, if any, at path+".bak".
+	Backup bool
+	// Perm is the permission of a newly created file; the default is 0644.
+	// An existing file keeps its permissions.
+	Perm os.FileMode
+}
+
+// SaveFile writes n to the file at path. The file is replaced atomically:
+// the content is written to a temporary file in the same directory, which
+// is then renamed to path, so readers see either the old or the new file
+// and never a partial one. If n is not a document, it is written as the
+// only content of the file.
+func (n *Node) SaveFile(path string, options SaveOptions) error {
+	var encoder *encoding.Encoder
+	var config outputConfiguration
+	for _, opt := range options.Output {
+		opt(&config)
+	}
+	name, bom := "", options.BOM || config.bom
+	if options.Encoding != "" {
+		// IANA names are looked up first, as the WHATWG labels used for
+		// decoding map ISO-8859-1 to windows-1252.
+		e, err := ianaindex.IANA.Encoding(options.Encoding)
+		if e == nil || err != nil {
+			if e, _ = charset.Lookup(options.Encoding); e == nil {
+				return errors.New("xmlquery: unsupported encoding " + options.Encoding)
+			}
+		}
+		canonical, _ := ianaindex.IANA.Name(e)
+		canonical = strings.ToLower(canonical)
+		if canonical != "utf-8" {
+			encoder = encoding.HTMLEscapeUnsupported(e.NewEncoder())
+		}
+		if bom && !strings.HasPrefix(canonical, "utf-") {
+			return errors.New("xmlquery: byte order mark requires a Unicode encoding")
+		}
+		if canonical == "utf-16" {
+			bom = false // written by the encoder
+		}
+		name = options.Encoding
+	}
+
+	perm := options.Perm
+	if perm == 0 {
+		perm = 0o644
+	}
+	info, err := os.Stat(path)
+	switch {
+	case err == nil:
+		perm = info.Mode().Perm()
+	case !errors.Is(err, os.ErrNotExist):
+		return err
+	}
+
+	dir, base := filepath.Split(path)
+	if dir == "" {
+		dir = "."
+	}
+	tmp, err := os.CreateTemp(dir, "."+base+".tmp*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	defer tmp.Close()
+
+	var w io.Writer = tmp
+	if encoder != nil {
+		w = encoder.Writer(tmp)
+	}
+	b := bufio.NewWriter(w)
+	if bom {
+		b.WriteString("\uFEFF")
+	}
+	// The mark is written above, in the encoding of the file.
+	output := append(options.Output[:len(options.Output):len(options.Output)], func(oc *outputConfiguration) {
+		oc.bom = false
+	})
+	n.writeFile(b, name, output)
+	if err := b.Flush(); err != nil {
+		return err
+	}
+	if err := tmp.Chmod(perm); err != nil {
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if options.Backup && info != nil {
+		if err := backupFile(path, path+".bak"); err != nil {
+			return err
+		}
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+// writeFile writes n as a file with the given encoding name, rewriting or
+// adding the XML declaration to declare it.
+func (n *Node) writeFile(w *bufio.Writer, encodingName string, opts []OutputOption) {
+	if encodingName == "" {
+		if n.Type != DocumentNode {
+			opts = append([]OutputOption{WithOutputSelf()}, opts...)
+		}
+		n.writeTo(w, opts)
+		return
+	}
+	var decl *Node
+	if n.Type == DocumentNode {
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			if c.Type == DeclarationNode && c.Data == "xml" {
+				decl = c
+				break
+			}
+		}
+	}
+	version, standalone := "1.0", ""
+	if decl != nil {
+		if v := decl.SelectAttr("version"); v != "" {
+			version = v
+		}
+		standalone = decl.SelectAttr("standalone")
+	}
+	w.WriteString(`<?xml version="` + version + `" encoding="` + encodingName + `"`)
+	if standalone != "" {
+		w.WriteString(` standalone="` + standalone + `"`)
+	}
+	w.WriteString("?>")
+	opts = append([]OutputOption{WithOutputSelf()}, opts...)
+	if n.Type != DocumentNode {
+		n.writeTo(w, opts)
+		return
+	}
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		if c != decl {
+			c.writeTo(w, opts)
+		}
+	}
+}
+
+// backupFile replaces backup with the content of path, preferring a hard
+// link over a copy.
+func backupFile(path, backup string) error {
+	if err := os.Remove(backup); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	if os.Link(path, backup) == nil {
+		return nil
+	}
+	src, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+	info, err := src.Stat()
+	if err != nil {
+		return err
+	}
+	dst, err := os.OpenFile(backup, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, info.Mode().Perm())
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(dst, src); err != nil {
+		dst.Close()
+		return err
+	}
+	return dst.Close()
+}
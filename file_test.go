@@ -0,0 +1,106 @@
+package xmlquery
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestLoadFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "doc.xml")
+	if err := os.WriteFile(path, []byte(`<r><a>1</a></r>`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	doc, err := LoadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	testValue(t, FindOne(doc, "//a").InnerText(), "1")
+	testTrue(t, strings.HasPrefix(doc.DocumentURI(), "file://"))
+
+	if _, err := LoadFile(filepath.Join(t.TempDir(), "missing.xml")); !os.IsNotExist(err) {
+		t.Fatalf("expected not-exist error, got %v", err)
+	}
+}
+
+func TestSaveFile(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "doc.xml")
+	doc, err := Parse(strings.NewReader(`<?xml version="1.0" standalone="yes"?><?pi x="1"?><r><a>café</a></r>`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := doc.SaveFile(path, SaveOptions{Perm: 0o600}); err != nil {
+		t.Fatal(err)
+	}
+	data, _ := os.ReadFile(path)
+	testValue(t, string(data), `<?xml version="1.0" standalone="yes"?><?pi x="1"?><r><a>café</a></r>`)
+	info, _ := os.Stat(path)
+	testValue(t, info.Mode().Perm(), os.FileMode(0o600))
+
+	// Replacing keeps the permissions and the previous content as backup.
+	FindOne(doc, "//a").FirstChild.Data = "tea €"
+	if err := doc.SaveFile(path, SaveOptions{Encoding: "ISO-8859-1", Backup: true}); err != nil {
+		t.Fatal(err)
+	}
+	data, _ = os.ReadFile(path)
+	testValue(t, string(data), "<?xml version=\"1.0\" encoding=\"ISO-8859-1\" standalone=\"yes\"?><?pi x=\"1\"?><r><a>tea &#8364;</a></r>")
+	info, _ = os.Stat(path)
+	testValue(t, info.Mode().Perm(), os.FileMode(0o600))
+	backup, _ := os.ReadFile(path + ".bak")
+	testValue(t, string(backup), `<?xml version="1.0" standalone="yes"?><?pi x="1"?><r><a>café</a></r>`)
+
+	again, err := LoadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	testValue(t, FindOne(again, "//a").InnerText(), "tea €")
+
+	// No temporary files are left behind.
+	entries, _ := os.ReadDir(dir)
+	testValue(t, len(entries), 2)
+}
+
+func TestSaveFileBOM(t *testing.T) {
+	dir := t.TempDir()
+	doc, err := Parse(strings.NewReader(`<r>x</r>`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	a := FindOne(doc, "//r")
+	path := filepath.Join(dir, "utf8.xml")
+	if err := a.SaveFile(path, SaveOptions{BOM: true}); err != nil {
+		t.Fatal(err)
+	}
+	data, _ := os.ReadFile(path)
+	testValue(t, string(data), "\xef\xbb\xbf<r>x</r>")
+
+	path = filepath.Join(dir, "utf16.xml")
+	if err := a.SaveFile(path, SaveOptions{BOM: true, Encoding: "UTF-16LE"}); err != nil {
+		t.Fatal(err)
+	}
+	data, _ = os.ReadFile(path)
+	testTrue(t, bytes.HasPrefix(data, []byte{0xff, 0xfe, '<', 0, '?', 0}))
+
+	// WithBOM asks for the mark too, which is written only once.
+	if err := a.SaveFile(path, SaveOptions{Encoding: "UTF-16LE", Output: []OutputOption{WithBOM()}}); err != nil {
+		t.Fatal(err)
+	}
+	data, _ = os.ReadFile(path)
+	testTrue(t, bytes.HasPrefix(data, []byte{0xff, 0xfe, '<', 0, '?', 0}))
+	doc, err = LoadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	testValue(t, doc.BOM(), "UTF-16LE")
+	testValue(t, FindOne(doc, "/r").InnerText(), "x")
+
+	if err := a.SaveFile(path, SaveOptions{BOM: true, Encoding: "ISO-8859-1"}); err == nil {
+		t.Fatal("expected error for BOM with a non-Unicode encoding")
+	}
+	if err := a.SaveFile(path, SaveOptions{Encoding: "no-such-encoding"}); err == nil {
+		t.Fatal("expected error for unknown encoding")
+	}
+}
@@ -8,4 +8,4 @@ require (
 	golang.org/x/net v0.33.0
 )
 
-require golang.org/x/text v0.21.0 // indirect
+require golang.org/x/text v0.21.0
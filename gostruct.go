@@ -0,0 +1,290 @@
+package xmlquery
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"go/format"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// GoStructOptions configures GenerateGoStructs.
+type GoStructOptions struct {
+	// Package, if not empty, is written as the package clause, followed
+	// by the import of encoding/xml, so that the result is a complete
+	// source file. Otherwise only the type declarations are written.
+	Package string
+}
+
+// GenerateGoStructs infers the structure of the sample documents or
+// elements and returns the declarations of Go structs with xml tags that
+// encoding/xml can decode them into, as a starting point for the types of a
+// new kind of document.
+//
+// Elements with the same namespace and local name share a struct type,
+// named after the local name; elements that never have attributes or child
+// elements become fields of a basic type instead. A child element that
+// appears more than once in a parent becomes a slice, and an optional one a
+// pointer or, for basic types, a field tagged omitempty. The type of the
+// values of a field is bool, int or float64 if all the non-empty values in
+// the samples parse as such, and string otherwise. Each root element gets
+// an XMLName field.
+//
+//	src, err := xmlquery.GenerateGoStructs(xmlquery.GoStructOptions{Package: "feed"}, doc1, doc2)
+func GenerateGoStructs(opts GoStructOptions, samples ...*Node) ([]byte, error) {
+	g := &structGen{types: make(map[structKey]*structInfo), names: make(map[string]bool)}
+	for _, n := range samples {
+		root := n
+		if n.Type == DocumentNode {
+			n.Materialize()
+			root = firstChildElement(n)
+		}
+		if root == nil || root.Type != ElementNode {
+			return nil, errors.New("xmlquery: sample has no root element")
+		}
+		g.info(root).root = true
+		g.add(root)
+	}
+	if len(g.order) == 0 {
+		return nil, errors.New("xmlquery: no samples to generate Go structs from")
+	}
+	var b bytes.Buffer
+	if opts.Package != "" {
+		fmt.Fprintf(&b, "package %s\n\nimport \"encoding/xml\"\n\n", opts.Package)
+	}
+	for _, info := range g.order {
+		if !info.simple() {
+			g.write(&b, info)
+		}
+	}
+	src, err := format.Source(append(bytes.TrimRight(b.Bytes(), "\n"), '\n'))
+	if err != nil {
+		return nil, fmt.Errorf("xmlquery: generating Go structs: %v", err)
+	}
+	return src, nil
+}
+
+// structKey is the expanded name of an element or attribute.
+type structKey struct {
+	uri, local string
+}
+
+// valueStats records which basic types the values of a field parse as.
+type valueStats struct {
+	seen                     bool // a non-empty value
+	notBool, notInt, notReal bool
+}
+
+func (s *valueStats) add(v string) {
+	v = strings.TrimSpace(v)
+	if v == "" {
+		return
+	}
+	s.seen = true
+	if v != "true" && v != "false" {
+		s.notBool = true
+	}
+	digits := strings.TrimPrefix(v, "-")
+	if _, err := strconv.ParseInt(v, 10, 64); err != nil || (len(digits) > 1 && digits[0] == '0') || v[0] == '+' {
+		// Leading zeros are kept in codes such as "007".
+		s.notInt = true
+	}
+	if _, err := strconv.ParseFloat(v, 64); err != nil || strings.Trim(digits, "0123456789.eE+-") != "" || (len(digits) > 1 && digits[0] == '0' && digits[1] != '.') {
+		s.notReal = true
+	}
+}
+
+// goType returns the Go type of the values.
+func (s *valueStats) goType() string {
+	switch {
+	case !s.seen:
+		return "string"
+	case !s.notBool:
+		return "bool"
+	case !s.notInt:
+		return "int"
+	case !s.notReal:
+		return "float64"
+	}
+	return "string"
+}
+
+// structField is an attribute or child element of a struct.
+type structField struct {
+	key     structKey
+	present int // number of parents having it
+	max     int // maximum number of occurrences in a parent
+	values  valueStats
+}
+
+// structInfo is what is known of the elements sharing a name.
+type structInfo struct {
+	key      structKey
+	name     string // of the Go type
+	root     bool
+	count    int // number of elements
+	attrs    []*structField
+	children []*structField
+	text     valueStats
+}
+
+// simple reports whether the elements are mapped to a basic type.
+func (info *structInfo) simple() bool {
+	return !info.root && len(info.attrs) == 0 && len(info.children) == 0
+}
+
+type structGen struct {
+	types map[structKey]*structInfo
+	order []*structInfo
+	names map[string]bool // Go type names in use
+}
+
+func (g *structGen) info(n *Node) *structInfo {
+	key := structKey{n.NamespaceURI, n.Data}
+	info := g.types[key]
+	if info == nil {
+		info = &structInfo{key: key, name: goIdentifier(n.Data)}
+		for i := 2; g.names[info.name]; i++ {
+			info.name = goIdentifier(n.Data) + strconv.Itoa(i)
+		}
+		g.names[info.name] = true
+		g.types[key] = info
+		g.order = append(g.order, info)
+	}
+	return info
+}
+
+func findStructField(fields *[]*structField, key structKey) *structField {
+	for _, f := range *fields {
+		if f.key == key {
+			return f
+		}
+	}
+	f := &structField{key: key}
+	*fields = append(*fields, f)
+	return f
+}
+
+// add records element n and its descendants.
+func (g *structGen) add(n *Node) {
+	n.Materialize()
+	info := g.info(n)
+	info.count++
+	for i := range n.Attr {
+		attr := &n.Attr[i]
+		if isNamespaceDecl(*attr) {
+			continue
+		}
+		f := findStructField(&info.attrs, structKey{attr.NamespaceURI, attr.Name.Local})
+		f.present++
+		f.max = 1
+		f.values.add(attr.Value)
+	}
+	var text strings.Builder
+	occurrences := make(map[*structField]int)
+	for child := n.FirstChild; child != nil; child = child.NextSibling {
+		switch child.Type {
+		case ElementNode:
+			f := findStructField(&info.children, structKey{child.NamespaceURI, child.Data})
+			if occurrences[f]++; occurrences[f] == 1 {
+				f.present++
+			}
+			if occurrences[f] > f.max {
+				f.max = occurrences[f]
+			}
+			g.add(child)
+		case TextNode, CharDataNode:
+			text.WriteString(child.Data)
+		}
+	}
+	info.text.add(text.String())
+}
+
+// write writes the declaration of the struct type of info.
+func (g *structGen) write(b *bytes.Buffer, info *structInfo) {
+	fields := make(map[string]bool)
+	field := func(name, suffix string) string {
+		if fields[name] {
+			name += suffix
+		}
+		for i, base := 2, name; fields[name]; i++ {
+			name = base + strconv.Itoa(i)
+		}
+		fields[name] = true
+		return name
+	}
+	fmt.Fprintf(b, "type %s struct {\n", info.name)
+	if info.root {
+		fmt.Fprintf(b, "%s xml.Name `xml:%q`\n", field("XMLName", ""), goTagName(info.key, ""))
+	}
+	for _, f := range info.attrs {
+		tag := goTagName(f.key, "") + ",attr"
+		if f.present < info.count {
+			tag += ",omitempty"
+		}
+		fmt.Fprintf(b, "%s %s `xml:%q`\n", field(goIdentifier(f.key.local), "Attr"), f.values.goType(), tag)
+	}
+	for _, f := range info.children {
+		child := g.types[f.key]
+		typ, tag := child.name, goTagName(f.key, info.key.uri)
+		switch {
+		case child.simple():
+			typ = child.text.goType()
+			if f.max > 1 {
+				typ = "[]" + typ
+			} else if f.present < info.count {
+				tag += ",omitempty"
+			}
+		case f.max > 1:
+			typ = "[]" + typ
+		case f.present < info.count:
+			// Recursive elements are always optional somewhere, which
+			// keeps the types finite.
+			typ = "*" + typ
+		}
+		fmt.Fprintf(b, "%s %s `xml:%q`\n", field(goIdentifier(f.key.local), "Elem"), typ, tag)
+	}
+	if info.text.seen {
+		fmt.Fprintf(b, "%s %s `xml:\",chardata\"`\n", field("Text", "Value"), info.text.goType())
+	}
+	b.WriteString("}\n\n")
+}
+
+// goTagName returns the name of key in an xml tag, with its namespace
+// unless it is parentURI.
+func goTagName(key structKey, parentURI string) string {
+	if key.uri == "" || key.uri == parentURI {
+		return key.local
+	}
+	return key.uri + " " + key.local
+}
+
+// goInitialisms are written in upper case in Go identifiers.
+var goInitialisms = map[string]bool{
+	"API": true, "HTML": true, "HTTP": true, "ID": true, "IP": true, "JSON": true,
+	"SKU": true, "URI": true, "URL": true, "UUID": true, "XML": true,
+}
+
+// goIdentifier returns an exported Go identifier for the XML name s, such as
+// OrderID for order-id.
+func goIdentifier(s string) string {
+	var b strings.Builder
+	for _, part := range strings.FieldsFunc(s, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	}) {
+		if upper := strings.ToUpper(part); goInitialisms[upper] {
+			b.WriteString(upper)
+			continue
+		}
+		r := []rune(part)
+		r[0] = unicode.ToUpper(r[0])
+		b.WriteString(string(r))
+	}
+	id := b.String()
+	if r := []rune(id); len(r) == 0 || !unicode.IsUpper(r[0]) {
+		id = "X" + id
+	}
+	return id
+}
@@ -0,0 +1,78 @@
+package xmlquery
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestGenerateGoStructs(t *testing.T) {
+	doc1, err := Parse(strings.NewReader(`<feed xmlns="urn:feed" xmlns:p="urn:price" version="2">
+	<item id="1" sku="007"><title>Tea</title><p:price>3</p:price><tag>a</tag><tag>b</tag><in-stock>true</in-stock></item>
+	<item id="2"><title>Coffee</title><p:price>4.5</p:price><note lang="en">hot</note><part><part/></part></item>
+</feed>`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	doc2, err := Parse(strings.NewReader(`<feed xmlns="urn:feed" version="3"><updated>2024-01-02</updated></feed>`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	src, err := GenerateGoStructs(GoStructOptions{Package: "feed"}, doc1, doc2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	testValue(t, string(src), "package feed\n\nimport \"encoding/xml\"\n\n"+
+		"type Feed struct {\n"+
+		"\tXMLName xml.Name `xml:\"urn:feed feed\"`\n"+
+		"\tVersion int      `xml:\"version,attr\"`\n"+
+		"\tItem    []Item   `xml:\"item\"`\n"+
+		"\tUpdated string   `xml:\"updated,omitempty\"`\n"+
+		"}\n\n"+
+		"type Item struct {\n"+
+		"\tID      int      `xml:\"id,attr\"`\n"+
+		"\tSKU     string   `xml:\"sku,attr,omitempty\"`\n"+
+		"\tTitle   string   `xml:\"title\"`\n"+
+		"\tPrice   float64  `xml:\"urn:price price\"`\n"+
+		"\tTag     []string `xml:\"tag\"`\n"+
+		"\tInStock bool     `xml:\"in-stock,omitempty\"`\n"+
+		"\tNote    *Note    `xml:\"note\"`\n"+
+		"\tPart    *Part    `xml:\"part\"`\n"+
+		"}\n\n"+
+		"type Note struct {\n"+
+		"\tLang string `xml:\"lang,attr\"`\n"+
+		"\tText string `xml:\",chardata\"`\n"+
+		"}\n\n"+
+		"type Part struct {\n"+
+		"\tPart *Part `xml:\"part\"`\n"+
+		"}\n")
+
+	decls, err := GenerateGoStructs(GoStructOptions{}, FindOne(doc1, "//item[2]/note"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	testValue(t, string(decls), "type Note struct {\n"+
+		"\tXMLName xml.Name `xml:\"urn:feed note\"`\n"+
+		"\tLang    string   `xml:\"lang,attr\"`\n"+
+		"\tText    string   `xml:\",chardata\"`\n"+
+		"}\n")
+
+	if _, err := GenerateGoStructs(GoStructOptions{}); err == nil {
+		t.Error("GenerateGoStructs without samples returned no error")
+	}
+	if _, err := GenerateGoStructs(GoStructOptions{}, &Node{Type: DocumentNode}); err == nil {
+		t.Error("GenerateGoStructs with an empty document returned no error")
+	}
+}
+
+func TestGoIdentifier(t *testing.T) {
+	for s, want := range map[string]string{
+		"order":      "Order",
+		"order-id":   "OrderID",
+		"orderDate":  "OrderDate",
+		"item_url.x": "ItemURLX",
+		"été":        "Été",
+		"名前":         "X名前",
+	} {
+		testValue(t, goIdentifier(s), want)
+	}
+}
@@ -0,0 +1,85 @@
+package xmlquery
+
+import (
+	"sync/atomic"
+	"time"
+)
+
+// Hooks are callbacks reporting the parsing and querying done by the
+// package, to monitor an XML workload with metrics or tracing without
+// wrapping every call site, see SetHooks. Each hook is called when an
+// operation starts and returns the function to call when it ends, or nil,
+// so that it maps to a span:
+//
+//	xmlquery.SetHooks(&xmlquery.Hooks{
+//		QueryStart: func(expr string) func(xmlquery.QueryInfo) {
+//			_, span := tracer.Start(ctx, "xpath")
+//			return func(info xmlquery.QueryInfo) {
+//				span.SetAttributes(attribute.String("xpath", info.Expr))
+//				span.End()
+//			}
+//		},
+//	})
+//
+// Hooks are called synchronously by the goroutine doing the work, and may
+// be called concurrently.
+type Hooks struct {
+	// ParseStart is called when a document starts being parsed, by the
+	// functions that build a whole tree, including the subtrees deferred
+	// by ParseLazy. StreamParser and PullParser are not reported.
+	ParseStart func() func(ParseInfo)
+	// QueryStart is called when an expression starts being evaluated, by
+	// the queries a Profiler records.
+	QueryStart func(expr string) func(QueryInfo)
+}
+
+// ParseInfo describes a parse, see Hooks.
+type ParseInfo struct {
+	Duration time.Duration
+	// Bytes is the size of the input read, after decompression or
+	// conversion from another encoding.
+	Bytes int64
+	// Nodes is the number of nodes created, attributes excluded.
+	Nodes int
+	// Err is the error the parse failed with, if any.
+	Err error
+}
+
+// QueryInfo describes an evaluation of an expression, see Hooks.
+type QueryInfo struct {
+	Expr     string
+	Duration time.Duration
+	// NodesVisited is the number of moves of the navigators, see
+	// QueryStats.
+	NodesVisited int64
+}
+
+// activeHooks are the hooks set with SetHooks, if any.
+var activeHooks atomic.Pointer[Hooks]
+
+// SetHooks makes the package report its work to h from now on, replacing
+// the hooks set before. A nil h removes them.
+func SetHooks(h *Hooks) {
+	activeHooks.Store(h)
+}
+
+// parseAll parses the rest of the input and returns the document, reporting
+// the parse to the hooks.
+func (p *parser) parseAll() (*Node, error) {
+	h := activeHooks.Load()
+	if h == nil || h.ParseStart == nil {
+		return p.parseDocument()
+	}
+	end := h.ParseStart()
+	start := time.Now()
+	doc, err := p.parseDocument()
+	if end != nil {
+		end(ParseInfo{
+			Duration: time.Since(start),
+			Bytes:    p.decoder.InputOffset(),
+			Nodes:    p.nodes,
+			Err:      err,
+		})
+	}
+	return doc, err
+}
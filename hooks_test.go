@@ -0,0 +1,50 @@
+package xmlquery
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestHooks(t *testing.T) {
+	var parses []ParseInfo
+	var queries []QueryInfo
+	started := 0
+	SetHooks(&Hooks{
+		ParseStart: func() func(ParseInfo) {
+			return func(info ParseInfo) { parses = append(parses, info) }
+		},
+		QueryStart: func(expr string) func(QueryInfo) {
+			started++
+			if expr == "//b" {
+				return nil
+			}
+			return func(info QueryInfo) { queries = append(queries, info) }
+		},
+	})
+	defer SetHooks(nil)
+
+	s := `<r><a/><a/></r>`
+	doc, err := Parse(strings.NewReader(s))
+	if err != nil {
+		t.Fatal(err)
+	}
+	testValue(t, len(Find(doc, "//a")), 2)
+	testValue(t, len(Find(doc, "//b")), 0)
+	_, err = Parse(strings.NewReader("<r>"))
+	testTrue(t, err != nil)
+
+	testValue(t, len(parses), 2)
+	testValue(t, parses[0].Bytes, int64(len(s)))
+	// The declaration added by the parser, r and the a elements.
+	testValue(t, parses[0].Nodes, 4)
+	testTrue(t, parses[0].Err == nil && parses[1].Err != nil)
+
+	testValue(t, started, 2)
+	testValue(t, len(queries), 1)
+	testValue(t, queries[0].Expr, "//a")
+	testTrue(t, queries[0].NodesVisited > 0)
+
+	SetHooks(nil)
+	Find(doc, "//a")
+	testValue(t, len(queries), 1)
+}
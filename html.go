@@ -0,0 +1,78 @@
+package xmlquery
+
+import (
+	"io"
+	"strings"
+
+	"github.com/suifengpiao14/xmlquery/xml"
+	"golang.org/x/net/html"
+)
+
+// htmlNamespaces maps the foreign namespaces of the HTML parser to their URIs.
+var htmlNamespaces = map[string]string{
+	"svg":  "http://www.w3.org/2000/svg",
+	"math": "http://www.w3.org/1998/Math/MathML",
+}
+
+// htmlEntity returns the text of the HTML5 named character reference
+// &name;, for xml.Decoder.DefaultEntity.
+func htmlEntity(name string) (string, bool) {
+	ref := "&" + name + ";"
+	text := html.UnescapeString(ref)
+	// The references HTML allows without a semicolon, such as &not, are
+	// also replaced at the start of longer names: &notit; gives "¬it;".
+	// No replacement text ends with a semicolon, except that of &semi;.
+	if text == ref || strings.HasSuffix(text, ";") && name != "semi" {
+		return "", false
+	}
+	return text, true
+}
+
+// ParseHTML parses an HTML document with golang.org/x/net/html and converts
+// the result into a Node tree, so that it can be queried and written like a
+// parsed XML document. Element names are lower case, as produced by the
+// HTML parser, and the doctype becomes a NotationNode.
+func ParseHTML(r io.Reader) (*Node, error) {
+	root, err := html.Parse(r)
+	if err != nil {
+		return nil, err
+	}
+	return FromHTML(root), nil
+}
+
+// FromHTML converts a tree produced by golang.org/x/net/html into a Node
+// tree. The original tree is not modified.
+func FromHTML(n *html.Node) *Node {
+	return fromHTML(n, 0)
+}
+
+func fromHTML(n *html.Node, level int) *Node {
+	node := &Node{level: level}
+	switch n.Type {
+	case html.DocumentNode:
+		node.Type = DocumentNode
+	case html.ElementNode:
+		node.Type = ElementNode
+		node.Data = n.Data
+		node.NamespaceURI = htmlNamespaces[n.Namespace]
+		if len(n.Attr) > 0 {
+			node.Attr = make([]Attr, len(n.Attr))
+			for i, a := range n.Attr {
+				node.Attr[i] = Attr{Name: xml.Name{Space: a.Namespace, Local: a.Key}, Value: a.Val}
+			}
+		}
+	case html.CommentNode:
+		node.Type = CommentNode
+		node.Data = n.Data
+	case html.DoctypeNode:
+		node.Type = NotationNode
+		node.Data = strings.TrimSpace("DOCTYPE " + n.Data)
+	default:
+		node.Type = TextNode
+		node.Data = n.Data
+	}
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		AddChild(node, fromHTML(c, level+1))
+	}
+	return node
+}
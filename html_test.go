@@ -0,0 +1,33 @@
+package xmlquery
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParseHTML(t *testing.T) {
+	s := `<!DOCTYPE html>
+<html><head><title>Page</title></head>
+<body>
+<!-- nav -->
+<ul id="menu"><li><a href="/a">A</a><li><a href="/b" class="x">B</a></ul>
+<p>one<br>two
+<svg><circle r="1"></circle></svg>
+</body></html>`
+	doc, err := ParseHTML(strings.NewReader(s))
+	if err != nil {
+		t.Fatal(err)
+	}
+	verifyNodePointers(t, doc)
+	testValue(t, doc.FirstChild.Type, NotationNode)
+	testValue(t, doc.FirstChild.Data, "DOCTYPE html")
+	testValue(t, FindOne(doc, "//title").InnerText(), "Page")
+	links := Find(doc, "//ul[@id='menu']/li/a/@href")
+	testValue(t, len(links), 2)
+	testValue(t, links[1].InnerText(), "/b")
+	testValue(t, FindOne(doc, "//a[@class='x']").InnerText(), "B")
+	testValue(t, FindOne(doc, "//comment()").Data, " nav ")
+	testValue(t, FindOne(doc, "//circle").NamespaceURI, "http://www.w3.org/2000/svg")
+	testValue(t, FindOne(doc, "//li").OutputXML(true), `<li><a href="/a">A</a></li>`)
+	testValue(t, FindOne(doc, "//body").Level(), 2)
+}
@@ -0,0 +1,163 @@
+package xmlquery
+
+import (
+	"strings"
+)
+
+// GetElementByID returns the element whose ID is id, or nil if there is no
+// such element. IDs are taken from xml:id attributes and from attributes
+// declared with type ID in the document's internal DTD subset.
+//
+// Documents created by Parse keep an ID table built while parsing, so the
+// lookup doesn't scan the tree. For trees built or modified by hand, call
+// RebuildIDs to bring the table up to date.
+//
+// The XPath function id() of the queries compiled by this package looks
+// elements up in the same table. Its argument is a whitespace separated
+// list of IDs, or an expression selecting nodes whose values are such
+// lists.
+func (n *Node) GetElementByID(id string) *Node {
+	root := n
+	for root.Parent != nil {
+		root = root.Parent
+	}
+	if root.doc == nil || root.doc.ids == nil {
+		root.RebuildIDs()
+	}
+	return root.doc.ids[id]
+}
+
+// RebuildIDs rescans the whole tree containing n for xml:id attributes and
+// replaces the ID table used by GetElementByID. DTD-declared ID attributes
+// are recognized if the tree contains the DOCTYPE directive that declares
+// them.
+func (n *Node) RebuildIDs() {
+	idAttrs := make(map[string]map[string]bool)
+	ids := make(map[string]*Node)
+	var walk func(*Node)
+	walk = func(node *Node) {
+		switch node.Type {
+		case NotationNode:
+			parseDTDIDAttrs(node.Data, idAttrs)
+		case ElementNode:
+			if id, ok := elementID(node, idAttrs); ok {
+				if _, dup := ids[id]; !dup {
+					ids[id] = node
+				}
+			}
+		}
+		for child := node.FirstChild; child != nil; child = child.NextSibling {
+			walk(child)
+		}
+	}
+	for n.Parent != nil {
+		n = n.Parent
+	}
+	walk(n)
+	n.docData().ids = ids
+}
+
+// elementID returns the ID of element n, if it has one.
+func elementID(n *Node, idAttrs map[string]map[string]bool) (string, bool) {
+	declared := idAttrs[n.Data]
+	if n.Prefix != "" {
+		declared = idAttrs[n.Prefix+":"+n.Data]
+	}
+	for _, attr := range n.Attr {
+		if attr.Name.Space == "xml" && attr.Name.Local == "id" {
+			return strings.TrimSpace(attr.Value), true
+		}
+		if declared != nil {
+			name := attr.Name.Local
+			if attr.Name.Space != "" {
+				name = attr.Name.Space + ":" + name
+			}
+			if declared[name] {
+				return strings.TrimSpace(attr.Value), true
+			}
+		}
+	}
+	return "", false
+}
+
+// parseDTDIDAttrs scans the ATTLIST declarations of a DOCTYPE directive and
+// records every attribute of type ID in idAttrs, keyed by element name.
+func parseDTDIDAttrs(directive string, idAttrs map[string]map[string]bool) {
+	for {
+		i := strings.Index(directive, "<!ATTLIST")
+		if i < 0 {
+			return
+		}
+		directive = directive[i+len("<!ATTLIST"):]
+		end := strings.IndexByte(directive, '>')
+		if end < 0 {
+			return
+		}
+		fields := dtdFields(directive[:end])
+		directive = directive[end:]
+		if len(fields) == 0 {
+			continue
+		}
+		elem := fields[0]
+		// Each attribute definition is: Name AttType DefaultDecl, where
+		// DefaultDecl is #REQUIRED, #IMPLIED, or an optional #FIXED
+		// followed by a quoted default value.
+		for j := 1; j+1 < len(fields); {
+			name, typ := fields[j], fields[j+1]
+			j += 2
+			if typ == "NOTATION" && j < len(fields) {
+				j++ // the enumerated notation list
+			}
+			if j < len(fields) {
+				switch fields[j] {
+				case "#REQUIRED", "#IMPLIED":
+					j++
+				case "#FIXED":
+					j += 2
+				default:
+					j++
+				}
+			}
+			if typ == "ID" {
+				if idAttrs[elem] == nil {
+					idAttrs[elem] = make(map[string]bool)
+				}
+				idAttrs[elem][name] = true
+			}
+		}
+	}
+}
+
+// dtdFields splits a markup declaration body into whitespace separated
+// fields, keeping quoted literals and parenthesized groups intact.
+func dtdFields(s string) []string {
+	var fields []string
+	for i := 0; i < len(s); {
+		switch c := s[i]; {
+		case c == ' ' || c == '\t' || c == '\r' || c == '\n':
+			i++
+		case c == '"' || c == '\'':
+			j := strings.IndexByte(s[i+1:], c)
+			if j < 0 {
+				return append(fields, s[i:])
+			}
+			fields = append(fields, s[i:i+j+2])
+			i += j + 2
+		case c == '(':
+			j := strings.IndexByte(s[i:], ')')
+			if j < 0 {
+				return append(fields, s[i:])
+			}
+			fields = append(fields, s[i:i+j+1])
+			i += j + 1
+		default:
+			j := strings.IndexAny(s[i:], " \t\r\n")
+			if j < 0 {
+				return append(fields, s[i:])
+			}
+			fields = append(fields, s[i:i+j])
+			i += j
+		}
+	}
+	return fields
+}
@@ -0,0 +1,96 @@
+package xmlquery
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestGetElementByID(t *testing.T) {
+	s := `<doc><a xml:id="x1"><b xml:id=" x2 ">b</b></a><c xml:id="x1"></c></doc>`
+	doc, err := Parse(strings.NewReader(s))
+	if err != nil {
+		t.Fatal(err)
+	}
+	testValue(t, doc.GetElementByID("x1").Data, "a")
+	testValue(t, doc.GetElementByID("x2").Data, "b")
+	testTrue(t, doc.GetElementByID("x3") == nil)
+}
+
+func TestGetElementByIDFromDTD(t *testing.T) {
+	s := `<?xml version="1.0"?>
+<!DOCTYPE catalog [
+  <!ELEMENT catalog (item*)>
+  <!ATTLIST item
+    kind (book|cd) #IMPLIED
+    code ID #REQUIRED
+    note CDATA #FIXED "n">
+  <!ATTLIST other ref IDREF #IMPLIED>
+]>
+<catalog><item code="c1" kind="book"></item><other ref="c1"></other></catalog>`
+	doc, err := Parse(strings.NewReader(s))
+	if err != nil {
+		t.Fatal(err)
+	}
+	n := doc.GetElementByID("c1")
+	testTrue(t, n != nil)
+	testValue(t, n.Data, "item")
+}
+
+func TestRebuildIDs(t *testing.T) {
+	doc := &Node{Type: DocumentNode}
+	root := &Node{Type: ElementNode, Data: "root"}
+	AddChild(doc, root)
+	child := &Node{Type: ElementNode, Data: "child"}
+	child.SetAttr("xml:id", "c")
+	AddChild(root, child)
+	testTrue(t, doc.GetElementByID("c") == child)
+
+	other := &Node{Type: ElementNode, Data: "other"}
+	other.SetAttr("xml:id", "o")
+	AddChild(root, other)
+	testTrue(t, doc.GetElementByID("o") == nil)
+	doc.RebuildIDs()
+	testTrue(t, doc.GetElementByID("o") == other)
+}
+
+func TestGetElementByIDFromDescendant(t *testing.T) {
+	doc := &Node{Type: DocumentNode}
+	root := &Node{Type: ElementNode, Data: "root"}
+	AddChild(doc, root)
+	child := &Node{Type: ElementNode, Data: "child"}
+	child.SetAttr("xml:id", "c")
+	AddChild(root, child)
+	testTrue(t, child.GetElementByID("c") == child)
+	testTrue(t, root.doc == nil && child.doc == nil)
+	testTrue(t, doc.doc != nil && doc.doc.ids["c"] == child)
+}
+
+func TestXPathID(t *testing.T) {
+	s := `<doc><a xml:id="x1" ref="x3 x2"/><b xml:id="x2">b</b><c xml:id="x3"/><d ref="x1"/><e>x3</e></doc>`
+	doc, err := Parse(strings.NewReader(s))
+	if err != nil {
+		t.Fatal(err)
+	}
+	names := func(nodes []*Node) string {
+		var s []string
+		for _, n := range nodes {
+			s = append(s, n.Data)
+		}
+		return strings.Join(s, ",")
+	}
+	testValue(t, names(Find(doc, "id('x2')")), "b")
+	testValue(t, names(Find(doc, "id(' x3  x1 x9 x3')")), "a,c")
+	testValue(t, names(Find(doc, "id(//a/@ref)")), "b,c")
+	testValue(t, names(Find(doc, "id(//d/@ref | //e)")), "a,c")
+	testValue(t, names(Find(doc, "//*[@ref][id(@ref)/@xml:id = 'x1']")), "d")
+	testValue(t, names(Find(doc, "id('x9')")), "")
+	testValue(t, FindOne(doc, "//a").SelectAttr("ref"), "x3 x2")
+	testValue(t, names(Find(doc, "//*[@id = 'id(\"x1\")']")), "")
+	testValue(t, names(Find(doc, "//a[count(@*) = 2]")), "a")
+	if n, err := Evaluate(doc, "string(id('x2'))"); err != nil || n.String() != "b" {
+		t.Fatalf("string(id('x2')) = %v, %v", n, err)
+	}
+	if _, err := Query(doc, "id('x1', 'x2')"); err == nil {
+		t.Fatal("expected an error for id() with 2 arguments")
+	}
+}
@@ -0,0 +1,231 @@
+package xmlquery
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/antchfx/xpath"
+)
+
+// An Index is a hash index over the nodes of a document. Each node selected
+// by the Match expression is stored under the string value(s) of the Use
+// expression evaluated relative to that node, so that repeated lookups by key
+// don't need to rescan the whole document.
+type Index struct {
+	Match string // XPath selecting the nodes to index, e.g. "item".
+	Use   string // XPath evaluated relative to each node to produce its key, e.g. "@id".
+
+	top    *Node
+	match  *xpath.Expr
+	use    *xpath.Expr
+	cancel func() // unregisters the observer setting stale
+
+	// Lookups from concurrent queries may rebuild a stale index, so keys
+	// and stale are guarded by mu.
+	mu    sync.Mutex
+	keys  map[string][]*Node
+	stale bool // the tree changed since the index was built
+}
+
+// CreateIndex builds a hash index over the nodes in the tree of n.
+//
+// A match expression that does not start with '/' is treated like an XSLT
+// pattern and selects matching nodes anywhere in the tree, so "item" is
+// equivalent to "//item". The use expression is evaluated relative to each
+// matched node; if it yields a node-set, the node is indexed under the value
+// of every node in the set.
+//
+// The index is remembered by n and returned again by later calls with the
+// same arguments. It follows changes of the tree made with AddChild,
+// SetAttr and the other functions reported to observers, see Observe: the
+// index is rebuilt the next time it is used after such a change. Call
+// Rebuild after assigning to the fields of nodes directly.
+//
+// Queries on n of the form //name[use='key'], where name and use are the
+// unprefixed match and use expressions of an index, are answered from the
+// index instead of scanning the tree, so creating one speeds up Find,
+// QueryAll and the like without changing their calls.
+//
+//	idx, err := doc.CreateIndex("item", "@id")
+//	item := idx.LookupOne("bk101")
+func (n *Node) CreateIndex(match, use string) (*Index, error) {
+	if idx := n.Index(match, use); idx != nil {
+		return idx, nil
+	}
+	expr := match
+	if !strings.HasPrefix(expr, "/") {
+		expr = "//" + expr
+	}
+	matchExpr, err := getQueryFor(n, expr)
+	if err != nil {
+		return nil, fmt.Errorf("xmlquery: invalid index match expression '%s': %s", match, err.Error())
+	}
+	useExpr, err := getQueryFor(n, use)
+	if err != nil {
+		return nil, fmt.Errorf("xmlquery: invalid index use expression '%s': %s", use, err.Error())
+	}
+	idx := &Index{
+		Match: match,
+		Use:   use,
+		top:   n,
+		match: matchExpr,
+		use:   useExpr,
+	}
+	idx.Rebuild()
+	idx.cancel = n.Observe(func(*Mutation) {
+		idx.mu.Lock()
+		idx.stale = true
+		idx.mu.Unlock()
+	})
+	d := n.docData()
+	d.indexes = append(d.indexes, idx)
+	return idx, nil
+}
+
+// Index returns the index previously created on n with the given match and
+// use expressions, or nil if there is none.
+func (n *Node) Index(match, use string) *Index {
+	if n.doc == nil {
+		return nil
+	}
+	for _, idx := range n.doc.indexes {
+		if idx.Match == match && idx.Use == use {
+			return idx
+		}
+	}
+	return nil
+}
+
+// DropIndex removes the index with the given match and use expressions from n.
+func (n *Node) DropIndex(match, use string) {
+	if n.doc == nil {
+		return
+	}
+	for i, idx := range n.doc.indexes {
+		if idx.Match == match && idx.Use == use {
+			idx.cancel()
+			n.doc.indexes = append(n.doc.indexes[:i], n.doc.indexes[i+1:]...)
+			return
+		}
+	}
+}
+
+// Rebuild rescans the indexed tree and replaces the contents of the index.
+func (idx *Index) Rebuild() {
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+	idx.rebuild()
+}
+
+func (idx *Index) rebuild() {
+	keys := make(map[string][]*Node)
+	// Match expressions with predicates, such as //item[@type], return
+	// nested nodes after the outer ones, but lookups are in document
+	// order. Sorting is a linear check for results in order already.
+	for _, node := range sortDocumentOrder(QuerySelectorAll(idx.top, idx.match)) {
+		for _, key := range evalStrings(idx.use, node) {
+			keys[key] = append(keys[key], node)
+		}
+	}
+	idx.keys, idx.stale = keys, false
+}
+
+// current returns the contents of the index, rebuilding it first if the
+// tree changed since it was built. The map is replaced, never modified, by
+// later rebuilds.
+func (idx *Index) current() map[string][]*Node {
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+	if idx.stale {
+		idx.rebuild()
+	}
+	return idx.keys
+}
+
+// Lookup returns all indexed nodes with the given key, in document order.
+func (idx *Index) Lookup(key string) []*Node {
+	return idx.current()[key]
+}
+
+// LookupOne returns the first indexed node with the given key, or nil.
+func (idx *Index) LookupOne(key string) *Node {
+	if nodes := idx.current()[key]; len(nodes) > 0 {
+		return nodes[0]
+	}
+	return nil
+}
+
+// Len returns the number of distinct keys in the index.
+func (idx *Index) Len() int {
+	return len(idx.current())
+}
+
+// evalStrings evaluates expr relative to n and returns its result as a list
+// of strings: one per node for a node-set, otherwise a single value.
+func evalStrings(expr *xpath.Expr, n *Node) []string {
+	switch v := expr.Evaluate(selectorNavigator(n, expr)).(type) {
+	case *xpath.NodeIterator:
+		var values []string
+		for v.MoveNext() {
+			values = append(values, v.Current().Value())
+		}
+		return values
+	case string:
+		return []string{v}
+	case float64:
+		return []string{strconv.FormatFloat(v, 'f', -1, 64)}
+	case bool:
+		return []string{strconv.FormatBool(v)}
+	}
+	return nil
+}
+
+// indexLookup answers a query of the form //name[use='key'] from an index
+// created on top with that match and use expression, if there is one. ok
+// is false if the query must be evaluated.
+func indexLookup(top *Node, expr string) (nodes []*Node, ok bool) {
+	if top.doc == nil || len(top.doc.indexes) == 0 {
+		return nil, false
+	}
+	name, use, key, ok := parseIndexedQuery(expr)
+	if !ok {
+		return nil, false
+	}
+	for _, idx := range top.doc.indexes {
+		if idx.top == top && strings.TrimPrefix(idx.Match, "//") == name && strings.TrimSpace(idx.Use) == use {
+			return idx.Lookup(key), true
+		}
+	}
+	return nil, false
+}
+
+// parseIndexedQuery splits an expression of the form //name[use='key'], in
+// which use is a relative path such as @id or code, into its parts.
+func parseIndexedQuery(expr string) (name, use, key string, ok bool) {
+	s := strings.TrimSpace(expr)
+	if !strings.HasPrefix(s, "//") || !strings.HasSuffix(s, "]") {
+		return "", "", "", false
+	}
+	open := strings.IndexByte(s, '[')
+	if open < 0 {
+		return "", "", "", false
+	}
+	name, pred := s[2:open], s[open+1:len(s)-1]
+	eq := strings.IndexByte(pred, '=')
+	if eq <= 0 || pred[eq-1] == '!' {
+		return "", "", "", false
+	}
+	use, key = strings.TrimSpace(pred[:eq]), strings.TrimSpace(pred[eq+1:])
+	if len(key) < 2 || (key[0] != '\'' && key[0] != '"') || key[len(key)-1] != key[0] || strings.IndexByte(key[1:len(key)-1], key[0]) >= 0 {
+		return "", "", "", false
+	}
+	// Prefixed names are left out, as they may be resolved with namespace
+	// bindings the index was not built with.
+	if name == "" || strings.ContainsAny(name, "/[]()@*:=!<>|$'\" \t\r\n") ||
+		use == "" || strings.ContainsAny(use, "[]():=!<>|$'\" \t\r\n") || strings.HasPrefix(use, "/") {
+		return "", "", "", false
+	}
+	return name, use, key[1 : len(key)-1], true
+}
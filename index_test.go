@@ -0,0 +1,176 @@
+package xmlquery
+
+import (
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestCreateIndex(t *testing.T) {
+	s := `<catalog>
+		<item id="a"><name>first</name></item>
+		<group><item id="b"><name>second</name></item></group>
+		<item id="a"><name>third</name></item>
+		<item><name>no id</name></item>
+	</catalog>`
+	doc, err := Parse(strings.NewReader(s))
+	if err != nil {
+		t.Fatal(err)
+	}
+	idx, err := doc.CreateIndex("item", "@id")
+	if err != nil {
+		t.Fatal(err)
+	}
+	testValue(t, idx.Len(), 2)
+	testValue(t, len(idx.Lookup("a")), 2)
+	testValue(t, idx.Lookup("a")[1].SelectElement("name").InnerText(), "third")
+	testValue(t, idx.LookupOne("b").SelectElement("name").InnerText(), "second")
+	testTrue(t, idx.LookupOne("c") == nil)
+
+	again, err := doc.CreateIndex("item", "@id")
+	if err != nil {
+		t.Fatal(err)
+	}
+	testTrue(t, again == idx)
+	testTrue(t, doc.Index("item", "@id") == idx)
+
+	byName, err := doc.CreateIndex("/catalog/item", "name")
+	if err != nil {
+		t.Fatal(err)
+	}
+	testValue(t, byName.Len(), 3)
+	testTrue(t, byName.LookupOne("second") == nil)
+
+	doc.DropIndex("item", "@id")
+	testTrue(t, doc.Index("item", "@id") == nil)
+}
+
+func TestCreateIndexRebuild(t *testing.T) {
+	doc, err := Parse(strings.NewReader(`<catalog><item id="a"></item></catalog>`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	idx, err := doc.CreateIndex("item", "@id")
+	if err != nil {
+		t.Fatal(err)
+	}
+	item := &Node{Type: ElementNode, Data: "item"}
+	item.SetAttr("id", "b")
+	AddChild(FindOne(doc, "//catalog"), item)
+	testTrue(t, idx.LookupOne("b") == item)
+	testTrue(t, FindOne(doc, "//item[@id='b']") == item)
+
+	item.SetAttr("id", "c")
+	testTrue(t, idx.LookupOne("b") == nil)
+	testTrue(t, idx.LookupOne("c") == item)
+	RemoveFromTree(item)
+	testValue(t, idx.Len(), 1)
+
+	// Direct changes of the fields are not noticed.
+	first := FindOne(doc, "//item")
+	first.Attr[0].Value = "d"
+	testTrue(t, idx.LookupOne("d") == nil)
+	idx.Rebuild()
+	testTrue(t, idx.LookupOne("d") == first)
+
+	// A dropped index no longer observes the tree.
+	doc.DropIndex("item", "@id")
+	first.SetAttr("id", "e")
+	testTrue(t, !idx.stale)
+}
+
+func TestCreateIndexDocumentOrder(t *testing.T) {
+	doc, err := Parse(strings.NewReader(`<r><item k="x" n="1"><item k="x" n="2"/></item><item k="x" n="3"/></r>`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	idx, err := doc.CreateIndex("item[@n]", "@k")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var ns []string
+	for _, n := range idx.Lookup("x") {
+		ns = append(ns, n.SelectAttr("n"))
+	}
+	testValue(t, strings.Join(ns, ","), "1,2,3")
+}
+
+func TestCreateIndexInvalidExpr(t *testing.T) {
+	doc, err := Parse(strings.NewReader(`<catalog></catalog>`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := doc.CreateIndex("item[", "@id"); err == nil {
+		t.Fatal("expected error for invalid match expression")
+	}
+	if _, err := doc.CreateIndex("item", "@id["); err == nil {
+		t.Fatal("expected error for invalid use expression")
+	}
+}
+
+func TestIndexAcceleratesFind(t *testing.T) {
+	s := `<catalog>
+		<item id="a"><code>x</code></item>
+		<group><item id="b"><code>y</code></item></group>
+		<item id="a"><code>y</code></item>
+	</catalog>`
+	doc, err := Parse(strings.NewReader(s))
+	if err != nil {
+		t.Fatal(err)
+	}
+	queries := []string{
+		"//item[@id='a']", `//item[@id="b"]`, "//item[@id='c']", "//item[code='y']",
+		"//item[@id!='a']", "//item[@id='a']/code", "//group[@id='a']",
+	}
+	var want [][]*Node
+	for _, q := range queries {
+		want = append(want, Find(doc, q))
+	}
+	if _, err := doc.CreateIndex("item", "@id"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := doc.CreateIndex("//item", "code"); err != nil {
+		t.Fatal(err)
+	}
+	for i, q := range queries {
+		// The index returns nodes in document order, which queries using the
+		// descendant axis don't always do.
+		testDeepEqual(t, sortDocumentOrder(Find(doc, q)), sortDocumentOrder(want[i]))
+	}
+	testTrue(t, FindOne(doc, "//item[@id='a']") == want[0][0])
+
+	for _, q := range queries[:4] {
+		_, ok := indexLookup(doc, q)
+		testTrue(t, ok)
+	}
+	for _, q := range queries[4:] {
+		_, ok := indexLookup(doc, q)
+		testTrue(t, !ok)
+	}
+	// Only queries on the indexed node use the index.
+	_, ok := indexLookup(FindOne(doc, "//group"), "//item[@id='b']")
+	testTrue(t, !ok)
+}
+
+func TestIndexConcurrentRefresh(t *testing.T) {
+	doc, err := Parse(strings.NewReader(`<list><item id="1"/><item id="2"/><item id="3"/></list>`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := doc.CreateIndex("item", "@id"); err != nil {
+		t.Fatal(err)
+	}
+	// The change makes the index stale, so the first lookups rebuild it.
+	FindOne(doc, "//item[@id='3']").SetAttr("id", "2")
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if n := len(Find(doc, "//item[@id='2']")); n != 2 {
+				t.Errorf("expected 2 items, got %d", n)
+			}
+		}()
+	}
+	wg.Wait()
+}
@@ -0,0 +1,89 @@
+package xmlquery
+
+// Ancestors returns an iterator over the ancestors of n, from its parent up
+// to the root of the tree. Like Attrs, it returns a function with the
+// signature of iter.Seq, which is called with a callback or, with Go 1.23
+// or later, used in a range statement:
+//
+//	for a := range n.Ancestors() {
+//		fmt.Println(a.Data)
+//	}
+func (n *Node) Ancestors() func(yield func(*Node) bool) {
+	return func(yield func(*Node) bool) {
+		for p := n.Parent; p != nil; p = p.Parent {
+			if !yield(p) {
+				return
+			}
+		}
+	}
+}
+
+// Descendants returns an iterator over the descendants of n of all types,
+// in document order, see Ancestors. The subtree must not be modified
+// during the iteration.
+func (n *Node) Descendants() func(yield func(*Node) bool) {
+	return func(yield func(*Node) bool) {
+		n.Materialize()
+		for curr := n.FirstChild; curr != nil; {
+			if !yield(curr) {
+				return
+			}
+			curr.Materialize()
+			if curr.FirstChild != nil {
+				curr = curr.FirstChild
+				continue
+			}
+			for curr != n && curr.NextSibling == nil {
+				curr = curr.Parent
+			}
+			if curr == n {
+				return
+			}
+			curr = curr.NextSibling
+		}
+	}
+}
+
+// ChildElements returns an iterator over the child elements of n, see
+// Ancestors. The current element may be removed during the iteration.
+func (n *Node) ChildElements() func(yield func(*Node) bool) {
+	return func(yield func(*Node) bool) {
+		n.Materialize()
+		for child := n.FirstChild; child != nil; {
+			next := child.NextSibling
+			if child.Type == ElementNode && !yield(child) {
+				return
+			}
+			child = next
+		}
+	}
+}
+
+// FollowingSiblings returns an iterator over the siblings of n of all types
+// that follow it. The current sibling may be removed during the iteration.
+func (n *Node) FollowingSiblings() func(yield func(*Node) bool) {
+	return func(yield func(*Node) bool) {
+		for s := n.NextSibling; s != nil; {
+			next := s.NextSibling
+			if !yield(s) {
+				return
+			}
+			s = next
+		}
+	}
+}
+
+// PrecedingSiblings returns an iterator over the siblings of n of all types
+// that precede it, from the nearest one. The current sibling may be removed
+// during the iteration.
+func (n *Node) PrecedingSiblings() func(yield func(*Node) bool) {
+	return func(yield func(*Node) bool) {
+		for s := n.PrevSibling; s != nil; {
+			prev := s.PrevSibling
+			if !yield(s) {
+				return
+			}
+			s = prev
+		}
+	}
+}
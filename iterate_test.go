@@ -0,0 +1,54 @@
+package xmlquery
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestNodeIterators(t *testing.T) {
+	doc, err := Parse(strings.NewReader(`<r><a><b><c/></b>t</a><!--x--><d/><e/></r>`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	names := func(seq func(yield func(*Node) bool)) string {
+		var s []string
+		seq(func(n *Node) bool {
+			switch n.Type {
+			case ElementNode, TextNode:
+				s = append(s, n.Data)
+			default:
+				s = append(s, n.Type.String())
+			}
+			return true
+		})
+		return strings.Join(s, ",")
+	}
+	r := FindOne(doc, "/r")
+	c := FindOne(doc, "//c")
+	d := FindOne(doc, "//d")
+	testValue(t, names(c.Ancestors()), "b,a,r,DocumentNode")
+	testValue(t, names(r.Descendants()), "a,b,c,t,CommentNode,d,e")
+	testValue(t, names(FindOne(doc, "//a").Descendants()), "b,c,t")
+	testValue(t, names(c.Descendants()), "")
+	testValue(t, names(r.ChildElements()), "a,d,e")
+	testValue(t, names(c.ChildElements()), "")
+	testValue(t, names(d.FollowingSiblings()), "e")
+	testValue(t, names(d.PrecedingSiblings()), "CommentNode,a")
+
+	// Iteration stops when yield returns false.
+	count := 0
+	r.Descendants()(func(n *Node) bool {
+		count++
+		return n.Data != "c"
+	})
+	testValue(t, count, 3)
+
+	// The current child may be removed.
+	r.ChildElements()(func(n *Node) bool {
+		if n.Data != "a" {
+			RemoveFromTree(n)
+		}
+		return true
+	})
+	testValue(t, names(r.ChildElements()), "a")
+}
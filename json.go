@@ -0,0 +1,181 @@
+package xmlquery
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+)
+
+// JSONOptions configures how nodes are mapped to JSON by MarshalJSON and
+// JSON.
+//
+// An element becomes an object holding its attributes, under AttrPrefix
+// followed by the attribute name, its child elements, under their name, and
+// its text, under TextKey. Child elements sharing a name are collected in an
+// array. An element with neither attributes nor child elements becomes its
+// text alone. Keys keep the order of the document and all values are
+// strings; comments and processing instructions are left out.
+//
+//	<order id="7"><item>a</item><item>b</item><note>n</note></order>
+//
+// becomes
+//
+//	{"order":{"@id":"7","item":["a","b"],"note":"n"}}
+type JSONOptions struct {
+	AttrPrefix string // prefix of attribute keys, "@" if empty
+	TextKey    string // key of the text of elements with attributes or children, "#text" if empty
+	// NamespaceDecls keeps xmlns attributes, which are left out by
+	// default.
+	NamespaceDecls bool
+	// PreserveSpace keeps the whitespace around text, which is trimmed by
+	// default; text made only of whitespace is always left out of
+	// elements with child elements.
+	PreserveSpace bool
+}
+
+// DefaultJSONOptions is the mapping used by MarshalJSON.
+var DefaultJSONOptions = JSONOptions{AttrPrefix: "@", TextKey: "#text"}
+
+// withDefaults returns opts with the empty AttrPrefix and TextKey replaced
+// by those of DefaultJSONOptions.
+func (opts JSONOptions) withDefaults() JSONOptions {
+	if opts.AttrPrefix == "" {
+		opts.AttrPrefix = DefaultJSONOptions.AttrPrefix
+	}
+	if opts.TextKey == "" {
+		opts.TextKey = DefaultJSONOptions.TextKey
+	}
+	return opts
+}
+
+// MarshalJSON implements json.Marshaler using DefaultJSONOptions, so that
+// nodes can be embedded in values encoded with encoding/json. A document or
+// element is encoded as an object with the name of the element as its only
+// key; other nodes are encoded as their text.
+func (n *Node) MarshalJSON() ([]byte, error) {
+	return n.JSON(DefaultJSONOptions)
+}
+
+// JSON returns the JSON encoding of n with the given mapping, see
+// MarshalJSON.
+func (n *Node) JSON(opts JSONOptions) ([]byte, error) {
+	if n == nil {
+		return []byte("null"), nil
+	}
+	opts = opts.withDefaults()
+	var b bytes.Buffer
+	switch n.Type {
+	case DocumentNode:
+		root := firstChildElement(n)
+		if root == nil {
+			return []byte("null"), nil
+		}
+		n = root
+		fallthrough
+	case ElementNode:
+		b.WriteByte('{')
+		writeJSONString(&b, qualifiedName(n))
+		b.WriteByte(':')
+		writeJSONElement(&b, n, &opts)
+		b.WriteByte('}')
+	default:
+		writeJSONString(&b, n.InnerText())
+	}
+	return b.Bytes(), nil
+}
+
+// writeJSONElement writes the value element n is mapped to.
+func writeJSONElement(b *bytes.Buffer, n *Node, opts *JSONOptions) {
+	attrs, names, groups, s := jsonFields(n, opts)
+	if len(attrs) == 0 && len(names) == 0 {
+		writeJSONString(b, s)
+		return
+	}
+	b.WriteByte('{')
+	comma := false
+	key := func(k string) {
+		if comma {
+			b.WriteByte(',')
+		}
+		comma = true
+		writeJSONString(b, k)
+		b.WriteByte(':')
+	}
+	for _, attr := range attrs {
+		key(opts.AttrPrefix + qualifiedAttrName(attr))
+		writeJSONString(b, attr.Value)
+	}
+	for _, name := range names {
+		key(name)
+		group := groups[name]
+		if len(group) == 1 {
+			writeJSONElement(b, group[0], opts)
+			continue
+		}
+		b.WriteByte('[')
+		for i, child := range group {
+			if i > 0 {
+				b.WriteByte(',')
+			}
+			writeJSONElement(b, child, opts)
+		}
+		b.WriteByte(']')
+	}
+	if s != "" {
+		key(opts.TextKey)
+		writeJSONString(b, s)
+	}
+	b.WriteByte('}')
+}
+
+// jsonFields returns the fields element n is mapped to, see JSONOptions: its
+// attributes, the names of its child elements in document order, the child
+// elements by name and its text.
+func jsonFields(n *Node, opts *JSONOptions) (attrs []*Attr, names []string, groups map[string][]*Node, text string) {
+	n.Materialize()
+	groups = make(map[string][]*Node)
+	var b strings.Builder
+	for child := n.FirstChild; child != nil; child = child.NextSibling {
+		switch child.Type {
+		case ElementNode:
+			name := qualifiedName(child)
+			if _, ok := groups[name]; !ok {
+				names = append(names, name)
+			}
+			groups[name] = append(groups[name], child)
+		case TextNode, CharDataNode:
+			b.WriteString(child.Data)
+		}
+	}
+	text = b.String()
+	if !opts.PreserveSpace || (len(names) > 0 && strings.TrimSpace(text) == "") {
+		text = strings.TrimSpace(text)
+	}
+	for i := range n.Attr {
+		if opts.NamespaceDecls || !isNamespaceDecl(n.Attr[i]) {
+			attrs = append(attrs, &n.Attr[i])
+		}
+	}
+	return attrs, names, groups, text
+}
+
+func writeJSONString(b *bytes.Buffer, s string) {
+	data, _ := json.Marshal(s) // encoding a string cannot fail
+	b.Write(data)
+}
+
+// MarshalText implements encoding.TextMarshaler. It returns n as compact
+// XML, with special characters in attribute values escaped, or the value
+// of an attribute node.
+func (n *Node) MarshalText() ([]byte, error) {
+	if n == nil {
+		return nil, nil
+	}
+	switch n.Type {
+	case AttributeNode:
+		return []byte(n.InnerText()), nil
+	case DocumentNode:
+		return []byte(n.OutputXMLWithOptions(WithEscapeMode(EscapeDefault))), nil
+	}
+	return []byte(n.OutputXMLWithOptions(WithOutputSelf(), WithEscapeMode(EscapeDefault))), nil
+}
@@ -0,0 +1,75 @@
+package xmlquery
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestNodeMarshalJSON(t *testing.T) {
+	doc, err := Parse(strings.NewReader(`<?xml version="1.0"?>
+<order id="7" xmlns:x="urn:x">
+	<item>a</item>
+	<item sku="b">b <![CDATA[&]]></item>
+	<x:note> n </x:note>
+	<empty/>
+	<!--c-->
+	tail
+</order>`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := json.Marshal(doc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	testValue(t, string(data), `{"order":{"@id":"7","item":["a",{"@sku":"b","#text":"b \u0026"}],"x:note":"n","empty":"","#text":"tail"}}`)
+
+	v := struct {
+		Order *Node `json:"order"`
+		Note  *Node `json:"note"`
+		ID    *Node `json:"id"`
+		None  *Node `json:"none"`
+	}{
+		Order: FindOne(doc, "//item[2]"),
+		Note:  FindOne(doc, "//x:note/text()"),
+		ID:    FindOne(doc, "/order/@id"),
+	}
+	data, err = json.Marshal(v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	testValue(t, string(data), `{"order":{"item":{"@sku":"b","#text":"b \u0026"}},"note":" n ","id":"7","none":null}`)
+
+	data, err = doc.JSON(JSONOptions{AttrPrefix: "-", TextKey: "_", NamespaceDecls: true, PreserveSpace: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	testValue(t, string(data), `{"order":{"-id":"7","-xmlns:x":"urn:x","item":["a",{"-sku":"b","_":"b \u0026"}],"x:note":" n ","empty":"","_":"\n\t\n\t\n\t\n\t\n\t\n\ttail\n"}}`)
+
+	// Empty fields take their defaults.
+	data, err = FindOne(doc, "//item[2]").JSON(JSONOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	testValue(t, string(data), `{"item":{"@sku":"b","#text":"b \u0026"}}`)
+}
+
+func TestNodeMarshalText(t *testing.T) {
+	doc, err := Parse(strings.NewReader(`<a t="x&amp;y"><b>1 &lt; 2</b></a>`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for n, want := range map[*Node]string{
+		doc:                      `<?xml version="1.0"?><a t="x&amp;y"><b>1 &lt; 2</b></a>`,
+		FindOne(doc, "//b"):      `<b>1 &lt; 2</b>`,
+		FindOne(doc, "//a/@t"):   `x&y`,
+		FindOne(doc, "//text()"): `1 &lt; 2`,
+	} {
+		data, err := n.MarshalText()
+		if err != nil {
+			t.Fatal(err)
+		}
+		testValue(t, string(data), want)
+	}
+}
@@ -0,0 +1,439 @@
+package xmlquery
+
+import (
+	"encoding/hex"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/antchfx/xpath"
+)
+
+// DeclareKey declares the key name on the document of n, like xsl:key in
+// XSLT, so that the XPath function key(name, value) of queries on the
+// document returns the nodes selected by match whose use expression has
+// the given value. The nodes are looked up in an index, see CreateIndex for
+// the meaning of match and use. Declaring a name again with other
+// expressions adds their nodes to the key.
+//
+//	doc.DeclareKey("customer", "customer", "@id")
+//	orders := xmlquery.Find(doc, "//order[key('customer', @customer)/@country = 'NL']")
+//
+// The name of a key() call must be a string literal. Its value may be a
+// string or number literal, or an expression selecting nodes, in which
+// case the nodes with the value of any of them are returned. key() with an
+// undeclared name returns no nodes. key() is available in the expressions
+// compiled by this package, not in those compiled with xpath.Compile.
+func (n *Node) DeclareKey(name, match, use string) error {
+	idx, err := n.CreateIndex(match, use)
+	if err != nil {
+		return err
+	}
+	d := n.docData()
+	for _, declared := range d.keys[name] {
+		if declared == idx {
+			return nil
+		}
+	}
+	if d.keys == nil {
+		d.keys = make(map[string][]*Index)
+	}
+	d.keys[name] = append(d.keys[name], idx)
+	return nil
+}
+
+// Key returns the nodes of the key name declared on the document of n with
+// the given value, in document order, like the XPath call key(name, value).
+func (n *Node) Key(name, value string) []*Node {
+	for top := n; top != nil; top = top.Parent {
+		if top.doc != nil && len(top.doc.keys[name]) > 0 {
+			return lookupKey(top.doc.keys[name], value)
+		}
+	}
+	return nil
+}
+
+func lookupKey(indexes []*Index, value string) []*Node {
+	var nodes []*Node
+	for _, idx := range indexes {
+		for _, node := range idx.Lookup(value) {
+			if node.Type != AttributeNode {
+				nodes = append(nodes, node)
+			}
+		}
+	}
+	if len(indexes) > 1 {
+		nodes = sortUniqueNodes(nodes)
+	}
+	return nodes
+}
+
+// lookupIDs returns the elements of the tree of root with the IDs listed in
+// value, separated by whitespace, in document order, like the XPath call
+// id(value).
+func lookupIDs(root *Node, value string) []*Node {
+	var nodes []*Node
+	for _, id := range strings.Fields(value) {
+		if n := root.GetElementByID(id); n != nil {
+			nodes = append(nodes, n)
+		}
+	}
+	if len(nodes) > 1 {
+		nodes = sortUniqueNodes(nodes)
+	}
+	return nodes
+}
+
+// sortUniqueNodes sorts nodes in document order and drops duplicates.
+func sortUniqueNodes(nodes []*Node) []*Node {
+	nodes = sortDocumentOrder(nodes)
+	for i := len(nodes) - 1; i > 0; i-- {
+		if nodes[i] == nodes[i-1] {
+			nodes = append(nodes[:i], nodes[i+1:]...)
+		}
+	}
+	return nodes
+}
+
+// keyAttrPrefix starts the names of the attributes standing for key()
+// calls. The xpath package has no extension functions, so
+// rewriteKeyCalls replaces key(name, value) with a path through such an
+// attribute of the nodes selected by value, or of the root for a literal
+// value, whose children are the nodes of the key:
+//
+//	key('k', @ref)  ->  ((@ref)/attribute::node()[local-name()='xmlquery-key-6b']/node())
+//	key('k', 'A1')  ->  (/attribute::node()[local-name()='xmlquery-key-6b-4131']/node())
+//
+// The name encodes the key name and the literal value in hex. id() calls
+// are rewritten the same way, see idAttr. The
+// navigator of an expression using keys, see selectorNavigator, reports
+// these attributes after the real ones. They have the type of text nodes,
+// so that @* and @name tests of the expression don't select them.
+const keyAttrPrefix = "xmlquery-key-"
+
+// A keyCall is a key() call of an expression.
+type keyCall struct {
+	attr    string // name of the attribute standing for the call
+	name    string // name of the key
+	value   string // literal value
+	literal bool
+	id      bool // an id() call, looked up in the ID table
+}
+
+// keyState is the state of a navigator of an expression using keys.
+type keyState struct {
+	calls []keyCall
+	virt  int     // index in calls of the key() attribute the navigator is on, or -1
+	iter  bool    // moved to a real attribute by MoveToNextAttribute
+	list  []*Node // nodes of the key the navigator moved to as children
+	pos   int     // index in list of the current node
+}
+
+// rewriteKeyCalls replaces the key() calls of expr, see keyAttrPrefix.
+// String literals are left untouched.
+func rewriteKeyCalls(expr string) (string, error) {
+	return rewriteCalls(expr, "key", func(args []string) (string, error) {
+		if len(args) != 2 {
+			return "", fmt.Errorf("xmlquery: key() takes 2 arguments in %q", expr)
+		}
+		name, ok := unquoteXPathLiteral(args[0])
+		if !ok {
+			return "", fmt.Errorf("xmlquery: the name of key() must be a string literal in %q", expr)
+		}
+		attr := keyAttrPrefix + hex.EncodeToString([]byte(name))
+		if value, ok := keyLiteral(args[1]); ok {
+			return "(/" + keyAttrStep(attr+"-"+hex.EncodeToString([]byte(value))) + "/node())", nil
+		}
+		arg, err := rewriteKeyCalls(args[1])
+		if err != nil {
+			return "", err
+		}
+		return "((" + arg + ")/" + keyAttrStep(attr) + "/node())", nil
+	})
+}
+
+// idAttr is the name of the attributes standing for id() calls. It can't
+// be confused with a key() attribute since it isn't hex.
+const idAttr = keyAttrPrefix + "id"
+
+// rewriteIDCalls replaces the id() calls of expr like rewriteKeyCalls does
+// with key() calls, using idAttr for the name of the key.
+func rewriteIDCalls(expr string) (string, error) {
+	return rewriteCalls(expr, "id", func(args []string) (string, error) {
+		if len(args) != 1 {
+			return "", fmt.Errorf("xmlquery: id() takes 1 argument in %q", expr)
+		}
+		if value, ok := keyLiteral(args[0]); ok {
+			return "(/" + keyAttrStep(idAttr+"-"+hex.EncodeToString([]byte(value))) + "/node())", nil
+		}
+		arg, err := rewriteIDCalls(args[0])
+		if err != nil {
+			return "", err
+		}
+		return "((" + arg + ")/" + keyAttrStep(idAttr) + "/node())", nil
+	})
+}
+
+// rewriteCalls replaces the calls of the function name in expr with the
+// result of rewrite for their arguments. String literals are left
+// untouched.
+func rewriteCalls(expr, name string, rewrite func(args []string) (string, error)) (string, error) {
+	if !strings.Contains(expr, name) {
+		return expr, nil
+	}
+	var b strings.Builder
+	for i := 0; i < len(expr); {
+		c := expr[i]
+		if c == '"' || c == '\'' {
+			end := strings.IndexByte(expr[i+1:], c)
+			if end < 0 {
+				b.WriteString(expr[i:])
+				break
+			}
+			b.WriteString(expr[i : i+end+2])
+			i += end + 2
+			continue
+		}
+		if strings.HasPrefix(expr[i:], name) && !isXPathNameEnd(expr[:i]) {
+			if args, n, ok := scanCallArgs(expr[i+len(name):]); ok {
+				s, err := rewrite(args)
+				if err != nil {
+					return "", err
+				}
+				b.WriteString(s)
+				i += len(name) + n
+				continue
+			}
+		}
+		b.WriteByte(c)
+		i++
+	}
+	return b.String(), nil
+}
+
+// keyAttrStep returns the step selecting the key() attribute named attr.
+func keyAttrStep(attr string) string {
+	return "attribute::node()[local-name()='" + attr + "']"
+}
+
+// scanCallArgs scans the parenthesized arguments of a function call at the
+// start of s. It returns the arguments and the number of bytes scanned.
+func scanCallArgs(s string) (args []string, n int, ok bool) {
+	for n < len(s) && strings.IndexByte(" \t\r\n", s[n]) >= 0 {
+		n++
+	}
+	if n == len(s) || s[n] != '(' {
+		return nil, 0, false
+	}
+	n++
+	depth, start := 0, n
+	for ; n < len(s); n++ {
+		switch c := s[n]; c {
+		case '"', '\'':
+			end := strings.IndexByte(s[n+1:], c)
+			if end < 0 {
+				return nil, 0, false
+			}
+			n += end + 1
+		case '(', '[':
+			depth++
+		case ']':
+			depth--
+		case ')':
+			if depth == 0 {
+				if arg := strings.TrimSpace(s[start:n]); arg != "" || len(args) > 0 {
+					args = append(args, arg)
+				}
+				return args, n + 1, true
+			}
+			depth--
+		case ',':
+			if depth == 0 {
+				args = append(args, strings.TrimSpace(s[start:n]))
+				start = n + 1
+			}
+		}
+	}
+	return nil, 0, false
+}
+
+// unquoteXPathLiteral returns the value of the string literal s.
+func unquoteXPathLiteral(s string) (string, bool) {
+	if len(s) < 2 || (s[0] != '"' && s[0] != '\'') || s[len(s)-1] != s[0] || strings.IndexByte(s[1:len(s)-1], s[0]) >= 0 {
+		return "", false
+	}
+	return s[1 : len(s)-1], true
+}
+
+// keyLiteral returns the string value of s if it is a string or number
+// literal.
+func keyLiteral(s string) (string, bool) {
+	if v, ok := unquoteXPathLiteral(s); ok {
+		return v, true
+	}
+	if s == "" || strings.ContainsAny(s, "eEinfINFxXpP+_") {
+		return "", false
+	}
+	f, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		return "", false
+	}
+	return strconv.FormatFloat(f, 'f', -1, 64), true
+}
+
+// keyCallsOf returns the key() calls of an expression rewritten by
+// rewriteKeyCalls.
+func keyCallsOf(expr string) []keyCall {
+	var calls []keyCall
+	for s := expr; ; {
+		i := strings.Index(s, "'"+keyAttrPrefix)
+		if i < 0 {
+			return calls
+		}
+		s = s[i+1:]
+		end := strings.IndexByte(s, '\'')
+		if end < 0 {
+			return calls
+		}
+		call := keyCall{attr: s[:end]}
+		parts := strings.Split(strings.TrimPrefix(call.attr, keyAttrPrefix), "-")
+		if call.attr == idAttr || strings.HasPrefix(call.attr, idAttr+"-") {
+			call.id = true
+		} else {
+			name, err := hex.DecodeString(parts[0])
+			if err != nil {
+				continue
+			}
+			call.name = string(name)
+		}
+		if len(parts) > 1 {
+			value, err := hex.DecodeString(parts[1])
+			if err != nil {
+				continue
+			}
+			call.value, call.literal = string(value), true
+		}
+		calls = append(calls, call)
+	}
+}
+
+// selectorNavigator returns a navigator of top for evaluating selector,
+// which reports the attributes standing for its key() calls, if any.
+func selectorNavigator(top *Node, selector *xpath.Expr) *NodeNavigator {
+	nav := CreateXPathNavigator(top)
+	if calls := keyCallsOf(selector.String()); len(calls) > 0 {
+		nav.keys = &keyState{calls: calls, virt: -1}
+	}
+	return nav
+}
+
+// onKey reports whether x is on an attribute standing for a key() call.
+func (x *NodeNavigator) onKey() bool {
+	return x.keys != nil && x.keys.virt >= 0
+}
+
+// keyValue returns the value of the key() call attribute x is on: the
+// literal value, or the string value of the node the attribute belongs to.
+func (x *NodeNavigator) keyValue() string {
+	call := &x.keys.calls[x.keys.virt]
+	if call.literal {
+		return call.value
+	}
+	owner := *x
+	owner.keys = nil
+	return owner.Value()
+}
+
+// keyMoveToNextAttribute moves through the real attributes of an element,
+// then through the key() call attributes.
+func (x *NodeNavigator) keyMoveToNextAttribute() bool {
+	k := x.keys
+	k.list = nil
+	if k.virt >= 0 {
+		if k.virt < len(k.calls)-1 {
+			k.virt++
+			return true
+		}
+		return false
+	}
+	if x.attr == -1 || k.iter {
+		if x.attr < len(x.curr.Attr)-1 {
+			x.attr++
+			k.iter = true
+			return true
+		}
+		if k.iter {
+			x.attr = -1
+		}
+	}
+	k.iter = false
+	k.virt = 0
+	return true
+}
+
+// keyMoveToChild moves from a key() call attribute to the first node of
+// the key. handled is false if x is not on such an attribute.
+func (x *NodeNavigator) keyMoveToChild() (ok, handled bool) {
+	k := x.keys
+	k.iter = false
+	if k.virt < 0 {
+		k.list = nil
+		return false, false
+	}
+	call := &k.calls[k.virt]
+	value := call.value
+	if !call.literal {
+		value = x.keyValue()
+	}
+	root := x.curr
+	for root.Parent != nil {
+		root = root.Parent
+	}
+	var list []*Node
+	if call.id {
+		list = lookupIDs(root, value)
+	} else if root.doc != nil {
+		list = lookupKey(root.doc.keys[call.name], value)
+	}
+	if len(list) == 0 {
+		return false, true
+	}
+	x.curr, x.attr = list[0], -1
+	k.virt, k.list, k.pos = -1, list, 0
+	return true, true
+}
+
+// keyMoveSibling moves between the nodes of a key by delta, or to the
+// first one if first is set. handled is false if x is on neither a key()
+// call attribute nor a node of a key.
+func (x *NodeNavigator) keyMoveSibling(delta int, first bool) (ok, handled bool) {
+	k := x.keys
+	k.iter = false
+	if k.virt >= 0 {
+		return false, true
+	}
+	if k.list == nil {
+		return false, false
+	}
+	pos := k.pos + delta
+	if first {
+		pos = 0
+	}
+	if pos < 0 || pos >= len(k.list) || pos == k.pos {
+		return false, true
+	}
+	x.curr, k.pos = k.list[pos], pos
+	return true, true
+}
+
+// keyMoveToParent moves from a key() call attribute to the node it belongs
+// to. handled is false otherwise.
+func (x *NodeNavigator) keyMoveToParent() (ok, handled bool) {
+	k := x.keys
+	k.iter, k.list = false, nil
+	if k.virt < 0 {
+		return false, false
+	}
+	k.virt = -1
+	return true, true
+}
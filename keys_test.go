@@ -0,0 +1,75 @@
+package xmlquery
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestDeclareKey(t *testing.T) {
+	doc, err := Parse(strings.NewReader(`<shop>
+  <customer id="c1" country="NL">Ann</customer>
+  <customer id="c2" country="DE">Bob</customer>
+  <customer id="c3" country="NL">Cid</customer>
+  <order id="o1" customer="c1"><ref>c2</ref></order>
+  <order id="o2" customer="c2"><ref>c3</ref><ref>c1</ref></order>
+  <order id="o3" customer="c3"/>
+  <order id="o4" customer="c9"/>
+</shop>`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := doc.DeclareKey("customer", "customer", "@id"); err != nil {
+		t.Fatal(err)
+	}
+	if err := doc.DeclareKey("customer", "customer", "@id"); err != nil {
+		t.Fatal(err)
+	}
+	if err := doc.DeclareKey("country", "customer", "@country"); err != nil {
+		t.Fatal(err)
+	}
+	ids := func(nodes []*Node) string {
+		var s []string
+		for _, n := range nodes {
+			s = append(s, n.SelectAttr("id"))
+		}
+		return strings.Join(s, ",")
+	}
+
+	testValue(t, ids(Find(doc, "key('customer', 'c2')")), "c2")
+	testValue(t, ids(Find(doc, `key("country", "NL")`)), "c1,c3")
+	testValue(t, ids(Find(doc, "//order[key('customer', @customer)/@country = 'NL']")), "o1,o3")
+	testValue(t, ids(Find(doc, "//order[key('customer', ref)/@country = 'DE']")), "o1")
+	testValue(t, ids(Find(doc, "//order[not(key('customer', @customer))]")), "o4")
+	testValue(t, ids(sortDocumentOrder(Find(doc, "key('customer', //order[@id='o2']/ref)"))), "c1,c3")
+	testValue(t, ids(Find(doc, "key('country', key('customer', 'c1')/@country)")), "c1,c3")
+	testValue(t, ids(Find(doc, "key('nope', 'c1')")), "")
+	testValue(t, ids(Find(doc, "//order[@customer = 'key(\"customer\", 1)']")), "")
+	testValue(t, FindOne(doc, "key('customer', 'c3')").InnerText(), "Cid")
+	if n, err := Evaluate(doc, "count(key('country', 'NL'))"); err != nil || n.Number() != 2 {
+		t.Errorf("count = %v, %v", n, err)
+	}
+	// Attributes are not affected by the key() attributes.
+	testValue(t, ids(Find(doc, "//order[key('customer', @customer)][count(@*) = 2]")), "o1,o2,o3")
+	testValue(t, strings.Join(attrNames(Find(doc, "//customer[key('customer', 'c1')]/@*")), ","), "id,country,id,country,id,country")
+
+	testValue(t, ids(doc.Key("country", "DE")), "c2")
+	testValue(t, ids(FindOne(doc, "//order").Key("customer", "c1")), "c1")
+
+	// Keys follow changes of the tree.
+	FindOne(doc, "//customer[@id='c2']").SetAttr("country", "NL")
+	testValue(t, ids(Find(doc, `key("country", "NL")`)), "c1,c2,c3")
+
+	for _, expr := range []string{"key('customer')", "key(@id, 'c1')"} {
+		if _, err := QueryAll(doc, expr); err == nil {
+			t.Errorf("%s: no error", expr)
+		}
+	}
+}
+
+func attrNames(nodes []*Node) []string {
+	var names []string
+	for _, n := range nodes {
+		names = append(names, n.Data)
+	}
+	return names
+}
@@ -0,0 +1,47 @@
+package xmlquery
+
+import (
+	"strings"
+)
+
+// Lang returns the language of n, the value of the nearest xml:lang
+// attribute on n or its ancestors, or "" if none applies. An empty xml:lang
+// attribute means the language is unknown and also yields "".
+func (n *Node) Lang() string {
+	for p := n; p != nil; p = p.Parent {
+		if p.Type != ElementNode {
+			continue
+		}
+		if attr := p.SelectAttrNode("xml:lang"); attr != nil {
+			return strings.TrimSpace(attr.Value)
+		}
+	}
+	return ""
+}
+
+// SelectElementByLang finds the first child element with the specified name
+// whose language, as returned by Lang, matches the language range lang.
+// Matching follows the basic filtering of RFC 4647, like the XPath lang()
+// function: it is case-insensitive, "en" matches "en" and "en-GB" but not
+// "eng", and "*" matches any language.
+//
+//	title := tu.SelectElementByLang("tuv", "de")
+func (n *Node) SelectElementByLang(name, lang string) *Node {
+	for _, e := range n.SelectElements(name) {
+		if matchLang(e.Lang(), lang) {
+			return e
+		}
+	}
+	return nil
+}
+
+// matchLang reports whether the language tag matches the language range.
+func matchLang(tag, lang string) bool {
+	if lang == "*" {
+		return tag != ""
+	}
+	if len(tag) < len(lang) || !strings.EqualFold(tag[:len(lang)], lang) {
+		return false
+	}
+	return len(tag) == len(lang) || tag[len(lang)] == '-'
+}
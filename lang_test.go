@@ -0,0 +1,33 @@
+package xmlquery
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestLang(t *testing.T) {
+	s := `<tmx xml:lang="en">
+		<tu>
+			<tuv><seg>Hello</seg></tuv>
+			<tuv xml:lang="de-DE"><seg>Hallo</seg></tuv>
+			<tuv xml:lang="eng"><seg>Hi</seg></tuv>
+			<tuv xml:lang=""><seg>?</seg></tuv>
+		</tu>
+	</tmx>`
+	doc, err := Parse(strings.NewReader(s))
+	if err != nil {
+		t.Fatal(err)
+	}
+	segs := Find(doc, "//seg")
+	testValue(t, segs[0].Lang(), "en")
+	testValue(t, segs[1].Lang(), "de-DE")
+	testValue(t, segs[3].Lang(), "")
+
+	tu := FindOne(doc, "//tu")
+	testValue(t, tu.SelectElementByLang("tuv", "DE").InnerText(), "Hallo")
+	testValue(t, tu.SelectElementByLang("tuv", "en").InnerText(), "Hello")
+	testValue(t, tu.SelectElementByLang("tuv", "eng").InnerText(), "Hi")
+	testValue(t, tu.SelectElementByLang("tuv", "*").InnerText(), "Hello")
+	testTrue(t, tu.SelectElementByLang("tuv", "fr") == nil)
+	testTrue(t, tu.SelectElementByLang("tuv", "de-AT") == nil)
+}
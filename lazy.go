@@ -0,0 +1,163 @@
+package xmlquery
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"strings"
+	"sync"
+)
+
+// lazySource is the input of a document created by ParseLazy.
+type lazySource struct {
+	r        io.ReaderAt
+	depth    int
+	options  ParserOptions
+	entities map[string]string // the entities known at the end of the document
+}
+
+// lazyNode records where the content of a deferred element is located.
+type lazyNode struct {
+	src        *lazySource
+	start, end int64 // byte range of the element, including its tags
+	once       sync.Once
+	err        error
+}
+
+// ParseLazy is like Parse, but only builds the nodes down to the given
+// element depth (1 is the root element). The content of elements at that
+// depth is skipped and parsed from r the first time it is accessed by a
+// query, InnerText, OutputXML or Materialize, so memory stays proportional to
+// the part of the document that is actually used.
+//
+// Queries that walk the whole tree, such as "//record", still materialize
+// every subtree; prefer absolute paths like "/export/record[@id='x']/field"
+// which only descend into the matching elements. Elements inside deferred
+// subtrees are not part of the ID table.
+//
+// The input must be UTF-8 encoded and r must remain readable for as long as
+// the document is used.
+func ParseLazy(r io.ReaderAt, depth int) (*Node, error) {
+	return ParseLazyWithOptions(r, depth, ParserOptions{})
+}
+
+// ParseLazyWithOptions is like ParseLazy, but with custom options.
+func ParseLazyWithOptions(r io.ReaderAt, depth int, options ParserOptions) (*Node, error) {
+	if depth < 1 {
+		return nil, fmt.Errorf("xmlquery: invalid lazy depth %d", depth)
+	}
+	options.UseArena = false
+	p := createParser(io.NewSectionReader(r, 0, math.MaxInt64))
+	options.apply(p)
+	p.lazy = &lazySource{r: r, depth: depth, options: options}
+	doc, err := p.parseAll()
+	if err != nil {
+		return nil, err
+	}
+	// The fragments don't include the DOCTYPE, so hand them the entities
+	// it declared.
+	p.lazy.entities = p.decoder.Entity
+	return doc, nil
+}
+
+// Materialize parses the deferred content of n if n was created by ParseLazy
+// and its content has not been parsed yet. It returns the error, if any, that
+// occurred while parsing the content; queries ignore such errors and see the
+// element as empty.
+func (n *Node) Materialize() error {
+	if n.lazy == nil {
+		return nil
+	}
+	n.lazy.once.Do(func() {
+		n.lazy.err = n.lazy.load(n)
+	})
+	return n.lazy.err
+}
+
+// load parses the byte range of l and moves the resulting children to n.
+func (l *lazyNode) load(n *Node) error {
+	// Re-declare the namespaces in scope at n, so prefixes used inside the
+	// fragment resolve the same way they did in the full document.
+	var b strings.Builder
+	b.WriteString("<xmlquery-lazy")
+	declared := make(map[string]bool)
+	for p := n.Parent; p != nil; p = p.Parent {
+		for _, attr := range p.Attr {
+			var name string
+			switch {
+			case attr.Name.Space == "xmlns":
+				name = "xmlns:" + attr.Name.Local
+			case attr.Name.Space == "" && attr.Name.Local == "xmlns":
+				name = "xmlns"
+			default:
+				continue
+			}
+			if !declared[name] {
+				declared[name] = true
+				fmt.Fprintf(&b, ` %s="%s"`, name, escapeAttrValue(attr.Value))
+			}
+		}
+	}
+	b.WriteString(">")
+	r := io.MultiReader(
+		strings.NewReader(b.String()),
+		io.NewSectionReader(l.src.r, l.start, l.end-l.start),
+		strings.NewReader("</xmlquery-lazy>"),
+	)
+	options := l.src.options
+	if len(l.src.entities) > 0 {
+		decoder := DecoderOptions{Strict: true} // as in xml.NewDecoder
+		if options.Decoder != nil {
+			decoder = *options.Decoder
+		}
+		decoder.Entity = l.src.entities
+		options.Decoder = &decoder
+	}
+	doc, err := ParseWithOptions(r, options)
+	if err != nil {
+		return err
+	}
+	wrapper := FindOne(doc, "/xmlquery-lazy")
+	var elem *Node
+	for c := wrapper.FirstChild; c != nil; c = c.NextSibling {
+		if c.Type == ElementNode {
+			elem = c
+			break
+		}
+	}
+	if elem == nil {
+		return fmt.Errorf("xmlquery: no element found at offset %d", l.start)
+	}
+	// Link the children directly: materializing is part of reading the
+	// document, so observers must not see it as an insertion.
+	delta := n.level - elem.level
+	for c := elem.FirstChild; c != nil; c = c.NextSibling {
+		c.Parent = n
+		shiftLevel(c, delta)
+	}
+	n.FirstChild, n.LastChild = elem.FirstChild, elem.LastChild
+	elem.FirstChild, elem.LastChild = nil, nil
+	return nil
+}
+
+func shiftLevel(n *Node, delta int) {
+	n.level += delta
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		shiftLevel(c, delta)
+	}
+}
+
+// setLevel sets the level of n to level and those of its descendants
+// accordingly, for nodes created outside the parser.
+func setLevel(n *Node, level int) {
+	n.level = level
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		setLevel(c, level+1)
+	}
+}
+
+var attrValueEscaper = strings.NewReplacer(`&`, "&amp;", `<`, "&lt;", `"`, "&quot;")
+
+func escapeAttrValue(s string) string {
+	return attrValueEscaper.Replace(s)
+}
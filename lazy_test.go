@@ -0,0 +1,72 @@
+package xmlquery
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParseLazy(t *testing.T) {
+	s := `<?xml version="1.0"?>
+<export xmlns="urn:default" xmlns:x="urn:x">
+	<record id="1"><x:field>one</x:field><field>uno</field></record>
+	<record id="2" x:flag="y"><x:field>two</x:field></record>
+	<empty id="3"/>
+</export>`
+	doc, err := ParseLazy(strings.NewReader(s), 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	records := Find(doc, "/export/record")
+	testValue(t, len(records), 2)
+	testTrue(t, records[0].FirstChild == nil)
+	testTrue(t, records[1].FirstChild == nil)
+
+	n := FindOne(doc, "/export/record[@id='2']/x:field")
+	testTrue(t, n != nil)
+	testValue(t, n.InnerText(), "two")
+	testValue(t, n.NamespaceURI, "urn:x")
+	testValue(t, n.Prefix, "x")
+	testValue(t, n.Level(), 3)
+	testTrue(t, records[0].FirstChild == nil)
+	verifyNodePointers(t, doc)
+
+	testValue(t, records[0].InnerText(), "oneuno")
+	testValue(t, records[0].OutputXML(true), `<record id="1" xmlns="urn:default" xmlns:x="urn:x"><x:field>one</x:field><field>uno</field></record>`)
+	testValue(t, FindOne(doc, "//field[.='uno']").NamespaceURI, "urn:default")
+
+	empty := FindOne(doc, "/export/empty")
+	if err := empty.Materialize(); err != nil {
+		t.Fatal(err)
+	}
+	testTrue(t, empty.FirstChild == nil)
+	testValue(t, empty.SelectAttr("id"), "3")
+}
+
+func TestParseLazyInvalidDepth(t *testing.T) {
+	if _, err := ParseLazy(strings.NewReader(`<a></a>`), 0); err == nil {
+		t.Fatal("expected error for depth 0")
+	}
+}
+
+func TestParseLazyEntities(t *testing.T) {
+	s := `<!DOCTYPE export [<!ENTITY co "Acme">]>
+<export><record><name>&co; &amp; co</name></record></export>`
+	doc, err := ParseLazyWithOptions(strings.NewReader(s), 2, ParserOptions{DTDEntities: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	testValue(t, FindOne(doc, "/export/record/name").InnerText(), "Acme & co")
+}
+
+func TestParseLazyDoesNotNotify(t *testing.T) {
+	doc, err := ParseLazy(strings.NewReader(`<export><record><field>one</field></record></export>`), 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var mutations int
+	cancel := doc.Observe(func(*Mutation) { mutations++ })
+	defer cancel()
+	testValue(t, FindOne(doc, "/export/record/field").InnerText(), "one")
+	testValue(t, mutations, 0)
+	verifyNodePointers(t, doc)
+}
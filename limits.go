@@ -0,0 +1,80 @@
+package xmlquery
+
+import (
+	"strconv"
+
+	"github.com/suifengpiao14/xmlquery/xml"
+)
+
+// Limits caps the resources a document may use, to parse untrusted input
+// safely, see ParserOptions.Limits. A zero field means no limit.
+type Limits struct {
+	// MaxAttrsPerElement is the maximum number of attributes of an
+	// element, namespace declarations included.
+	MaxAttrsPerElement int
+	// MaxElements is the maximum number of elements of the document.
+	MaxElements int
+	// MaxAttrBytes is the maximum total size of the attributes of the
+	// document: their local names, namespace URIs and values, after
+	// entities are replaced.
+	MaxAttrBytes int64
+}
+
+// Limit identifies one of the Limits.
+type Limit int
+
+const (
+	LimitAttrsPerElement Limit = iota + 1 // Limits.MaxAttrsPerElement
+	LimitElements                         // Limits.MaxElements
+	LimitAttrBytes                        // Limits.MaxAttrBytes
+)
+
+func (l Limit) String() string {
+	switch l {
+	case LimitAttrsPerElement:
+		return "attributes per element"
+	case LimitElements:
+		return "elements"
+	case LimitAttrBytes:
+		return "attribute bytes"
+	}
+	return "Limit(" + strconv.Itoa(int(l)) + ")"
+}
+
+// A LimitError is the cause of the ParseError returned when a document
+// exceeds one of the Limits. Use errors.As to retrieve it:
+//
+//	var lerr *xmlquery.LimitError
+//	if errors.As(err, &lerr) && lerr.Limit == xmlquery.LimitElements {
+//		return http.StatusRequestEntityTooLarge
+//	}
+type LimitError struct {
+	Limit Limit
+	Max   int64 // the value of the limit
+}
+
+func (e *LimitError) Error() string {
+	return "document exceeds the limit of " + strconv.FormatInt(e.Max, 10) + " " + e.Limit.String()
+}
+
+// checkLimits counts the start element with attributes attrs against
+// p.limits and returns a ParseError if the document exceeds them.
+func (p *parser) checkLimits(attrs []xml.Attr) error {
+	limits := p.limits
+	if limits.MaxAttrsPerElement > 0 && len(attrs) > limits.MaxAttrsPerElement {
+		return p.parseError(&LimitError{Limit: LimitAttrsPerElement, Max: int64(limits.MaxAttrsPerElement)})
+	}
+	p.elements++
+	if limits.MaxElements > 0 && p.elements > limits.MaxElements {
+		return p.parseError(&LimitError{Limit: LimitElements, Max: int64(limits.MaxElements)})
+	}
+	if limits.MaxAttrBytes > 0 {
+		for _, attr := range attrs {
+			p.attrBytes += int64(len(attr.Name.Space) + len(attr.Name.Local) + len(attr.Value))
+		}
+		if p.attrBytes > limits.MaxAttrBytes {
+			return p.parseError(&LimitError{Limit: LimitAttrBytes, Max: limits.MaxAttrBytes})
+		}
+	}
+	return nil
+}
@@ -0,0 +1,46 @@
+package xmlquery
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestLimits(t *testing.T) {
+	s := `<r xmlns:a="urn:a"><e x="1" y="2"/><e a:x="12345"/></r>`
+
+	tests := []struct {
+		limits Limits
+		limit  Limit
+	}{
+		{Limits{MaxAttrsPerElement: 1}, LimitAttrsPerElement},
+		{Limits{MaxElements: 2}, LimitElements},
+		// xmlns:a="urn:a" is 11 bytes, x="1" and y="2" 2 each and
+		// a:x="12345" 11, as the URI counts instead of the prefix.
+		{Limits{MaxAttrBytes: 25}, LimitAttrBytes},
+	}
+	for _, test := range tests {
+		_, err := ParseWithOptions(strings.NewReader(s), ParserOptions{Limits: test.limits})
+		var lerr *LimitError
+		if !errors.As(err, &lerr) {
+			t.Fatalf("%+v: got error %v, want a LimitError", test.limits, err)
+		}
+		testValue(t, lerr.Limit, test.limit)
+		var perr *ParseError
+		testTrue(t, errors.As(err, &perr))
+	}
+
+	// The document fits in the limits exactly.
+	doc, err := ParseWithOptions(strings.NewReader(s), ParserOptions{Limits: Limits{
+		MaxAttrsPerElement: 2,
+		MaxElements:        3,
+		MaxAttrBytes:       26,
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	testValue(t, len(Find(doc, "//e")), 2)
+
+	_, err = ParseWithOptions(strings.NewReader(s), ParserOptions{Limits: Limits{MaxElements: 1}})
+	testValue(t, err.Error(), `xmlquery: line 1, column 20: document exceeds the limit of 1 elements (near "<e x=\"1\" y=\"2\"/>")`)
+}
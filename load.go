@@ -0,0 +1,159 @@
+package xmlquery
+
+import (
+	"bufio"
+	"compress/flate"
+	"compress/gzip"
+	"compress/zlib"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"strings"
+
+	"golang.org/x/net/html/charset"
+)
+
+// ErrResponseTooLarge is returned by LoadURLWithContext when the response
+// is larger than LoadURLOptions.MaxSize.
+var ErrResponseTooLarge = errors.New("xmlquery: response too large")
+
+// LoadURLOptions control how LoadURLWithContext fetches a document.
+type LoadURLOptions struct {
+	// Client is the HTTP client used for the request; the default is
+	// http.DefaultClient.
+	Client *http.Client
+	// Header holds additional request headers, such as Authorization.
+	Header http.Header
+	// MaxSize limits the size of the response body in bytes, after
+	// decompression. Zero means no limit.
+	MaxSize int64
+	// Parser are the options used to parse the response.
+	Parser ParserOptions
+}
+
+// LoadURLWithContext loads the XML document from the specified URL. The
+// request is canceled when ctx is done.
+//
+// The response must have a 2xx status and an XML content type. Responses
+// compressed with gzip or deflate are decoded. A charset parameter in the
+// Content-Type header takes precedence over the encoding declaration of
+// the document, as required by RFC 7303.
+func LoadURLWithContext(ctx context.Context, url string, options LoadURLOptions) (*Node, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	for k, v := range options.Header {
+		req.Header[k] = v
+	}
+	if req.Header.Get("Accept") == "" {
+		req.Header.Set("Accept", "application/xml, text/xml;q=0.9, */*;q=0.1")
+	}
+	if req.Header.Get("Accept-Encoding") == "" {
+		req.Header.Set("Accept-Encoding", "gzip, deflate")
+	}
+	client := options.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return nil, fmt.Errorf("xmlquery: %s: %s", url, resp.Status)
+	}
+	contentType := resp.Header.Get("Content-Type")
+	if !xmlMIMERegex.MatchString(contentType) {
+		return nil, fmt.Errorf("invalid XML document(%s)", contentType)
+	}
+	if options.MaxSize > 0 && resp.ContentLength > options.MaxSize && resp.Header.Get("Content-Encoding") == "" {
+		return nil, ErrResponseTooLarge
+	}
+
+	var body io.Reader = resp.Body
+	switch enc := strings.ToLower(strings.TrimSpace(resp.Header.Get("Content-Encoding"))); enc {
+	case "", "identity":
+	case "gzip", "x-gzip":
+		zr, err := gzip.NewReader(body)
+		if err != nil {
+			return nil, err
+		}
+		defer zr.Close()
+		body = zr
+	case "deflate":
+		// Servers disagree on whether deflate means zlib or raw DEFLATE.
+		br := bufio.NewReader(body)
+		if header, err := br.Peek(2); err == nil && (uint16(header[0])<<8|uint16(header[1]))%31 == 0 && header[0]&0x0f == 8 {
+			zr, err := zlib.NewReader(br)
+			if err != nil {
+				return nil, err
+			}
+			defer zr.Close()
+			body = zr
+		} else {
+			fr := flate.NewReader(br)
+			defer fr.Close()
+			body = fr
+		}
+	default:
+		return nil, fmt.Errorf("xmlquery: unsupported content encoding %q", enc)
+	}
+	if options.MaxSize > 0 {
+		body = &limitedReader{r: body, n: options.MaxSize}
+	}
+
+	parserOptions := options.Parser
+	if _, params, err := mime.ParseMediaType(contentType); err == nil && params["charset"] != "" {
+		if !strings.EqualFold(params["charset"], "utf-8") {
+			if body, err = charset.NewReaderLabel(params["charset"], body); err != nil {
+				return nil, err
+			}
+		}
+		// The input is now UTF-8 whatever its declaration says.
+		decoder := DecoderOptions{Strict: true}
+		if parserOptions.Decoder != nil {
+			decoder = *parserOptions.Decoder
+		}
+		decoder.CharsetReader = func(_ string, input io.Reader) (io.Reader, error) {
+			return input, nil
+		}
+		parserOptions.Decoder = &decoder
+	}
+
+	doc, err := ParseWithOptions(body, parserOptions)
+	if err != nil {
+		if errors.Is(err, ErrResponseTooLarge) {
+			return nil, ErrResponseTooLarge
+		}
+		return nil, err
+	}
+	doc.SetDocumentURI(resp.Request.URL.String())
+	return doc, nil
+}
+
+// limitedReader is like io.LimitedReader, but fails with
+// ErrResponseTooLarge when the limit is exceeded.
+type limitedReader struct {
+	r io.Reader
+	n int64
+}
+
+func (l *limitedReader) Read(p []byte) (int, error) {
+	if l.n < 0 {
+		return 0, ErrResponseTooLarge
+	}
+	if int64(len(p)) > l.n+1 {
+		p = p[:l.n+1]
+	}
+	n, err := l.r.Read(p)
+	l.n -= int64(n)
+	if l.n < 0 {
+		return n, ErrResponseTooLarge
+	}
+	return n, err
+}
@@ -0,0 +1,89 @@
+package xmlquery
+
+import (
+	"bytes"
+	"compress/flate"
+	"compress/gzip"
+	"compress/zlib"
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestLoadURLWithContext(t *testing.T) {
+	const doc = `<?xml version="1.0"?><r><a>café</a></r>`
+	compress := func(newWriter func(io.Writer) io.WriteCloser) []byte {
+		var b bytes.Buffer
+		w := newWriter(&b)
+		w.Write([]byte(doc))
+		w.Close()
+		return b.Bytes()
+	}
+	bodies := map[string][]byte{
+		"gzip":    compress(func(w io.Writer) io.WriteCloser { return gzip.NewWriter(w) }),
+		"deflate": compress(func(w io.Writer) io.WriteCloser { return zlib.NewWriter(w) }),
+		"raw-deflate": compress(func(w io.Writer) io.WriteCloser {
+			fw, _ := flate.NewWriter(w, flate.DefaultCompression)
+			return fw
+		}),
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/plain":
+			if r.Header.Get("X-Token") != "secret" {
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+			w.Header().Set("Content-Type", "application/xml")
+			w.Write([]byte(doc))
+		case "/gzip", "/deflate", "/raw-deflate":
+			w.Header().Set("Content-Type", "application/xml")
+			w.Header().Set("Content-Encoding", strings.TrimPrefix(r.URL.Path[1:], "raw-"))
+			w.Write(bodies[r.URL.Path[1:]])
+		case "/latin1":
+			// The header wins over the declaration.
+			w.Header().Set("Content-Type", "text/xml; charset=ISO-8859-1")
+			w.Write([]byte("<?xml version=\"1.0\" encoding=\"UTF-8\"?><r><a>caf\xe9</a></r>"))
+		case "/html":
+			w.Header().Set("Content-Type", "text/html")
+			w.Write([]byte(doc))
+		}
+	}))
+	defer server.Close()
+
+	ctx := context.Background()
+	header := http.Header{"X-Token": {"secret"}}
+	for _, path := range []string{"/plain", "/gzip", "/deflate", "/raw-deflate", "/latin1"} {
+		n, err := LoadURLWithContext(ctx, server.URL+path, LoadURLOptions{Header: header, Client: server.Client()})
+		if err != nil {
+			t.Fatalf("%s: %v", path, err)
+		}
+		testValue(t, FindOne(n, "//a").InnerText(), "café")
+		testValue(t, n.DocumentURI(), server.URL+path)
+	}
+
+	if _, err := LoadURLWithContext(ctx, server.URL+"/plain", LoadURLOptions{}); err == nil {
+		t.Fatal("expected error for unauthorized response")
+	}
+	if _, err := LoadURLWithContext(ctx, server.URL+"/html", LoadURLOptions{}); err == nil {
+		t.Fatal("expected error for non-XML content type")
+	}
+	for _, path := range []string{"/plain", "/gzip"} {
+		_, err := LoadURLWithContext(ctx, server.URL+path, LoadURLOptions{Header: header, MaxSize: 10})
+		if err != ErrResponseTooLarge {
+			t.Fatalf("%s: expected ErrResponseTooLarge, got %v", path, err)
+		}
+	}
+	if _, err := LoadURLWithContext(ctx, server.URL+"/plain", LoadURLOptions{Header: header, MaxSize: int64(len(doc))}); err != nil {
+		t.Fatal(err)
+	}
+
+	canceled, cancel := context.WithCancel(ctx)
+	cancel()
+	if _, err := LoadURLWithContext(canceled, server.URL+"/plain", LoadURLOptions{Header: header}); err == nil {
+		t.Fatal("expected error for canceled context")
+	}
+}
@@ -0,0 +1,64 @@
+package xmlquery
+
+import (
+	"unsafe"
+)
+
+// MemoryStats is an estimate of the memory retained by a subtree, see
+// Node.MemoryStats.
+type MemoryStats struct {
+	// Bytes is the estimated number of heap bytes of the nodes, their
+	// strings and their attributes.
+	Bytes int64
+	// Nodes is the number of nodes, and ByType their number by type.
+	Nodes  int
+	ByType map[NodeType]int
+	// Attrs is the number of attributes.
+	Attrs int
+}
+
+const (
+	nodeSize = int64(unsafe.Sizeof(Node{}))
+	attrSize = int64(unsafe.Sizeof(Attr{}))
+)
+
+// MemorySize returns an estimate of the heap bytes retained by the subtree
+// rooted at n, see MemoryStats.
+func (n *Node) MemorySize() int64 {
+	return n.MemoryStats().Bytes
+}
+
+// MemoryStats returns an estimate of the memory retained by the subtree
+// rooted at n and its number of nodes, for capacity planning of caches of
+// documents. The estimate counts the nodes, the bytes of their strings and
+// their attribute slices, and the source kept for ParserOptions.RoundTrip.
+// Strings shared by several nodes are counted for each of them, and
+// indexes, the unparsed content of lazily parsed elements and allocator
+// overhead are not counted, so the actual footprint may differ by some
+// percent.
+func (n *Node) MemoryStats() MemoryStats {
+	s := MemoryStats{ByType: make(map[NodeType]int)}
+	var walk func(*Node)
+	walk = func(n *Node) {
+		s.Nodes++
+		s.ByType[n.Type]++
+		s.Bytes += nodeSize + int64(len(n.Data)+len(n.Prefix)+len(n.NamespaceURI))
+		s.Attrs += len(n.Attr)
+		s.Bytes += attrSize * int64(cap(n.Attr))
+		for _, attr := range n.Attr {
+			s.Bytes += int64(len(attr.Name.Space) + len(attr.Name.Local) + len(attr.Value) + len(attr.NamespaceURI))
+		}
+		if n.lazy != nil {
+			s.Bytes += int64(unsafe.Sizeof(lazyNode{}))
+		}
+		if r := n.raw; r != nil {
+			s.Bytes += int64(unsafe.Sizeof(rawNode{})) + int64(len(r.start)+len(r.end)+len(r.data)+len(r.prefix))
+			s.Bytes += attrSize * int64(cap(r.attrs))
+		}
+		for child := n.FirstChild; child != nil; child = child.NextSibling {
+			walk(child)
+		}
+	}
+	walk(n)
+	return s
+}
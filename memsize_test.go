@@ -0,0 +1,30 @@
+package xmlquery
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestMemoryStats(t *testing.T) {
+	doc, err := Parse(strings.NewReader(`<r a="1"><b>text</b><!--c--><b x="y" z="w"/></r>`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := doc.MemoryStats()
+	testValue(t, s.Nodes, 7)
+	testValue(t, s.ByType[DocumentNode], 1)
+	testValue(t, s.ByType[DeclarationNode], 1) // added by the parser
+	testValue(t, s.ByType[ElementNode], 3)
+	testValue(t, s.ByType[TextNode], 1)
+	testValue(t, s.ByType[CommentNode], 1)
+	testValue(t, s.Attrs, 4) // version of the declaration included
+	testTrue(t, s.Bytes >= 7*nodeSize+3*attrSize+int64(len("rbtextcbxyzwa1")))
+	testValue(t, doc.MemorySize(), s.Bytes)
+
+	// A subtree retains less than its document; text adds its length.
+	b := FindOne(doc, "//b")
+	testTrue(t, b.MemorySize() < s.Bytes)
+	before := b.MemorySize()
+	b.FirstChild.Data += "more"
+	testValue(t, b.MemorySize(), before+4)
+}
@@ -0,0 +1,75 @@
+package xmlquery
+
+import (
+	"bytes"
+	"net/url"
+	"os"
+	"path/filepath"
+	"unsafe"
+)
+
+// ParseFile parses the XML file at path. Where the platform supports it, the
+// file is memory-mapped and the Data of text, CDATA and comment nodes refers
+// directly to the mapping instead of holding a copy, which keeps the resident
+// memory of large read-only documents low.
+//
+// The returned document must be released with Close. After Close, the Data
+// of its nodes must no longer be accessed; use strings.Clone to keep values
+// beyond that point.
+func ParseFile(path string) (*Node, error) {
+	return ParseFileWithOptions(path, ParserOptions{})
+}
+
+// ParseFileWithOptions is like ParseFile, but with custom options.
+func ParseFileWithOptions(path string, options ParserOptions) (*Node, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	m, err := mapFile(f)
+	if err != nil {
+		return nil, err
+	}
+	p := createParser(bytes.NewReader(m.data))
+	options.apply(p)
+	if bom := p.doc.BOM(); bom == "" || bom == "UTF-8" {
+		p.source = m.data
+	}
+	doc, err := p.parseAll()
+	if err != nil {
+		m.Close()
+		return nil, err
+	}
+	doc.docData().closer = m
+	if abs, err := filepath.Abs(path); err == nil {
+		doc.SetDocumentURI((&url.URL{Scheme: "file", Path: filepath.ToSlash(abs)}).String())
+	}
+	return doc, nil
+}
+
+// Close releases the input referenced by a document created with ParseFile.
+// It does nothing for other documents.
+func (n *Node) Close() error {
+	if n.doc == nil || n.doc.closer == nil {
+		return nil
+	}
+	err := n.doc.closer.Close()
+	n.doc.closer = nil
+	return err
+}
+
+// sourceString returns b as a string. If the parser has the whole input in
+// p.source and b appears unchanged in the input between start and the
+// current offset, the string shares the memory of the input.
+func (p *parser) sourceString(b []byte, start int64) string {
+	end := p.decoder.InputOffset()
+	if p.source == nil || len(b) == 0 || start < 0 || end > int64(len(p.source)) || start >= end {
+		return string(b)
+	}
+	src := p.source[start:end]
+	if i := bytes.Index(src, b); i >= 0 {
+		return unsafe.String(&src[i], len(b))
+	}
+	return string(b)
+}
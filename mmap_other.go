@@ -0,0 +1,27 @@
+//go:build !(linux || darwin || freebsd || netbsd || openbsd || dragonfly)
+
+package xmlquery
+
+import (
+	"io"
+	"os"
+)
+
+// mappedFile falls back to reading the whole file on platforms without mmap.
+// Text still references this single buffer instead of being copied.
+type mappedFile struct {
+	data []byte
+}
+
+func mapFile(f *os.File) (*mappedFile, error) {
+	data, err := io.ReadAll(f)
+	if err != nil {
+		return nil, err
+	}
+	return &mappedFile{data: data}, nil
+}
+
+func (m *mappedFile) Close() error {
+	m.data = nil
+	return nil
+}
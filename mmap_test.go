@@ -0,0 +1,62 @@
+package xmlquery
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"unsafe"
+)
+
+func TestParseFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "doc.xml")
+	s := `<?xml version="1.0"?><doc><a>plain text</a><b>a &amp; b</b><c><![CDATA[<raw>]]></c><!--note--></doc>`
+	if err := os.WriteFile(path, []byte(s), 0644); err != nil {
+		t.Fatal(err)
+	}
+	doc, err := ParseFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	testValue(t, FindOne(doc, "//a").InnerText(), "plain text")
+	testTrue(t, strings.HasPrefix(doc.DocumentURI(), "file:///"))
+	testTrue(t, strings.HasSuffix(doc.DocumentURI(), "/doc.xml"))
+	testValue(t, FindOne(doc, "//b").InnerText(), "a & b")
+	testValue(t, FindOne(doc, "//c").InnerText(), "<raw>")
+	testValue(t, FindOne(doc, "//comment()").Data, "note")
+	testValue(t, doc.OutputXML(false), `<?xml version="1.0"?><doc><a>plain text</a><b>a &amp; b</b><c><![CDATA[<raw>]]></c><!--note--></doc>`)
+
+	if err := doc.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := doc.Close(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestParserSourceString(t *testing.T) {
+	s := `<doc><a>shared</a><b>x &lt; y</b></doc>`
+	source := []byte(s)
+	doc, err := func() (*Node, error) {
+		p := createParser(strings.NewReader(s))
+		p.source = source
+		return p.parseAll()
+	}()
+	if err != nil {
+		t.Fatal(err)
+	}
+	inSource := func(v string) bool {
+		d := uintptr(unsafe.Pointer(unsafe.StringData(v)))
+		begin := uintptr(unsafe.Pointer(&source[0]))
+		return d >= begin && d < begin+uintptr(len(source))
+	}
+	testTrue(t, inSource(FindOne(doc, "//a").FirstChild.Data))
+	testTrue(t, !inSource(FindOne(doc, "//b").FirstChild.Data))
+	testValue(t, FindOne(doc, "//b").InnerText(), "x < y")
+}
+
+func TestParseFileNotExist(t *testing.T) {
+	if _, err := ParseFile(filepath.Join(t.TempDir(), "missing.xml")); err == nil {
+		t.Fatal("expected error for missing file")
+	}
+}
@@ -0,0 +1,138 @@
+package xmlquery
+
+import (
+	"errors"
+)
+
+// ErrMoveIntoSelf is returned when a node would be moved into its own
+// subtree, which would detach it from the tree and create a cycle.
+var ErrMoveIntoSelf = errors.New("xmlquery: cannot move a node into its own subtree")
+
+// MoveBefore detaches n from where it is and inserts it as the previous
+// sibling of ref, which must have a parent. Observers see a removal
+// followed by an insertion.
+func MoveBefore(n, ref *Node) error {
+	if n == ref {
+		return nil
+	}
+	if err := checkMove(n, ref.Parent); err != nil {
+		return err
+	}
+	RemoveFromTree(n)
+	insertBefore(n, ref)
+	return nil
+}
+
+// insertBefore inserts the detached node n as the previous sibling of ref.
+func insertBefore(n, ref *Node) {
+	parent := ref.Parent
+	n.Parent, n.PrevSibling, n.NextSibling = parent, ref.PrevSibling, ref
+	if ref.PrevSibling != nil {
+		ref.PrevSibling.NextSibling = n
+	} else {
+		parent.FirstChild = n
+	}
+	ref.PrevSibling = n
+	setLevel(n, parent.level+1)
+	notify(parent, Mutation{Type: NodeInserted, Target: parent, Node: n})
+}
+
+// MoveAfter detaches n from where it is and inserts it as the next sibling
+// of ref, which must have a parent, see MoveBefore.
+func MoveAfter(n, ref *Node) error {
+	if n == ref {
+		return nil
+	}
+	if err := checkMove(n, ref.Parent); err != nil {
+		return err
+	}
+	if ref.NextSibling == nil {
+		RemoveFromTree(n)
+		AddChild(ref.Parent, n)
+		setLevel(n, ref.Parent.level+1)
+		return nil
+	}
+	if ref.NextSibling == n {
+		return nil
+	}
+	return MoveBefore(n, ref.NextSibling)
+}
+
+// Reparent detaches n from where it is and appends it to the children of
+// parent, an element or a document, see MoveBefore.
+func Reparent(n, parent *Node) error {
+	if err := checkMove(n, parent); err != nil {
+		return err
+	}
+	RemoveFromTree(n)
+	AddChild(parent, n)
+	setLevel(n, parent.level+1)
+	return nil
+}
+
+// Wrap inserts a new element named name, as in "prefix:local", in place of
+// n and moves n into it. The prefix is resolved in the scope of the parent
+// of n. Wrap returns the new element.
+//
+//	// <price>10</price> becomes <amount><price>10</price></amount>
+//	amount, err := xmlquery.Wrap(price, "amount")
+func Wrap(n *Node, name string) (*Node, error) {
+	if err := checkMove(n, n.Parent); err != nil {
+		return nil, err
+	}
+	xname := newXMLName(name)
+	wrapper := &Node{
+		Type:         ElementNode,
+		Data:         xname.Local,
+		Prefix:       xname.Space,
+		NamespaceURI: namespacesInScope(n.Parent)[xname.Space],
+	}
+	insertBefore(wrapper, n)
+	RemoveFromTree(n)
+	AddChild(wrapper, n)
+	setLevel(n, wrapper.level+1)
+	return wrapper, nil
+}
+
+// Unwrap replaces element n with its children, which keep their order, and
+// leaves n detached and empty. Namespace declarations of n the children
+// rely on are declared again on them.
+func Unwrap(n *Node) error {
+	if n.Type != ElementNode {
+		return errors.New("xmlquery: cannot unwrap a " + n.Type.String())
+	}
+	if n.Parent == nil {
+		return errors.New("xmlquery: cannot unwrap a node without parent")
+	}
+	n.Materialize()
+	scope := namespacesInScope(n.Parent)
+	for n.FirstChild != nil {
+		child := n.FirstChild
+		RemoveFromTree(child)
+		insertBefore(child, n)
+		fixNamespaces(child, scope)
+	}
+	RemoveFromTree(n)
+	return nil
+}
+
+// checkMove returns an error if n can't be moved into parent.
+func checkMove(n, parent *Node) error {
+	if parent == nil {
+		return errors.New("xmlquery: cannot move a node next to a node without parent")
+	}
+	if parent.Type != ElementNode && parent.Type != DocumentNode {
+		return errors.New("xmlquery: cannot move a node into a " + parent.Type.String())
+	}
+	switch n.Type {
+	case DocumentNode, AttributeNode:
+		return errors.New("xmlquery: cannot move a " + n.Type.String())
+	}
+	for p := parent; p != nil; p = p.Parent {
+		if p == n {
+			return ErrMoveIntoSelf
+		}
+	}
+	parent.Materialize()
+	return nil
+}
@@ -0,0 +1,95 @@
+package xmlquery
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestMoveNodes(t *testing.T) {
+	doc, err := Parse(strings.NewReader(`<r><a><x/></a><b/><c/></r>`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := FindOne(doc, "/r")
+	a, b, c, x := FindOne(doc, "//a"), FindOne(doc, "//b"), FindOne(doc, "//c"), FindOne(doc, "//x")
+
+	testTrue(t, MoveBefore(c, a) == nil)
+	testValue(t, r.OutputXML(false), "<c></c><a><x></x></a><b></b>")
+	testTrue(t, MoveAfter(c, b) == nil)
+	testValue(t, r.OutputXML(false), "<a><x></x></a><b></b><c></c>")
+	testTrue(t, MoveAfter(a, b) == nil)
+	testValue(t, r.OutputXML(false), "<b></b><a><x></x></a><c></c>")
+	testTrue(t, MoveAfter(b, a) == nil)
+	testValue(t, r.OutputXML(false), "<a><x></x></a><b></b><c></c>")
+	testTrue(t, MoveBefore(a, a) == nil)
+	testTrue(t, MoveAfter(x, b) == nil)
+	testValue(t, r.OutputXML(false), "<a></a><b></b><x></x><c></c>")
+	testValue(t, x.Level(), 2)
+	testTrue(t, Reparent(x, a) == nil)
+	testValue(t, r.OutputXML(false), "<a><x></x></a><b></b><c></c>")
+	testValue(t, x.Level(), 3)
+	testTrue(t, Reparent(a, r) == nil)
+	testValue(t, r.OutputXML(false), "<b></b><c></c><a><x></x></a>")
+	testValue(t, a.Level(), 2)
+	testTrue(t, Reparent(x, c) == nil)
+	testValue(t, r.OutputXML(false), "<b></b><c><x></x></c><a></a>")
+	testValue(t, x.Level(), 3)
+	verifyNodePointers(t, doc)
+
+	// Cycles and invalid targets are rejected, leaving the tree unchanged.
+	testTrue(t, errors.Is(Reparent(c, x), ErrMoveIntoSelf))
+	testTrue(t, errors.Is(MoveBefore(r, x), ErrMoveIntoSelf))
+	testTrue(t, Reparent(a, &Node{Type: TextNode, Data: "t"}) != nil)
+	testTrue(t, MoveBefore(a, r.Parent) != nil)
+	testTrue(t, Reparent(doc, r) != nil)
+	testValue(t, r.OutputXML(false), "<b></b><c><x></x></c><a></a>")
+	verifyNodePointers(t, doc)
+
+	// Observers see a removal and an insertion.
+	var events []string
+	cancel := doc.Observe(func(m *Mutation) {
+		events = append(events, m.Target.Data+":"+m.Node.Data)
+	})
+	defer cancel()
+	testTrue(t, MoveBefore(a, b) == nil)
+	testValue(t, strings.Join(events, ","), "r:a,r:a")
+}
+
+func TestWrapUnwrap(t *testing.T) {
+	doc, err := Parse(strings.NewReader(`<r xmlns:v="urn:v"><v:price>10</v:price><b/></r>`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := FindOne(doc, "/r")
+	price := FindOne(doc, "//v:price")
+	amount, err := Wrap(price, "v:amount")
+	if err != nil {
+		t.Fatal(err)
+	}
+	testValue(t, amount.NamespaceURI, "urn:v")
+	testValue(t, amount.Level(), 2)
+	testValue(t, price.Level(), 3)
+	testValue(t, r.OutputXML(false), `<v:amount xmlns:v="urn:v"><v:price>10</v:price></v:amount><b></b>`)
+	verifyNodePointers(t, doc)
+
+	if _, err := Wrap(doc, "x"); err == nil {
+		t.Fatal("expected an error wrapping a document")
+	}
+
+	// The children of an unwrapped element keep the namespaces they
+	// relied on.
+	doc, err = Parse(strings.NewReader(`<r><w xmlns:v="urn:v"><v:a/>t<b/></w><c/></r>`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	r = FindOne(doc, "/r")
+	w := FindOne(doc, "//w")
+	testTrue(t, Unwrap(w) == nil)
+	testValue(t, r.OutputXML(false), `<v:a xmlns:v="urn:v"></v:a>t<b></b><c></c>`)
+	testValue(t, FindOne(doc, "//b").Level(), 2)
+	testTrue(t, w.Parent == nil && w.FirstChild == nil)
+	verifyNodePointers(t, doc)
+	testTrue(t, Unwrap(w) != nil)
+	testTrue(t, Unwrap(r.FirstChild.NextSibling) != nil)
+}
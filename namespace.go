@@ -0,0 +1,273 @@
+package xmlquery
+
+import (
+	"sort"
+)
+
+// RewritePrefixes renames namespace prefixes in the subtree rooted at n
+// according to mapping, which maps old prefixes to new ones. Element names,
+// attribute names and xmlns declarations are renamed consistently, so the
+// namespaces of all nodes stay the same.
+//
+// The empty string stands for the default namespace: mapping "" to "x"
+// moves unprefixed elements in the default namespace to prefix x, and
+// mapping "x" to "" makes x the default namespace. The reserved prefixes
+// xml and xmlns are never renamed.
+//
+//	xmlquery.RewritePrefixes(fragment, map[string]string{"ns1": "vendor"})
+func RewritePrefixes(n *Node, mapping map[string]string) {
+	n.Materialize()
+	if n.Type == ElementNode {
+		n.resetAttrIndex()
+		if to, ok := rewritePrefix(mapping, n.Prefix); ok && (n.Prefix != "" || n.NamespaceURI != "") {
+			n.Prefix = to
+		}
+		for i := range n.Attr {
+			attr := &n.Attr[i]
+			switch {
+			case attr.Name.Space == "" && attr.Name.Local == "xmlns":
+				if to, ok := rewritePrefix(mapping, ""); ok && to != "" {
+					attr.Name.Space, attr.Name.Local = "xmlns", to
+				}
+			case attr.Name.Space == "xmlns":
+				if to, ok := rewritePrefix(mapping, attr.Name.Local); ok {
+					if to == "" {
+						attr.Name.Space, attr.Name.Local = "", "xmlns"
+					} else {
+						attr.Name.Local = to
+					}
+				}
+			case attr.Name.Space != "":
+				// Unprefixed attributes are in no namespace, so the
+				// default namespace doesn't apply to them.
+				if to, ok := rewritePrefix(mapping, attr.Name.Space); ok && to != "" {
+					attr.Name.Space = to
+				}
+			}
+		}
+	}
+	for child := n.FirstChild; child != nil; child = child.NextSibling {
+		RewritePrefixes(child, mapping)
+	}
+}
+
+func rewritePrefix(mapping map[string]string, prefix string) (string, bool) {
+	if prefix == "xml" || prefix == "xmlns" {
+		return "", false
+	}
+	to, ok := mapping[prefix]
+	if !ok || to == "xml" || to == "xmlns" {
+		return "", false
+	}
+	return to, true
+}
+
+// StripNamespaces removes namespaces from the subtree rooted at n: element
+// and attribute prefixes and namespace URIs are cleared and xmlns
+// declarations are removed, so every element can be queried by its local
+// name alone. Attributes in the xml namespace, such as xml:lang, are kept.
+// If two attributes of an element end up with the same name, only the
+// first is kept.
+func StripNamespaces(n *Node) {
+	n.Materialize()
+	if n.Type == ElementNode {
+		n.Prefix, n.NamespaceURI = "", ""
+		attrs := n.Attr[:0]
+		for _, attr := range n.Attr {
+			switch {
+			case attr.Name.Space == "xml":
+			case attr.Name.Space == "xmlns", attr.Name.Space == "" && attr.Name.Local == "xmlns":
+				continue
+			default:
+				attr.Name.Space, attr.NamespaceURI = "", ""
+			}
+			dup := false
+			for _, a := range attrs {
+				if a.Name == attr.Name {
+					dup = true
+					break
+				}
+			}
+			if !dup {
+				attrs = append(attrs, attr)
+			}
+		}
+		n.Attr = attrs
+		n.resetAttrIndex()
+	}
+	for child := n.FirstChild; child != nil; child = child.NextSibling {
+		StripNamespaces(child)
+	}
+}
+
+// ImportNode appends a copy of the subtree src to dst as its last child and
+// returns the copy. src may belong to another document. Namespace
+// declarations that the subtree relied on from its original ancestors are
+// re-declared on the copy where the namespaces in scope at dst differ, so
+// that its prefixes stay bound to the same namespaces when it is written.
+func ImportNode(dst, src *Node) *Node {
+	n := src.Clone()
+	AddChild(dst, n)
+	fixNamespaces(n, namespacesInScope(dst))
+	return n
+}
+
+// namespacesInScope returns the namespace declarations in scope at n, by
+// prefix; the default namespace has the empty prefix.
+func namespacesInScope(n *Node) map[string]string {
+	scope := map[string]string{"xml": "http://www.w3.org/XML/1998/namespace"}
+	var chain []*Node
+	for ; n != nil; n = n.Parent {
+		chain = append(chain, n)
+	}
+	for i := len(chain) - 1; i >= 0; i-- {
+		declareNamespaces(chain[i], scope)
+	}
+	return scope
+}
+
+// declareNamespaces adds the xmlns declarations of n to scope.
+func declareNamespaces(n *Node, scope map[string]string) {
+	for _, attr := range n.Attr {
+		switch {
+		case attr.Name.Space == "xmlns":
+			scope[attr.Name.Local] = attr.Value
+		case attr.Name.Space == "" && attr.Name.Local == "xmlns":
+			scope[""] = attr.Value
+		}
+	}
+}
+
+// hasNamespaceDecls reports whether element n has xmlns attributes.
+func hasNamespaceDecls(n *Node) bool {
+	for _, attr := range n.Attr {
+		if isNamespaceDecl(attr) {
+			return true
+		}
+	}
+	return false
+}
+
+// fixNamespaces adds xmlns declarations to the subtree rooted at n wherever
+// a prefix is used that isn't bound to the node's namespace in scope, the
+// namespaces declared around n.
+func fixNamespaces(n *Node, scope map[string]string) {
+	if n.Type != ElementNode {
+		for child := n.FirstChild; child != nil; child = child.NextSibling {
+			fixNamespaces(child, scope)
+		}
+		return
+	}
+	local := make(map[string]string, len(scope))
+	for k, v := range scope {
+		local[k] = v
+	}
+	declareNamespaces(n, local)
+	need := func(prefix, uri string) {
+		if prefix == "xml" || prefix == "xmlns" || local[prefix] == uri {
+			return
+		}
+		if prefix == "" {
+			n.Attr = append(n.Attr, Attr{Name: newXMLName("xmlns"), Value: uri})
+		} else {
+			n.Attr = append(n.Attr, Attr{Name: newXMLName("xmlns:" + prefix), Value: uri, NamespaceURI: "xmlns"})
+		}
+		local[prefix] = uri
+	}
+	need(n.Prefix, n.NamespaceURI)
+	for i := 0; i < len(n.Attr); i++ {
+		if attr := n.Attr[i]; attr.Name.Space != "" && attr.Name.Space != "xmlns" && attr.NamespaceURI != "" {
+			need(attr.Name.Space, attr.NamespaceURI)
+		}
+	}
+	for child := n.FirstChild; child != nil; child = child.NextSibling {
+		fixNamespaces(child, local)
+	}
+}
+
+// WithoutNamespaceFixup writes the subtree of a node as it is. By default,
+// when the node written is not a document, the namespace declarations of
+// its ancestors that the output relies on are added to the elements at the
+// top of the output, so that the fragment has no undeclared prefixes.
+func WithoutNamespaceFixup() OutputOption {
+	return func(oc *outputConfiguration) {
+		oc.noNamespaceFixup = true
+	}
+}
+
+// setNamespaceRoot prepares config to write the subtree rooted at n, whose
+// ancestors declare the namespaces in scope, see WithoutNamespaceFixup.
+func (config *outputConfiguration) setNamespaceRoot(n *Node, scope map[string]string) {
+	config.nsRoot, config.nsDecls = n, nil
+	if n.Type == ElementNode {
+		config.nsDecls = inheritedNamespaces(n, scope)
+	}
+}
+
+// inheritedNamespaces returns the xmlns declarations the subtree rooted at
+// element n relies on from scope, the namespaces declared around n, sorted
+// by prefix.
+func inheritedNamespaces(n *Node, scope map[string]string) []Attr {
+	if len(scope) == 1 {
+		// Only the xml prefix, which is never declared.
+		return nil
+	}
+	need := make(map[string]string)
+	declared := make(map[string]int)
+	use := func(prefix string) {
+		if prefix == "xml" || prefix == "xmlns" || declared[prefix] > 0 {
+			return
+		}
+		if uri := scope[prefix]; uri != "" {
+			need[prefix] = uri
+		}
+	}
+	var walk func(*Node)
+	walk = func(n *Node) {
+		n.Materialize()
+		var own []string
+		for _, attr := range n.Attr {
+			switch {
+			case attr.Name.Space == "xmlns":
+				own = append(own, attr.Name.Local)
+			case attr.Name.Space == "" && attr.Name.Local == "xmlns":
+				own = append(own, "")
+			}
+		}
+		for _, prefix := range own {
+			declared[prefix]++
+		}
+		use(n.Prefix)
+		for _, attr := range n.Attr {
+			if attr.Name.Space != "" && attr.Name.Space != "xmlns" {
+				use(attr.Name.Space)
+			}
+		}
+		for child := n.FirstChild; child != nil; child = child.NextSibling {
+			if child.Type == ElementNode {
+				walk(child)
+			}
+		}
+		for _, prefix := range own {
+			declared[prefix]--
+		}
+	}
+	walk(n)
+	if len(need) == 0 {
+		return nil
+	}
+	prefixes := make([]string, 0, len(need))
+	for prefix := range need {
+		prefixes = append(prefixes, prefix)
+	}
+	sort.Strings(prefixes)
+	decls := make([]Attr, 0, len(prefixes))
+	for _, prefix := range prefixes {
+		if prefix == "" {
+			decls = append(decls, Attr{Name: newXMLName("xmlns"), Value: need[prefix]})
+		} else {
+			decls = append(decls, Attr{Name: newXMLName("xmlns:" + prefix), Value: need[prefix], NamespaceURI: "xmlns"})
+		}
+	}
+	return decls
+}
@@ -0,0 +1,86 @@
+package xmlquery
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestRewritePrefixes(t *testing.T) {
+	s := `<a:root xmlns:a="urn:a" xmlns:b="urn:b" xmlns="urn:d"><b:item a:id="1" xml:lang="en" plain="x"><child/></b:item></a:root>`
+	doc, err := Parse(strings.NewReader(s))
+	if err != nil {
+		t.Fatal(err)
+	}
+	root := doc.SelectElement("a:root")
+	RewritePrefixes(root, map[string]string{"a": "x", "b": "", "": "d", "xml": "y"})
+	testValue(t, root.OutputXML(true),
+		`<x:root xmlns:x="urn:a" xmlns="urn:b" xmlns:d="urn:d"><item x:id="1" xml:lang="en" plain="x"><d:child></d:child></item></x:root>`)
+
+	item := FindOne(doc, "//item")
+	testValue(t, item.NamespaceURI, "urn:b")
+	testValue(t, item.SelectAttr("x:id"), "1")
+}
+
+func TestStripNamespaces(t *testing.T) {
+	s := `<root xmlns="urn:d" xmlns:a="urn:a" xmlns:b="urn:b"><a:item a:id="1" b:id="2" xml:lang="en"><b:name>x</b:name></a:item></root>`
+	doc, err := Parse(strings.NewReader(s))
+	if err != nil {
+		t.Fatal(err)
+	}
+	StripNamespaces(doc)
+	testValue(t, doc.OutputXMLWithOptions(WithOutDeclarationNode()), `<root><item id="1" xml:lang="en"><name>x</name></item></root>`)
+	name := FindOne(doc, "/root/item/name")
+	if name == nil {
+		t.Fatal("name not found after stripping namespaces")
+	}
+	testValue(t, name.NamespaceURI, "")
+}
+
+func TestImportNode(t *testing.T) {
+	src, err := Parse(strings.NewReader(`<feed xmlns="urn:atom" xmlns:m="urn:media"><entry m:id="1"><m:thumb/><title xmlns:x="urn:x"><x:b/></title></entry></feed>`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	dst, err := Parse(strings.NewReader(`<out xmlns="urn:other" xmlns:m="urn:media"><list/></out>`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	list := FindOne(dst, "//list")
+	entry := FindOne(src, "//entry")
+	n := ImportNode(list, entry)
+	testTrue(t, n != entry && n.Parent == list)
+	testTrue(t, entry.Parent != nil)
+	testValue(t, n.OutputXML(true), `<entry m:id="1" xmlns="urn:atom" xmlns:m="urn:media"><m:thumb></m:thumb><title xmlns:x="urn:x"><x:b></x:b></title></entry>`)
+
+	doc, err := Parse(strings.NewReader(dst.OutputXML(false)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	testValue(t, FindOne(doc, "//entry").NamespaceURI, "urn:atom")
+	testValue(t, FindOne(doc, "//m:thumb").NamespaceURI, "urn:media")
+
+	plain, err := Parse(strings.NewReader(`<plain/>`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	n = ImportNode(plain.SelectElement("plain"), entry)
+	testValue(t, n.OutputXML(true), `<entry m:id="1" xmlns="urn:atom" xmlns:m="urn:media"><m:thumb></m:thumb><title xmlns:x="urn:x"><x:b></x:b></title></entry>`)
+}
+
+func TestOutputNamespaceFixup(t *testing.T) {
+	s := `<r xmlns="urn:d" xmlns:a="urn:a" xmlns:b="urn:b"><a:x b:id="1"><y/><a:z xmlns:a="urn:other"/></a:x><p xmlns=""><q/></p></r>`
+	doc, err := Parse(strings.NewReader(s))
+	if err != nil {
+		t.Fatal(err)
+	}
+	x := FindOne(doc, "//a:x")
+	testValue(t, x.OutputXML(true), `<a:x b:id="1" xmlns="urn:d" xmlns:a="urn:a" xmlns:b="urn:b"><y></y><a:z xmlns:a="urn:other"></a:z></a:x>`)
+	testValue(t, x.OutputXMLWithOptions(WithOutputSelf(), WithoutNamespaceFixup()), `<a:x b:id="1"><y></y><a:z xmlns:a="urn:other"></a:z></a:x>`)
+	// Each element written gets the declarations it needs.
+	testValue(t, x.OutputXML(false), `<y xmlns="urn:d"></y><a:z xmlns:a="urn:other"></a:z>`)
+	// An undeclared default namespace needs no declaration.
+	testValue(t, FindOne(doc, "//p").OutputXML(true), `<p xmlns=""><q></q></p>`)
+	// The tree is left as it is.
+	testValue(t, len(x.Attr), 1)
+	testValue(t, doc.OutputXML(false), `<?xml version="1.0"?>`+s[:strings.Index(s, "<y/>")]+`<y></y><a:z xmlns:a="urn:other"></a:z></a:x><p xmlns=""><q></q></p></r>`)
+}
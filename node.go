@@ -2,10 +2,11 @@ package xmlquery
 
 import (
 	"bufio"
-	"fmt"
-	"html"
+	"bytes"
 	"io"
 	"strings"
+	"sync"
+	"sync/atomic"
 
 	"github.com/suifengpiao14/xmlquery/xml"
 )
@@ -24,7 +25,9 @@ const (
 	ElementNode
 	// TextNode is the text content of a node.
 	TextNode
-	// CharDataNode node <![CDATA[content]]>
+	// CharDataNode node <![CDATA[content]]>. XPath text() tests match it
+	// like a TextNode; output re-emits it as a CDATA section unless
+	// WithCDATAAsText is given.
 	CharDataNode
 	// CommentNode a comment (for example, <!-- my comment --> ).
 	CommentNode
@@ -34,6 +37,12 @@ const (
 	NotationNode
 )
 
+// An Attr is an attribute of an element node. Name.Space holds the
+// attribute's prefix as written in the document, and NamespaceURI the
+// namespace the prefix is bound to.
+//
+// Attributes are kept in the order they appear in the input, and that order
+// is preserved by SetAttr, RemoveAttr and serialization; AddAttr appends.
 type Attr struct {
 	Name         xml.Name
 	Value        string
@@ -51,7 +60,35 @@ type Node struct {
 	NamespaceURI string
 	Attr         []Attr
 
-	level int // node level in the tree
+	level int           // node level in the tree
+	doc   *documentData // document-wide state, only set on the root of a tree
+	lazy  *lazyNode     // location of the unparsed content, see ParseLazy
+	raw   *rawNode      // source text, see ParserOptions.RoundTrip
+	data  userData      // see SetUserData
+
+	attrIndex atomic.Pointer[attrIndex] // index of the attributes, see attrPos
+}
+
+// documentData holds state that belongs to a whole tree rather than to a
+// single node, such as indexes and the ID table.
+type documentData struct {
+	indexes   []*Index            // indexes created by CreateIndex
+	ids       map[string]*Node    // element lookup table for GetElementByID
+	arena     *nodeArena          // slabs the tree was allocated from, see ParserOptions.UseArena
+	closer    io.Closer           // releases the input the tree references, see ParseFile
+	uri       string              // where the document was loaded from, see SetDocumentURI
+	observers []*observer         // callbacks registered with Observe
+	bom       string              // encoding named by the byte order mark of the input, see BOM
+	keys      map[string][]*Index // indexes of the keys declared with DeclareKey
+	foldCase  bool                // names match case-insensitively, see ParserOptions.CaseInsensitiveNames
+}
+
+// docData returns the document-wide state of n, creating it if necessary.
+func (n *Node) docData() *documentData {
+	if n.doc == nil {
+		n.doc = &documentData{}
+	}
+	return n.doc
 }
 
 type outputConfiguration struct {
@@ -62,7 +99,22 @@ type outputConfiguration struct {
 	useIndentation            string
 	skipDeclarationNode       bool
 	TextNodeIgnoreHtmlEscaper bool // 忽略html转义字符，比如&nbsp;等特殊符号不会被转义为对应的实体。
-
+	escapeMode                EscapeMode
+	escapeAttrs               bool // attribute values are escaped too, see WithEscapeMode
+	asciiOnly                 bool
+	escapeAttrWhitespace      bool
+	cdataAsText               bool
+	attrWrapWidth             int
+	attrOrder                 AttrOrder
+	bom                       bool
+	normalization             NormalizationForm
+	encodingDecl              EncodingDeclPolicy
+	noNamespaceFixup          bool
+	nsRoot                    *Node  // the element nsDecls are added to, see setNamespaceRoot
+	nsDecls                   []Attr // the xmlns declarations nsRoot relies on from its ancestors
+	skipXMLDeclaration        bool
+	skipProcInsts             bool
+	filter                    func(*Node) bool
 }
 
 type OutputOption func(*outputConfiguration)
@@ -99,6 +151,67 @@ func WithoutComments() OutputOption {
 	}
 }
 
+// WithoutXMLDeclaration skips the XML declaration, <?xml ...?>, in output.
+// Unlike WithOutDeclarationNode, processing instructions are kept.
+func WithoutXMLDeclaration() OutputOption {
+	return func(oc *outputConfiguration) {
+		oc.skipXMLDeclaration = true
+	}
+}
+
+// WithoutProcessingInstructions skips processing instructions, such as
+// <?xml-stylesheet ...?>, in output. The XML declaration is kept.
+func WithoutProcessingInstructions() OutputOption {
+	return func(oc *outputConfiguration) {
+		oc.skipProcInsts = true
+	}
+}
+
+// WithNodeFilter skips the nodes for which keep returns false, with their
+// descendants, in output. It is called for every node about to be written,
+// including the node itself with WithOutputSelf, but not for attributes.
+//
+//	// Drop the annotations of the source.
+//	doc.OutputXMLWithOptions(xmlquery.WithNodeFilter(func(n *xmlquery.Node) bool {
+//		return n.Type != xmlquery.ElementNode || n.Prefix != "doc"
+//	}))
+func WithNodeFilter(keep func(n *Node) bool) OutputOption {
+	return func(oc *outputConfiguration) {
+		oc.filter = keep
+	}
+}
+
+// skipped reports whether n is left out of the output by config.
+func (config *outputConfiguration) skipped(n *Node) bool {
+	switch {
+	case n.Type == DeclarationNode && (config.skipDeclarationNode ||
+		config.skipXMLDeclaration && n.Data == "xml" ||
+		config.skipProcInsts && n.Data != "xml"):
+		return true
+	case n.Type == CommentNode && config.skipComments:
+		return true
+	}
+	return config.filter != nil && !config.filter(n)
+}
+
+// WithAttributeWrap wraps the attributes of a start tag that would be longer
+// than width characters, indentation included: the first attribute stays
+// after the element name and each following one goes on its own line,
+// aligned with the first. It only applies together with WithIndentation.
+func WithAttributeWrap(width int) OutputOption {
+	return func(oc *outputConfiguration) {
+		oc.attrWrapWidth = width
+	}
+}
+
+// WithCDATAAsText writes CDATA sections as escaped text instead of
+// re-emitting them verbatim.
+func WithCDATAAsText() OutputOption {
+	return func(oc *outputConfiguration) {
+		oc.cdataAsText = true
+	}
+}
+
 // WithPreserveSpace will preserve spaces in output
 func WithPreserveSpace() OutputOption {
 	return func(oc *outputConfiguration) {
@@ -125,6 +238,27 @@ func newXMLName(name string) xml.Name {
 	}
 }
 
+// firstChildElement returns the first element child of n.
+func firstChildElement(n *Node) *Node {
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		if c.Type == ElementNode {
+			return c
+		}
+	}
+	return nil
+}
+
+// childElementNS returns the first child element of n with the given
+// namespace URI and local name.
+func childElementNS(n *Node, ns, local string) *Node {
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		if c.Type == ElementNode && c.Data == local && c.NamespaceURI == ns {
+			return c
+		}
+	}
+	return nil
+}
+
 func (n *Node) Level() int {
 	return n.level
 }
@@ -138,6 +272,7 @@ func (n *Node) InnerText() string {
 			b.WriteString(n.Data)
 		case CommentNode:
 		default:
+			n.Materialize()
 			for child := n.FirstChild; child != nil; child = child.NextSibling {
 				output(b, child)
 			}
@@ -165,14 +300,38 @@ func calculatePreserveSpaces(n *Node, pastValue bool) bool {
 	return pastValue
 }
 
+// xmlWriter is the writer used during serialization; both *bufio.Writer
+// and *bytes.Buffer implement it, so output avoids fmt and intermediate
+// strings.
+type xmlWriter interface {
+	io.Writer
+	io.StringWriter
+	io.ByteWriter
+}
+
+// textEscaper escapes text exactly like html.EscapeString, but writes the
+// result directly to the output instead of allocating a new string.
+var textEscaper = strings.NewReplacer(
+	`&`, "&amp;",
+	`'`, "&#39;",
+	`<`, "&lt;",
+	`>`, "&gt;",
+	`"`, "&#34;",
+)
+
+var bufferPool = sync.Pool{
+	New: func() interface{} { return new(bytes.Buffer) },
+}
+
 type indentation struct {
 	level    int
 	hasChild bool
 	indent   string
-	w        io.Writer
+	prefixes []string // prefixes[i] is a newline followed by i indents
+	w        xmlWriter
 }
 
-func newIndentation(indent string, w io.Writer) *indentation {
+func newIndentation(indent string, w xmlWriter) *indentation {
 	if indent == "" {
 		return nil
 	}
@@ -186,7 +345,19 @@ func (i *indentation) NewLine() {
 	if i == nil {
 		return
 	}
-	io.WriteString(i.w, "\n")
+	i.w.WriteByte('\n')
+}
+
+// writeLine writes a newline followed by the indentation of the current level.
+func (i *indentation) writeLine() {
+	for len(i.prefixes) <= i.level {
+		if len(i.prefixes) == 0 {
+			i.prefixes = append(i.prefixes, "\n")
+		} else {
+			i.prefixes = append(i.prefixes, i.prefixes[len(i.prefixes)-1]+i.indent)
+		}
+	}
+	i.w.WriteString(i.prefixes[i.level])
 }
 
 func (i *indentation) Open() {
@@ -194,8 +365,7 @@ func (i *indentation) Open() {
 		return
 	}
 
-	io.WriteString(i.w, "\n")
-	io.WriteString(i.w, strings.Repeat(i.indent, i.level))
+	i.writeLine()
 
 	i.level++
 	i.hasChild = false
@@ -207,77 +377,92 @@ func (i *indentation) Close() {
 	}
 	i.level--
 	if i.hasChild {
-		io.WriteString(i.w, "\n")
-		io.WriteString(i.w, strings.Repeat(i.indent, i.level))
+		i.writeLine()
 	}
 	i.hasChild = true
 }
 
-func outputXML(w io.Writer, n *Node, preserveSpaces bool, config *outputConfiguration, indent *indentation) {
+// writeName writes a prefixed name, as in "prefix:local".
+func writeName(w xmlWriter, prefix, local string) {
+	if prefix != "" {
+		w.WriteString(prefix)
+		w.WriteByte(':')
+	}
+	w.WriteString(local)
+}
+
+func outputXML(w xmlWriter, n *Node, preserveSpaces bool, config *outputConfiguration, indent *indentation) {
 	preserveSpaces = calculatePreserveSpaces(n, preserveSpaces)
-	if config.skipDeclarationNode && n.Type == DeclarationNode {
+	if config.skipped(n) {
+		return
+	}
+	if n.raw != nil && writeRaw(w, n, preserveSpaces, config, indent) {
 		return
 	}
-	switch n.Type {
+	nodeType := n.Type
+	if nodeType == CharDataNode && config.cdataAsText {
+		nodeType = TextNode
+	}
+	switch nodeType {
 	case TextNode:
-		s := n.sanitizedData(preserveSpaces)
-		if !config.TextNodeIgnoreHtmlEscaper {
-			s = html.EscapeString(s)
+		s := config.normalization.normalize(n.sanitizedData(preserveSpaces))
+		if config.TextNodeIgnoreHtmlEscaper {
+			w.WriteString(s)
+		} else if config.escapeAttrs {
+			writeEscaped(w, s, 0, config)
+		} else {
+			textEscaper.WriteString(w, s)
 		}
-
-		io.WriteString(w, s)
 		return
 	case CharDataNode:
-		io.WriteString(w, "<![CDATA[")
-		io.WriteString(w, n.Data)
-		io.WriteString(w, "]]>")
+		w.WriteString("<![CDATA[")
+		w.WriteString(config.normalization.normalize(n.Data))
+		w.WriteString("]]>")
 		return
 	case CommentNode:
 		if !config.skipComments {
-			io.WriteString(w, "<!--")
-			io.WriteString(w, n.Data)
-			io.WriteString(w, "-->")
+			w.WriteString("<!--")
+			w.WriteString(n.Data)
+			w.WriteString("-->")
 		}
 		return
 	case NotationNode:
 		indent.NewLine()
-		fmt.Fprintf(w, "<!%s>", n.Data)
+		w.WriteString("<!")
+		w.WriteString(n.Data)
+		w.WriteByte('>')
 		return
 	case DeclarationNode:
-		io.WriteString(w, "<?"+n.Data)
+		w.WriteString("<?")
+		w.WriteString(n.Data)
 	default:
+		n.Materialize()
 		indent.Open()
-		if n.Prefix == "" {
-			io.WriteString(w, "<"+n.Data)
-		} else {
-			fmt.Fprintf(w, "<%s:%s", n.Prefix, n.Data)
-		}
+		w.WriteByte('<')
+		writeName(w, n.Prefix, n.Data)
 	}
 
-	for _, attr := range n.Attr {
-		if attr.Name.Local == "" {
-			fmt.Fprintf(w, ` %v `, attr.Value)
-			continue
-		}
-		if attr.Name.Space != "" {
-			fmt.Fprintf(w, ` %s:%s=`, attr.Name.Space, attr.Name.Local)
-		} else {
-			fmt.Fprintf(w, ` %s=`, attr.Name.Local)
-		}
-		value := attr.Value
-		if strings.Contains(value, `"`) && !strings.Contains(value, `'`) {
-			fmt.Fprintf(w, `'%v'`, value)
-		} else {
-			fmt.Fprintf(w, `"%v"`, value)
+	attrs := orderedAttrs(n, config.attrOrder)
+	if n.Type == DeclarationNode && n.Data == "xml" {
+		attrs = declarationAttrs(attrs, config.encodingDecl)
+	}
+	if n == config.nsRoot && len(config.nsDecls) > 0 {
+		attrs = append(attrs[:len(attrs):len(attrs)], config.nsDecls...)
+	}
+	if indent != nil && config.attrWrapWidth > 0 && n.Type == ElementNode && len(attrs) > 1 {
+		writeWrappedAttrs(w, n, attrs, config, indent)
+	} else {
+		for i := range attrs {
+			writeAttr(w, n, &attrs[i], config)
 		}
 	}
 	if n.Type == DeclarationNode {
-		io.WriteString(w, "?>")
+		w.WriteString("?>")
 	} else {
 		if n.FirstChild != nil || !config.emptyElementTagSupport {
-			io.WriteString(w, ">")
+			w.WriteByte('>')
 		} else {
-			io.WriteString(w, "/>")
+			w.WriteString("/>")
 			indent.Close()
 			return
 		}
@@ -287,12 +472,71 @@ func outputXML(w io.Writer, n *Node, preserveSpaces bool, config *outputConfigur
 	}
 	if n.Type != DeclarationNode {
 		indent.Close()
-		if n.Prefix == "" {
-			fmt.Fprintf(w, "</%s>", n.Data)
-		} else {
-			fmt.Fprintf(w, "</%s:%s>", n.Prefix, n.Data)
+		w.WriteString("</")
+		writeName(w, n.Prefix, n.Data)
+		w.WriteByte('>')
+	}
+}
+
+// writeAttr writes attr of n, preceded by a space.
+func writeAttr(w xmlWriter, n *Node, attr *Attr, config *outputConfiguration) {
+	if attr.Name.Local == "" {
+		w.WriteByte(' ')
+		w.WriteString(attr.Value)
+		w.WriteByte(' ')
+		return
+	}
+	w.WriteByte(' ')
+	writeName(w, attr.Name.Space, attr.Name.Local)
+	w.WriteByte('=')
+	quote := byte('"')
+	if strings.Contains(attr.Value, `"`) && !strings.Contains(attr.Value, `'`) {
+		quote = '\''
+	}
+	w.WriteByte(quote)
+	value := config.normalization.normalize(attr.Value)
+	if config.escapeAttrs && n.Type != DeclarationNode {
+		writeEscaped(w, value, quote, config)
+	} else {
+		w.WriteString(value)
+	}
+	w.WriteByte(quote)
+}
+
+// writeWrappedAttrs writes the attributes of element n, wrapping them as
+// described by WithAttributeWrap. indent has already been opened for n.
+func writeWrappedAttrs(w xmlWriter, n *Node, nodeAttrs []Attr, config *outputConfiguration, indent *indentation) {
+	attrs := make([]string, len(nodeAttrs))
+	var b strings.Builder
+	nameLen := len(n.Data)
+	if n.Prefix != "" {
+		nameLen += len(n.Prefix) + 1
+	}
+	width := (indent.level-1)*len(indent.indent) + 1 + nameLen + 1 // "<name" and ">"
+	for i := range nodeAttrs {
+		writeAttr(&b, n, &nodeAttrs[i], config)
+		attrs[i] = b.String()
+		width += len(attrs[i])
+		b.Reset()
+	}
+	if n.FirstChild == nil && config.emptyElementTagSupport {
+		width++
+	}
+	if width <= config.attrWrapWidth {
+		for _, attr := range attrs {
+			w.WriteString(attr)
 		}
+		return
 	}
+	w.WriteString(attrs[0])
+	continuation := strings.Repeat(" ", 1+nameLen)
+	indent.level-- // continuation lines start at the level of the tag
+	for _, attr := range attrs[1:] {
+		indent.writeLine()
+		w.WriteString(continuation)
+		w.WriteString(attr)
+	}
+	indent.level++
 }
 
 // OutputXML returns the text that including tags name.
@@ -305,8 +549,15 @@ func (n *Node) OutputXML(self bool) string {
 
 // OutputXMLWithOptions returns the text that including tags name.
 func (n *Node) OutputXMLWithOptions(opts ...OutputOption) string {
-	var b strings.Builder
-	n.WriteWithOptions(&b, opts...)
+	b := bufferPool.Get().(*bytes.Buffer)
+	defer func() {
+		// Don't keep very large buffers alive in the pool.
+		if b.Cap() <= 1<<20 {
+			b.Reset()
+			bufferPool.Put(b)
+		}
+	}()
+	n.writeTo(b, opts)
 	return b.String()
 }
 
@@ -314,12 +565,23 @@ func (n *Node) OutputXMLWithOptions(opts ...OutputOption) string {
 func (n *Node) Write(writer io.Writer, self bool) {
 	if self {
 		n.WriteWithOptions(writer, WithOutputSelf())
+		return
 	}
 	n.WriteWithOptions(writer)
 }
 
 // WriteWithOptions writes xml with given options to given writer.
 func (n *Node) WriteWithOptions(writer io.Writer, opts ...OutputOption) {
+	if w, ok := writer.(*bytes.Buffer); ok {
+		n.writeTo(w, opts)
+		return
+	}
+	b := bufio.NewWriter(writer)
+	defer b.Flush()
+	n.writeTo(b, opts)
+}
+
+func (n *Node) writeTo(w xmlWriter, opts []OutputOption) {
 	config := &outputConfiguration{}
 	// Set the options
 	for _, opt := range opts {
@@ -327,14 +589,26 @@ func (n *Node) WriteWithOptions(writer io.Writer, opts ...OutputOption) {
 	}
 	pastPreserveSpaces := config.preserveSpaces
 	preserveSpaces := calculatePreserveSpaces(n, pastPreserveSpaces)
-	b := bufio.NewWriter(writer)
-	defer b.Flush()
 
+	if config.bom {
+		w.WriteString("\uFEFF")
+	}
+	fixup := !config.noNamespaceFixup && n.Type != DocumentNode
 	if config.printSelf && n.Type != DocumentNode {
-		outputXML(b, n, preserveSpaces, config, newIndentation(config.useIndentation, b))
+		if fixup {
+			config.setNamespaceRoot(n, namespacesInScope(n.Parent))
+		}
+		outputXML(w, n, preserveSpaces, config, newIndentation(config.useIndentation, w))
 	} else {
+		var scope map[string]string
+		if fixup {
+			scope = namespacesInScope(n)
+		}
 		for n := n.FirstChild; n != nil; n = n.NextSibling {
-			outputXML(b, n, preserveSpaces, config, newIndentation(config.useIndentation, b))
+			if fixup {
+				config.setNamespaceRoot(n, scope)
+			}
+			outputXML(w, n, preserveSpaces, config, newIndentation(config.useIndentation, w))
 		}
 	}
 }
@@ -346,29 +620,53 @@ func AddAttr(n *Node, key, val string) {
 		Value: val,
 	}
 	n.Attr = append(n.Attr, attr)
+	n.resetAttrIndex()
+	notify(n, Mutation{Type: AttrSet, Target: n, Name: key})
 }
 
 // SetAttr allows an attribute value with the specified name to be changed.
-// If the attribute did not previously exist, it will be created.
+// The attribute keeps its position; if it did not previously exist, it will
+// be created after the existing attributes.
 func (n *Node) SetAttr(key, value string) {
-	name := newXMLName(key)
-	for i, attr := range n.Attr {
-		if attr.Name == name {
-			n.Attr[i].Value = value
-			return
-		}
+	if i := n.attrPos(newXMLName(key)); i >= 0 {
+		old := n.Attr[i].Value
+		n.Attr[i].Value = value
+		notify(n, Mutation{Type: AttrSet, Target: n, Name: key, OldValue: old})
+		return
 	}
 	AddAttr(n, key, value)
 }
 
+// Attrs returns an iterator over the attributes of n in document order,
+// including namespace declarations. The iterator calls yield for each
+// attribute until yield returns false; the value of the attribute may be
+// modified in place, but attributes must not be renamed, added or removed
+// during the iteration.
+//
+//	n.Attrs()(func(attr *Attr) bool {
+//		fmt.Println(attr.Name.Local, attr.Value)
+//		return true
+//	})
+//
+// The signature matches iter.Seq, so with Go 1.23 or later the iterator can
+// also be used in a range statement.
+func (n *Node) Attrs() func(yield func(*Attr) bool) {
+	return func(yield func(*Attr) bool) {
+		for i := range n.Attr {
+			if !yield(&n.Attr[i]) {
+				return
+			}
+		}
+	}
+}
+
 // RemoveAttr removes the attribute with the specified name.
 func (n *Node) RemoveAttr(key string) {
-	name := newXMLName(key)
-	for i, attr := range n.Attr {
-		if attr.Name == name {
-			n.Attr = append(n.Attr[:i], n.Attr[i+1:]...)
-			return
-		}
+	if i := n.attrPos(newXMLName(key)); i >= 0 {
+		old := n.Attr[i].Value
+		n.Attr = append(n.Attr[:i], n.Attr[i+1:]...)
+		n.resetAttrIndex()
+		notify(n, Mutation{Type: AttrRemoved, Target: n, Name: key, OldValue: old})
 	}
 }
 
@@ -385,6 +683,7 @@ func AddChild(parent, n *Node) {
 	}
 
 	parent.LastChild = n
+	notify(parent, Mutation{Type: NodeInserted, Target: parent, Node: n})
 }
 
 // AddSibling adds a new node 'n' as a sibling of a given node 'sibling'.
@@ -403,6 +702,7 @@ func AddSibling(sibling, n *Node) {
 	if sibling.Parent != nil {
 		sibling.Parent.LastChild = n
 	}
+	notify(n, Mutation{Type: NodeInserted, Target: n.Parent, Node: n})
 }
 
 // RemoveFromTree removes a node and its subtree from the document
@@ -411,6 +711,7 @@ func RemoveFromTree(n *Node) {
 	if n.Parent == nil {
 		return
 	}
+	observers, parent := observersOf(n), n.Parent
 	if n.Parent.FirstChild == n {
 		if n.Parent.LastChild == n {
 			n.Parent.FirstChild = nil
@@ -431,4 +732,5 @@ func RemoveFromTree(n *Node) {
 	n.Parent = nil
 	n.PrevSibling = nil
 	n.NextSibling = nil
+	notifyObservers(observers, Mutation{Type: NodeRemoved, Target: parent, Node: n})
 }
@@ -1,6 +1,7 @@
 package xmlquery
 
 import (
+	"fmt"
 	"html"
 	"reflect"
 	"strings"
@@ -69,6 +70,15 @@ func testValue(t *testing.T, val, expected interface{}) {
 	t.Fatalf("expected value is %+v, but got %+v", expected, val)
 }
 
+// testDeepEqual is like testValue for values that can't be compared with
+// ==, such as slices and maps.
+func testDeepEqual(t *testing.T, val, expected interface{}) {
+	if reflect.DeepEqual(val, expected) {
+		return
+	}
+	t.Fatalf("expected value is %+v, but got %+v", expected, val)
+}
+
 func testTrue(t *testing.T, v bool) {
 	if v {
 		return
@@ -233,6 +243,28 @@ func TestRemoveAttr(t *testing.T) {
 	}
 }
 
+func TestAttrOrder(t *testing.T) {
+	doc, err := Parse(strings.NewReader(`<a xmlns:x="urn:x" z="1" x:m="2" b="3" y="4"/>`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	a := doc.SelectElement("a")
+	a.SetAttr("b", "30")
+	a.RemoveAttr("z")
+	a.SetAttr("c", "5")
+	testValue(t, a.OutputXML(true), `<a xmlns:x="urn:x" x:m="2" b="30" y="4" c="5"></a>`)
+
+	var names []string
+	a.Attrs()(func(attr *Attr) bool {
+		names = append(names, attr.Name.Local)
+		attr.Value = strings.ToUpper(attr.Value)
+		return attr.Name.Local != "y"
+	})
+	testDeepEqual(t, names, []string{"x", "m", "b", "y"})
+	testValue(t, a.SelectAttr("x:m"), "2")
+	testValue(t, a.SelectAttr("xmlns:x"), "URN:X")
+}
+
 func TestRemoveFromTree(t *testing.T) {
 	xml := `<?procinst?>
 		<!--comment-->
@@ -350,6 +382,21 @@ func TestSelectElement(t *testing.T) {
 	}
 }
 
+func TestFirstChildElement(t *testing.T) {
+	doc, err := Parse(strings.NewReader(`<?xml version="1.0"?><!--c--><r xmlns:a="urn:a"><b/><a:b/><b xmlns="urn:a"/></r>`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	root := firstChildElement(doc)
+	testValue(t, root.Data, "r")
+	testValue(t, firstChildElement(root).Data, "b")
+	testTrue(t, firstChildElement(firstChildElement(root)) == nil)
+
+	testValue(t, childElementNS(root, "", "b"), root.FirstChild)
+	testValue(t, childElementNS(root, "urn:a", "b").Prefix, "a")
+	testTrue(t, childElementNS(root, "urn:b", "b") == nil)
+}
+
 func TestEscapeOutputValue(t *testing.T) {
 	data := `<AAA>&lt;*&gt;</AAA>`
 
@@ -647,6 +694,42 @@ func TestOutputXMLWithXMLInCDATA(t *testing.T) {
 	}
 }
 
+func TestOutputXMLWithCDATAAsText(t *testing.T) {
+	doc, err := Parse(strings.NewReader(`<node>a <![CDATA[<b> & c]]></node>`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	node := doc.SelectElement("node")
+	testValue(t, node.OutputXMLWithOptions(WithCDATAAsText()), `a&lt;b&gt; &amp; c`)
+	testValue(t, node.OutputXMLWithOptions(WithCDATAAsText(), WithPreserveSpace()), `a &lt;b&gt; &amp; c`)
+
+	doc, err = ParseWithOptions(strings.NewReader(`<node><![CDATA[<b>]]></node>`), ParserOptions{RoundTrip: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	testValue(t, doc.OutputXMLWithOptions(WithCDATAAsText(), WithOutDeclarationNode()), `<node>&lt;b&gt;</node>`)
+}
+
+func TestOutputXMLWithAttributeWrap(t *testing.T) {
+	doc, err := Parse(strings.NewReader(`<config><server host="example.com" port="8080" tls="true"><x:limit xmlns:x="urn:x" rate="10"/></server><short a="1" b="2"/></config>`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := `
+<config>
+  <server host="example.com"
+          port="8080"
+          tls="true">
+    <x:limit xmlns:x="urn:x"
+             rate="10"/>
+  </server>
+  <short a="1" b="2"/>
+</config>`
+	testValue(t, doc.OutputXMLWithOptions(WithOutDeclarationNode(), WithIndentation("  "), WithAttributeWrap(30), WithEmptyTagSupport()), expected)
+	// Without indentation, attributes are never wrapped.
+	testValue(t, doc.SelectElement("config/short").OutputXMLWithOptions(WithOutputSelf(), WithAttributeWrap(1)), `<short a="1" b="2"></short>`)
+}
+
 func TestOutputXMLWithDefaultOptions(t *testing.T) {
 	s := `<?xml version="1.0" encoding="utf-8"?><node><empty></empty></node>`
 	expected := `<?xml version="1.0" encoding="utf-8"?><node><empty></empty></node>`
@@ -746,3 +829,67 @@ func TestOutputXMLWithSingleQuotes(t *testing.T) {
 		t.Errorf(`expected "%s", obtained "%s"`, expected, output)
 	}
 }
+
+func TestWriteSelf(t *testing.T) {
+	doc, err := Parse(strings.NewReader(`<a><b>text</b></a>`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	a := FindOne(doc, "//a")
+	var b strings.Builder
+	a.Write(&b, true)
+	testValue(t, b.String(), `<a><b>text</b></a>`)
+}
+
+func benchmarkDocument(b *testing.B) *Node {
+	var s strings.Builder
+	s.WriteString(`<?xml version="1.0"?><catalog xmlns:x="urn:x">`)
+	for i := 0; i < 1000; i++ {
+		fmt.Fprintf(&s, `<item id="%d" x:kind="book &amp; cd"><title>Title %d &lt;draft&gt;</title><nested><deep><deeper>text</deeper></deep></nested><!--c--></item>`, i, i)
+	}
+	s.WriteString(`</catalog>`)
+	doc, err := Parse(strings.NewReader(s.String()))
+	if err != nil {
+		b.Fatal(err)
+	}
+	return doc
+}
+
+func BenchmarkOutputXML(b *testing.B) {
+	doc := benchmarkDocument(b)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		doc.OutputXML(false)
+	}
+}
+
+func BenchmarkOutputXMLIndented(b *testing.B) {
+	doc := benchmarkDocument(b)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		doc.OutputXMLWithOptions(WithIndentation("  "))
+	}
+}
+
+func TestOutputFilters(t *testing.T) {
+	s := `<?xml version="1.0"?><?xml-stylesheet href="a.xsl"?><!--c--><r><doc:note xmlns:doc="urn:doc">n</doc:note><a>1<!--x--></a></r>`
+	for _, roundTrip := range []bool{false, true} {
+		doc, err := ParseWithOptions(strings.NewReader(s), ParserOptions{RoundTrip: roundTrip})
+		if err != nil {
+			t.Fatal(err)
+		}
+		testValue(t, doc.OutputXMLWithOptions(WithoutXMLDeclaration(), WithoutComments()),
+			`<?xml-stylesheet href="a.xsl"?><r><doc:note xmlns:doc="urn:doc">n</doc:note><a>1</a></r>`)
+		testValue(t, doc.OutputXMLWithOptions(WithoutProcessingInstructions()),
+			`<?xml version="1.0"?><!--c--><r><doc:note xmlns:doc="urn:doc">n</doc:note><a>1<!--x--></a></r>`)
+		testValue(t, doc.OutputXMLWithOptions(WithOutDeclarationNode(), WithNodeFilter(func(n *Node) bool {
+			return n.Type != CommentNode && n.Prefix != "doc"
+		})), `<r><a>1</a></r>`)
+		a := FindOne(doc, "//a")
+		testValue(t, a.OutputXMLWithOptions(WithOutputSelf(), WithNodeFilter(func(n *Node) bool {
+			return n != a
+		})), ``)
+	}
+}
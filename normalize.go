@@ -0,0 +1,34 @@
+package xmlquery
+
+import "strings"
+
+// Normalize merges adjacent text nodes and removes empty text nodes in the
+// subtree of n, as left behind by AddChild, RemoveFromTree and SetData, so
+// that every run of text is held by a single node. CDATA sections are kept
+// as they are. Changes are reported to observers like the mutations that
+// perform them.
+func (n *Node) Normalize() {
+	n.Materialize()
+	for child := n.FirstChild; child != nil; {
+		next := child.NextSibling
+		if child.Type != TextNode {
+			child.Normalize()
+			child = next
+			continue
+		}
+		var b strings.Builder
+		b.WriteString(child.Data)
+		for next != nil && next.Type == TextNode {
+			b.WriteString(next.Data)
+			merged := next
+			next = next.NextSibling
+			RemoveFromTree(merged)
+		}
+		if b.Len() == 0 {
+			RemoveFromTree(child)
+		} else if b.Len() != len(child.Data) {
+			child.SetData(b.String())
+		}
+		child = next
+	}
+}
@@ -0,0 +1,39 @@
+package xmlquery
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestNormalize(t *testing.T) {
+	doc, err := Parse(strings.NewReader(`<a>one<b>x</b>two<![CDATA[c]]>three<e/></a>`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	a := doc.SelectElement("a")
+	b := a.SelectElement("b")
+	RemoveFromTree(b)
+	RemoveFromTree(a.SelectElement("e"))
+	AddChild(a, &Node{Type: TextNode, Data: "four"})
+	AddChild(a, &Node{Type: TextNode})
+	AddChild(a, b)
+	AddChild(b, &Node{Type: TextNode})
+	AddChild(b, &Node{Type: TextNode, Data: "y"})
+
+	var mutations []MutationType
+	cancel := doc.Observe(func(m *Mutation) {
+		mutations = append(mutations, m.Type)
+	})
+	defer cancel()
+
+	doc.Normalize()
+	var texts []string
+	for child := a.FirstChild; child != nil; child = child.NextSibling {
+		texts = append(texts, child.Data)
+	}
+	testDeepEqual(t, texts, []string{"onetwo", "c", "threefour", "b"})
+	testValue(t, b.FirstChild.Data, "xy")
+	testTrue(t, b.FirstChild == b.LastChild)
+	verifyNodePointers(t, doc)
+	testDeepEqual(t, mutations, []MutationType{NodeRemoved, DataChanged, NodeRemoved, NodeRemoved, DataChanged, NodeRemoved, NodeRemoved, DataChanged})
+}
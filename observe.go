@@ -0,0 +1,114 @@
+package xmlquery
+
+import (
+	"sync/atomic"
+)
+
+// A MutationType is the kind of change described by a Mutation.
+type MutationType int
+
+const (
+	// NodeInserted is reported by AddChild and AddSibling.
+	NodeInserted MutationType = iota + 1
+	// NodeRemoved is reported by RemoveFromTree.
+	NodeRemoved
+	// AttrSet is reported by AddAttr and SetAttr.
+	AttrSet
+	// AttrRemoved is reported by RemoveAttr.
+	AttrRemoved
+	// DataChanged is reported by SetData.
+	DataChanged
+)
+
+// A Mutation describes a change of a tree, see Observe.
+type Mutation struct {
+	Type MutationType
+	// Target is the node that changed: the parent of the inserted or
+	// removed node, the element whose attribute changed, or the node
+	// whose data changed.
+	Target *Node
+	// Node is the inserted or removed node.
+	Node *Node
+	// Name is the name of the attribute that changed, as in "prefix:local".
+	Name string
+	// OldValue is the previous value of the attribute or data, "" if there
+	// was none.
+	OldValue string
+}
+
+// observer is a callback registered with Observe.
+type observer struct {
+	fn func(*Mutation)
+}
+
+// observerCount is the number of registered observers in all trees, so that
+// mutations don't look for observers while there are none.
+var observerCount int32
+
+// Observe registers fn to be called after every change of the tree n
+// belongs to that is made with AddChild, AddSibling, RemoveFromTree,
+// AddAttr, SetAttr, RemoveAttr or SetData. Changes made by assigning to
+// the fields of a Node directly are not reported. The observer is kept by
+// the root of the tree, so register it on a document rather than on a
+// detached subtree that is inserted later.
+//
+// The returned function unregisters fn.
+//
+//	cancel := doc.Observe(func(m *xmlquery.Mutation) {
+//		if m.Type == xmlquery.NodeInserted {
+//			index(m.Node)
+//		}
+//	})
+//	defer cancel()
+func (n *Node) Observe(fn func(*Mutation)) (cancel func()) {
+	root := n
+	for root.Parent != nil {
+		root = root.Parent
+	}
+	o := &observer{fn: fn}
+	d := root.docData()
+	d.observers = append(d.observers, o)
+	atomic.AddInt32(&observerCount, 1)
+	return func() {
+		for i, other := range d.observers {
+			if other == o {
+				d.observers = append(d.observers[:i:i], d.observers[i+1:]...)
+				atomic.AddInt32(&observerCount, -1)
+				return
+			}
+		}
+	}
+}
+
+// observersOf returns the observers of the tree n belongs to.
+func observersOf(n *Node) []*observer {
+	if n == nil || atomic.LoadInt32(&observerCount) == 0 {
+		return nil
+	}
+	for n.Parent != nil {
+		n = n.Parent
+	}
+	if n.doc == nil {
+		return nil
+	}
+	return n.doc.observers
+}
+
+// notify calls the observers of the tree n belongs to.
+func notify(n *Node, m Mutation) {
+	notifyObservers(observersOf(n), m)
+}
+
+func notifyObservers(observers []*observer, m Mutation) {
+	for _, o := range observers {
+		o.fn(&m)
+	}
+}
+
+// SetData replaces the Data of n, the content of a text, CDATA or comment
+// node or the name of an element, and reports the change to observers.
+func (n *Node) SetData(data string) {
+	old := n.Data
+	n.Data = data
+	notify(n, Mutation{Type: DataChanged, Target: n, OldValue: old})
+}
@@ -0,0 +1,43 @@
+package xmlquery
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestObserve(t *testing.T) {
+	doc, err := Parse(strings.NewReader(`<root><a id="1">text</a></root>`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []Mutation
+	cancel := doc.Observe(func(m *Mutation) {
+		got = append(got, *m)
+	})
+	root := doc.SelectElement("root")
+	a := root.SelectElement("a")
+	b := &Node{Type: ElementNode, Data: "b"}
+
+	AddChild(root, b)
+	AddSibling(a, &Node{Type: CommentNode, Data: "c"})
+	a.SetAttr("id", "2")
+	a.SetAttr("x:new", "v")
+	a.RemoveAttr("id")
+	a.FirstChild.SetData("changed")
+	RemoveFromTree(b)
+	AddChild(b, &Node{Type: TextNode, Data: "detached"})
+
+	testValue(t, len(got), 7)
+	testTrue(t, got[0].Type == NodeInserted && got[0].Target == root && got[0].Node == b)
+	testTrue(t, got[1].Type == NodeInserted && got[1].Target == root && got[1].Node.Type == CommentNode)
+	testValue(t, got[2], Mutation{Type: AttrSet, Target: a, Name: "id", OldValue: "1"})
+	testValue(t, got[3], Mutation{Type: AttrSet, Target: a, Name: "x:new"})
+	testValue(t, got[4], Mutation{Type: AttrRemoved, Target: a, Name: "id", OldValue: "2"})
+	testValue(t, got[5], Mutation{Type: DataChanged, Target: a.FirstChild, OldValue: "text"})
+	testTrue(t, got[6].Type == NodeRemoved && got[6].Target == root && got[6].Node == b)
+
+	cancel()
+	AddChild(root, &Node{Type: ElementNode, Data: "c"})
+	testValue(t, len(got), 7)
+	cancel()
+}
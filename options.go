@@ -8,12 +8,120 @@ import (
 
 type ParserOptions struct {
 	Decoder *DecoderOptions
+	// UseArena allocates all nodes and attributes of the document from large
+	// slabs instead of one heap object each, which reduces GC pressure when
+	// parsing many documents. Call Free on the returned document once it is
+	// no longer needed so its slabs can be reused. Ignored by StreamParser.
+	UseArena bool
+	// RoundTrip keeps the source text of every node, so that writing the
+	// document reproduces the input byte for byte: quote characters,
+	// whitespace inside tags, entity and character references and the
+	// presence of an XML declaration are all preserved. Nodes that have been
+	// modified since parsing are written normally, so editing one element
+	// gives a minimal textual diff. The source is not used when writing with
+	// WithIndentation or an escaping option, or for documents in an encoding
+	// other than UTF-8.
+	RoundTrip bool
+	// SkipWhitespaceText omits text nodes that contain only whitespace, such
+	// as the indentation between elements, unless xml:space="preserve"
+	// applies to them.
+	SkipWhitespaceText bool
+	// InvalidUTF8 chooses what happens to bytes that are not valid UTF-8 in
+	// text, attribute values, comments and processing instructions, after
+	// conversion from the declared encoding. By default the input is
+	// rejected with a ParseError giving the position of the bad sequence.
+	InvalidUTF8 InvalidUTF8Policy
+	// DuplicateAttrs chooses what happens to an element that repeats an
+	// attribute, which is not well-formed XML. By default all occurrences
+	// are kept.
+	DuplicateAttrs DuplicateAttrPolicy
+	// WellFormed checks the input against all well-formedness constraints,
+	// including those the decoder doesn't enforce: a single root element,
+	// no text outside of it, the XML declaration only at the start, a
+	// single DOCTYPE before the root element, declared namespace prefixes
+	// and no duplicate attributes. It implies a strict decoder. With
+	// ParseRecover, every violation is reported with its position, which
+	// makes it suitable for linting.
+	WellFormed bool
+	// DTDEntities makes the general entities declared in the internal
+	// subset of the DOCTYPE known, so that references to them are replaced
+	// by their text. By default only the predefined entities and those of
+	// DecoderOptions.Entity are known, and references to other entities
+	// fail in a strict decoder.
+	DTDEntities bool
+	// EntityResolver, if set, is asked for the external DTD subset and
+	// the external parsed entities the DOCTYPE refers to, so that the
+	// entities they declare can be used in the document. A Catalog maps
+	// them to local files. Setting it implies DTDEntities.
+	EntityResolver EntityResolver
+	// Normalization converts text, CDATA sections and attribute values to
+	// a Unicode normalization form, so that strings compare equal whatever
+	// composition the producer used. With RoundTrip, nodes whose content
+	// changed are written normally.
+	Normalization NormalizationForm
+	// HTMLEntities makes the named character references of HTML5, such as
+	// &nbsp;, &eacute; or &mdash;, known without a declaration, for
+	// documents such as CMS exports and feeds that use them. Entities
+	// declared by the document, see DTDEntities, or given in
+	// DecoderOptions.Entity take precedence. The references are resolved
+	// with golang.org/x/net/html, and must end with a semicolon.
+	HTMLEntities bool
+	// Limits caps the number of elements and the number and size of
+	// attributes, so that a hostile document fails with a LimitError
+	// before it uses too much memory. Elements in the subtrees deferred by
+	// ParseLazy are counted when they are parsed, separately for each
+	// subtree.
+	Limits Limits
+	// CaseInsensitiveNames makes element and attribute names match whatever
+	// their case in the queries of the document, SelectElement and
+	// SelectAttr, for HTML-like and legacy sources that write <Item>,
+	// <ITEM> and <item> interchangeably. See Node.SetCaseInsensitiveNames.
+	CaseInsensitiveNames bool
 }
 
+// InvalidUTF8Policy is the handling of invalid UTF-8, see
+// ParserOptions.InvalidUTF8.
+type InvalidUTF8Policy int
+
+const (
+	// InvalidUTF8Reject fails parsing with a ParseError.
+	InvalidUTF8Reject InvalidUTF8Policy = iota
+	// InvalidUTF8Replace replaces each invalid byte with U+FFFD, the
+	// Unicode replacement character. Names must still be valid.
+	InvalidUTF8Replace
+)
+
 func (options ParserOptions) apply(parser *parser) {
 	if options.Decoder != nil {
 		(*options.Decoder).apply(parser.decoder)
 	}
+	if options.UseArena {
+		parser.arena = &nodeArena{}
+		parser.doc.docData().arena = parser.arena
+	}
+	parser.skipWhitespace = options.SkipWhitespaceText
+	parser.decoder.ReplaceInvalidUTF8 = options.InvalidUTF8 == InvalidUTF8Replace
+	parser.duplicateAttrs = options.DuplicateAttrs
+	parser.entityResolver = options.EntityResolver
+	parser.dtdEntities = options.DTDEntities || options.EntityResolver != nil
+	parser.normalization = options.Normalization
+	parser.limits = options.Limits
+	if options.CaseInsensitiveNames {
+		parser.doc.docData().foldCase = true
+	}
+	if options.HTMLEntities {
+		parser.decoder.DefaultEntity = htmlEntity
+	}
+	if options.WellFormed {
+		parser.wellFormed = true
+		parser.decoder.Strict = true
+		if parser.duplicateAttrs == DuplicateAttrKeepAll {
+			parser.duplicateAttrs = DuplicateAttrError
+		}
+	}
+	if options.RoundTrip {
+		parser.startRecording()
+	}
 }
 
 // DecoderOptions implement the very same options than the standard
@@ -2,6 +2,8 @@ package xmlquery
 
 import (
 	"bytes"
+	"errors"
+	"strings"
 	"testing"
 
 	"github.com/suifengpiao14/xmlquery/xml"
@@ -45,3 +47,30 @@ func TestApplyEmptyOptions(t *testing.T) {
 	// expecting this call to do anything
 	options.apply(parser)
 }
+
+func TestInvalidUTF8Policy(t *testing.T) {
+	s := "<a x=\"1\xff\"><!--c\xfe-->b\xc3</a>"
+	for _, input := range []string{s, "<a><!--c\xfe--></a>", "<a><?pi \xff?></a>"} {
+		_, err := Parse(strings.NewReader(input))
+		var perr *ParseError
+		if !errors.As(err, &perr) || !strings.Contains(err.Error(), "invalid UTF-8") {
+			t.Errorf("Parse(%q) = %v, want an invalid UTF-8 ParseError", input, err)
+		}
+	}
+
+	doc, err := ParseWithOptions(strings.NewReader(s), ParserOptions{InvalidUTF8: InvalidUTF8Replace})
+	if err != nil {
+		t.Fatal(err)
+	}
+	a := doc.SelectElement("a")
+	testValue(t, a.SelectAttr("x"), "1�")
+	testValue(t, a.FirstChild.Data, "c�")
+	testValue(t, a.LastChild.Data, "b�")
+
+	// Input in another encoding is checked after conversion.
+	doc, err = ParseWithOptions(strings.NewReader("<?xml version=\"1.0\" encoding=\"ISO-8859-1\"?><a>\xe9</a>"), ParserOptions{InvalidUTF8: InvalidUTF8Replace})
+	if err != nil {
+		t.Fatal(err)
+	}
+	testValue(t, doc.SelectElement("a").InnerText(), "é")
+}
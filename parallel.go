@@ -0,0 +1,369 @@
+package xmlquery
+
+import (
+	"regexp"
+	"runtime"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/antchfx/xpath"
+)
+
+// positionalRegex matches expressions whose result depends on the position
+// of nodes among their siblings, which can't be evaluated per element.
+var positionalRegex = regexp.MustCompile(`\[\s*[0-9$]|position\s*\(|last\s*\(|count\s*\(|preceding|following`)
+
+// QueryAllParallel is like QueryAll, but splits the children of the document
+// element into up to workers partitions and looks for matches in each
+// partition in its own goroutine. The results are merged and returned in
+// document order.
+// If workers <= 0, runtime.NumCPU() is used.
+//
+// Only expressions starting with a //name step, such as
+// "//record[@type='x']/field", are split: the elements called name are
+// found in parallel, and the rest of the expression is evaluated from each
+// of them against the whole document, so predicates and later steps may
+// use absolute paths, parent steps and the other axes. Other expressions,
+// and those that depend on sibling positions (position(), last(), count(),
+// numeric predicates, preceding/following axes), are evaluated
+// sequentially. The result holds the nodes QueryAll selects, each of them
+// once.
+func QueryAllParallel(top *Node, expr string, workers int) ([]*Node, error) {
+	exp, err := getQueryFor(top, expr)
+	if err != nil {
+		return nil, err
+	}
+	return QuerySelectorAllParallel(top, exp, workers), nil
+}
+
+// QuerySelectorAllParallel is like QueryAllParallel, but takes a compiled
+// selector.
+func QuerySelectorAllParallel(top *Node, selector *xpath.Expr, workers int) []*Node {
+	if workers <= 0 {
+		workers = runtime.NumCPU()
+	}
+	expr := selector.String()
+	name, rest, ok := splitDescendantStep(expr)
+	root := top
+	if root.Type == DocumentNode {
+		root = soleChildElement(top)
+	}
+	if !ok || root == nil || workers < 2 || positionalRegex.MatchString(expr) {
+		return sortDocumentOrder(uniqueNodes(QuerySelectorAll(top, selector)))
+	}
+	self, err := getQuery("self::" + name + rest)
+	if err != nil {
+		return sortDocumentOrder(uniqueNodes(QuerySelectorAll(top, selector)))
+	}
+	root.Materialize()
+	var children []*Node
+	for child := root.FirstChild; child != nil; child = child.NextSibling {
+		if child.Type == ElementNode {
+			children = append(children, child)
+		}
+	}
+	if len(children) < 2 {
+		return sortDocumentOrder(uniqueNodes(QuerySelectorAll(top, selector)))
+	}
+	if workers > len(children) {
+		workers = len(children)
+	}
+
+	local := name
+	if i := strings.LastIndexByte(local, ':'); i >= 0 {
+		local = local[i+1:]
+	}
+	// match appends the nodes selected by the rest of the expression from
+	// the elements of the subtree of n that the name test may select.
+	var match func(n *Node, found []*Node) []*Node
+	match = func(n *Node, found []*Node) []*Node {
+		if local == "*" || strings.EqualFold(n.Data, local) {
+			nav := selectorNavigator(top, self)
+			nav.curr = n
+			t := self.Select(nav)
+			for t.MoveNext() {
+				found = append(found, getCurrentNode(t))
+			}
+		}
+		n.Materialize()
+		for child := n.FirstChild; child != nil; child = child.NextSibling {
+			if child.Type == ElementNode {
+				found = match(child, found)
+			}
+		}
+		return found
+	}
+
+	results := make([][]*Node, workers)
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			for _, child := range children[i*len(children)/workers : (i+1)*len(children)/workers] {
+				results[i] = match(child, results[i])
+			}
+		}(i)
+	}
+	// The document element itself is matched here; its children are
+	// matched by the workers.
+	var nodes []*Node
+	if local == "*" || strings.EqualFold(root.Data, local) {
+		nav := selectorNavigator(top, self)
+		nav.curr = root
+		t := self.Select(nav)
+		for t.MoveNext() {
+			nodes = append(nodes, getCurrentNode(t))
+		}
+	}
+	wg.Wait()
+
+	// Later steps, such as parent steps, may select the same node from
+	// elements of several partitions, or nodes outside of them.
+	for _, found := range results {
+		nodes = append(nodes, found...)
+	}
+	return sortDocumentOrder(uniqueNodes(nodes))
+}
+
+// soleChildElement returns the only element child of n, or nil if n has
+// none or several.
+func soleChildElement(n *Node) *Node {
+	var elem *Node
+	for child := n.FirstChild; child != nil; child = child.NextSibling {
+		if child.Type == ElementNode {
+			if elem != nil {
+				return nil
+			}
+			elem = child
+		}
+	}
+	return elem
+}
+
+// uniqueNodes drops the repeated nodes of nodes. Attribute nodes, which are
+// created for each query result, are the same if they belong to the same
+// element and have the same name and value.
+func uniqueNodes(nodes []*Node) []*Node {
+	type attrKey struct {
+		owner       *Node
+		name, value string
+	}
+	seen := make(map[*Node]bool, len(nodes))
+	seenAttrs := make(map[attrKey]bool)
+	unique := nodes[:0]
+	for _, n := range nodes {
+		if n.Type == AttributeNode {
+			key := attrKey{n.Parent, n.Data, n.InnerText()}
+			if seenAttrs[key] {
+				continue
+			}
+			seenAttrs[key] = true
+		} else {
+			if seen[n] {
+				continue
+			}
+			seen[n] = true
+		}
+		unique = append(unique, n)
+	}
+	return unique
+}
+
+// splitDescendantStep splits an expression of the form //name followed by
+// predicates and relative steps into the name test and the rest. ok is
+// false for other expressions, such as unions, comparisons or expressions
+// starting with another step.
+func splitDescendantStep(expr string) (name, rest string, ok bool) {
+	expr = strings.TrimSpace(expr)
+	if !strings.HasPrefix(expr, "//") {
+		return "", "", false
+	}
+	s := expr[2:]
+	i := 0
+	for i < len(s) && (isNameTestByte(s[i]) || s[i] == ':' || s[i] == '*') {
+		i++
+	}
+	name, rest = s[:i], s[i:]
+	if name == "" || strings.Contains(name, "::") || strings.HasSuffix(name, ":") {
+		return "", "", false
+	}
+	if strings.Contains(name, "*") && name != "*" && !strings.HasSuffix(name, ":*") {
+		return "", "", false
+	}
+	if rest != "" && rest[0] != '[' && rest[0] != '/' {
+		return "", "", false
+	}
+	if !isRelativePath(rest) {
+		return "", "", false
+	}
+	return name, rest, true
+}
+
+// isRelativePath reports whether s, outside of predicates and string
+// literals, is made only of location steps, so that appending it to a step
+// gives a path rather than a union, a comparison or an arithmetic
+// expression.
+func isRelativePath(s string) bool {
+	depth := 0
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case c == '"' || c == '\'':
+			end := strings.IndexByte(s[i+1:], c)
+			if end < 0 {
+				return false
+			}
+			i += end + 1
+		case c == '[':
+			depth++
+		case c == ']':
+			depth--
+		case depth > 0:
+		case c == '/' || c == '@' || c == ':' || c == '.' || isNameTestByte(c):
+		case c == '*':
+			if i == 0 || (s[i-1] != '/' && s[i-1] != '@' && s[i-1] != ':') {
+				return false
+			}
+		case c == '(':
+			// Node tests such as text() and node().
+			if i+1 == len(s) || s[i+1] != ')' {
+				return false
+			}
+			i++
+		default:
+			return false
+		}
+	}
+	return depth == 0
+}
+
+// isNameTestByte reports whether c may be part of an NCName.
+func isNameTestByte(c byte) bool {
+	return c == '_' || c == '-' || c == '.' || c >= '0' && c <= '9' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= 0x80
+}
+
+// sortDocumentOrder sorts nodes in document order and returns them. Nodes
+// that are in order already are returned as they are.
+func sortDocumentOrder(nodes []*Node) []*Node {
+	if len(nodes) < 2 {
+		return nodes
+	}
+	var o documentOrder
+	keys := make([][]int, len(nodes))
+	for i, n := range nodes {
+		keys[i] = o.key(n)
+	}
+	for i := 1; i < len(keys); i++ {
+		if compareOrderKeys(keys[i-1], keys[i]) > 0 {
+			sort.Stable(nodesByOrderKey{nodes, keys})
+			break
+		}
+	}
+	return nodes
+}
+
+type nodesByOrderKey struct {
+	nodes []*Node
+	keys  [][]int
+}
+
+func (s nodesByOrderKey) Len() int { return len(s.nodes) }
+
+func (s nodesByOrderKey) Less(i, j int) bool {
+	return compareOrderKeys(s.keys[i], s.keys[j]) < 0
+}
+
+func (s nodesByOrderKey) Swap(i, j int) {
+	s.nodes[i], s.nodes[j] = s.nodes[j], s.nodes[i]
+	s.keys[i], s.keys[j] = s.keys[j], s.keys[i]
+}
+
+// compareDocumentOrder returns -1 if a precedes b in document order, 1 if it
+// follows b, and 0 if they are the same node or not in the same tree. The
+// attribute nodes returned by queries are ordered right after their element.
+func compareDocumentOrder(a, b *Node) int {
+	var o documentOrder
+	ka, kb := o.key(a), o.key(b)
+	if ka[0] != kb[0] {
+		return 0
+	}
+	return compareOrderKeys(ka, kb)
+}
+
+// documentOrder computes keys that order nodes like the document. The
+// positions of nodes among their siblings are computed once per parent, so
+// keying n nodes takes time proportional to n times their depth plus the
+// number of siblings of their ancestors, rather than walking siblings for
+// every comparison.
+type documentOrder struct {
+	pos   map[*Node]int // position of a node among the children of its parent
+	roots map[*Node]int // number of a tree, in the order the trees were seen
+}
+
+// key returns the number of the tree of n followed by the positions of the
+// ancestors of n and of n itself among their siblings. Attribute nodes,
+// which aren't children of their element, get the position -1, so they sort
+// right after the element and before its children.
+func (o *documentOrder) key(n *Node) []int {
+	var key []int
+	for ; n.Parent != nil; n = n.Parent {
+		key = append(key, o.position(n))
+	}
+	if o.roots == nil {
+		o.roots = make(map[*Node]int)
+	}
+	root, ok := o.roots[n]
+	if !ok {
+		root = len(o.roots)
+		o.roots[n] = root
+	}
+	key = append(key, root)
+	for i, j := 0, len(key)-1; i < j; i, j = i+1, j-1 {
+		key[i], key[j] = key[j], key[i]
+	}
+	return key
+}
+
+// position returns the position of n among the children of its parent, or
+// -1 if n isn't one of them.
+func (o *documentOrder) position(n *Node) int {
+	if pos, ok := o.pos[n]; ok {
+		return pos
+	}
+	if o.pos == nil {
+		o.pos = make(map[*Node]int)
+	}
+	i := 0
+	for c := n.Parent.FirstChild; c != nil; c = c.NextSibling {
+		o.pos[c] = i
+		i++
+	}
+	pos, ok := o.pos[n]
+	if !ok {
+		pos = -1
+		o.pos[n] = pos
+	}
+	return pos
+}
+
+// compareOrderKeys compares two keys returned by documentOrder.key. A key
+// that is a prefix of the other, an ancestor, comes first.
+func compareOrderKeys(a, b []int) int {
+	for i := 0; i < len(a) && i < len(b); i++ {
+		if a[i] != b[i] {
+			if a[i] < b[i] {
+				return -1
+			}
+			return 1
+		}
+	}
+	switch {
+	case len(a) < len(b):
+		return -1
+	case len(a) > len(b):
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,146 @@
+package xmlquery
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestQueryAllParallel(t *testing.T) {
+	var b strings.Builder
+	b.WriteString(`<?xml version="1.0"?><!--head--><export id="e">`)
+	for i := 0; i < 50; i++ {
+		fmt.Fprintf(&b, `<record type="%d"><field id="f%d">%d</field><nested><record type="1"></record></nested></record>`, i%3, i, i)
+	}
+	b.WriteString(`</export><!--tail-->`)
+	doc, err := Parse(strings.NewReader(b.String()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, expr := range []string{
+		"//record[@type='1']",
+		"/export/record/field",
+		"//field[. > 40]",
+		"//@id",
+		"//node()",
+		"//comment()",
+		"//record[1]",
+		"//record[last()]",
+		"/export",
+		"//record/..",
+		"//field[../@type='2']/@id",
+		"//record[@type = //field[. = '4']/../@type]",
+		"//*[@id]",
+	} {
+		expected := sortDocumentOrder(uniqueNodes(Find(doc, expr)))
+		for _, workers := range []int{0, 1, 3, 7, 100} {
+			actual, err := QueryAllParallel(doc, expr, workers)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(actual) != len(expected) {
+				t.Fatalf("%s with %d workers: expected %d nodes, got %d", expr, workers, len(expected), len(actual))
+			}
+			for i := range expected {
+				if expected[i].Type == AttributeNode {
+					testValue(t, actual[i].Parent, expected[i].Parent)
+					continue
+				}
+				if actual[i] != expected[i] {
+					t.Fatalf("%s with %d workers: node %d differs", expr, workers, i)
+				}
+			}
+		}
+	}
+	if _, err := QueryAllParallel(doc, "//record[", 2); err == nil {
+		t.Fatal("expected error for invalid expression")
+	}
+}
+
+func TestQueryAllParallelOutsidePartition(t *testing.T) {
+	doc, err := Parse(strings.NewReader(`<root><def id="d1"/><a id="1" ref="d1"/><a id="2" x="1"/><b><a id="3"/></b><a id="4"/><a id="5"/><a id="6"/></root>`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, expr := range []string{
+		"//a[@ref = //def/@id]",
+		"//a[../def]",
+		"//a[@x='1']/..",
+	} {
+		expected := Find(doc, expr)
+		actual, err := QueryAllParallel(doc, expr, 4)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(expected) == 0 {
+			t.Fatalf("%s: expected matches", expr)
+		}
+		testDeepEqual(t, actual, sortDocumentOrder(uniqueNodes(expected)))
+	}
+}
+
+func TestSplitDescendantStep(t *testing.T) {
+	for expr, want := range map[string][2]string{
+		"//a":                  {"a", ""},
+		"//x:a[@id]/b":         {"x:a", "[@id]/b"},
+		"//*[@x='1' or y]/..":  {"*", "[@x='1' or y]/.."},
+		"//a/text()":           {"a", "/text()"},
+		"//a | //b":            {},
+		"//a = 1":              {},
+		"//a/b*2":              {},
+		"/root/a":              {},
+		"//@id":                {},
+		"//text()":             {},
+		"//child::a":           {},
+		"//a['1'] | //b['|']":  {},
+		"//a[@x='] | //b[@y']": {"a", "[@x='] | //b[@y']"},
+	} {
+		name, rest, ok := splitDescendantStep(expr)
+		testValue(t, ok, want[0] != "")
+		testValue(t, name+rest, want[0]+want[1])
+	}
+}
+
+func TestCompareDocumentOrder(t *testing.T) {
+	doc, err := Parse(strings.NewReader(`<a><b><c></c></b><d></d></a>`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	a, b, c, d := FindOne(doc, "//a"), FindOne(doc, "//b"), FindOne(doc, "//c"), FindOne(doc, "//d")
+	testValue(t, compareDocumentOrder(a, c), -1)
+	testValue(t, compareDocumentOrder(c, a), 1)
+	testValue(t, compareDocumentOrder(c, d), -1)
+	testValue(t, compareDocumentOrder(d, b), 1)
+	testValue(t, compareDocumentOrder(b, b), 0)
+	testValue(t, compareDocumentOrder(b, &Node{}), 0)
+}
+
+func TestSortDocumentOrder(t *testing.T) {
+	doc, err := Parse(strings.NewReader(`<a x="1"><b y="2"><c/></b><d/></a>`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	a, b, c, d := FindOne(doc, "//a"), FindOne(doc, "//b"), FindOne(doc, "//c"), FindOne(doc, "//d")
+	x, y := FindOne(doc, "//@x"), FindOne(doc, "//@y")
+	other := &Node{Type: ElementNode, Data: "other"}
+	nodes := sortDocumentOrder([]*Node{d, y, other, c, x, b, a})
+	testDeepEqual(t, nodes, []*Node{a, x, b, y, c, d, other})
+}
+
+func BenchmarkSortDocumentOrder(b *testing.B) {
+	root := &Node{Type: ElementNode, Data: "root"}
+	var nodes []*Node
+	for i := 0; i < 20000; i++ {
+		n := &Node{Type: ElementNode, Data: "item"}
+		AddChild(root, n)
+		nodes = append(nodes, n)
+	}
+	reversed := make([]*Node, len(nodes))
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		for j, n := range nodes {
+			reversed[len(nodes)-1-j] = n
+		}
+		sortDocumentOrder(reversed)
+	}
+}
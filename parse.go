@@ -2,6 +2,7 @@ package xmlquery
 
 import (
 	"bufio"
+	"context"
 	"fmt"
 	"io"
 	"net/http"
@@ -26,7 +27,12 @@ func LoadURL(url string) (*Node, error) {
 	defer resp.Body.Close()
 	// Make sure the Content-Type has a valid XML MIME type
 	if xmlMIMERegex.MatchString(resp.Header.Get("Content-Type")) {
-		return Parse(resp.Body)
+		doc, err := Parse(resp.Body)
+		if err != nil {
+			return nil, err
+		}
+		doc.SetDocumentURI(resp.Request.URL.String())
+		return doc, nil
 	}
 	return nil, fmt.Errorf("invalid XML document(%s)", resp.Header.Get("Content-Type"))
 }
@@ -40,9 +46,48 @@ func Parse(r io.Reader) (*Node, error) {
 func ParseWithOptions(r io.Reader, options ParserOptions) (*Node, error) {
 	p := createParser(r)
 	options.apply(p)
+	return p.parseAll()
+}
+
+// ParseRecover is like ParseWithOptions, but doesn't give up at the first
+// syntax error. It skips the offending input, such as a stray '<' or an
+// unexpected end tag, and continues parsing; mismatched end tags close the
+// open elements and undefined entities are kept as text, as with a
+// non-strict decoder, unless options.WellFormed is set; each undefined
+// entity is still reported. It returns the best-effort tree and the errors
+// it recovered from, in input order. An error is only returned if the input
+// can't be read at all.
+//
+// If the input ends inside an element, the tree contains everything up to
+// that point and the last diagnostic reports the unexpected end.
+func ParseRecover(r io.Reader, options ParserOptions) (*Node, []*ParseError, error) {
+	p := createParser(r)
+	options.apply(p)
+	p.decoder.Strict = options.WellFormed
+	p.decoder.StrayEndTags = true
+	p.decoder.UndefinedEntity = p.undefinedEntity
+	p.recover = true
+	p.recoveredAt = -1
+	doc, err := p.parseAll()
+	if err != nil {
+		return nil, p.diagnostics, err
+	}
+	return doc, p.diagnostics, nil
+}
+
+// parseDocument parses the rest of the input and returns the document.
+func (p *parser) parseDocument() (*Node, error) {
 	for {
 		_, err := p.parse()
+		if err == io.EOF && p.wellFormed {
+			if err := p.checkEnd(); err != nil {
+				return nil, err
+			}
+		}
 		if err == io.EOF {
+			if d := p.doc.docData(); d.ids == nil {
+				d.ids = make(map[string]*Node)
+			}
 			return p.doc, nil
 		}
 		if err != nil {
@@ -58,11 +103,36 @@ type parser struct {
 	prev                *Node
 	streamElementXPath  *xpath.Expr   // Under streaming mode, this specifies the xpath to the target element node(s).
 	streamElementFilter *xpath.Expr   // If specified, it provides further filtering on the target element.
+	streamElementSelf   bool          // If set, streamElementXPath is evaluated from each new element instead of the document.
 	streamNode          *Node         // Need to remember the last target node So we can clean it up upon next Read() call.
 	streamNodePrev      *Node         // Need to remember target node's prev so upon target node removal, we can restore correct prev.
 	reader              *cachedReader // Need to maintain a reference to the reader, so we can determine whether a node contains CDATA.
 	once                sync.Once
 	space2prefix        map[string]*xmlnsPrefix
+	idAttrs             map[string]map[string]bool // DTD-declared ID attributes, keyed by element name.
+	arena               *nodeArena                 // If set, nodes and attributes are allocated from it.
+	lazy                *lazySource                // If set, element subtrees at lazy.depth are deferred.
+	source              []byte                     // If set, the whole input; text is referenced instead of copied.
+	skipWhitespace      bool                       // If set, whitespace-only text is dropped, see ParserOptions.SkipWhitespaceText.
+	recover             bool                       // If set, parsing continues after syntax errors, see ParseRecover.
+	diagnostics         []*ParseError              // The syntax errors recovered from.
+	recoveredAt         int64                      // The token start of the last syntax error recovered from, or -1.
+	tokenLine           int                        // The line of the start of the token being read, see parseError.
+	tokenColumn         int                        // The column of the start of the token being read.
+	tokenOffset         int64                      // The offset of the start of the token being read.
+	duplicateAttrs      DuplicateAttrPolicy        // See ParserOptions.DuplicateAttrs.
+	wellFormed          bool                       // If set, see ParserOptions.WellFormed, the following are tracked.
+	rootSeen            bool                       // A root element has been read.
+	doctypeSeen         bool                       // A DOCTYPE has been read.
+	dtdEntities         bool                       // See ParserOptions.DTDEntities.
+	entityResolver      EntityResolver             // See ParserOptions.EntityResolver.
+	ownEntities         bool                       // decoder.Entity has been copied, see declareEntities.
+	normalization       NormalizationForm          // See ParserOptions.Normalization.
+	limits              Limits                     // See ParserOptions.Limits.
+	elements            int                        // The number of elements read, counted against limits.
+	attrBytes           int64                      // The size of the attributes read, counted against limits.
+	ctx                 context.Context            // If set, parsing stops when it is done, see StreamParser.ReadNext.
+	nodes               int                        // The number of nodes created, see Hooks.
 }
 
 type xmlnsPrefix struct {
@@ -71,16 +141,23 @@ type xmlnsPrefix struct {
 }
 
 func createParser(r io.Reader) *parser {
-	reader := newCachedReader(bufio.NewReader(r))
+	bom, br := decodeBOM(bufio.NewReader(r))
+	reader := newCachedReader(br)
 	p := &parser{
 		decoder: xml.NewDecoder(reader),
 		doc:     &Node{Type: DocumentNode},
 		level:   0,
 		reader:  reader,
+		idAttrs: make(map[string]map[string]bool),
 	}
 	if p.decoder.CharsetReader == nil {
 		p.decoder.CharsetReader = charset.NewReaderLabel
 	}
+	if bom != "" {
+		p.doc.docData().bom = bom
+		// UTF-16 input has been converted already.
+		p.decoder.IgnoreDeclaredEncoding = bom != "UTF-8"
+	}
 	p.prev = p.doc
 	return p
 }
@@ -92,27 +169,49 @@ func (p *parser) parse() (*Node, error) {
 
 	var streamElementNodeCounter int
 	for {
+		if p.ctx != nil {
+			select {
+			case <-p.ctx.Done():
+				return nil, p.ctx.Err()
+			default:
+			}
+		}
+		start := p.decoder.InputOffset()
+		p.tokenLine, p.tokenColumn = p.decoder.InputPos()
+		p.tokenOffset = start
 		p.reader.StartCaching()
 		tok, err := p.decoder.Token()
 		p.reader.StopCaching()
 		if err != nil {
-			return nil, err
+			if p.recover {
+				if err = p.recoverFrom(err); err == nil {
+					continue
+				}
+				return nil, err
+			}
+			return nil, p.parseError(err)
 		}
+		end := p.decoder.InputOffset()
 
+		if p.wellFormed {
+			if err := p.checkProlog(tok, start); err != nil {
+				return nil, err
+			}
+		}
 		switch tok := tok.(type) {
 		case xml.StartElement:
 			if p.level == 0 {
 				// mising XML declaration
-				attributes := make([]Attr, 1)
+				attributes := p.allocAttrs(1)
 				attributes[0].Name = xml.Name{Local: "version"}
 				attributes[0].Value = "1.0"
-				node := &Node{
-					Type:  DeclarationNode,
-					Data:  "xml",
-					Attr:  attributes,
-					level: 1,
-				}
+				node := p.allocNode(DeclarationNode, "xml", 1)
+				node.Attr = attributes
 				AddChild(p.prev, node)
+				if p.reader.recording {
+					// Not part of the input, so it has no source to write.
+					node.raw = newRawNode(node, "")
+				}
 				p.level = 1
 				p.prev = node
 			}
@@ -129,32 +228,45 @@ func (p *parser) parse() (*Node, error) {
 				}
 			}
 
-			if space := tok.Name.Space; space != "" {
+			if p.wellFormed {
+				if err := p.checkStartElement(&tok); err != nil {
+					return nil, err
+				}
+			} else if space := tok.Name.Space; space != "" {
 				if _, found := p.space2prefix[space]; !found && p.decoder.Strict {
-					return nil, fmt.Errorf("xmlquery: invalid XML document, namespace %s is missing", space)
+					return nil, p.parseError(fmt.Errorf("invalid XML document, namespace %s is missing", space))
 				}
 			}
 
-			attributes := make([]Attr, len(tok.Attr))
+			if p.duplicateAttrs != DuplicateAttrKeepAll {
+				if tok.Attr, err = p.removeDuplicateAttrs(tok.Attr); err != nil {
+					return nil, err
+				}
+			}
+
+			if err := p.checkLimits(tok.Attr); err != nil {
+				return nil, err
+			}
+
+			attributes := p.allocAttrs(len(tok.Attr))
+			normalized := false // an attribute value changed, so the source doesn't apply
 			for i, att := range tok.Attr {
 				name := att.Name
 				if prefix, ok := p.space2prefix[name.Space]; ok {
 					name.Space = prefix.name
 				}
+				value := p.normalization.normalize(att.Value)
+				normalized = normalized || value != att.Value
 				attributes[i] = Attr{
 					Name:         name,
-					Value:        att.Value,
+					Value:        value,
 					NamespaceURI: att.Name.Space,
 				}
 			}
 
-			node := &Node{
-				Type:         ElementNode,
-				Data:         tok.Name.Local,
-				NamespaceURI: tok.Name.Space,
-				Attr:         attributes,
-				level:        p.level,
-			}
+			node := p.allocNode(ElementNode, tok.Name.Local, p.level)
+			node.NamespaceURI = tok.Name.Space
+			node.Attr = attributes
 
 			if p.level == p.prev.level {
 				AddSibling(p.prev, node)
@@ -175,13 +287,30 @@ func (p *parser) parse() (*Node, error) {
 					}
 				}
 			}
+			// The ID table is only maintained for full documents; in streaming
+			// mode the target nodes are discarded between Read() calls.
+			if p.streamElementXPath == nil {
+				if id, ok := elementID(node, p.idAttrs); ok {
+					d := p.doc.docData()
+					if d.ids == nil {
+						d.ids = make(map[string]*Node)
+					}
+					if _, dup := d.ids[id]; !dup {
+						d.ids[id] = node
+					}
+				}
+			}
 			// If we're in the streaming mode, we need to remember the node if it is the target node
 			// so that when we finish processing the node's EndElement, we know how/what to return to
 			// caller. Also we need to remove the target node from the tree upon next Read() call so
 			// memory doesn't grow unbounded.
 			if p.streamElementXPath != nil {
 				if p.streamNode == nil {
-					if QuerySelector(p.doc, p.streamElementXPath) != nil {
+					top := p.doc
+					if p.streamElementSelf {
+						top = node
+					}
+					if QuerySelector(top, p.streamElementXPath) != nil {
 						p.streamNode = node
 						p.streamNodePrev = p.prev
 						streamElementNodeCounter = 1
@@ -190,10 +319,28 @@ func (p *parser) parse() (*Node, error) {
 					streamElementNodeCounter++
 				}
 			}
+			if p.reader.recording && !normalized {
+				p.recordRaw(node, start, end)
+			}
 			p.prev = node
+			if p.lazy != nil && node.level == p.lazy.depth {
+				// Skip the content for now, it is parsed on first access.
+				if err := p.decoder.Skip(); err != nil {
+					// The error is in the skipped content, not at the
+					// start element.
+					p.tokenLine, p.tokenColumn = p.decoder.InputPos()
+					p.tokenOffset = p.decoder.InputOffset()
+					return nil, p.parseError(err)
+				}
+				node.lazy = &lazyNode{src: p.lazy, start: start, end: p.decoder.InputOffset()}
+				break
+			}
 			p.level++
 		case xml.EndElement:
 			p.level--
+			if p.reader.recording {
+				p.recordRawEnd(start, end)
+			}
 			// If we're in streaming mode, and we already have a potential streaming
 			// target node identified (p.streamNode != nil) then we need to check if
 			// this is the real one we want to return to caller.
@@ -228,14 +375,26 @@ func (p *parser) parse() (*Node, error) {
 				}
 			}
 		case xml.CharData:
-			// First, normalize the cache...
-			cached := strings.ToUpper(string(p.reader.Cache()))
+			if start == 0 && p.doc.BOM() == "UTF-8" {
+				if tok = tok[len(utf8BOM):]; len(tok) == 0 {
+					break
+				}
+				start += int64(len(utf8BOM))
+			}
+			if p.skipWhitespace && isWhitespace(tok) && !p.preserveSpace() {
+				break
+			}
 			nodeType := TextNode
-			if strings.HasPrefix(cached, "<![CDATA[") || strings.HasPrefix(cached, "![CDATA[") {
+			if p.decoder.CDATA() {
 				nodeType = CharDataNode
 			}
 
-			node := &Node{Type: nodeType, Data: string(tok), level: p.level}
+			data := p.sourceString(tok, start)
+			text := p.normalization.normalize(data)
+			node := p.allocNode(nodeType, text, p.level)
+			if p.reader.recording && text == data {
+				p.recordRaw(node, start, end)
+			}
 			if p.level == p.prev.level {
 				AddSibling(p.prev, node)
 			} else if p.level > p.prev.level {
@@ -247,7 +406,10 @@ func (p *parser) parse() (*Node, error) {
 				AddSibling(p.prev.Parent, node)
 			}
 		case xml.Comment:
-			node := &Node{Type: CommentNode, Data: string(tok), level: p.level}
+			node := p.allocNode(CommentNode, p.sourceString(tok, start), p.level)
+			if p.reader.recording {
+				p.recordRaw(node, start, end)
+			}
 			if p.level == p.prev.level {
 				AddSibling(p.prev, node)
 			} else if p.level > p.prev.level {
@@ -259,15 +421,20 @@ func (p *parser) parse() (*Node, error) {
 				AddSibling(p.prev.Parent, node)
 			}
 		case xml.ProcInst: // Processing Instruction
-			if p.prev.Type != DeclarationNode {
+			if p.level == 0 {
 				p.level++
 			}
-			node := &Node{Type: DeclarationNode, Data: tok.Target, level: p.level}
-			pairs := strings.Split(string(tok.Inst), " ")
-			for _, pair := range pairs {
-				pair = strings.TrimSpace(pair)
-				if i := strings.Index(pair, "="); i > 0 {
-					AddAttr(node, pair[:i], strings.Trim(pair[i+1:], `"'`))
+			node := p.allocNode(DeclarationNode, tok.Target, p.level)
+			if strings.TrimSpace(string(tok.Inst)) != "" {
+				node.SetProcInstData(string(tok.Inst))
+			}
+			if p.reader.recording {
+				if enc := node.SelectAttr("encoding"); tok.Target == "xml" && enc != "" && !strings.EqualFold(enc, "utf-8") {
+					// The rest of the input is converted to UTF-8, so
+					// offsets no longer refer to the input.
+					p.stopRecording()
+				} else {
+					p.recordRaw(node, start, end)
 				}
 			}
 			if p.level == p.prev.level {
@@ -282,7 +449,16 @@ func (p *parser) parse() (*Node, error) {
 			}
 			p.prev = node
 		case xml.Directive:
-			node := &Node{Type: NotationNode, Data: string(tok), level: p.level}
+			node := p.allocNode(NotationNode, string(tok), p.level)
+			if p.reader.recording {
+				p.recordRaw(node, start, end)
+			}
+			parseDTDIDAttrs(node.Data, p.idAttrs)
+			if p.dtdEntities {
+				if err := p.declareEntities(node.Data); err != nil {
+					return nil, p.parseError(err)
+				}
+			}
 			if p.level == p.prev.level {
 				AddSibling(p.prev, node)
 			} else if p.level > p.prev.level {
@@ -297,10 +473,42 @@ func (p *parser) parse() (*Node, error) {
 	}
 }
 
+// isWhitespace reports whether b consists of XML whitespace only.
+func isWhitespace(b []byte) bool {
+	for _, c := range b {
+		if c != ' ' && c != '\t' && c != '\r' && c != '\n' {
+			return false
+		}
+	}
+	return true
+}
+
+// preserveSpace reports whether xml:space="preserve" applies at the current
+// position.
+func (p *parser) preserveSpace() bool {
+	n := p.prev
+	for n != nil && n.level >= p.level {
+		n = n.Parent
+	}
+	for ; n != nil; n = n.Parent {
+		if n.Type != ElementNode {
+			continue
+		}
+		switch n.SelectAttr("xml:space") {
+		case "preserve":
+			return true
+		case "default":
+			return false
+		}
+	}
+	return false
+}
+
 // StreamParser enables loading and parsing an XML document in a streaming
 // fashion.
 type StreamParser struct {
-	p *parser
+	p         *parser
+	validator Validator // See SetValidator.
 }
 
 // CreateStreamParser creates a StreamParser. Argument streamElementXPath is
@@ -367,19 +575,32 @@ func CreateStreamParserWithOptions(
 	streamElementXPath string,
 	streamElementFilter ...string,
 ) (*StreamParser, error) {
-	elemXPath, err := getQuery(streamElementXPath)
+	// The parser isn't created yet, so the names are lowered as getQueryFor
+	// would for the document.
+	compile := getQuery
+	if options.CaseInsensitiveNames {
+		compile = func(expr string) (*xpath.Expr, error) {
+			return getQuery(lowerQueryNames(expr))
+		}
+	}
+	elemXPath, err := compile(streamElementXPath)
 	if err != nil {
 		return nil, fmt.Errorf("invalid streamElementXPath '%s', err: %s", streamElementXPath, err.Error())
 	}
 	elemFilter := (*xpath.Expr)(nil)
 	if len(streamElementFilter) > 0 {
-		elemFilter, err = getQuery(streamElementFilter[0])
+		elemFilter, err = compile(streamElementFilter[0])
 		if err != nil {
 			return nil, fmt.Errorf("invalid streamElementFilter '%s', err: %s", streamElementFilter[0], err.Error())
 		}
 	}
 	parser := createParser(r)
 	options.apply(parser)
+	// Streamed nodes are discarded one by one, which an arena can't do.
+	if parser.arena != nil {
+		parser.arena = nil
+		parser.doc.doc.arena = nil
+	}
 	sp := &StreamParser{
 		p: parser,
 	}
@@ -393,23 +614,35 @@ func CreateStreamParserWithOptions(
 // reading the rest of the XML document, io.EOF will be returned. At any time,
 // any XML parsing error encountered will be returned, and the stream parsing
 // stopped. Calling Read() after an error is returned (including io.EOF) results
-// undefined behavior. Also note, due to the streaming nature, calling Read()
+// undefined behavior, except after a *RecordError. Also note, due to the streaming nature, calling Read()
 // will automatically remove any previous target node(s) from the document tree.
 func (sp *StreamParser) Read() (*Node, error) {
 	// Because this is a streaming read, we need to release/remove last
 	// target node from the node tree to free up memory.
-	if sp.p.streamNode != nil {
-		// We need to remove all siblings before the current stream node,
-		// because the document may contain unwanted nodes between the target
-		// ones (for example new line text node), which would otherwise
-		// accumulate as first childs, and slow down the stream over time
-		for sp.p.streamNode.PrevSibling != nil {
-			RemoveFromTree(sp.p.streamNode.PrevSibling)
+	sp.removeRecord()
+	n, err := sp.p.parse()
+	if err == nil && sp.validator != nil {
+		if verr := sp.validator.Validate(n); verr != nil {
+			return n, &RecordError{Node: n, Err: verr}
 		}
-		sp.p.prev = sp.p.streamNode.Parent
-		RemoveFromTree(sp.p.streamNode)
-		sp.p.streamNode = nil
-		sp.p.streamNodePrev = nil
 	}
-	return sp.p.parse()
+	return n, err
+}
+
+// removeRecord removes the last target node, if any, from the node tree.
+func (sp *StreamParser) removeRecord() {
+	if sp.p.streamNode == nil {
+		return
+	}
+	// We need to remove all siblings before the current stream node,
+	// because the document may contain unwanted nodes between the target
+	// ones (for example new line text node), which would otherwise
+	// accumulate as first childs, and slow down the stream over time
+	for sp.p.streamNode.PrevSibling != nil {
+		RemoveFromTree(sp.p.streamNode.PrevSibling)
+	}
+	sp.p.prev = sp.p.streamNode.Parent
+	RemoveFromTree(sp.p.streamNode)
+	sp.p.streamNode = nil
+	sp.p.streamNodePrev = nil
 }
@@ -44,10 +44,11 @@ func TestLoadURLSuccess(t *testing.T) {
 			w.Write([]byte(s))
 		}))
 		defer server.Close()
-		_, err := LoadURL(server.URL)
+		doc, err := LoadURL(server.URL)
 		if err != nil {
 			t.Fatal(err)
 		}
+		testValue(t, doc.DocumentURI(), server.URL)
 	}
 }
 
@@ -570,6 +571,24 @@ func TestCDATA(t *testing.T) {
 	testOutputXML(t, "first call result", `<CCC><![CDATA[c1]]></CCC>`, n)
 }
 
+func TestCDATANodeType(t *testing.T) {
+	for _, s := range []string{
+		`<a>x<![CDATA[<b>]]>y</a>`,
+		`<?xml version="1.0" encoding="ISO-8859-1"?><a>x<![CDATA[<b>]]>y</a>`,
+	} {
+		doc, err := Parse(strings.NewReader(s))
+		if err != nil {
+			t.Fatal(err)
+		}
+		var types []NodeType
+		for _, n := range Find(doc, "//a/text()") {
+			types = append(types, n.Type)
+		}
+		testDeepEqual(t, types, []NodeType{TextNode, CharDataNode, TextNode})
+		testValue(t, FindOne(doc, "//a").OutputXML(false), `x<![CDATA[<b>]]>y`)
+	}
+}
+
 func TestXMLPreservation(t *testing.T) {
 	s := `
 	<?xml version="1.0" encoding="UTF-8"?>
@@ -604,7 +623,7 @@ func TestStreamParser_DefaultNamespace(t *testing.T) {
 		t.Fatal(err.Error())
 	}
 
-	var x = `<Object id="ObjectA">ObjectA</Object>`
+	var x = `<Object id="ObjectA" xmlns="http://example.com/schema/2007/someschema">ObjectA</Object>`
 	testOutputXML(t, "first call result", x, n)
 
 	n, err = sp.Read()
@@ -612,7 +631,7 @@ func TestStreamParser_DefaultNamespace(t *testing.T) {
 		t.Fatal(err.Error())
 	}
 
-	x = `<Object id="ObjectB">ObjectB</Object>`
+	x = `<Object id="ObjectB" xmlns="http://example.com/schema/2007/someschema">ObjectB</Object>`
 	testOutputXML(t, "second call result", x, n)
 
 	n, err = sp.Read()
@@ -620,7 +639,7 @@ func TestStreamParser_DefaultNamespace(t *testing.T) {
 		t.Fatal(err.Error())
 	}
 
-	x = `<Object id="ObjectC">ObjectD</Object>`
+	x = `<Object id="ObjectC" xmlns="http://example.com/schema/2007/someschema">ObjectD</Object>`
 	testOutputXML(t, "third call result", x, n)
 }
 
@@ -670,3 +689,26 @@ func TestParse1(t *testing.T) {
 	div := root.SelectElement(`div`)
 	fmt.Println(div)
 }
+
+func TestSkipWhitespaceText(t *testing.T) {
+	s := `<root>
+	<a> x </a>
+	<pre xml:space="preserve">
+		<b>  </b>
+		<c xml:space="default">  </c>
+	</pre>
+</root>`
+	doc, err := ParseWithOptions(strings.NewReader(s), ParserOptions{SkipWhitespaceText: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	root := doc.SelectElement("root")
+	testValue(t, root.FirstChild.Data, "a")
+	testValue(t, root.FirstChild.NextSibling.Data, "pre")
+	testValue(t, root.FirstChild.FirstChild.Data, " x ")
+	pre := root.SelectElement("pre")
+	testValue(t, pre.FirstChild.Type, TextNode)
+	testValue(t, pre.SelectElement("b").FirstChild.Data, "  ")
+	testTrue(t, pre.SelectElement("c").FirstChild == nil)
+	testValue(t, pre.LastChild.Data, "\n\t")
+}
@@ -0,0 +1,182 @@
+package xmlquery
+
+import (
+	"strconv"
+	"strings"
+)
+
+// Path returns an absolute XPath expression that selects exactly n, such as
+// /AAA/CCC[2]/DDD[1]. Every step has a position predicate, so the path
+// stays unambiguous when siblings with the same name are present. Text and
+// CDATA nodes are addressed with text(), comments with comment(), and
+// attribute nodes, as returned by queries like //@id, with @name.
+//
+// For a node that isn't attached to a document, the path starts at the
+// root of its tree. Queries skip whitespace-only text nodes that follow a
+// sibling, so the path of such a node selects nothing.
+func (n *Node) Path() string {
+	if n.Type == DocumentNode {
+		return "/"
+	}
+	var steps []string
+	for node := n; node != nil && node.Type != DocumentNode; node = node.Parent {
+		steps = append(steps, pathStep(node))
+	}
+	var b strings.Builder
+	for i := len(steps) - 1; i >= 0; i-- {
+		b.WriteByte('/')
+		b.WriteString(steps[i])
+	}
+	return b.String()
+}
+
+// pathStep returns the location step that selects n from its parent.
+func pathStep(n *Node) string {
+	if n.Type == AttributeNode {
+		return "@" + n.Data
+	}
+	test := nodeTest(n)
+	pos := 1
+	for s := n.PrevSibling; s != nil; s = s.PrevSibling {
+		if s.PrevSibling != nil && s.Type == TextNode && strings.TrimSpace(s.Data) == "" {
+			continue // skipped by NodeNavigator.MoveToNext
+		}
+		if nodeTest(s) == test && (test != "*" || sameName(s, n)) {
+			pos++
+		}
+	}
+	if test == "*" {
+		test = qualifiedName(n)
+	}
+	return test + "[" + strconv.Itoa(pos) + "]"
+}
+
+// nodeTest returns the kind of node test matching n; "*" stands for an
+// element name test.
+func nodeTest(n *Node) string {
+	switch n.Type {
+	case ElementNode:
+		return "*"
+	case TextNode, CharDataNode, NotationNode:
+		return "text()"
+	case CommentNode:
+		return "comment()"
+	}
+	return "node()"
+}
+
+func sameName(a, b *Node) bool {
+	return a.Data == b.Data && a.Prefix == b.Prefix
+}
+
+// qualifiedName returns the name of element n as written, prefix:local.
+func qualifiedName(n *Node) string {
+	if n.Prefix == "" {
+		return n.Data
+	}
+	return n.Prefix + ":" + n.Data
+}
+
+// defaultKeyAttrs are the attributes Selector uses to identify elements.
+var defaultKeyAttrs = []string{"id", "xml:id", "key", "name"}
+
+// Selector returns a short XPath expression that selects exactly n, for
+// storing references to nodes that stay valid when the document changes
+// slightly. It anchors the expression at the nearest element (n itself or
+// an ancestor) that has a key attribute with a value unique in the
+// document, as in //CCC[@id='3']/DDD, and only adds positions where names
+// are ambiguous.
+//
+// The key attributes are id, xml:id, key and name, or keyAttrs if given, in
+// order of preference. If no shorter expression is found, Selector returns
+// the same as Path.
+func (n *Node) Selector(keyAttrs ...string) string {
+	if len(keyAttrs) == 0 {
+		keyAttrs = defaultKeyAttrs
+	}
+	if n.Type == DocumentNode {
+		return "/"
+	}
+	top := n
+	for top.Parent != nil {
+		top = top.Parent
+	}
+
+	var steps []string
+	node := n
+	if n.Type != ElementNode {
+		steps = append(steps, pathStep(n))
+		node = n.Parent
+	}
+	anchor := "/"
+	for ; node != nil && node.Type == ElementNode; node = node.Parent {
+		if key := keySelector(top, node, keyAttrs); key != "" {
+			anchor = key
+			break
+		}
+		steps = append(steps, shortStep(node))
+	}
+	var b strings.Builder
+	b.WriteString(anchor)
+	for i := len(steps) - 1; i >= 0; i-- {
+		if b.Len() > 1 || anchor != "/" {
+			b.WriteByte('/')
+		}
+		b.WriteString(steps[i])
+	}
+	if expr := b.String(); selectsOnly(top, expr, n) {
+		return expr
+	}
+	return n.Path()
+}
+
+// selectsOnly reports whether expr selects n and nothing else. Attribute
+// nodes are created anew by every query, so they are compared by owner
+// element and name.
+func selectsOnly(top *Node, expr string, n *Node) bool {
+	nodes := Find(top, expr)
+	if len(nodes) != 1 {
+		return false
+	}
+	if m := nodes[0]; n.Type == AttributeNode {
+		return m.Type == AttributeNode && m.Parent == n.Parent && m.Data == n.Data
+	}
+	return nodes[0] == n
+}
+
+// keySelector returns an expression like //item[@id='3'] selecting only n
+// in the tree of top, or "" if none of the key attributes of n is unique.
+func keySelector(top, n *Node, keyAttrs []string) string {
+	for _, name := range keyAttrs {
+		attr := n.SelectAttrNode(name)
+		if attr == nil || attr.Value == "" {
+			continue
+		}
+		quote := "'"
+		if strings.Contains(attr.Value, "'") {
+			if strings.Contains(attr.Value, `"`) {
+				continue
+			}
+			quote = `"`
+		}
+		expr := "//" + qualifiedName(n) + "[@" + name + "=" + quote + attr.Value + quote + "]"
+		if selectsOnly(top, expr, n) {
+			return expr
+		}
+	}
+	return ""
+}
+
+// shortStep is like pathStep for element n, but omits the position if n is
+// the only child element with its name.
+func shortStep(n *Node) string {
+	if n.Parent == nil {
+		return qualifiedName(n)
+	}
+	for s := n.Parent.FirstChild; s != nil; s = s.NextSibling {
+		if s != n && s.Type == ElementNode && sameName(s, n) {
+			return pathStep(n)
+		}
+	}
+	return qualifiedName(n)
+}
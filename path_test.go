@@ -0,0 +1,67 @@
+package xmlquery
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestPath(t *testing.T) {
+	s := `<AAA xmlns:x="urn:x">
+		<BBB/>
+		<CCC><DDD id="1"/></CCC>
+		<CCC>text<DDD/><!--c--><DDD/><x:EEE/>more<![CDATA[data]]></CCC>
+	</AAA>`
+	doc, err := Parse(strings.NewReader(s))
+	if err != nil {
+		t.Fatal(err)
+	}
+	testValue(t, doc.Path(), "/")
+	ddd := Find(doc, "//DDD")
+	testValue(t, ddd[0].Path(), "/AAA[1]/CCC[1]/DDD[1]")
+	testValue(t, ddd[2].Path(), "/AAA[1]/CCC[2]/DDD[2]")
+	testValue(t, FindOne(doc, "//comment()").Path(), "/AAA[1]/CCC[2]/comment()[1]")
+	testValue(t, FindOne(doc, "//@id").Path(), "/AAA[1]/CCC[1]/DDD[1]/@id")
+
+	var walk func(*Node)
+	walk = func(n *Node) {
+		hidden := n.PrevSibling != nil && n.Type == TextNode && strings.TrimSpace(n.Data) == ""
+		if n.Type != DeclarationNode && n.Type != DocumentNode && !hidden {
+			if got := FindOne(doc, n.Path()); got != n {
+				t.Errorf("%s selects %v, want %v", n.Path(), got, n)
+			}
+		}
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			walk(c)
+		}
+	}
+	walk(doc)
+	testTrue(t, FindOne(doc, FindOne(doc, "//@id").Path()).InnerText() == "1")
+
+	detached := &Node{Type: ElementNode, Data: "a"}
+	AddChild(detached, &Node{Type: ElementNode, Data: "b"})
+	testValue(t, detached.FirstChild.Path(), "/a[1]/b[1]")
+}
+
+func TestSelector(t *testing.T) {
+	s := `<AAA>
+		<CCC id="1"><DDD/></CCC>
+		<CCC id="3"><DDD/><EEE/><EEE name="it's"/></CCC>
+		<CCC><DDD/></CCC>
+		<FFF id="3"/>
+	</AAA>`
+	doc, err := Parse(strings.NewReader(s))
+	if err != nil {
+		t.Fatal(err)
+	}
+	ddd := Find(doc, "//DDD")
+	testValue(t, ddd[0].Selector(), "//CCC[@id='1']/DDD")
+	testValue(t, ddd[1].Selector(), "//CCC[@id='3']/DDD")
+	testValue(t, ddd[2].Selector(), "/AAA/CCC[3]/DDD")
+	testValue(t, Find(doc, "//EEE")[0].Selector(), "//CCC[@id='3']/EEE[1]")
+	testValue(t, Find(doc, "//EEE")[1].Selector(), `//EEE[@name="it's"]`)
+	testValue(t, FindOne(doc, "//FFF").Selector(), "//FFF[@id='3']")
+	testValue(t, FindOne(doc, "//CCC[@id='1']/@id").Selector(), "//CCC[@id='1']/@id")
+	testValue(t, FindOne(doc, "//AAA").Selector(), "/AAA")
+	testValue(t, ddd[2].Selector("missing"), "/AAA/CCC[3]/DDD")
+	testValue(t, doc.Selector(), "/")
+}
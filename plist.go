@@ -0,0 +1,234 @@
+package xmlquery
+
+import (
+	"encoding/base64"
+	"fmt"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// plistDoctype is the document type declaration of XML property lists.
+const plistDoctype = `DOCTYPE plist PUBLIC "-//Apple//DTD PLIST 1.0//EN" "http://www.apple.com/DTDs/PropertyList-1.0.dtd"`
+
+// plistTimeLayout is the format of <date> values.
+const plistTimeLayout = "2006-01-02T15:04:05Z"
+
+// DecodePlist converts an XML property list into Go values. n may be the
+// document, its <plist> element, or any value element inside it. Values are
+// mapped as follows:
+//
+//	<dict>          map[string]interface{}
+//	<array>         []interface{}
+//	<string>        string
+//	<integer>       int64 (uint64 if too large for int64)
+//	<real>          float64
+//	<true/>         true
+//	<false/>        false
+//	<date>          time.Time
+//	<data>          []byte
+func DecodePlist(n *Node) (interface{}, error) {
+	if n.Type == DocumentNode {
+		if n = firstChildElement(n); n == nil {
+			return nil, fmt.Errorf("xmlquery: empty property list")
+		}
+	}
+	if n.Type == ElementNode && n.Data == "plist" {
+		v := firstChildElement(n)
+		if v == nil {
+			return nil, nil
+		}
+		n = v
+	}
+	return decodePlistValue(n)
+}
+
+func decodePlistValue(n *Node) (interface{}, error) {
+	text := func() string { return strings.TrimSpace(n.InnerText()) }
+	switch n.Data {
+	case "dict":
+		m := make(map[string]interface{})
+		var key *Node
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			if c.Type != ElementNode {
+				continue
+			}
+			if key == nil {
+				if c.Data != "key" {
+					return nil, fmt.Errorf("xmlquery: expected <key> in <dict>, found <%s>", c.Data)
+				}
+				key = c
+				continue
+			}
+			v, err := decodePlistValue(c)
+			if err != nil {
+				return nil, err
+			}
+			m[key.InnerText()] = v
+			key = nil
+		}
+		if key != nil {
+			return nil, fmt.Errorf("xmlquery: missing value for key %q in <dict>", key.InnerText())
+		}
+		return m, nil
+	case "array":
+		a := make([]interface{}, 0)
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			if c.Type != ElementNode {
+				continue
+			}
+			v, err := decodePlistValue(c)
+			if err != nil {
+				return nil, err
+			}
+			a = append(a, v)
+		}
+		return a, nil
+	case "string":
+		return n.InnerText(), nil
+	case "integer":
+		s := text()
+		neg := strings.HasPrefix(s, "-")
+		digits, base := strings.TrimLeft(s, "+-"), 10
+		if strings.HasPrefix(digits, "0x") || strings.HasPrefix(digits, "0X") {
+			digits, base = digits[2:], 16
+		}
+		if neg {
+			digits = "-" + digits
+		}
+		if i, err := strconv.ParseInt(digits, base, 64); err == nil {
+			return i, nil
+		}
+		if u, err := strconv.ParseUint(digits, base, 64); err == nil {
+			return u, nil
+		}
+		return nil, fmt.Errorf("xmlquery: invalid plist integer %q", s)
+	case "real":
+		f, err := strconv.ParseFloat(text(), 64)
+		if err != nil {
+			return nil, fmt.Errorf("xmlquery: invalid plist real %q", text())
+		}
+		return f, nil
+	case "true":
+		return true, nil
+	case "false":
+		return false, nil
+	case "date":
+		t, err := time.Parse(plistTimeLayout, text())
+		if err != nil {
+			return nil, fmt.Errorf("xmlquery: invalid plist date %q", text())
+		}
+		return t, nil
+	case "data":
+		s := strings.Map(func(r rune) rune {
+			if r == ' ' || r == '\t' || r == '\r' || r == '\n' {
+				return -1
+			}
+			return r
+		}, n.InnerText())
+		b, err := base64.StdEncoding.DecodeString(s)
+		if err != nil {
+			return nil, fmt.Errorf("xmlquery: invalid plist data: %s", err.Error())
+		}
+		return b, nil
+	}
+	return nil, fmt.Errorf("xmlquery: unknown plist element <%s>", n.Data)
+}
+
+// EncodePlist converts v into an XML property list document, the reverse of
+// DecodePlist. Besides the types produced by DecodePlist, v may contain any
+// integer, unsigned integer or float type, maps with string keys and slices
+// or arrays of supported values. Dictionary keys are written in sorted
+// order. Write the result with WithEmptyTagSupport to get <true/> rather than
+// <true></true>.
+func EncodePlist(v interface{}) (*Node, error) {
+	doc := &Node{Type: DocumentNode}
+	decl := &Node{Type: DeclarationNode, Data: "xml"}
+	decl.SetAttr("version", "1.0")
+	decl.SetAttr("encoding", "UTF-8")
+	AddChild(doc, decl)
+	AddChild(doc, &Node{Type: NotationNode, Data: plistDoctype})
+	plist := &Node{Type: ElementNode, Data: "plist"}
+	plist.SetAttr("version", "1.0")
+	AddChild(doc, plist)
+	value, err := encodePlistValue(reflect.ValueOf(v))
+	if err != nil {
+		return nil, err
+	}
+	AddChild(plist, value)
+	return doc, nil
+}
+
+func encodePlistValue(v reflect.Value) (*Node, error) {
+	elem := func(name, text string) *Node {
+		n := &Node{Type: ElementNode, Data: name}
+		if text != "" {
+			AddChild(n, &Node{Type: TextNode, Data: text})
+		}
+		return n
+	}
+	for v.Kind() == reflect.Interface || v.Kind() == reflect.Ptr {
+		if v.IsNil() {
+			return nil, fmt.Errorf("xmlquery: cannot encode nil as plist value")
+		}
+		v = v.Elem()
+	}
+	if !v.IsValid() {
+		return nil, fmt.Errorf("xmlquery: cannot encode nil as plist value")
+	}
+	if t, ok := v.Interface().(time.Time); ok {
+		return elem("date", t.UTC().Format(plistTimeLayout)), nil
+	}
+	switch v.Kind() {
+	case reflect.String:
+		return elem("string", v.String()), nil
+	case reflect.Bool:
+		if v.Bool() {
+			return elem("true", ""), nil
+		}
+		return elem("false", ""), nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return elem("integer", strconv.FormatInt(v.Int(), 10)), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return elem("integer", strconv.FormatUint(v.Uint(), 10)), nil
+	case reflect.Float32, reflect.Float64:
+		return elem("real", strconv.FormatFloat(v.Float(), 'g', -1, 64)), nil
+	case reflect.Slice, reflect.Array:
+		if v.Type().Elem().Kind() == reflect.Uint8 {
+			b := make([]byte, v.Len())
+			reflect.Copy(reflect.ValueOf(b), v)
+			return elem("data", base64.StdEncoding.EncodeToString(b)), nil
+		}
+		array := elem("array", "")
+		for i := 0; i < v.Len(); i++ {
+			c, err := encodePlistValue(v.Index(i))
+			if err != nil {
+				return nil, err
+			}
+			AddChild(array, c)
+		}
+		return array, nil
+	case reflect.Map:
+		if v.Type().Key().Kind() != reflect.String {
+			return nil, fmt.Errorf("xmlquery: plist dictionary keys must be strings, not %s", v.Type().Key())
+		}
+		keys := make([]string, 0, v.Len())
+		for _, k := range v.MapKeys() {
+			keys = append(keys, k.String())
+		}
+		sort.Strings(keys)
+		dict := elem("dict", "")
+		for _, k := range keys {
+			c, err := encodePlistValue(v.MapIndex(reflect.ValueOf(k).Convert(v.Type().Key())))
+			if err != nil {
+				return nil, err
+			}
+			AddChild(dict, elem("key", k))
+			AddChild(dict, c)
+		}
+		return dict, nil
+	}
+	return nil, fmt.Errorf("xmlquery: cannot encode %s as plist value", v.Type())
+}
@@ -0,0 +1,107 @@
+package xmlquery
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestDecodePlist(t *testing.T) {
+	s := `<?xml version="1.0" encoding="UTF-8"?>
+<!DOCTYPE plist PUBLIC "-//Apple//DTD PLIST 1.0//EN" "http://www.apple.com/DTDs/PropertyList-1.0.dtd">
+<plist version="1.0">
+<dict>
+	<key>PayloadDisplayName</key>
+	<string>Wi-Fi &amp; VPN</string>
+	<key>PayloadVersion</key>
+	<integer>1</integer>
+	<key>Mask</key>
+	<integer>0xFF</integer>
+	<key>Ratio</key>
+	<real>0.5</real>
+	<key>Enabled</key>
+	<true/>
+	<key>Removable</key>
+	<false/>
+	<key>Expires</key>
+	<date>2024-01-02T03:04:05Z</date>
+	<key>Blob</key>
+	<data>
+	aGVsbG8=
+	</data>
+	<key>Items</key>
+	<array>
+		<string>a</string>
+		<dict/>
+		<array/>
+	</array>
+</dict>
+</plist>`
+	doc, err := Parse(strings.NewReader(s))
+	if err != nil {
+		t.Fatal(err)
+	}
+	v, err := DecodePlist(doc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	m := v.(map[string]interface{})
+	testValue(t, m["PayloadDisplayName"], "Wi-Fi & VPN")
+	testValue(t, m["PayloadVersion"], int64(1))
+	testValue(t, m["Mask"], int64(255))
+	testValue(t, m["Ratio"], 0.5)
+	testValue(t, m["Enabled"], true)
+	testValue(t, m["Removable"], false)
+	testValue(t, m["Expires"], time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC))
+	testDeepEqual(t, m["Blob"], []byte("hello"))
+	testDeepEqual(t, m["Items"], []interface{}{"a", map[string]interface{}{}, []interface{}{}})
+}
+
+func TestEncodePlist(t *testing.T) {
+	v := map[string]interface{}{
+		"b":    true,
+		"a":    []interface{}{"x", int64(2), 1.5},
+		"data": []byte("hi"),
+		"when": time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC),
+	}
+	doc, err := EncodePlist(v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	out := doc.OutputXMLWithOptions(WithEmptyTagSupport())
+	testValue(t, out, `<?xml version="1.0" encoding="UTF-8"?><!`+plistDoctype+`><plist version="1.0"><dict><key>a</key><array><string>x</string><integer>2</integer><real>1.5</real></array><key>b</key><true/><key>data</key><data>aGk=</data><key>when</key><date>2024-01-02T03:04:05Z</date></dict></plist>`)
+
+	parsed, err := Parse(strings.NewReader(out))
+	if err != nil {
+		t.Fatal(err)
+	}
+	back, err := DecodePlist(parsed)
+	if err != nil {
+		t.Fatal(err)
+	}
+	testDeepEqual(t, back, v)
+
+	if _, err := EncodePlist(map[int]string{1: "x"}); err == nil {
+		t.Fatal("expected error for non-string keys")
+	}
+	if _, err := EncodePlist(struct{}{}); err == nil {
+		t.Fatal("expected error for unsupported type")
+	}
+}
+
+func TestDecodePlistInvalid(t *testing.T) {
+	for _, s := range []string{
+		`<plist><dict><string>no key</string></dict></plist>`,
+		`<plist><dict><key>k</key></dict></plist>`,
+		`<plist><integer>x</integer></plist>`,
+		`<plist><unknown></unknown></plist>`,
+	} {
+		doc, err := Parse(strings.NewReader(s))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := DecodePlist(doc); err == nil {
+			t.Fatalf("expected error for %s", s)
+		}
+	}
+}
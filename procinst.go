@@ -0,0 +1,212 @@
+package xmlquery
+
+import (
+	"fmt"
+	"strings"
+)
+
+// NewComment returns a comment node with the given text, ready to be
+// inserted with AddChild or AddSibling. The text must not contain "--".
+//
+//	AddChild(doc, NewComment(" generated at "+time.Now().Format(time.RFC3339)+" "))
+func NewComment(text string) *Node {
+	return &Node{Type: CommentNode, Data: text}
+}
+
+// NewProcInst returns a processing-instruction node such as
+// <?xml-stylesheet href="style.xsl" type="text/xsl"?>, ready to be inserted
+// with AddChild or AddSibling. Like parsed processing instructions, it is a
+// DeclarationNode whose Data is the target; data of the form name="value"
+// is available as attributes of the node.
+//
+//	pi := NewProcInst("xml-stylesheet", `href="style.xsl" type="text/xsl"`)
+func NewProcInst(target, data string) *Node {
+	n := &Node{Type: DeclarationNode, Data: target}
+	n.SetProcInstData(data)
+	return n
+}
+
+// SetProcInstData replaces the data of processing-instruction node n.
+func (n *Node) SetProcInstData(data string) {
+	if attrs, ok := parsePseudoAttrs(data); ok {
+		n.Attr = attrs
+		return
+	}
+	n.Attr = []Attr{{Value: strings.TrimSpace(data)}}
+}
+
+// ProcInstData returns the data of processing-instruction node n, the text
+// between the target and the closing "?>".
+func (n *Node) ProcInstData() string {
+	var b strings.Builder
+	for i, attr := range n.Attr {
+		if i > 0 {
+			b.WriteByte(' ')
+		}
+		if attr.Name.Local == "" {
+			b.WriteString(attr.Value)
+			continue
+		}
+		if attr.Name.Space != "" {
+			b.WriteString(attr.Name.Space)
+			b.WriteByte(':')
+		}
+		b.WriteString(attr.Name.Local)
+		quote := byte('"')
+		if strings.Contains(attr.Value, `"`) && !strings.Contains(attr.Value, `'`) {
+			quote = '\''
+		}
+		b.WriteByte('=')
+		b.WriteByte(quote)
+		b.WriteString(attr.Value)
+		b.WriteByte(quote)
+	}
+	return b.String()
+}
+
+// ParsePseudoAttrs parses processing-instruction data made of pseudo
+// attributes, such as the data of
+// <?xml-stylesheet href="style.xsl" type="text/xsl"?>, into a map from name
+// to value. Values may be quoted with either ' or ", and the last of
+// repeated names wins. It returns an error if the data has any other
+// content.
+func ParsePseudoAttrs(data string) (map[string]string, error) {
+	attrs, ok := parsePseudoAttrs(data)
+	if !ok {
+		return nil, fmt.Errorf("xmlquery: invalid pseudo-attributes %q", data)
+	}
+	m := make(map[string]string, len(attrs))
+	for _, attr := range attrs {
+		name := attr.Name.Local
+		if attr.Name.Space != "" {
+			name = attr.Name.Space + ":" + name
+		}
+		m[name] = attr.Value
+	}
+	return m, nil
+}
+
+// parsePseudoAttrs parses processing-instruction data made of pseudo
+// attributes, as in `href="a.xsl" type='text/xsl'`. It reports false if the
+// data has any other content.
+func parsePseudoAttrs(data string) ([]Attr, bool) {
+	var attrs []Attr
+	s := strings.TrimSpace(data)
+	for s != "" {
+		eq := strings.IndexByte(s, '=')
+		if eq <= 0 {
+			return nil, false
+		}
+		name := strings.TrimSpace(s[:eq])
+		if name == "" || strings.ContainsAny(name, " \t\r\n\"'") {
+			return nil, false
+		}
+		s = strings.TrimLeft(s[eq+1:], " \t\r\n")
+		if s == "" || (s[0] != '"' && s[0] != '\'') {
+			return nil, false
+		}
+		end := strings.IndexByte(s[1:], s[0])
+		if end < 0 {
+			return nil, false
+		}
+		attrs = append(attrs, Attr{Name: newXMLName(name), Value: s[1 : end+1]})
+		s = s[end+2:]
+		if s != "" && !strings.ContainsAny(s[:1], " \t\r\n") {
+			return nil, false
+		}
+		s = strings.TrimLeft(s, " \t\r\n")
+	}
+	return attrs, true
+}
+
+// procInstTest selects the processing instructions among the nodes of an
+// axis: xpath compiles processing-instruction() as an element name test,
+// while the navigator reports processing instructions as root nodes.
+const procInstTest = "node()[not(self::* or self::text() or self::comment()) and name()!='xml' and name()!=''"
+
+// rewriteProcInstTests replaces the processing-instruction() and
+// processing-instruction('target') node tests of expr with an equivalent
+// test the xpath package evaluates correctly. String literals are left
+// untouched.
+func rewriteProcInstTests(expr string) string {
+	const keyword = "processing-instruction"
+	if !strings.Contains(expr, keyword) {
+		return expr
+	}
+	var b strings.Builder
+	for i := 0; i < len(expr); {
+		c := expr[i]
+		if c == '"' || c == '\'' {
+			end := strings.IndexByte(expr[i+1:], c)
+			if end < 0 {
+				b.WriteString(expr[i:])
+				break
+			}
+			b.WriteString(expr[i : i+end+2])
+			i += end + 2
+			continue
+		}
+		if strings.HasPrefix(expr[i:], keyword) && !isXPathNameEnd(expr[:i]) {
+			if n, target, ok := scanProcInstTest(expr[i+len(keyword):]); ok {
+				b.WriteString(procInstTest)
+				if target != "" {
+					b.WriteString(" and name()=")
+					b.WriteString(target)
+				}
+				b.WriteByte(']')
+				i += len(keyword) + n
+				continue
+			}
+		}
+		b.WriteByte(c)
+		i++
+	}
+	return b.String()
+}
+
+// isXPathNameEnd reports whether a keyword following s is only part of a
+// longer name, such as a:processing-instruction, or of a variable reference.
+func isXPathNameEnd(s string) bool {
+	if s == "" {
+		return false
+	}
+	switch c := s[len(s)-1]; {
+	case c == ':':
+		return !strings.HasSuffix(s, "::")
+	case c == '-' || c == '_' || c == '.' || c == '$':
+		return true
+	case c >= '0' && c <= '9', c >= 'a' && c <= 'z', c >= 'A' && c <= 'Z', c >= 0x80:
+		return true
+	}
+	return false
+}
+
+// scanProcInstTest scans the parentheses of a processing-instruction node
+// test at the start of s. It returns the number of bytes scanned and the
+// target literal, including its quotes.
+func scanProcInstTest(s string) (n int, target string, ok bool) {
+	skipSpace := func() {
+		for n < len(s) && strings.IndexByte(" \t\r\n", s[n]) >= 0 {
+			n++
+		}
+	}
+	skipSpace()
+	if n == len(s) || s[n] != '(' {
+		return 0, "", false
+	}
+	n++
+	skipSpace()
+	if n < len(s) && (s[n] == '"' || s[n] == '\'') {
+		end := strings.IndexByte(s[n+1:], s[n])
+		if end < 0 {
+			return 0, "", false
+		}
+		target = s[n : n+end+2]
+		n += end + 2
+		skipSpace()
+	}
+	if n == len(s) || s[n] != ')' {
+		return 0, "", false
+	}
+	return n + 1, target, true
+}
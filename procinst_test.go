@@ -0,0 +1,108 @@
+package xmlquery
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestNewComment(t *testing.T) {
+	doc, err := Parse(strings.NewReader(`<a/>`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	AddSibling(doc.SelectElement("a"), NewComment(" generated "))
+	testValue(t, doc.OutputXMLWithOptions(WithOutDeclarationNode()), `<a></a><!-- generated -->`)
+}
+
+func TestNewProcInst(t *testing.T) {
+	pi := NewProcInst("xml-stylesheet", ` href="style.xsl"  type='text/xsl' `)
+	testValue(t, pi.Type, DeclarationNode)
+	testValue(t, pi.SelectAttr("href"), "style.xsl")
+	testValue(t, pi.SelectAttr("type"), "text/xsl")
+	testValue(t, pi.ProcInstData(), `href="style.xsl" type="text/xsl"`)
+	testValue(t, pi.OutputXML(true), `<?xml-stylesheet href="style.xsl" type="text/xsl"?>`)
+
+	pi.SetProcInstData(`href="other.xsl"`)
+	testValue(t, pi.OutputXML(true), `<?xml-stylesheet href="other.xsl"?>`)
+
+	php := NewProcInst("php", "echo 1; ")
+	testValue(t, php.ProcInstData(), "echo 1;")
+	testValue(t, php.OutputXML(true), `<?php echo 1; ?>`)
+}
+
+func TestParsePseudoAttrs(t *testing.T) {
+	attrs, ok := parsePseudoAttrs(`a="1 2" b = 'x"y'`)
+	testTrue(t, ok)
+	testDeepEqual(t, attrs, []Attr{{Name: newXMLName("a"), Value: "1 2"}, {Name: newXMLName("b"), Value: `x"y`}})
+	for _, s := range []string{`a`, `a=1`, `a="1`, `a="1"b="2"`, `="1"`} {
+		if _, ok := parsePseudoAttrs(s); ok {
+			t.Errorf("parsePseudoAttrs(%q) should fail", s)
+		}
+	}
+}
+
+func TestParsePseudoAttrsMap(t *testing.T) {
+	m, err := ParsePseudoAttrs(` href="dark mode.css" type='text/css' x:media="print" `)
+	if err != nil {
+		t.Fatal(err)
+	}
+	testDeepEqual(t, m, map[string]string{"href": "dark mode.css", "type": "text/css", "x:media": "print"})
+	if _, err := ParsePseudoAttrs(`echo 1;`); err == nil {
+		t.Fatal("expected error for data without pseudo-attributes")
+	}
+}
+
+func TestQueryProcInst(t *testing.T) {
+	doc, err := Parse(strings.NewReader(`<?xml version="1.0"?>
+<?xml-stylesheet href="dark mode.css" type="text/css"?>
+<root><!--c--><?build target="dist"?><a>text</a><?php echo 1; ?></root>`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	names := func(expr string) []string {
+		var s []string
+		for _, n := range Find(doc, expr) {
+			s = append(s, n.Data)
+		}
+		return s
+	}
+	testDeepEqual(t, names("//processing-instruction()"), []string{"xml-stylesheet", "build", "php"})
+	testDeepEqual(t, names("/processing-instruction('xml-stylesheet')"), []string{"xml-stylesheet"})
+	testDeepEqual(t, names(`//processing-instruction( "build" )`), []string{"build"})
+	testDeepEqual(t, names("//a/following-sibling::processing-instruction()[1]"), []string{"php"})
+	testDeepEqual(t, names("//processing-instruction('missing')"), []string(nil))
+	testDeepEqual(t, names("//*"), []string{"root", "a"})
+
+	pi := FindOne(doc, "/processing-instruction('xml-stylesheet')")
+	testValue(t, pi.SelectAttr("href"), "dark mode.css")
+	for expr, want := range map[string]string{
+		"string(//processing-instruction('php'))": "echo 1;",
+		"count(//processing-instruction())":       "3",
+	} {
+		r, err := Evaluate(doc, expr)
+		if err != nil {
+			t.Fatal(err)
+		}
+		testValue(t, r.String(), want)
+	}
+
+	expr, err := Compile("//p:processing-instruction | //processing-instruction('build')", map[string]string{"p": "urn:p"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	testValue(t, QuerySelector(doc, expr).Data, "build")
+}
+
+func TestRewriteProcInstTests(t *testing.T) {
+	for _, s := range []string{
+		"//a",
+		"//a[.='processing-instruction()']",
+		`//a[@b="processing-instruction('x')"]`,
+		"//p:processing-instruction()",
+		"//processing-instruction",
+		"$processing-instruction",
+	} {
+		testValue(t, rewriteProcInstTests(s), s)
+	}
+	testValue(t, rewriteProcInstTests("self::processing-instruction('x')"), "self::"+procInstTest+" and name()='x']")
+}
@@ -0,0 +1,166 @@
+package xmlquery
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/antchfx/xpath"
+)
+
+// A Profiler records statistics about the queries evaluated while it is
+// started: how many times each expression was evaluated, the time spent
+// and the number of nodes visited, to find the expressions worth
+// optimizing or indexing, see CreateIndex.
+//
+//	p := xmlquery.NewProfiler()
+//	p.Start()
+//	extract(doc)
+//	p.Stop()
+//	fmt.Print(p)
+//
+// Queries made with QuerySelectorAll, QuerySelector, QuerySelectorN,
+// QueryAndWrite, EvaluateSelector, QuerySelectorAllWithOptions and the
+// functions built on them, such as Find and QueryAll, are recorded. Queries
+// answered by an index are recorded with no visited nodes.
+type Profiler struct {
+	mu    sync.Mutex
+	stats map[string]*QueryStats
+}
+
+// QueryStats are the statistics of an expression, see Profiler.
+type QueryStats struct {
+	Expr string
+	// Count is the number of evaluations.
+	Count int64
+	// Total is the time spent evaluating the expression, including the
+	// time spent by the caller between two results of QuerySelectorN.
+	Total time.Duration
+	// NodesVisited is the number of moves of the navigators from a node
+	// to another one, attributes included.
+	NodesVisited int64
+}
+
+// Average returns the average time of an evaluation.
+func (s QueryStats) Average() time.Duration {
+	if s.Count == 0 {
+		return 0
+	}
+	return s.Total / time.Duration(s.Count)
+}
+
+// activeProfiler is the started Profiler, if any.
+var activeProfiler atomic.Pointer[Profiler]
+
+// NewProfiler returns a Profiler that is not started.
+func NewProfiler() *Profiler {
+	return &Profiler{stats: make(map[string]*QueryStats)}
+}
+
+// Start makes p record the queries evaluated from now on, by any goroutine.
+// It stops the profiler started before, if any.
+func (p *Profiler) Start() {
+	activeProfiler.Store(p)
+}
+
+// Stop stops recording queries, if p is the started profiler. The
+// statistics recorded so far are kept.
+func (p *Profiler) Stop() {
+	activeProfiler.CompareAndSwap(p, nil)
+}
+
+// Reset clears the statistics of p.
+func (p *Profiler) Reset() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.stats = make(map[string]*QueryStats)
+}
+
+// Report returns the statistics of the recorded expressions, by decreasing
+// total time.
+func (p *Profiler) Report() []QueryStats {
+	p.mu.Lock()
+	report := make([]QueryStats, 0, len(p.stats))
+	for _, s := range p.stats {
+		report = append(report, *s)
+	}
+	p.mu.Unlock()
+	sort.Slice(report, func(i, j int) bool {
+		if report[i].Total != report[j].Total {
+			return report[i].Total > report[j].Total
+		}
+		return report[i].Expr < report[j].Expr
+	})
+	return report
+}
+
+// String returns the report of p as a table.
+func (p *Profiler) String() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%8s %12s %12s %12s  %s\n", "count", "total", "average", "visited", "expression")
+	for _, s := range p.Report() {
+		fmt.Fprintf(&b, "%8d %12s %12s %12d  %s\n", s.Count, s.Total, s.Average(), s.NodesVisited, s.Expr)
+	}
+	return b.String()
+}
+
+func (p *Profiler) record(expr string, d time.Duration, visits int64) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	s := p.stats[expr]
+	if s == nil {
+		s = &QueryStats{Expr: expr}
+		p.stats[expr] = s
+	}
+	s.Count++
+	s.Total += d
+	s.NodesVisited += visits
+}
+
+// queryProfile is an evaluation being profiled or reported to the hooks.
+// The navigators of the evaluation count their moves in visits.
+type queryProfile struct {
+	p      *Profiler
+	hook   func(QueryInfo) // returned by Hooks.QueryStart
+	expr   string
+	start  time.Time
+	visits int64
+}
+
+// startProfile returns the profile of an evaluation of selector, or nil if
+// no profiler is started and no hook wants it.
+func startProfile(selector *xpath.Expr) *queryProfile {
+	p := activeProfiler.Load()
+	var hook func(QueryInfo)
+	if h := activeHooks.Load(); h != nil && h.QueryStart != nil {
+		hook = h.QueryStart(selector.String())
+	}
+	if p == nil && hook == nil {
+		return nil
+	}
+	return &queryProfile{p: p, hook: hook, expr: selector.String(), start: time.Now()}
+}
+
+// end records the evaluation.
+func (q *queryProfile) end() {
+	if q == nil {
+		return
+	}
+	d := time.Since(q.start)
+	if q.p != nil {
+		q.p.record(q.expr, d, q.visits)
+	}
+	if q.hook != nil {
+		q.hook(QueryInfo{Expr: q.expr, Duration: d, NodesVisited: q.visits})
+	}
+}
+
+// visit counts a move of a navigator.
+func (q *queryProfile) visit() {
+	if q != nil {
+		q.visits++
+	}
+}
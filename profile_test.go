@@ -0,0 +1,53 @@
+package xmlquery
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestProfiler(t *testing.T) {
+	doc, err := Parse(strings.NewReader(`<r><a id="1"><b/></a><a id="2"/><c/></r>`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	Find(doc, "//a") // not recorded
+
+	p := NewProfiler()
+	p.Start()
+	Find(doc, "//a")
+	Find(doc, "//a")
+	FindOne(doc, "/r/c")
+	if _, err := Evaluate(doc, "count(//@id)"); err != nil {
+		t.Fatal(err)
+	}
+	p.Stop()
+	Find(doc, "//a") // not recorded
+
+	stats := make(map[string]QueryStats)
+	for _, s := range p.Report() {
+		stats[s.Expr] = s
+	}
+	testValue(t, len(stats), 3)
+	testValue(t, stats["//a"].Count, int64(2))
+	testValue(t, stats["/r/c"].Count, int64(1))
+	testValue(t, stats["count(//@id)"].Count, int64(1))
+	testTrue(t, stats["//a"].NodesVisited > stats["/r/c"].NodesVisited)
+	testTrue(t, stats["/r/c"].NodesVisited > 0)
+	testTrue(t, stats["//a"].Average() <= stats["//a"].Total)
+
+	report := p.String()
+	testTrue(t, strings.HasPrefix(report, "   count"))
+	testTrue(t, strings.Contains(report, "//a\n"))
+
+	// Queries answered by an index don't visit nodes.
+	if _, err := doc.CreateIndex("a", "@id"); err != nil {
+		t.Fatal(err)
+	}
+	p.Reset()
+	p.Start()
+	defer p.Stop()
+	testValue(t, len(Find(doc, "//a[@id='2']")), 1)
+	report2 := p.Report()
+	testValue(t, len(report2), 1)
+	testValue(t, report2[0].NodesVisited, int64(0))
+}
@@ -0,0 +1,95 @@
+package xmlquery
+
+import (
+	"fmt"
+	"io"
+	"strings"
+)
+
+// A PullParser reads a document one element at a time, for code that
+// processes large inputs sequentially rather than with callbacks:
+//
+//	pp := xmlquery.NewPullParser(f, xmlquery.ParserOptions{})
+//	for {
+//		record, err := pp.NextElement("record")
+//		if err == io.EOF {
+//			break
+//		}
+//		if err != nil {
+//			return err
+//		}
+//		fmt.Println(pp.Path(), record.SelectAttr("id"))
+//	}
+//
+// Like a StreamParser, it keeps only the ancestors of the current element
+// and the elements around it in memory.
+type PullParser struct {
+	sp      *StreamParser
+	name    string // the name the stream parser looks for
+	current *Node
+}
+
+// NewPullParser returns a PullParser reading from r.
+func NewPullParser(r io.Reader, options ParserOptions) *PullParser {
+	p := createParser(r)
+	options.apply(p)
+	// Elements are discarded one by one, which an arena can't do.
+	if p.arena != nil {
+		p.arena = nil
+		p.doc.doc.arena = nil
+	}
+	p.streamElementSelf = true
+	return &PullParser{sp: &StreamParser{p: p}}
+}
+
+// NextElement reads up to the next element named name, as in
+// "prefix:local", or any element if name is "*", and returns it with its
+// whole subtree. Elements nested in the returned one are not returned
+// again. The element returned before is removed from the tree and must not
+// be used anymore. At the end of the input, NextElement returns io.EOF.
+func (pp *PullParser) NextElement(name string) (*Node, error) {
+	if name != pp.name || pp.sp.p.streamElementXPath == nil {
+		expr, err := getQueryFor(pp.sp.p.doc, "self::"+name)
+		if err != nil {
+			return nil, fmt.Errorf("xmlquery: invalid element name %q", name)
+		}
+		pp.sp.p.streamElementXPath = expr
+		pp.name = name
+	}
+	pp.current = nil
+	n, err := pp.sp.Read()
+	if err != nil {
+		return nil, err
+	}
+	pp.current = n
+	return n, nil
+}
+
+// Depth returns the depth of the element returned by NextElement, 1 for
+// the root element, or 0 if there is none.
+func (pp *PullParser) Depth() int {
+	depth := 0
+	for n := pp.current; n != nil; n = n.Parent {
+		if n.Type == ElementNode {
+			depth++
+		}
+	}
+	return depth
+}
+
+// Path returns the path of the element returned by NextElement from the
+// root element, such as "/catalog/books/book", or "" if there is none.
+func (pp *PullParser) Path() string {
+	var names []string
+	for n := pp.current; n != nil; n = n.Parent {
+		if n.Type == ElementNode {
+			names = append(names, qualifiedName(n))
+		}
+	}
+	var b strings.Builder
+	for i := len(names) - 1; i >= 0; i-- {
+		b.WriteByte('/')
+		b.WriteString(names[i])
+	}
+	return b.String()
+}
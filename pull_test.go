@@ -0,0 +1,48 @@
+package xmlquery
+
+import (
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestPullParser(t *testing.T) {
+	s := `<export>
+	<header><count>3</count></header>
+	<records>
+		<record id="1"><record id="1.1"/></record>
+		<record id="2"/>
+	</records>
+	<group><record id="3"/></group>
+</export>`
+	pp := NewPullParser(strings.NewReader(s), ParserOptions{})
+	testValue(t, pp.Depth(), 0)
+	testValue(t, pp.Path(), "")
+
+	header, err := pp.NextElement("header")
+	if err != nil {
+		t.Fatal(err)
+	}
+	testValue(t, FindOne(header, "count").InnerText(), "3")
+	testValue(t, pp.Path(), "/export/header")
+	testValue(t, pp.Depth(), 2)
+
+	var got []string
+	for {
+		record, err := pp.NextElement("record")
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		got = append(got, record.SelectAttr("id")+" "+pp.Path())
+		if record.SelectAttr("id") == "1" {
+			testValue(t, len(Find(record, "record")), 1)
+		}
+	}
+	testValue(t, strings.Join(got, ", "), "1 /export/records/record, 2 /export/records/record, 3 /export/group/record")
+
+	_, err = NewPullParser(strings.NewReader(s), ParserOptions{}).NextElement("a[")
+	testValue(t, err.Error(), `xmlquery: invalid element name "a["`)
+}
@@ -1,10 +1,27 @@
 /*
 Package xmlquery provides extract data from XML documents using XPath expression.
+
+# Concurrency
+
+A tree that is no longer modified may be queried from any number of
+goroutines at once: Find, FindOne, Query, QueryAll, QuerySelector,
+QuerySelectorAll, SelectElement(s), SelectAttr, InnerText and OutputXML only
+read the tree, and the shared selector cache is guarded by a mutex.
+
+Documents created by ParseLazy parse deferred content on first access; this
+is synchronized and doesn't change the rules above.
+
+Functions that modify a tree (AddChild, AddSibling, RemoveFromTree, SetAttr,
+CreateIndex, ...) must not run concurrently with any other access to the same
+tree. Use SharedDocument when one goroutine needs to modify a document while
+others keep querying it.
 */
 package xmlquery
 
 import (
+	"bufio"
 	"fmt"
+	"io"
 	"strings"
 
 	"github.com/antchfx/xpath"
@@ -20,6 +37,29 @@ func (n *Node) SelectElement(name string) *Node {
 	return FindOne(n, name)
 }
 
+// SelectElementNS returns the first child element of n with the given
+// namespace URI and local name, whatever prefix it is written with, or nil.
+// The empty namespaceURI selects elements in no namespace.
+//
+//	body := envelope.SelectElementNS("http://schemas.xmlsoap.org/soap/envelope/", "Body")
+func (n *Node) SelectElementNS(namespaceURI, local string) *Node {
+	n.Materialize()
+	return childElementNS(n, namespaceURI, local)
+}
+
+// SelectElementsNS returns the child elements of n with the given namespace
+// URI and local name, see SelectElementNS.
+func (n *Node) SelectElementsNS(namespaceURI, local string) []*Node {
+	n.Materialize()
+	var elems []*Node
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		if c.Type == ElementNode && c.Data == local && c.NamespaceURI == namespaceURI {
+			elems = append(elems, c)
+		}
+	}
+	return elems
+}
+
 // SelectAttr returns the attribute value with the specified name.
 func (n *Node) SelectAttr(name string) string {
 	if n.Type == AttributeNode {
@@ -29,9 +69,14 @@ func (n *Node) SelectAttr(name string) string {
 		return ""
 	}
 	xmlName := newXMLName(name)
-	for _, attr := range n.Attr {
-		if attr.Name == xmlName {
-			return attr.Value
+	if i := n.attrPos(xmlName); i >= 0 {
+		return n.Attr[i].Value
+	}
+	if len(n.Attr) > 0 && n.foldsCase() {
+		for _, attr := range n.Attr {
+			if strings.EqualFold(attr.Name.Local, xmlName.Local) && strings.EqualFold(attr.Name.Space, xmlName.Space) {
+				return attr.Value
+			}
 		}
 	}
 	return ""
@@ -55,16 +100,46 @@ func (n *Node) SelectAttrs(name string) []string {
 	return values
 }
 
+// SelectAttrNode returns the attribute with the specified name, or nil if
+// n has no such attribute. The returned attribute points into n.Attr, so
+// changing its Value changes the node and is reflected when n is written;
+// its Name must not be changed. It remains valid until attributes are
+// added to or removed from n.
+func (n *Node) SelectAttrNode(name string) *Attr {
+	if i := n.attrPos(newXMLName(name)); i >= 0 {
+		return &n.Attr[i]
+	}
+	return nil
+}
+
+// SelectAttrNodes is like SelectAttrNode, but returns all attributes with the
+// specified name.
+func (n *Node) SelectAttrNodes(name string) []*Attr {
+	xmlName := newXMLName(name)
+	var attrs []*Attr
+	for i := range n.Attr {
+		if n.Attr[i].Name == xmlName {
+			attrs = append(attrs, &n.Attr[i])
+		}
+	}
+	return attrs
+}
+
 var _ xpath.NodeNavigator = &NodeNavigator{}
 
 // CreateXPathNavigator creates a new xpath.NodeNavigator for the specified
 // XML Node.
 func CreateXPathNavigator(top *Node) *NodeNavigator {
-	return &NodeNavigator{curr: top, root: top, attr: -1}
+	return &NodeNavigator{curr: top, root: top, attr: -1, fold: top.foldsCase()}
 }
 
 func getCurrentNode(it *xpath.NodeIterator) *Node {
-	n := it.Current().(*NodeNavigator)
+	return navigatorNode(it.Current().(*NodeNavigator))
+}
+
+// navigatorNode returns the node n is positioned at. For an attribute, it
+// returns a new AttributeNode whose parent is the owner element.
+func navigatorNode(n *NodeNavigator) *Node {
 	if n.NodeType() == xpath.AttributeNode {
 		childNode := &Node{
 			Type: TextNode,
@@ -104,7 +179,7 @@ func FindOne(top *Node, expr string) *Node {
 // QueryAll searches the XML Node that matches by the specified XPath expr.
 // Returns an error if the expression `expr` cannot be parsed.
 func QueryAll(top *Node, expr string) ([]*Node, error) {
-	exp, err := getQuery(expr)
+	exp, err := getQueryFor(top, expr)
 	if err != nil {
 		return nil, err
 	}
@@ -114,20 +189,84 @@ func QueryAll(top *Node, expr string) ([]*Node, error) {
 // Query searches the XML Node that matches by the specified XPath expr,
 // and returns first matched element.
 func Query(top *Node, expr string) (*Node, error) {
-	exp, err := getQuery(expr)
+	exp, err := getQueryFor(top, expr)
 	if err != nil {
 		return nil, err
 	}
 	return QuerySelector(top, exp), nil
 }
 
+// QueryN is like QueryAll, but skips the first offset matches and returns
+// at most limit of the following ones. See QuerySelectorN.
+//
+//	// The third page of 10 items.
+//	items, err := xmlquery.QueryN(doc, "//item", 20, 10)
+func QueryN(top *Node, expr string, offset, limit int) ([]*Node, error) {
+	exp, err := getQueryFor(top, expr)
+	if err != nil {
+		return nil, err
+	}
+	return QuerySelectorN(top, exp, offset, limit), nil
+}
+
+// FindN is like QueryN but panics if `expr` is not a valid XPath expression.
+func FindN(top *Node, expr string, offset, limit int) []*Node {
+	nodes, err := QueryN(top, expr, offset, limit)
+	if err != nil {
+		panic(err)
+	}
+	return nodes
+}
+
+// Compile compiles an XPath expression for use with QuerySelector and
+// QuerySelectorAll, resolving prefixes with namespaces. If namespaces is nil,
+// prefixes are matched as written in the document. Unlike xpath.Compile, processing-instruction() node tests select the
+// processing instructions of the document.
+func Compile(expr string, namespaces map[string]string) (*xpath.Expr, error) {
+	return xpath.CompileWithNS(rewriteProcInstTests(expr), namespaces)
+}
+
 // QuerySelectorAll searches all of the XML Node that matches the specified
 // XPath selectors.
 func QuerySelectorAll(top *Node, selector *xpath.Expr) []*Node {
-	t := selector.Select(CreateXPathNavigator(top))
+	prof := startProfile(selector)
+	defer prof.end()
+	if nodes, ok := indexLookup(top, selector.String()); ok {
+		return append([]*Node(nil), nodes...)
+	}
+	nav := selectorNavigator(top, selector)
+	nav.prof = prof
+	t := selector.Select(nav)
+	var elems []*Node
+	for t.MoveNext() {
+		elems = append(elems, getCurrentNode(t))
+	}
+	return elems
+}
+
+// QuerySelectorN is like QuerySelectorAll, but skips the first offset
+// matches and returns at most limit of the following ones; a negative limit
+// returns all of them. Evaluation stops as soon as enough matches are found,
+// so the rest of the document is not searched.
+func QuerySelectorN(top *Node, selector *xpath.Expr, offset, limit int) []*Node {
+	if limit == 0 {
+		return nil
+	}
+	prof := startProfile(selector)
+	defer prof.end()
+	nav := selectorNavigator(top, selector)
+	nav.prof = prof
+	t := selector.Select(nav)
 	var elems []*Node
 	for t.MoveNext() {
+		if offset > 0 {
+			offset--
+			continue
+		}
 		elems = append(elems, getCurrentNode(t))
+		if len(elems) == limit {
+			break
+		}
 	}
 	return elems
 }
@@ -135,13 +274,67 @@ func QuerySelectorAll(top *Node, selector *xpath.Expr) []*Node {
 // QuerySelector returns the first matched XML Node by the specified XPath
 // selector.
 func QuerySelector(top *Node, selector *xpath.Expr) *Node {
-	t := selector.Select(CreateXPathNavigator(top))
+	prof := startProfile(selector)
+	defer prof.end()
+	if nodes, ok := indexLookup(top, selector.String()); ok {
+		if len(nodes) == 0 {
+			return nil
+		}
+		return nodes[0]
+	}
+	nav := selectorNavigator(top, selector)
+	nav.prof = prof
+	t := selector.Select(nav)
 	if t.MoveNext() {
 		return getCurrentNode(t)
 	}
 	return nil
 }
 
+// QueryAndWrite searches the XML Nodes that match the specified XPath expr
+// and writes each of them to w as soon as it is found, separated by sep, so
+// that the matches never need to be held in memory at once. Attribute
+// matches are written as their escaped value. The output options apply to
+// every match, except WithBOM, whose mark is written once before the first
+// match; the matched node itself is always included in the output.
+// It returns the number of nodes written.
+func QueryAndWrite(top *Node, expr string, w io.Writer, sep string, opts ...OutputOption) (int, error) {
+	exp, err := getQueryFor(top, expr)
+	if err != nil {
+		return 0, err
+	}
+	var config outputConfiguration
+	for _, opt := range opts {
+		opt(&config)
+	}
+	// A byte order mark starts the output, not every match.
+	opts = append([]OutputOption{WithOutputSelf()}, opts...)
+	opts = append(opts, func(oc *outputConfiguration) {
+		oc.bom = false
+	})
+	b := bufio.NewWriter(w)
+	count := 0
+	prof := startProfile(exp)
+	defer prof.end()
+	nav := selectorNavigator(top, exp)
+	nav.prof = prof
+	t := exp.Select(nav)
+	for t.MoveNext() {
+		if count > 0 {
+			b.WriteString(sep)
+		} else if config.bom {
+			b.WriteString("\uFEFF")
+		}
+		if n := getCurrentNode(t); n.Type == AttributeNode {
+			textEscaper.WriteString(b, n.InnerText())
+		} else {
+			n.writeTo(b, opts)
+		}
+		count++
+	}
+	return count, b.Flush()
+}
+
 // FindEach searches the html.Node and calls functions cb.
 // Important: this method is deprecated, instead, use for .. = range Find(){}.
 func FindEach(top *Node, expr string, cb func(int, *Node)) {
@@ -164,6 +357,9 @@ func FindEachWithBreak(top *Node, expr string, cb func(int, *Node) bool) {
 type NodeNavigator struct {
 	root, curr *Node
 	attr       int
+	keys       *keyState     // if set, the expression calls key(), see rewriteKeyCalls
+	prof       *queryProfile // if set, the moves are counted, see Profiler
+	fold       bool          // if set, names are reported in lower case, see ParserOptions.CaseInsensitiveNames
 }
 
 func (x *NodeNavigator) Current() *Node {
@@ -171,6 +367,9 @@ func (x *NodeNavigator) Current() *Node {
 }
 
 func (x *NodeNavigator) NodeType() xpath.NodeType {
+	if x.onKey() {
+		return xpath.TextNode // see keyAttrPrefix
+	}
 	switch x.curr.Type {
 	case CommentNode:
 		return xpath.CommentNode
@@ -188,24 +387,36 @@ func (x *NodeNavigator) NodeType() xpath.NodeType {
 }
 
 func (x *NodeNavigator) LocalName() string {
+	if x.onKey() {
+		return x.keys.calls[x.keys.virt].attr
+	}
 	if x.attr != -1 {
-		return x.curr.Attr[x.attr].Name.Local
+		return x.foldName(x.curr.Attr[x.attr].Name.Local)
+	}
+	if x.curr.Type == ElementNode {
+		return x.foldName(x.curr.Data)
 	}
 	return x.curr.Data
 
 }
 
 func (x *NodeNavigator) Prefix() string {
+	if x.onKey() {
+		return ""
+	}
 	if x.NodeType() == xpath.AttributeNode {
 		if x.attr != -1 {
-			return x.curr.Attr[x.attr].Name.Space
+			return x.foldName(x.curr.Attr[x.attr].Name.Space)
 		}
 		return ""
 	}
-	return x.curr.Prefix
+	return x.foldName(x.curr.Prefix)
 }
 
 func (x *NodeNavigator) NamespaceURL() string {
+	if x.onKey() {
+		return ""
+	}
 	if x.attr != -1 {
 		return x.curr.Attr[x.attr].NamespaceURI
 	}
@@ -213,6 +424,9 @@ func (x *NodeNavigator) NamespaceURL() string {
 }
 
 func (x *NodeNavigator) Value() string {
+	if x.onKey() {
+		return x.keyValue()
+	}
 	switch x.curr.Type {
 	case CommentNode:
 		return x.curr.Data
@@ -220,56 +434,107 @@ func (x *NodeNavigator) Value() string {
 		if x.attr != -1 {
 			return x.curr.Attr[x.attr].Value
 		}
+		// Most elements compared in predicates hold a single text node; return
+		// its data directly rather than building the string value.
+		if child := x.curr.FirstChild; child != nil && child == x.curr.LastChild {
+			switch child.Type {
+			case TextNode, CharDataNode:
+				return child.Data
+			}
+		}
 		return x.curr.InnerText()
 	case TextNode:
 		return x.curr.Data
+	case DeclarationNode:
+		return x.curr.ProcInstData()
 	}
 	return ""
 }
 
 func (x *NodeNavigator) Copy() xpath.NodeNavigator {
 	n := *x
+	if x.keys != nil {
+		// A copy starts a new axis: from an attribute, MoveToNextAttribute
+		// moves to the key() attributes of that attribute.
+		k := *x.keys
+		k.iter = false
+		n.keys = &k
+	}
 	return &n
 }
 
 func (x *NodeNavigator) MoveToRoot() {
 	x.curr = x.root
+	x.attr = -1
+	if x.keys != nil {
+		x.keys.virt, x.keys.iter, x.keys.list = -1, false, nil
+	}
 }
 
 func (x *NodeNavigator) MoveToParent() bool {
+	if x.keys != nil {
+		if ok, handled := x.keyMoveToParent(); handled {
+			return ok
+		}
+	}
 	if x.attr != -1 {
 		x.attr = -1
+		x.prof.visit()
 		return true
 	} else if node := x.curr.Parent; node != nil {
 		x.curr = node
+		x.prof.visit()
 		return true
 	}
 	return false
 }
 
 func (x *NodeNavigator) MoveToNextAttribute() bool {
+	if x.keys != nil {
+		return x.keyMoveToNextAttribute()
+	}
 	if x.attr >= len(x.curr.Attr)-1 {
 		return false
 	}
 	x.attr++
+	x.prof.visit()
 	return true
 }
 
 func (x *NodeNavigator) MoveToChild() bool {
+	if x.keys != nil {
+		if ok, handled := x.keyMoveToChild(); handled {
+			return ok
+		}
+	}
 	if x.attr != -1 {
 		return false
 	}
+	x.curr.Materialize()
 	if node := x.curr.FirstChild; node != nil {
 		x.curr = node
+		x.prof.visit()
 		return true
 	}
 	return false
 }
 
 func (x *NodeNavigator) MoveToFirst() bool {
+	if x.keys != nil {
+		if ok, handled := x.keyMoveSibling(0, true); handled {
+			return ok
+		}
+	}
 	if x.attr != -1 || x.curr.PrevSibling == nil {
 		return false
 	}
+	// The parent links the first sibling directly; only detached chains
+	// without a parent need to be walked.
+	if parent := x.curr.Parent; parent != nil && parent.FirstChild != nil {
+		x.curr = parent.FirstChild
+		x.prof.visit()
+		return true
+	}
 	for {
 		node := x.curr.PrevSibling
 		if node == nil {
@@ -277,6 +542,7 @@ func (x *NodeNavigator) MoveToFirst() bool {
 		}
 		x.curr = node
 	}
+	x.prof.visit()
 	return true
 }
 
@@ -285,11 +551,17 @@ func (x *NodeNavigator) String() string {
 }
 
 func (x *NodeNavigator) MoveToNext() bool {
+	if x.keys != nil {
+		if ok, handled := x.keyMoveSibling(1, false); handled {
+			return ok
+		}
+	}
 	if x.attr != -1 {
 		return false
 	}
 	for node := x.curr.NextSibling; node != nil; node = x.curr.NextSibling {
 		x.curr = node
+		x.prof.visit()
 		if x.curr.Type != TextNode || strings.TrimSpace(x.curr.Data) != "" {
 			return true
 		}
@@ -298,11 +570,17 @@ func (x *NodeNavigator) MoveToNext() bool {
 }
 
 func (x *NodeNavigator) MoveToPrevious() bool {
+	if x.keys != nil {
+		if ok, handled := x.keyMoveSibling(-1, false); handled {
+			return ok
+		}
+	}
 	if x.attr != -1 {
 		return false
 	}
 	for node := x.curr.PrevSibling; node != nil; node = x.curr.PrevSibling {
 		x.curr = node
+		x.prof.visit()
 		if x.curr.Type != TextNode || strings.TrimSpace(x.curr.Data) != "" {
 			return true
 		}
@@ -318,5 +596,9 @@ func (x *NodeNavigator) MoveTo(other xpath.NodeNavigator) bool {
 
 	x.curr = node.curr
 	x.attr = node.attr
+	if node.keys != nil {
+		k := *node.keys
+		x.keys = &k
+	}
 	return true
 }
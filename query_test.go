@@ -4,6 +4,8 @@ import (
 	"fmt"
 	"strings"
 	"testing"
+
+	"github.com/antchfx/xpath"
 )
 
 // https://msdn.microsoft.com/en-us/library/ms762271(v=vs.85).aspx
@@ -129,6 +131,29 @@ func TestNavigator(t *testing.T) {
 	}
 }
 
+func TestNavigatorFastPaths(t *testing.T) {
+	doc := loadXML(`<r><a>one</a><b><![CDATA[two]]></b><c>x<!--y-->z</c><d/></r>`)
+	testValue(t, FindOne(doc, "//a").InnerText(), "one")
+	testValue(t, len(Find(doc, "//*[.='two']")), 1)
+	testValue(t, len(Find(doc, "//*[.='xz']")), 1)
+	testValue(t, len(Find(doc, "//*[.='']")), 1)
+	testValue(t, FindOne(doc, "//c/../*[last()]").Data, "d")
+	testValue(t, FindOne(doc, "//d/preceding-sibling::*[1]").Data, "c")
+
+	nav := CreateXPathNavigator(FindOne(doc, "//d"))
+	testTrue(t, nav.MoveToFirst())
+	testValue(t, nav.LocalName(), "a")
+	testTrue(t, !nav.MoveToFirst())
+
+	// Siblings without a parent are walked.
+	first := &Node{Type: ElementNode, Data: "first"}
+	second := &Node{Type: ElementNode, Data: "second", PrevSibling: first}
+	first.NextSibling = second
+	nav = CreateXPathNavigator(second)
+	testTrue(t, nav.MoveToFirst())
+	testValue(t, nav.Current(), first)
+}
+
 func TestAttributesNamespaces(t *testing.T) {
 	doc := loadXML(`
 		<root xmlns="ns://root" xmlns:nested="ns://nested" xmlns:other="ns://other">
@@ -170,3 +195,130 @@ func TestMissingTextNodes(t *testing.T) {
         t.Fatalf("Expected text nodes 3, got %d", len(results))
     }
 }
+
+func TestQueryAndWrite(t *testing.T) {
+	doc := loadXML(`<list><item id="a&amp;b"><name>one</name></item><item id="c"><name>two</name></item></list>`)
+	var b strings.Builder
+	count, err := QueryAndWrite(doc, "//item", &b, "\n")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if count != 2 {
+		t.Fatalf("expected 2 matches, got %d", count)
+	}
+	expected := "<item id=\"a&b\"><name>one</name></item>\n<item id=\"c\"><name>two</name></item>"
+	if b.String() != expected {
+		t.Fatalf("expected %q, got %q", expected, b.String())
+	}
+
+	b.Reset()
+	if _, err := QueryAndWrite(doc, "//item/@id", &b, ","); err != nil {
+		t.Fatal(err)
+	}
+	if b.String() != "a&amp;b,c" {
+		t.Fatalf("unexpected attribute output %q", b.String())
+	}
+
+	if _, err := QueryAndWrite(doc, "//item[", &b, ""); err == nil {
+		t.Fatal("expected error for invalid expression")
+	}
+}
+
+func TestFindN(t *testing.T) {
+	doc := loadXML(`<list><item>1</item><item>2</item><item>3</item><item>4</item></list>`)
+	text := func(nodes []*Node) string {
+		var s []string
+		for _, n := range nodes {
+			s = append(s, n.InnerText())
+		}
+		return strings.Join(s, ",")
+	}
+	testValue(t, text(FindN(doc, "//item", 0, 2)), "1,2")
+	testValue(t, text(FindN(doc, "//item", 1, 2)), "2,3")
+	testValue(t, text(FindN(doc, "//item", 3, 2)), "4")
+	testValue(t, text(FindN(doc, "//item", 1, -1)), "2,3,4")
+	testValue(t, len(FindN(doc, "//item", 5, 1)), 0)
+	testValue(t, len(FindN(doc, "//item", 0, 0)), 0)
+	if _, err := QueryN(doc, "//item[", 0, 1); err == nil {
+		t.Fatal("expected error for invalid expression")
+	}
+}
+
+func TestSelectAttrNode(t *testing.T) {
+	doc, err := Parse(strings.NewReader(`<root xmlns:x="urn:x"><item x:id="1" name="a" name="b"/></root>`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	item := FindOne(doc, "//item")
+	attr := item.SelectAttrNode("x:id")
+	if attr == nil {
+		t.Fatal("x:id attribute not found")
+	}
+	testValue(t, attr.Name.Space, "x")
+	testValue(t, attr.Name.Local, "id")
+	testValue(t, attr.NamespaceURI, "urn:x")
+	attr.Value = "2"
+	testValue(t, item.SelectAttr("x:id"), "2")
+	testValue(t, item.OutputXML(true), `<item x:id="2" name="a" name="b" xmlns:x="urn:x"></item>`)
+	testTrue(t, item.SelectAttrNode("missing") == nil)
+
+	names := item.SelectAttrNodes("name")
+	testValue(t, len(names), 2)
+	names[1].Value = "c"
+	testDeepEqual(t, item.SelectAttrs("name"), []string{"a", "c"})
+}
+
+func benchmarkQuery(b *testing.B, expr string) {
+	doc := benchmarkDocument(b)
+	selector := xpath.MustCompile(expr)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if QuerySelector(doc, selector) == nil {
+			b.Fatalf("%s: no match", expr)
+		}
+	}
+}
+
+func BenchmarkQueryAttributePredicate(b *testing.B) {
+	benchmarkQuery(b, "//item[@id='999']")
+}
+
+func BenchmarkQueryPosition(b *testing.B) {
+	benchmarkQuery(b, "/catalog/item[999]/title")
+}
+
+func BenchmarkQueryLast(b *testing.B) {
+	benchmarkQuery(b, "/catalog/item[last()]")
+}
+
+func BenchmarkQueryElementValue(b *testing.B) {
+	benchmarkQuery(b, "//item[title='Title 999 <draft>']")
+}
+
+func BenchmarkFindN(b *testing.B) {
+	doc := benchmarkDocument(b)
+	selector := xpath.MustCompile("//item")
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if len(QuerySelectorN(doc, selector, 10, 10)) != 10 {
+			b.Fatal("expected 10 matches")
+		}
+	}
+}
+
+func TestSelectElementNS(t *testing.T) {
+	s := `<r xmlns:a="urn:x" xmlns:b="urn:x"><a:item id="1"/><b:item id="2"/><item id="3"/><a:other/></r>`
+	doc, err := Parse(strings.NewReader(s))
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := doc.SelectElement("r")
+	testValue(t, r.SelectElementNS("urn:x", "item").SelectAttr("id"), "1")
+	testValue(t, r.SelectElementNS("", "item").SelectAttr("id"), "3")
+	testTrue(t, r.SelectElementNS("urn:y", "item") == nil)
+	items := r.SelectElementsNS("urn:x", "item")
+	testValue(t, len(items), 2)
+	testValue(t, items[1].SelectAttr("id"), "2")
+}
@@ -0,0 +1,91 @@
+package xmlquery
+
+import (
+	"strings"
+
+	"github.com/antchfx/xpath"
+	"github.com/suifengpiao14/xmlquery/xml"
+)
+
+// Redact replaces the values matched by the XPath expressions exprs below
+// top with replacement, keeping the structure of the document, so that a
+// payload can be logged without leaking credentials or personal data. It
+// returns the number of values replaced:
+//
+//   - the value of a matched attribute is replaced;
+//   - the text of a matched element is replaced in each of its text nodes
+//     and CDATA sections, descendants included, that are not only
+//     whitespace;
+//   - the content of a matched text node or comment is replaced.
+//
+// All the expressions are evaluated before anything is replaced.
+//
+//	xmlquery.Redact(doc, []string{"//password", "//card/*", "//@token"}, "***")
+func Redact(top *Node, exprs []string, replacement string) (int, error) {
+	type attrMatch struct {
+		elem *Node
+		name xml.Name
+	}
+	var (
+		nodes []*Node
+		attrs []attrMatch
+	)
+	for _, expr := range exprs {
+		exp, err := getQueryFor(top, expr)
+		if err != nil {
+			return 0, err
+		}
+		t := exp.Select(selectorNavigator(top, exp))
+		for t.MoveNext() {
+			nav := t.Current().(*NodeNavigator)
+			if nav.onKey() {
+				continue
+			}
+			if nav.NodeType() == xpath.AttributeNode {
+				attrs = append(attrs, attrMatch{nav.curr, nav.curr.Attr[nav.attr].Name})
+			} else {
+				nodes = append(nodes, nav.curr)
+			}
+		}
+	}
+	redacted := make(map[*Node]bool)
+	count := 0
+	redact := func(n *Node) {
+		if !redacted[n] {
+			redacted[n] = true
+			n.SetData(replacement)
+			count++
+		}
+	}
+	var redactText func(*Node)
+	redactText = func(n *Node) {
+		n.Materialize()
+		for child := n.FirstChild; child != nil; child = child.NextSibling {
+			switch child.Type {
+			case TextNode, CharDataNode:
+				if strings.TrimSpace(child.Data) != "" {
+					redact(child)
+				}
+			case ElementNode:
+				redactText(child)
+			}
+		}
+	}
+	for _, n := range nodes {
+		switch n.Type {
+		case DocumentNode, ElementNode:
+			redactText(n)
+		case TextNode, CharDataNode, CommentNode:
+			redact(n)
+		}
+	}
+	redactedAttrs := make(map[attrMatch]bool)
+	for _, a := range attrs {
+		if !redactedAttrs[a] {
+			redactedAttrs[a] = true
+			setAttrByName(a.elem, a.name, replacement)
+			count++
+		}
+	}
+	return count, nil
+}
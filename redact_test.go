@@ -0,0 +1,25 @@
+package xmlquery
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestRedact(t *testing.T) {
+	s := `<login token="abc" user="bob"><password>secret</password><card>
+	<number>4111</number><cvv><![CDATA[123]]></cvv>
+</card><!--pin 1234--></login>`
+	doc, err := Parse(strings.NewReader(s))
+	if err != nil {
+		t.Fatal(err)
+	}
+	count, err := Redact(doc, []string{"//password", "//card", "//@token", "//comment()", "//password/text()"}, "***")
+	if err != nil {
+		t.Fatal(err)
+	}
+	testValue(t, count, 5)
+	testValue(t, FindOne(doc, "/login").OutputXML(true), `<login token="***" user="bob"><password>***</password><card><number>***</number><cvv><![CDATA[***]]></cvv></card><!--***--></login>`)
+
+	_, err = Redact(doc, []string{"//password", "//["}, "***")
+	testTrue(t, err != nil)
+}
@@ -0,0 +1,36 @@
+package xmlquery
+
+import (
+	"regexp"
+)
+
+// FindByRegex returns the nodes selected by expr whose text matches the
+// regular expression pattern, in the syntax of the regexp package. The text
+// of an element is its InnerText and that of an attribute its value, so
+// that both can be searched:
+//
+//	// Elements whose text looks like an ISBN.
+//	nodes, err := xmlquery.FindByRegex(doc, "//book/*", `^97[89]-\d`)
+//	// Attributes holding an e-mail address.
+//	nodes, err = xmlquery.FindByRegex(doc, "//@*", `^[^@\s]+@[^@\s]+$`)
+//
+// Within an expression, the XPath 2.0 function matches(string, pattern)
+// does the same: "//book[matches(@isbn, '^978')]". FindByRegex returns an
+// error if expr or pattern is invalid.
+func FindByRegex(top *Node, expr, pattern string) ([]*Node, error) {
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, err
+	}
+	nodes, err := QueryAll(top, expr)
+	if err != nil {
+		return nil, err
+	}
+	var matched []*Node
+	for _, n := range nodes {
+		if re.MatchString(n.InnerText()) {
+			matched = append(matched, n)
+		}
+	}
+	return matched, nil
+}
@@ -0,0 +1,40 @@
+package xmlquery
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestFindByRegex(t *testing.T) {
+	doc, err := Parse(strings.NewReader(`<books>
+		<book isbn="978-0-13-468599-1"><title>Go</title><contact>a@example.com</contact></book>
+		<book isbn="0-201-63361-2"><title>Patterns</title><contact>none</contact></book>
+	</books>`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	nodes, err := FindByRegex(doc, "//book/@isbn", `^97[89]-`)
+	testTrue(t, err == nil)
+	testValue(t, len(nodes), 1)
+	testValue(t, nodes[0].InnerText(), "978-0-13-468599-1")
+
+	nodes, err = FindByRegex(doc, "//book/*", `^[^@\s]+@[^@\s]+$`)
+	testTrue(t, err == nil)
+	testValue(t, len(nodes), 1)
+	testValue(t, nodes[0].Data, "contact")
+
+	nodes, err = FindByRegex(doc, "//book", `(?i)patterns`)
+	testTrue(t, err == nil)
+	testValue(t, len(nodes), 1)
+	testValue(t, nodes[0].SelectAttr("isbn"), "0-201-63361-2")
+
+	// The matches() function of the expressions does the same.
+	testValue(t, len(Find(doc, "//book[matches(@isbn, '^978')]")), 1)
+
+	if _, err := FindByRegex(doc, "//book", `(`); err == nil {
+		t.Fatal("expected an error for an invalid pattern")
+	}
+	if _, err := FindByRegex(doc, "//book[", `a`); err == nil {
+		t.Fatal("expected an error for an invalid expression")
+	}
+}
@@ -0,0 +1,124 @@
+package xmlquery
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Rename renames the elements of the subtree rooted at n named oldName to
+// newName and returns how many were renamed. oldName is matched against the
+// name as written, "prefix:local", or against the namespace and local name
+// if it is written "{uri}local".
+//
+// A prefix of newName must be bound in the scope of each element. To move
+// the elements into a namespace, write newName "{uri}local": the elements
+// get a prefix bound to uri in their scope, their own if it is, or are put
+// in the default namespace otherwise. The namespace declarations the renamed
+// elements and their descendants need are added, so that the namespaces of
+// the other nodes don't change.
+//
+//	xmlquery.Rename(doc, "cust", "customer")
+//	xmlquery.Rename(doc, "customer", "{urn:acme:crm}customer")
+func Rename(n *Node, oldName, newName string) (int, error) {
+	var elems []*Node
+	var walk func(*Node)
+	walk = func(n *Node) {
+		n.Materialize()
+		if n.Type == ElementNode && elementHasName(n, oldName) {
+			elems = append(elems, n)
+		}
+		for child := n.FirstChild; child != nil; child = child.NextSibling {
+			walk(child)
+		}
+	}
+	walk(n)
+	return renameElements(elems, newName)
+}
+
+// RenameAll renames the elements selected by expr to newName, see Rename.
+// Other selected nodes are left as they are.
+func RenameAll(top *Node, expr, newName string) (int, error) {
+	nodes, err := QueryAll(top, expr)
+	if err != nil {
+		return 0, err
+	}
+	var elems []*Node
+	for _, n := range nodes {
+		if n.Type == ElementNode {
+			elems = append(elems, n)
+		}
+	}
+	return renameElements(elems, newName)
+}
+
+func renameElements(elems []*Node, newName string) (int, error) {
+	for i, n := range elems {
+		if err := renameElement(n, newName); err != nil {
+			return i, err
+		}
+	}
+	return len(elems), nil
+}
+
+// elementHasName reports whether element n has the given name, see Rename.
+func elementHasName(n *Node, name string) bool {
+	if uri, local, ok := splitClarkName(name); ok {
+		return n.NamespaceURI == uri && n.Data == local
+	}
+	return qualifiedName(n) == name
+}
+
+// splitClarkName splits a name written "{uri}local".
+func splitClarkName(name string) (uri, local string, ok bool) {
+	if !strings.HasPrefix(name, "{") {
+		return "", "", false
+	}
+	uri, local, ok = strings.Cut(name[1:], "}")
+	return uri, local, ok
+}
+
+// renameElement renames element n to name, see Rename.
+func renameElement(n *Node, name string) error {
+	scope := namespacesInScope(n)
+	var prefix, local, uri string
+	if u, l, ok := splitClarkName(name); ok {
+		local, uri = l, u
+		switch {
+		case scope[n.Prefix] == uri && (n.Prefix != "" || uri != ""):
+			prefix = n.Prefix
+		case uri != "":
+			var prefixes []string
+			for p, u := range scope {
+				if p != "" && u == uri {
+					prefixes = append(prefixes, p)
+				}
+			}
+			if len(prefixes) > 0 {
+				sort.Strings(prefixes)
+				prefix = prefixes[0]
+			}
+		}
+	} else {
+		xname := newXMLName(name)
+		prefix, local = xname.Space, xname.Local
+		var bound bool
+		if uri, bound = scope[prefix]; prefix != "" && !bound {
+			return fmt.Errorf("xmlquery: renaming <%s> to %s: prefix %s is not bound", qualifiedName(n), name, prefix)
+		}
+	}
+	if local == "" {
+		return fmt.Errorf("xmlquery: renaming <%s>: invalid name %q", qualifiedName(n), name)
+	}
+	n.Data, n.Prefix, n.NamespaceURI = local, prefix, uri
+	// A declaration of the prefix on n itself now binds it to uri;
+	// descendants that relied on it get their own below.
+	for i := range n.Attr {
+		attr := &n.Attr[i]
+		if (prefix == "" && attr.Name.Space == "" && attr.Name.Local == "xmlns") || (prefix != "" && attr.Name.Space == "xmlns" && attr.Name.Local == prefix) {
+			attr.Value = uri
+		}
+	}
+	fixNamespaces(n, namespacesInScope(n.Parent))
+	return nil
+}
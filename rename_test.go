@@ -0,0 +1,47 @@
+package xmlquery
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestRename(t *testing.T) {
+	doc, err := Parse(strings.NewReader(`<r xmlns:a="urn:a"><cust id="1"><cust/></cust><a:cust/><x/></r>`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := FindOne(doc, "/r")
+	count, err := Rename(doc, "cust", "customer")
+	testTrue(t, err == nil)
+	testValue(t, count, 2)
+	testValue(t, r.OutputXML(false), `<customer id="1"><customer></customer></customer><a:cust xmlns:a="urn:a"></a:cust><x></x>`)
+
+	count, err = Rename(doc, "{urn:a}cust", "a:customer")
+	testTrue(t, err == nil)
+	testValue(t, count, 1)
+	testValue(t, FindOne(doc, "//a:customer").NamespaceURI, "urn:a")
+
+	if _, err := Rename(doc, "x", "b:x"); err == nil {
+		t.Fatal("expected an error for an unbound prefix")
+	}
+
+	// Moving into a namespace uses a prefix bound to it, or the default
+	// namespace, which the children in no namespace are kept out of.
+	count, err = RenameAll(doc, "/r/customer", "{urn:a}client")
+	testTrue(t, err == nil)
+	testValue(t, count, 1)
+	testValue(t, r.FirstChild.Prefix, "a")
+	count, err = RenameAll(doc, "//x | //@id", "{urn:new}y")
+	testTrue(t, err == nil)
+	testValue(t, count, 1)
+	testValue(t, r.OutputXML(false), `<a:client id="1" xmlns:a="urn:a"><customer></customer></a:client><a:customer xmlns:a="urn:a"></a:customer><y xmlns="urn:new"></y>`)
+
+	doc, err = Parse(strings.NewReader(`<r xmlns="urn:old"><c/></r>`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = Rename(doc, "{urn:old}r", "{urn:new}root")
+	testTrue(t, err == nil)
+	testValue(t, FindOne(doc, "/*").OutputXML(true), `<root xmlns="urn:new"><c xmlns="urn:old"></c></root>`)
+	testValue(t, FindOne(doc, "//c").NamespaceURI, "urn:old")
+}
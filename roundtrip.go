@@ -0,0 +1,139 @@
+package xmlquery
+
+import (
+	"strings"
+)
+
+// rawNode is the source text of a node parsed with ParserOptions.RoundTrip,
+// together with the state of the node when it was parsed. As long as the
+// node still has that state, it is written out as its source text.
+type rawNode struct {
+	start  string // source of the start tag, or of the whole node if it isn't an element
+	end    string // source of the end tag; empty for <a/> and non-elements
+	data   string
+	prefix string
+	attrs  []Attr
+}
+
+// newRawNode records the source text of n.
+func newRawNode(n *Node, start string) *rawNode {
+	r := &rawNode{start: start, data: n.Data, prefix: n.Prefix}
+	if len(n.Attr) > 0 {
+		r.attrs = make([]Attr, len(n.Attr))
+		copy(r.attrs, n.Attr)
+	}
+	return r
+}
+
+// unchanged reports whether n still has the state it had when parsed.
+func (r *rawNode) unchanged(n *Node) bool {
+	if r == nil || n.Data != r.data || n.Prefix != r.prefix || len(n.Attr) != len(r.attrs) {
+		return false
+	}
+	for i := range n.Attr {
+		if n.Attr[i] != r.attrs[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// selfClosing reports whether the start tag is an empty-element tag.
+func (r *rawNode) selfClosing() bool {
+	return strings.HasSuffix(r.start, "/>")
+}
+
+// startRecording makes the parser keep the source text of every node.
+func (p *parser) startRecording() {
+	p.reader.recording = true
+	p.reader.recordedBase = p.decoder.InputOffset()
+}
+
+// stopRecording stops keeping source text, e.g. because the input is being
+// decoded from another charset and offsets no longer match the input.
+func (p *parser) stopRecording() {
+	p.reader.recording = false
+	p.reader.recorded = nil
+}
+
+// rawSource returns the input between the offsets start and end, and drops
+// the recorded input before end.
+func (p *parser) rawSource(start, end int64) (string, bool) {
+	r := p.reader
+	if !r.recording || start < r.recordedBase || end-r.recordedBase > int64(len(r.recorded)) {
+		return "", false
+	}
+	s := string(r.recorded[start-r.recordedBase : end-r.recordedBase])
+	r.recorded = append(r.recorded[:0], r.recorded[end-r.recordedBase:]...)
+	r.recordedBase = end
+	return s, true
+}
+
+// recordRaw stores the source of n, the node of the token between start and
+// end.
+func (p *parser) recordRaw(n *Node, start, end int64) {
+	if s, ok := p.rawSource(start, end); ok {
+		n.raw = newRawNode(n, s)
+	}
+}
+
+// recordRawEnd stores the source of the end tag between start and end for
+// the element closed at the current level.
+func (p *parser) recordRawEnd(start, end int64) {
+	s, ok := p.rawSource(start, end)
+	if !ok {
+		return
+	}
+	for n := p.prev; n != nil; n = n.Parent {
+		if n.Type == ElementNode && n.level == p.level {
+			if n.raw != nil {
+				n.raw.end = s
+			}
+			return
+		}
+	}
+}
+
+// writeRaw writes n as its source text if it was parsed with
+// ParserOptions.RoundTrip and hasn't changed since, and reports whether it
+// did so.
+func writeRaw(w xmlWriter, n *Node, preserveSpaces bool, config *outputConfiguration, indent *indentation) bool {
+	r := n.raw
+	if indent != nil || config.escapeAttrs || config.normalization != NoNormalization || !r.unchanged(n) ||
+		(n.Type == CharDataNode && config.cdataAsText) ||
+		(n.Type == ElementNode && config.attrOrder != AttrOrderDocument) ||
+		(n.Type == DeclarationNode && n.Data == "xml" && foreignEncoding(n.Attr, config.encodingDecl) >= 0) ||
+		(n == config.nsRoot && len(config.nsDecls) > 0) {
+		return false
+	}
+	switch n.Type {
+	case ElementNode:
+		n.Materialize()
+		if r.selfClosing() {
+			if n.FirstChild != nil {
+				return false
+			}
+			w.WriteString(r.start)
+			return true
+		}
+		w.WriteString(r.start)
+		for child := n.FirstChild; child != nil; child = child.NextSibling {
+			outputXML(w, child, preserveSpaces, config, indent)
+		}
+		if r.end != "" {
+			w.WriteString(r.end)
+		} else {
+			w.WriteString("</")
+			writeName(w, n.Prefix, n.Data)
+			w.WriteByte('>')
+		}
+	case DeclarationNode:
+		w.WriteString(r.start)
+		for child := n.FirstChild; child != nil; child = child.NextSibling {
+			outputXML(w, child, preserveSpaces, config, indent)
+		}
+	default:
+		w.WriteString(r.start)
+	}
+	return true
+}
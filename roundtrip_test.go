@@ -0,0 +1,51 @@
+package xmlquery
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestRoundTrip(t *testing.T) {
+	s := `<?xml version='1.0' encoding="UTF-8" ?>
+<!-- settings -->
+<config  version = '2'>
+	<server host="a&amp;b"   port='80'/>
+	<name>caf&#233; &lt;main&gt;</name>
+	<![CDATA[ <raw> ]]>
+	<empty></empty >
+</config>
+`
+	doc, err := ParseWithOptions(strings.NewReader(s), ParserOptions{RoundTrip: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	testValue(t, doc.OutputXML(false), s)
+
+	FindOne(doc, "//server").SetAttr("port", "8080")
+	want := strings.Replace(s, `<server host="a&amp;b"   port='80'/>`, `<server host="a&b" port="8080"></server>`, 1)
+	testValue(t, doc.OutputXML(false), want)
+
+	name := FindOne(doc, "//name")
+	name.FirstChild.Data = "bar"
+	want = strings.Replace(want, `caf&#233; &lt;main&gt;`, `bar`, 1)
+	testValue(t, doc.OutputXML(false), want)
+
+	AddChild(FindOne(doc, "//empty"), &Node{Type: TextNode, Data: "x"})
+	want = strings.Replace(want, `<empty></empty >`, `<empty>x</empty >`, 1)
+	testValue(t, doc.OutputXML(false), want)
+}
+
+func TestRoundTripNoDeclaration(t *testing.T) {
+	s := `<a><b/></a>`
+	doc, err := ParseWithOptions(strings.NewReader(s), ParserOptions{RoundTrip: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	testValue(t, doc.OutputXML(false), s)
+
+	AddChild(FindOne(doc, "//b"), &Node{Type: ElementNode, Data: "c"})
+	testValue(t, doc.OutputXML(false), `<a><b><c></c></b></a>`)
+
+	testValue(t, doc.Clone().OutputXML(false), `<a><b><c></c></b></a>`)
+	testValue(t, doc.OutputXMLWithOptions(WithIndentation(" ")), "<?xml version=\"1.0\"?>\n<a>\n <b>\n  <c></c>\n </b>\n</a>")
+}
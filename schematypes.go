@@ -0,0 +1,153 @@
+package xmlquery
+
+import (
+	"errors"
+	"math/big"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// errLexical reports text that doesn't match the lexical space of an XML
+// Schema type.
+var errLexical = errors.New("invalid lexical form")
+
+// AsInt returns the text of n as an xs:integer: digits with an optional
+// sign, and surrounding whitespace. It returns a *ValueError if the text is
+// not an integer or doesn't fit in an int64.
+func (n *Node) AsInt() (int64, error) {
+	s := strings.TrimSpace(n.InnerText())
+	digits := strings.TrimLeft(s, "+-")
+	if len(s)-len(digits) > 1 || !isDigits(digits) {
+		return 0, n.schemaValueError(s, "xs:integer", errLexical)
+	}
+	v, err := strconv.ParseInt(s, 10, 64)
+	if err != nil {
+		return 0, n.schemaValueError(s, "xs:integer", err.(*strconv.NumError).Err)
+	}
+	return v, nil
+}
+
+// AsDecimal returns the text of n as an xs:decimal, such as "-1.50" or
+// ".5", exactly. Exponents, fractions and special values like INF are not
+// decimals and return a *ValueError.
+func (n *Node) AsDecimal() (*big.Rat, error) {
+	s := strings.TrimSpace(n.InnerText())
+	num := s
+	if num != "" && (num[0] == '+' || num[0] == '-') {
+		num = num[1:]
+	}
+	whole, frac, _ := strings.Cut(num, ".")
+	if whole+frac == "" || !isDigits(whole+frac) {
+		return nil, n.schemaValueError(s, "xs:decimal", errLexical)
+	}
+	v, ok := new(big.Rat).SetString(strings.TrimPrefix(s, "+"))
+	if !ok {
+		return nil, n.schemaValueError(s, "xs:decimal", errLexical)
+	}
+	return v, nil
+}
+
+// AsBool returns the text of n as an xs:boolean: "true" or "1" for true and
+// "false" or "0" for false. Other spellings, such as "True", return a
+// *ValueError.
+func (n *Node) AsBool() (bool, error) {
+	s := strings.TrimSpace(n.InnerText())
+	switch s {
+	case "true", "1":
+		return true, nil
+	case "false", "0":
+		return false, nil
+	}
+	return false, n.schemaValueError(s, "xs:boolean", errLexical)
+}
+
+// AsTime returns the text of n as an xs:dateTime, such as
+// "2024-05-01T10:00:00.5+02:00", or an xs:date, such as "2024-05-01Z".
+// The timezone is optional; a value without one is returned in UTC. The
+// time 24:00:00 stands for the start of the next day. Years outside 0001 to
+// 9999 are not supported and return a *ValueError, like other text.
+func (n *Node) AsTime() (time.Time, error) {
+	s := strings.TrimSpace(n.InnerText())
+	t, err := parseSchemaTime(s)
+	if err != nil {
+		typ := "xs:dateTime"
+		if !strings.Contains(s, "T") {
+			typ = "xs:date"
+		}
+		return time.Time{}, n.schemaValueError(s, typ, err)
+	}
+	return t, nil
+}
+
+// parseSchemaTime parses an xs:dateTime or xs:date.
+func parseSchemaTime(s string) (time.Time, error) {
+	loc := time.UTC
+	switch {
+	case strings.HasSuffix(s, "Z"):
+		s = s[:len(s)-1]
+	case len(s) > 6 && (s[len(s)-6] == '+' || s[len(s)-6] == '-') && s[len(s)-3] == ':':
+		tz := s[len(s)-6:]
+		h, m := tz[1:3], tz[4:]
+		if !isDigits(h) || !isDigits(m) {
+			return time.Time{}, errLexical
+		}
+		hours, _ := strconv.Atoi(h)
+		minutes, _ := strconv.Atoi(m)
+		offset := hours*60 + minutes
+		if offset > 14*60 || minutes > 59 {
+			return time.Time{}, errLexical
+		}
+		if tz[0] == '-' {
+			offset = -offset
+		}
+		loc = time.FixedZone("", offset*60)
+		s = s[:len(s)-6]
+	}
+	date, clock, hasClock := strings.Cut(s, "T")
+	if len(date) != len("2006-01-02") {
+		return time.Time{}, errLexical
+	}
+	if !hasClock {
+		return time.ParseInLocation("2006-01-02", date, loc)
+	}
+	hms, frac, hasFrac := strings.Cut(clock, ".")
+	if len(hms) != len("15:04:05") || (hasFrac && !isDigits(frac)) {
+		return time.Time{}, errLexical
+	}
+	endOfDay := hms == "24:00:00"
+	if endOfDay {
+		if strings.Trim(frac, "0") != "" {
+			return time.Time{}, errLexical
+		}
+		hms = "00:00:00"
+	}
+	if hasFrac {
+		hms += "." + frac
+	}
+	t, err := time.ParseInLocation("2006-01-02T15:04:05", date+"T"+hms, loc)
+	if err != nil {
+		return time.Time{}, err
+	}
+	if endOfDay {
+		t = t.AddDate(0, 0, 1)
+	}
+	return t, nil
+}
+
+// isDigits reports whether s is a non-empty string of ASCII digits.
+func isDigits(s string) bool {
+	if s == "" {
+		return false
+	}
+	for i := 0; i < len(s); i++ {
+		if s[i] < '0' || s[i] > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+func (n *Node) schemaValueError(s, typ string, err error) error {
+	return &ValueError{Path: n.Path(), Value: s, Type: typ, Err: err}
+}
@@ -0,0 +1,84 @@
+package xmlquery
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSchemaTypedValues(t *testing.T) {
+	text := func(s string) *Node {
+		n := &Node{Type: ElementNode, Data: "v"}
+		AddChild(n, &Node{Type: TextNode, Data: s})
+		return n
+	}
+
+	for s, want := range map[string]int64{"42": 42, " -7\n": -7, "+0012": 12} {
+		if v, err := text(s).AsInt(); err != nil || v != want {
+			t.Errorf("AsInt(%q) = %d, %v, want %d", s, v, err, want)
+		}
+	}
+	for _, s := range []string{"", "1.0", "1e3", "--1", "0x10", "1_000", "99999999999999999999"} {
+		if _, err := text(s).AsInt(); err == nil {
+			t.Errorf("AsInt(%q) succeeded", s)
+		}
+	}
+
+	for s, want := range map[string]string{"1.50": "3/2", "-.5": "-1/2", "+3": "3", "2.": "2"} {
+		if v, err := text(s).AsDecimal(); err != nil || v.RatString() != want {
+			t.Errorf("AsDecimal(%q) = %v, %v, want %s", s, v, err, want)
+		}
+	}
+	for _, s := range []string{"", ".", "1e3", "1/2", "INF", "1.2.3"} {
+		if _, err := text(s).AsDecimal(); err == nil {
+			t.Errorf("AsDecimal(%q) succeeded", s)
+		}
+	}
+
+	for s, want := range map[string]bool{"true": true, " 1 ": true, "false": false, "0": false} {
+		if v, err := text(s).AsBool(); err != nil || v != want {
+			t.Errorf("AsBool(%q) = %v, %v, want %v", s, v, err, want)
+		}
+	}
+	for _, s := range []string{"True", "yes", ""} {
+		if _, err := text(s).AsBool(); err == nil {
+			t.Errorf("AsBool(%q) succeeded", s)
+		}
+	}
+
+	for s, want := range map[string]time.Time{
+		"2024-05-01T10:00:00+02:00":      time.Date(2024, 5, 1, 8, 0, 0, 0, time.UTC),
+		"2024-05-01T10:00:00.25Z":        time.Date(2024, 5, 1, 10, 0, 0, 250e6, time.UTC),
+		"2024-05-01T10:00:00":            time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC),
+		"2024-12-31T24:00:00-05:00":      time.Date(2025, 1, 1, 5, 0, 0, 0, time.UTC),
+		"2024-05-01":                     time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC),
+		"2024-05-01+01:00":               time.Date(2024, 4, 30, 23, 0, 0, 0, time.UTC),
+		"2024-05-01T10:00:00.123456789Z": time.Date(2024, 5, 1, 10, 0, 0, 123456789, time.UTC),
+	} {
+		if v, err := text(s).AsTime(); err != nil || !v.Equal(want) {
+			t.Errorf("AsTime(%q) = %v, %v, want %v", s, v, err, want)
+		}
+	}
+	for _, s := range []string{"2024-5-1", "2024-05-01T10:00", "2024-05-01 10:00:00", "2024-02-30", "2024-05-01T24:00:01", "2024-05-01T10:00:00+15:00"} {
+		if _, err := text(s).AsTime(); err == nil {
+			t.Errorf("AsTime(%q) succeeded", s)
+		}
+	}
+
+	doc, err := Parse(strings.NewReader(`<r><n at="x">12a</n></r>`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = FindOne(doc, "//n").AsInt()
+	var ve *ValueError
+	if !errors.As(err, &ve) || ve.Path != "/r[1]/n[1]" || ve.Type != "xs:integer" {
+		t.Fatalf("AsInt error = %v", err)
+	}
+	if got := err.Error(); got != `xmlquery: cannot convert "12a" at /r[1]/n[1] to xs:integer: invalid lexical form` {
+		t.Errorf("Error() = %s", got)
+	}
+	if v, err := FindOne(doc, "//n/@at").AsBool(); err == nil {
+		t.Errorf("AsBool on attribute = %v", v)
+	}
+}
@@ -0,0 +1,100 @@
+package xmlquery
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// SetByPath sets the text of the element at the slash-separated path below
+// n, creating every element of the path that doesn't exist yet, and returns
+// that element:
+//
+//	xmlquery.SetByPath(doc, "order/shipping/address/city", "Berlin")
+//	xmlquery.SetByPath(doc, "order/item[2]/@sku", "B-1")
+//
+// A step selects the first child element with that name, or the nth one
+// with name[n], counting from 1; missing elements are appended. Names are
+// compared like in queries, with their prefix; new elements get the
+// namespace their prefix, or the default namespace, is bound to. A final
+// @name step, which may also be written name@attr, sets an attribute
+// instead of the text. Setting the text replaces all children of the
+// element. When n is a document, the first step names its root element.
+func SetByPath(n *Node, path, value string) (*Node, error) {
+	path, attr, hasAttr := strings.Cut(path, "@")
+	path = strings.TrimSuffix(path, "/")
+	if hasAttr && (attr == "" || strings.Contains(attr, "/")) {
+		return nil, fmt.Errorf("xmlquery: invalid path %q", path+"@"+attr)
+	}
+	elem := n
+	if path != "" {
+		for _, step := range strings.Split(path, "/") {
+			next, err := childByStep(elem, step)
+			if err != nil {
+				return nil, err
+			}
+			elem = next
+		}
+	}
+	if elem.Type != ElementNode {
+		return nil, fmt.Errorf("xmlquery: path %q does not select an element", path)
+	}
+	if hasAttr {
+		elem.SetAttr(attr, value)
+		return elem, nil
+	}
+	elem.Materialize()
+	for child := elem.FirstChild; child != nil; child = elem.FirstChild {
+		RemoveFromTree(child)
+	}
+	if value != "" {
+		AddChild(elem, &Node{Type: TextNode, Data: value, level: elem.level + 1})
+	}
+	return elem, nil
+}
+
+// childByStep returns the child element of parent that a SetByPath step
+// selects, creating it if needed.
+func childByStep(parent *Node, step string) (*Node, error) {
+	name, pos := step, 1
+	if i := strings.IndexByte(step, '['); i >= 0 && strings.HasSuffix(step, "]") {
+		n, err := strconv.Atoi(step[i+1 : len(step)-1])
+		if err != nil || n < 1 {
+			return nil, fmt.Errorf("xmlquery: invalid path step %q", step)
+		}
+		name, pos = step[:i], n
+	}
+	if name == "" || strings.ContainsAny(name, "[]@*() \t\r\n") {
+		return nil, fmt.Errorf("xmlquery: invalid path step %q", step)
+	}
+	xname := newXMLName(name)
+	parent.Materialize()
+	count := 0
+	for child := parent.FirstChild; child != nil; child = child.NextSibling {
+		if child.Type != ElementNode {
+			continue
+		}
+		if child.Data == xname.Local && child.Prefix == xname.Space {
+			if count++; count == pos {
+				return child, nil
+			}
+		} else if parent.Type == DocumentNode {
+			return nil, fmt.Errorf("xmlquery: document already has root element %s", qualifiedName(child))
+		}
+	}
+	if parent.Type == DocumentNode && count > 0 {
+		return nil, fmt.Errorf("xmlquery: document can only have one root element")
+	}
+	var elem *Node
+	for ; count < pos; count++ {
+		elem = &Node{
+			Type:         ElementNode,
+			Data:         xname.Local,
+			Prefix:       xname.Space,
+			NamespaceURI: namespacesInScope(parent)[xname.Space],
+			level:        parent.level + 1,
+		}
+		AddChild(parent, elem)
+	}
+	return elem, nil
+}
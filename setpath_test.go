@@ -0,0 +1,54 @@
+package xmlquery
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestSetByPath(t *testing.T) {
+	doc := &Node{Type: DocumentNode}
+	city, err := SetByPath(doc, "order/shipping/address/city", "Berlin")
+	if err != nil {
+		t.Fatal(err)
+	}
+	testValue(t, city.Data, "city")
+	if _, err := SetByPath(doc, "order/shipping/address/zip", "10115"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := SetByPath(doc, "order/shipping/address/city", "Hamburg"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := SetByPath(doc, "order/item[2]/@sku", "B-1"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := SetByPath(doc, "order@id", "7"); err != nil {
+		t.Fatal(err)
+	}
+	testValue(t, doc.OutputXML(false), `<order id="7"><shipping><address><city>Hamburg</city><zip>10115</zip></address></shipping><item></item><item sku="B-1"></item></order>`)
+	verifyNodePointers(t, doc)
+	testValue(t, FindOne(doc, "//zip").Level(), 4)
+
+	for _, path := range []string{"other/x", "order//x", "order/item[0]", "order/item[x]", "order/@", "order@a/b", "order/*"} {
+		if _, err := SetByPath(doc, path, "v"); err == nil {
+			t.Errorf("SetByPath(%q) should fail", path)
+		}
+	}
+}
+
+func TestSetByPathNamespaces(t *testing.T) {
+	doc, err := Parse(strings.NewReader(`<o:order xmlns:o="urn:o" xmlns="urn:d"><o:note>old<b/></o:note></o:order>`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := SetByPath(doc, "o:order/o:note", "new"); err != nil {
+		t.Fatal(err)
+	}
+	qty, err := SetByPath(doc, "o:order/o:line/qty", "2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	testValue(t, qty.NamespaceURI, "urn:d")
+	testValue(t, qty.Parent.NamespaceURI, "urn:o")
+	testValue(t, doc.SelectElement("o:order").OutputXML(false), `<o:note xmlns:o="urn:o">new</o:note><o:line xmlns="urn:d" xmlns:o="urn:o"><qty>2</qty></o:line>`)
+	testValue(t, len(Find(doc, "//o:note/*")), 0)
+}
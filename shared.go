@@ -0,0 +1,116 @@
+package xmlquery
+
+import (
+	"sync"
+	"sync/atomic"
+)
+
+// Clone returns a deep copy of the subtree rooted at n. The copy has no
+// parent or siblings; indexes and the ID table of n are not copied. Its
+// names match case-insensitively if those of n do.
+func (n *Node) Clone() *Node {
+	clone := n.clone()
+	if n.foldsCase() {
+		clone.docData().foldCase = true
+	}
+	return clone
+}
+
+func (n *Node) clone() *Node {
+	n.Materialize()
+	clone := &Node{
+		Type:         n.Type,
+		Data:         n.Data,
+		Prefix:       n.Prefix,
+		NamespaceURI: n.NamespaceURI,
+		level:        n.level,
+		raw:          n.raw,
+	}
+	if n.Attr != nil {
+		clone.Attr = make([]Attr, len(n.Attr))
+		copy(clone.Attr, n.Attr)
+	}
+	for child := n.FirstChild; child != nil; child = child.NextSibling {
+		AddChild(clone, child.clone())
+	}
+	return clone
+}
+
+// Snapshot returns a copy of the tree of n that is ready to be shared by
+// goroutines that only read it, while the original continues to be edited.
+// Unlike Clone, it also carries the document URI and the keys declared
+// with DeclareKey, and builds the ID table up front, so that no read,
+// including GetElementByID, modifies the snapshot. The snapshot must not be
+// modified; take a new one instead.
+func (n *Node) Snapshot() *Node {
+	s := n.Clone()
+	s.freeze(n)
+	return s
+}
+
+// freeze prepares the tree rooted at n, a copy of the tree of src, for
+// concurrent reads.
+func (n *Node) freeze(src *Node) {
+	for src.Parent != nil {
+		src = src.Parent
+	}
+	n.RebuildIDs()
+	n.doc.uri = src.DocumentURI()
+	if src.doc == nil {
+		return
+	}
+	n.doc.foldCase = src.doc.foldCase
+	for name, indexes := range src.doc.keys {
+		for _, idx := range indexes {
+			// The expressions were valid for src already.
+			_ = n.DeclareKey(name, idx.Match, idx.Use)
+		}
+	}
+}
+
+// A SharedDocument lets many goroutines query a document while another
+// goroutine modifies it, using copy-on-write: readers get an immutable
+// snapshot from Load, and Update applies changes to a private copy that then
+// atomically replaces the snapshot. Readers holding an older snapshot are
+// not affected by the update. Updates copy the whole document, so they
+// suit documents that are read much more often than they change.
+type SharedDocument struct {
+	mu  sync.Mutex // serializes writers
+	cur atomic.Value
+}
+
+// NewSharedDocument creates a SharedDocument whose first snapshot is doc.
+// The caller must not modify doc afterwards.
+func NewSharedDocument(doc *Node) *SharedDocument {
+	doc.freeze(doc)
+	d := &SharedDocument{}
+	d.cur.Store(doc)
+	return d
+}
+
+// Load returns the current snapshot. The returned tree must only be read.
+func (d *SharedDocument) Load() *Node {
+	return d.cur.Load().(*Node)
+}
+
+// Update calls fn with a copy of the current snapshot. If fn returns nil,
+// the modified copy becomes the new snapshot; otherwise it is discarded and
+// the error is returned. Concurrent calls to Update are serialized.
+//
+// Each call copies the whole document, and rebuilds its ID table and the
+// keys declared on it, however small the change: the cost is O(document)
+// in time and memory. Untouched subtrees can't be shared with the previous
+// snapshot, since every node points to its parent. Batch changes into one
+// call rather than calling Update for each of them.
+func (d *SharedDocument) Update(fn func(doc *Node) error) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	cur := d.Load()
+	doc := cur.Clone()
+	if err := fn(doc); err != nil {
+		return err
+	}
+	doc.freeze(cur)
+	d.cur.Store(doc)
+	return nil
+}
@@ -0,0 +1,124 @@
+package xmlquery
+
+import (
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/suifengpiao14/xmlquery/xml"
+)
+
+func TestClone(t *testing.T) {
+	doc, err := Parse(strings.NewReader(`<?xml version="1.0"?><a x="1"><b>text</b><!--c--><d></d></a>`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	clone := doc.Clone()
+	verifyNodePointers(t, clone)
+	testValue(t, clone.OutputXML(false), doc.OutputXML(false))
+
+	FindOne(clone, "//a").SetAttr("x", "2")
+	RemoveFromTree(FindOne(clone, "//b"))
+	testValue(t, FindOne(doc, "//a").SelectAttr("x"), "1")
+	testTrue(t, FindOne(doc, "//b") != nil)
+}
+
+func TestSharedDocument(t *testing.T) {
+	doc, err := Parse(strings.NewReader(`<list><item>1</item></list>`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	shared := NewSharedDocument(doc)
+	before := shared.Load()
+
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 50; j++ {
+				if len(Find(shared.Load(), "//item")) == 0 {
+					t.Error("expected at least one item")
+					return
+				}
+			}
+		}()
+	}
+	for i := 0; i < 10; i++ {
+		err := shared.Update(func(doc *Node) error {
+			AddChild(FindOne(doc, "//list"), &Node{Type: ElementNode, Data: "item"})
+			return nil
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	wg.Wait()
+
+	testValue(t, len(Find(before, "//item")), 1)
+	testValue(t, len(Find(shared.Load(), "//item")), 11)
+
+	errStop := errors.New("stop")
+	err = shared.Update(func(doc *Node) error {
+		RemoveFromTree(FindOne(doc, "//list"))
+		return errStop
+	})
+	testValue(t, err, errStop)
+	testValue(t, len(Find(shared.Load(), "//item")), 11)
+}
+
+func TestSnapshot(t *testing.T) {
+	doc, err := Parse(strings.NewReader(`<list><item xml:id="a">1</item></list>`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	doc.SetDocumentURI("http://example.org/list.xml")
+	snap := doc.Snapshot()
+	testValue(t, snap.DocumentURI(), "http://example.org/list.xml")
+
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				if snap.GetElementByID("a") == nil || len(Find(snap, "//item")) != 1 {
+					t.Error("snapshot changed")
+					return
+				}
+			}
+		}()
+	}
+	for j := 0; j < 100; j++ {
+		AddChild(FindOne(doc, "//list"), &Node{Type: ElementNode, Data: "item"})
+	}
+	wg.Wait()
+	testValue(t, len(Find(doc, "//item")), 101)
+	testValue(t, len(Find(snap, "//item")), 1)
+}
+
+func TestSnapshotDocumentSettings(t *testing.T) {
+	doc, err := ParseWithOptions(strings.NewReader(`<List><Item id="a"/><ITEM id="b"/></List>`), ParserOptions{CaseInsensitiveNames: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := doc.DeclareKey("item", "item", "@id"); err != nil {
+		t.Fatal(err)
+	}
+	testValue(t, len(Find(doc.Clone(), "//item")), 2)
+	snap := doc.Snapshot()
+	testValue(t, len(Find(snap, "//item")), 2)
+	testValue(t, len(Find(snap, "key('item', 'b')")), 1)
+
+	shared := NewSharedDocument(doc)
+	err = shared.Update(func(doc *Node) error {
+		AddChild(FindOne(doc, "/list"), &Node{Type: ElementNode, Data: "item", Attr: []Attr{{Name: xml.Name{Local: "id"}, Value: "c"}}})
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	testValue(t, len(Find(shared.Load(), "//ITEM")), 3)
+	testValue(t, len(Find(shared.Load(), "key('item', 'c')")), 1)
+}
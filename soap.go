@@ -0,0 +1,194 @@
+package xmlquery
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// SOAPVersion is the version of a SOAP envelope.
+type SOAPVersion int
+
+const (
+	// SOAP11 is SOAP 1.1.
+	SOAP11 SOAPVersion = iota + 1
+	// SOAP12 is SOAP 1.2.
+	SOAP12
+)
+
+const (
+	// SOAP11EnvelopeNS is the envelope namespace of SOAP 1.1.
+	SOAP11EnvelopeNS = "http://schemas.xmlsoap.org/soap/envelope/"
+	// SOAP12EnvelopeNS is the envelope namespace of SOAP 1.2.
+	SOAP12EnvelopeNS = "http://www.w3.org/2003/05/soap-envelope"
+)
+
+// ErrNotSOAPEnvelope is returned when a document is not a SOAP envelope.
+var ErrNotSOAPEnvelope = errors.New("xmlquery: not a SOAP envelope")
+
+func (v SOAPVersion) namespace() string {
+	if v == SOAP12 {
+		return SOAP12EnvelopeNS
+	}
+	return SOAP11EnvelopeNS
+}
+
+func (v SOAPVersion) String() string {
+	switch v {
+	case SOAP11:
+		return "SOAP 1.1"
+	case SOAP12:
+		return "SOAP 1.2"
+	}
+	return fmt.Sprintf("SOAPVersion(%d)", int(v))
+}
+
+// A SOAPFault is a SOAP Fault element decoded from a response body. It is
+// returned as the error of SOAPBody.
+type SOAPFault struct {
+	Version SOAPVersion
+	Code    string // faultcode (1.1) or Code/Value (1.2)
+	Subcode string // Code/Subcode/Value (1.2 only)
+	Reason  string // faultstring (1.1) or the first Reason/Text (1.2)
+	Actor   string // faultactor (1.1) or Role (1.2)
+	Node    string // Node (1.2 only)
+	Detail  *Node  // detail (1.1) or Detail (1.2) element, if present
+}
+
+func (f *SOAPFault) Error() string {
+	code := f.Code
+	if f.Subcode != "" {
+		code += "/" + f.Subcode
+	}
+	return fmt.Sprintf("soap fault: %s: %s", code, f.Reason)
+}
+
+// NewSOAPEnvelope returns a new document containing a SOAP envelope of the
+// given version, with payload as the content of its Body and headers as the
+// content of its Header. The Header element is omitted if there are no
+// headers. The payload and header nodes are moved into the envelope; if
+// payload is a document, its document element is used.
+func NewSOAPEnvelope(version SOAPVersion, payload *Node, headers ...*Node) *Node {
+	ns := version.namespace()
+	newElement := func(name string) *Node {
+		return &Node{Type: ElementNode, Data: name, Prefix: "soap", NamespaceURI: ns}
+	}
+	doc := &Node{Type: DocumentNode}
+	decl := &Node{Type: DeclarationNode, Data: "xml"}
+	decl.SetAttr("version", "1.0")
+	decl.SetAttr("encoding", "UTF-8")
+	AddChild(doc, decl)
+
+	envelope := newElement("Envelope")
+	envelope.SetAttr("xmlns:soap", ns)
+	AddChild(doc, envelope)
+	if len(headers) > 0 {
+		header := newElement("Header")
+		for _, h := range headers {
+			RemoveFromTree(h)
+			AddChild(header, h)
+		}
+		AddChild(envelope, header)
+	}
+	body := newElement("Body")
+	AddChild(envelope, body)
+	if payload != nil {
+		if payload.Type == DocumentNode {
+			payload = firstChildElement(payload)
+		}
+		if payload != nil {
+			RemoveFromTree(payload)
+			AddChild(body, payload)
+		}
+	}
+	return doc
+}
+
+// SOAPEnvelopeVersion returns the SOAP version of the envelope in doc, or
+// ErrNotSOAPEnvelope if the document element is not a SOAP envelope.
+func SOAPEnvelopeVersion(doc *Node) (SOAPVersion, error) {
+	envelope := doc
+	if envelope.Type == DocumentNode {
+		envelope = firstChildElement(doc)
+	}
+	if envelope == nil || envelope.Data != "Envelope" {
+		return 0, ErrNotSOAPEnvelope
+	}
+	switch envelope.NamespaceURI {
+	case SOAP11EnvelopeNS:
+		return SOAP11, nil
+	case SOAP12EnvelopeNS:
+		return SOAP12, nil
+	}
+	return 0, ErrNotSOAPEnvelope
+}
+
+// SOAPBody returns the Body element of the SOAP envelope in doc. If the body
+// contains a Fault, the fault is decoded and returned as a *SOAPFault error
+// along with the body.
+func SOAPBody(doc *Node) (*Node, error) {
+	version, err := SOAPEnvelopeVersion(doc)
+	if err != nil {
+		return nil, err
+	}
+	envelope := doc
+	if envelope.Type == DocumentNode {
+		envelope = firstChildElement(doc)
+	}
+	ns := version.namespace()
+	body := childElementNS(envelope, ns, "Body")
+	if body == nil {
+		return nil, fmt.Errorf("xmlquery: %s envelope has no Body", version)
+	}
+	if fault := childElementNS(body, ns, "Fault"); fault != nil {
+		return body, decodeSOAPFault(version, fault)
+	}
+	return body, nil
+}
+
+// SOAPPayload returns the first element inside the Body of the SOAP
+// envelope in doc. Errors are reported as by SOAPBody.
+func SOAPPayload(doc *Node) (*Node, error) {
+	body, err := SOAPBody(doc)
+	if body == nil || err != nil {
+		return nil, err
+	}
+	for c := body.FirstChild; c != nil; c = c.NextSibling {
+		if c.Type == ElementNode {
+			return c, nil
+		}
+	}
+	return nil, nil
+}
+
+func decodeSOAPFault(version SOAPVersion, fault *Node) *SOAPFault {
+	f := &SOAPFault{Version: version}
+	text := func(n *Node) string {
+		if n == nil {
+			return ""
+		}
+		return strings.TrimSpace(n.InnerText())
+	}
+	if version == SOAP11 {
+		// The children of a SOAP 1.1 fault are unqualified.
+		f.Code = text(childElementNS(fault, "", "faultcode"))
+		f.Reason = text(childElementNS(fault, "", "faultstring"))
+		f.Actor = text(childElementNS(fault, "", "faultactor"))
+		f.Detail = childElementNS(fault, "", "detail")
+		return f
+	}
+	ns := version.namespace()
+	if code := childElementNS(fault, ns, "Code"); code != nil {
+		f.Code = text(childElementNS(code, ns, "Value"))
+		if sub := childElementNS(code, ns, "Subcode"); sub != nil {
+			f.Subcode = text(childElementNS(sub, ns, "Value"))
+		}
+	}
+	if reason := childElementNS(fault, ns, "Reason"); reason != nil {
+		f.Reason = text(childElementNS(reason, ns, "Text"))
+	}
+	f.Node = text(childElementNS(fault, ns, "Node"))
+	f.Actor = text(childElementNS(fault, ns, "Role"))
+	f.Detail = childElementNS(fault, ns, "Detail")
+	return f
+}
@@ -0,0 +1,98 @@
+package xmlquery
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestNewSOAPEnvelope(t *testing.T) {
+	payload, err := Parse(strings.NewReader(`<GetPrice xmlns="urn:shop"><Item>Apple</Item></GetPrice>`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	header := &Node{Type: ElementNode, Data: "Token"}
+	AddChild(header, &Node{Type: TextNode, Data: "abc"})
+
+	doc := NewSOAPEnvelope(SOAP11, payload, header)
+	testValue(t, doc.OutputXML(false), `<?xml version="1.0" encoding="UTF-8"?><soap:Envelope xmlns:soap="http://schemas.xmlsoap.org/soap/envelope/"><soap:Header><Token>abc</Token></soap:Header><soap:Body><GetPrice xmlns="urn:shop"><Item>Apple</Item></GetPrice></soap:Body></soap:Envelope>`)
+
+	// The generated envelope can be read back.
+	parsed, err := Parse(strings.NewReader(doc.OutputXML(false)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	n, err := SOAPPayload(parsed)
+	if err != nil {
+		t.Fatal(err)
+	}
+	testValue(t, n.Data, "GetPrice")
+
+	doc = NewSOAPEnvelope(SOAP12, nil)
+	testValue(t, doc.OutputXML(false), `<?xml version="1.0" encoding="UTF-8"?><soap:Envelope xmlns:soap="http://www.w3.org/2003/05/soap-envelope"><soap:Body></soap:Body></soap:Envelope>`)
+}
+
+func TestSOAPBodyFault11(t *testing.T) {
+	s := `<?xml version="1.0"?>
+<s:Envelope xmlns:s="http://schemas.xmlsoap.org/soap/envelope/">
+  <s:Body>
+    <s:Fault>
+      <faultcode>s:Client</faultcode>
+      <faultstring>Invalid item</faultstring>
+      <detail><code>42</code></detail>
+    </s:Fault>
+  </s:Body>
+</s:Envelope>`
+	doc, err := Parse(strings.NewReader(s))
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, err := SOAPBody(doc)
+	testTrue(t, body != nil)
+	var fault *SOAPFault
+	if !errors.As(err, &fault) {
+		t.Fatalf("expected a *SOAPFault, got %v", err)
+	}
+	testValue(t, fault.Version, SOAP11)
+	testValue(t, fault.Code, "s:Client")
+	testValue(t, fault.Reason, "Invalid item")
+	testValue(t, fault.Detail.SelectElement("code").InnerText(), "42")
+	testValue(t, fault.Error(), "soap fault: s:Client: Invalid item")
+}
+
+func TestSOAPBodyFault12(t *testing.T) {
+	s := `<env:Envelope xmlns:env="http://www.w3.org/2003/05/soap-envelope">
+  <env:Body>
+    <env:Fault>
+      <env:Code><env:Value>env:Sender</env:Value><env:Subcode><env:Value>m:Limit</env:Value></env:Subcode></env:Code>
+      <env:Reason><env:Text xml:lang="en">Too many requests</env:Text></env:Reason>
+      <env:Role>urn:gateway</env:Role>
+    </env:Fault>
+  </env:Body>
+</env:Envelope>`
+	doc, err := Parse(strings.NewReader(s))
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = SOAPBody(doc)
+	var fault *SOAPFault
+	if !errors.As(err, &fault) {
+		t.Fatalf("expected a *SOAPFault, got %v", err)
+	}
+	testValue(t, fault.Version, SOAP12)
+	testValue(t, fault.Code, "env:Sender")
+	testValue(t, fault.Subcode, "m:Limit")
+	testValue(t, fault.Reason, "Too many requests")
+	testValue(t, fault.Actor, "urn:gateway")
+	testTrue(t, fault.Detail == nil)
+}
+
+func TestSOAPBodyNotEnvelope(t *testing.T) {
+	doc, err := Parse(strings.NewReader(`<Envelope><Body></Body></Envelope>`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := SOAPBody(doc); err != ErrNotSOAPEnvelope {
+		t.Fatalf("expected ErrNotSOAPEnvelope, got %v", err)
+	}
+}
@@ -0,0 +1,103 @@
+package xmlquery
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// Split breaks a document into standalone documents, one for each element
+// selected by the XPath expr. Each document has an XML declaration and a
+// copy of the matched element as its root, with the namespace declarations
+// it relied on from its ancestors copied in. doc is not modified.
+//
+//	msgs, err := xmlquery.Split(batch, "//record")
+//	if err != nil {
+//		return err
+//	}
+//	for _, msg := range msgs {
+//		queue.Publish(msg.OutputXML(false))
+//	}
+func Split(doc *Node, expr string) ([]*Node, error) {
+	exp, err := getQueryFor(doc, expr)
+	if err != nil {
+		return nil, err
+	}
+	var docs []*Node
+	for _, n := range QuerySelectorAll(doc, exp) {
+		if n.Type != ElementNode {
+			continue
+		}
+		docs = append(docs, standaloneDocument(n))
+	}
+	return docs, nil
+}
+
+// standaloneDocument returns a new document whose root element is a copy
+// of element n.
+func standaloneDocument(n *Node) *Node {
+	doc := &Node{Type: DocumentNode}
+	decl := &Node{Type: DeclarationNode, Data: "xml"}
+	decl.SetAttr("version", "1.0")
+	AddChild(doc, decl)
+	root := n.Clone()
+	shiftLevel(root, 1-root.level)
+	AddChild(doc, root)
+	fixNamespaces(root, namespacesInScope(doc))
+	return doc
+}
+
+// SplitToWriters is the streaming form of Split: it reads the document from
+// r with a StreamParser and writes each element selected by the XPath expr
+// as a standalone document to the writer returned by sink for its index,
+// counted from 0, then closes the writer. Only the element being written is
+// kept in memory, so that large files can be sharded at constant memory.
+// The documents are written with opts, and have an XML declaration unless
+// WithoutXMLDeclaration is given. If sink returns nil, the element is
+// skipped. SplitToWriters returns the number of documents written.
+//
+//	var createErr error
+//	n, err := xmlquery.SplitToWriters(f, "/batch/record", func(i int) io.WriteCloser {
+//		out, err := os.Create(fmt.Sprintf("record-%05d.xml", i))
+//		if err != nil {
+//			createErr = err
+//			return nil
+//		}
+//		return out
+//	})
+func SplitToWriters(r io.Reader, expr string, sink func(i int) io.WriteCloser, opts ...OutputOption) (int, error) {
+	sp, err := CreateStreamParser(r, expr)
+	if err != nil {
+		return 0, err
+	}
+	written := 0
+	for i := 0; ; i++ {
+		n, err := sp.Read()
+		if errors.Is(err, io.EOF) {
+			return written, nil
+		}
+		if err != nil {
+			return written, err
+		}
+		w := sink(i)
+		if w != nil {
+			if err := writeStandalone(w, standaloneDocument(n), opts); err != nil {
+				return written, fmt.Errorf("xmlquery: writing element %d: %w", i, err)
+			}
+			written++
+		}
+		sp.Release(n)
+	}
+}
+
+// writeStandalone writes doc to w and closes it.
+func writeStandalone(w io.WriteCloser, doc *Node, opts []OutputOption) error {
+	b := bufio.NewWriter(w)
+	doc.writeTo(b, opts)
+	err := b.Flush()
+	if cerr := w.Close(); err == nil {
+		err = cerr
+	}
+	return err
+}
@@ -0,0 +1,93 @@
+package xmlquery
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestSplit(t *testing.T) {
+	s := `<batch xmlns="urn:batch" xmlns:m="urn:meta"><header/><record id="1"><m:ts>1</m:ts></record><record id="2"/></batch>`
+	doc, err := Parse(strings.NewReader(s))
+	if err != nil {
+		t.Fatal(err)
+	}
+	docs, err := Split(doc, "//record")
+	if err != nil {
+		t.Fatal(err)
+	}
+	testValue(t, len(docs), 2)
+	testValue(t, docs[0].OutputXML(false), `<?xml version="1.0"?><record id="1" xmlns="urn:batch"><m:ts xmlns:m="urn:meta">1</m:ts></record>`)
+	testValue(t, docs[1].OutputXML(false), `<?xml version="1.0"?><record id="2" xmlns="urn:batch"></record>`)
+	testValue(t, docs[0].SelectElement("record").Level(), 1)
+	verifyNodePointers(t, docs[0])
+
+	testValue(t, len(Find(doc, "//record")), 2)
+	again, err := Parse(strings.NewReader(docs[0].OutputXML(false)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	testValue(t, FindOne(again, "//m:ts").NamespaceURI, "urn:meta")
+
+	if _, err := Split(doc, "//record["); err == nil {
+		t.Fatal("expected error for invalid expression")
+	}
+}
+
+// closeBuffer is a buffer recording whether it was closed.
+type closeBuffer struct {
+	bytes.Buffer
+	closed bool
+}
+
+func (b *closeBuffer) Close() error {
+	b.closed = true
+	return nil
+}
+
+type failingWriter struct{}
+
+func (failingWriter) Write([]byte) (int, error) { return 0, errors.New("disk full") }
+func (failingWriter) Close() error              { return nil }
+
+func TestSplitToWriters(t *testing.T) {
+	s := `<batch xmlns="urn:batch" xmlns:m="urn:meta"><header/><record id="1"><m:ts>1</m:ts></record><record id="2"/><record id="3"/></batch>`
+	var outs []*closeBuffer
+	n, err := SplitToWriters(strings.NewReader(s), "/batch/record", func(i int) io.WriteCloser {
+		outs = append(outs, &closeBuffer{})
+		if i == 1 {
+			return nil
+		}
+		return outs[i]
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	testValue(t, n, 2)
+	testValue(t, len(outs), 3)
+	testValue(t, outs[0].String(), `<?xml version="1.0"?><record id="1" xmlns="urn:batch"><m:ts xmlns:m="urn:meta">1</m:ts></record>`)
+	testTrue(t, outs[0].closed)
+	testValue(t, outs[1].Len(), 0)
+	testValue(t, outs[2].String(), `<?xml version="1.0"?><record id="3" xmlns="urn:batch"></record>`)
+
+	var out closeBuffer
+	n, err = SplitToWriters(strings.NewReader(s), "//m:ts", func(i int) io.WriteCloser { return &out }, WithoutXMLDeclaration())
+	if err != nil {
+		t.Fatal(err)
+	}
+	testValue(t, n, 1)
+	testValue(t, out.String(), `<m:ts xmlns:m="urn:meta">1</m:ts>`)
+
+	n, err = SplitToWriters(strings.NewReader(s), "/batch/record", func(i int) io.WriteCloser { return failingWriter{} })
+	testTrue(t, err != nil && strings.Contains(err.Error(), "disk full"))
+	testValue(t, n, 0)
+
+	if _, err := SplitToWriters(strings.NewReader(s), "//record[", nil); err == nil {
+		t.Fatal("expected error for invalid expression")
+	}
+	if _, err := SplitToWriters(strings.NewReader(`<batch><record>`), "//record", func(i int) io.WriteCloser { return &closeBuffer{} }); err == nil {
+		t.Fatal("expected error for truncated input")
+	}
+}
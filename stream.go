@@ -0,0 +1,56 @@
+package xmlquery
+
+import (
+	"context"
+	"sync"
+)
+
+// releasedNodes are the nodes given back by StreamParser.Release, for the
+// stream parsers to reuse.
+var releasedNodes = sync.Pool{New: func() interface{} { return new(Node) }}
+
+// ReadNext is like Read, but stops with ctx.Err() once ctx is done. The
+// context is checked between tokens, so a read blocked on the input is
+// only interrupted by the reader itself. After ReadNext fails because of
+// the context, the parser can't be used anymore.
+func (sp *StreamParser) ReadNext(ctx context.Context) (*Node, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	sp.p.ctx = ctx
+	defer func() { sp.p.ctx = nil }()
+	return sp.Read()
+}
+
+// Release removes n, a target node returned by Read or ReadNext, from the
+// node tree if it is still there and recycles the nodes of its subtree for
+// the next records, so that long-running streams allocate little memory.
+// Neither n nor any node of its subtree may be used after Release; copy
+// the values to keep, such as with InnerText, before calling it.
+//
+//	for {
+//		n, err := sp.Read()
+//		if err != nil {
+//			break
+//		}
+//		process(n)
+//		sp.Release(n)
+//	}
+func (sp *StreamParser) Release(n *Node) {
+	if n == sp.p.streamNode {
+		sp.removeRecord()
+	} else if n.Parent != nil {
+		RemoveFromTree(n)
+	}
+	var release func(*Node)
+	release = func(n *Node) {
+		for child := n.FirstChild; child != nil; {
+			next := child.NextSibling
+			release(child)
+			child = next
+		}
+		*n = Node{}
+		releasedNodes.Put(n)
+	}
+	release(n)
+}
@@ -0,0 +1,60 @@
+package xmlquery
+
+import (
+	"context"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestStreamParserReadNext(t *testing.T) {
+	s := `<list><item>1</item><item>2</item></list>`
+	sp, err := CreateStreamParser(strings.NewReader(s), "/list/item")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	n, err := sp.ReadNext(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	testValue(t, n.InnerText(), "1")
+	cancel()
+	_, err = sp.ReadNext(ctx)
+	testTrue(t, errors.Is(err, context.Canceled))
+}
+
+func TestStreamParserRelease(t *testing.T) {
+	s := `<list><item id="1"><v>a</v></item> <item id="2"><v>b</v></item><item id="3"><v>c</v></item></list>`
+	sp, err := CreateStreamParser(strings.NewReader(s), "/list/item")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	var released []*Node
+	for {
+		n, err := sp.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		got = append(got, n.SelectAttr("id")+"="+FindOne(n, "v").InnerText())
+		if n.SelectAttr("id") == "2" {
+			// Released after the next record replaced it.
+			released = append(released, n)
+			continue
+		}
+		list := n.Parent
+		sp.Release(n)
+		testTrue(t, n.Parent == nil && n.Data == "" && n.FirstChild == nil)
+		testTrue(t, list.LastChild == nil || list.LastChild.Type != ElementNode)
+		for _, n := range released {
+			sp.Release(n)
+		}
+		released = nil
+	}
+	testValue(t, strings.Join(got, ","), "1=a,2=b,3=c")
+}
@@ -0,0 +1,343 @@
+package xmlquery
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/suifengpiao14/xmlquery/xml"
+	"golang.org/x/net/html/charset"
+)
+
+// A StreamMatcher selects elements from a token stream without building
+// the document: only the elements it matches are turned into nodes. It
+// understands a subset of XPath, an absolute location path made of child
+// (/) and descendant (//) steps whose node tests are names or *, each with
+// optional attribute predicates:
+//
+//	/feed/entry
+//	//item[@type='book']/title
+//	//*[@id][@lang!='en' and @draft]
+//
+// Names are matched as written in the document, prefix included, like
+// queries compiled without namespaces. A StreamMatcher may be used by
+// several goroutines at once.
+type StreamMatcher struct {
+	expr  string
+	steps []matchStep
+}
+
+type matchStep struct {
+	descendant bool
+	name       string // "prefix:local" or "*"
+	preds      []attrPred
+}
+
+// attrPred tests an attribute: its presence if op is "", otherwise its
+// value with "=" or "!=".
+type attrPred struct {
+	name, op, value string
+}
+
+// NewStreamMatcher compiles expr for Match. It returns an error if expr is
+// outside the supported subset.
+func NewStreamMatcher(expr string) (*StreamMatcher, error) {
+	m := &StreamMatcher{expr: expr}
+	s := strings.TrimSpace(expr)
+	if !strings.HasPrefix(s, "/") {
+		return nil, m.errorf("expression must be an absolute path")
+	}
+	for s != "" {
+		var step matchStep
+		switch {
+		case strings.HasPrefix(s, "//"):
+			step.descendant = true
+			s = s[2:]
+		case s[0] == '/':
+			s = s[1:]
+		default:
+			return nil, m.errorf("unexpected %q", s)
+		}
+		end := strings.IndexAny(s, "/[")
+		if end < 0 {
+			end = len(s)
+		}
+		step.name = strings.TrimSpace(s[:end])
+		if step.name == "" || strings.ContainsAny(step.name, " \t\r\n()@=!'\"]") || strings.Contains(step.name, "::") {
+			return nil, m.errorf("unsupported step %q", s[:end])
+		}
+		s = s[end:]
+		for strings.HasPrefix(s, "[") {
+			end := strings.IndexByte(s, ']')
+			if end < 0 {
+				return nil, m.errorf("missing ]")
+			}
+			for _, cond := range strings.Split(s[1:end], " and ") {
+				pred, ok := parseAttrPred(strings.TrimSpace(cond))
+				if !ok {
+					return nil, m.errorf("unsupported predicate [%s]", s[1:end])
+				}
+				step.preds = append(step.preds, pred)
+			}
+			s = strings.TrimLeft(s[end+1:], " \t\r\n")
+		}
+		m.steps = append(m.steps, step)
+	}
+	if len(m.steps) == 0 {
+		return nil, m.errorf("no steps")
+	}
+	return m, nil
+}
+
+func (m *StreamMatcher) errorf(format string, args ...interface{}) error {
+	return fmt.Errorf("xmlquery: unsupported streaming expression %q: %s", m.expr, fmt.Sprintf(format, args...))
+}
+
+// parseAttrPred parses @name, @name='value' or @name!='value'.
+func parseAttrPred(s string) (attrPred, bool) {
+	if !strings.HasPrefix(s, "@") {
+		return attrPred{}, false
+	}
+	s = s[1:]
+	end := strings.IndexAny(s, "!=")
+	if end < 0 {
+		return attrPred{name: s}, s != "" && !strings.ContainsAny(s, " \t\r\n'\"()[]/")
+	}
+	pred := attrPred{name: strings.TrimSpace(s[:end]), op: "="}
+	s = s[end:]
+	if strings.HasPrefix(s, "!=") {
+		pred.op = "!="
+	}
+	s = strings.TrimSpace(s[len(pred.op):])
+	if len(s) < 2 || (s[0] != '"' && s[0] != '\'') || s[len(s)-1] != s[0] || strings.IndexByte(s[1:len(s)-1], s[0]) >= 0 {
+		return attrPred{}, false
+	}
+	pred.value = s[1 : len(s)-1]
+	return pred, pred.name != "" && !strings.ContainsAny(pred.name, " \t\r\n'\"()[]/")
+}
+
+// String returns the expression m was compiled from.
+func (m *StreamMatcher) String() string {
+	return m.expr
+}
+
+// matches reports whether step matches the element start.
+func (step *matchStep) matches(start *xml.StartElement) bool {
+	if step.name != "*" && step.name != rawName(start.Name) {
+		return false
+	}
+	for _, pred := range step.preds {
+		value, found := "", false
+		for _, attr := range start.Attr {
+			if rawName(attr.Name) == pred.name {
+				value, found = attr.Value, true
+				break
+			}
+		}
+		switch {
+		case !found:
+			return false
+		case pred.op == "=" && value != pred.value, pred.op == "!=" && value == pred.value:
+			return false
+		}
+	}
+	return true
+}
+
+// rawName returns a name returned by RawToken as written, "prefix:local".
+func rawName(name xml.Name) string {
+	if name.Space == "" {
+		return name.Local
+	}
+	return name.Space + ":" + name.Local
+}
+
+// Match reads the XML document from r and calls fn with each element that
+// m selects, in document order. Each element is the root element of a new
+// document that holds a copy of its subtree, with the namespace
+// declarations it relies on from its ancestors; nothing else of the input
+// is kept in memory. Elements selected inside a selected element are part
+// of its subtree and are not reported on their own.
+//
+// Match stops at the first error returned by fn and returns it.
+func (m *StreamMatcher) Match(r io.Reader, fn func(*Node) error) error {
+	bom, br := decodeBOM(bufio.NewReader(r))
+	decoder := xml.NewDecoder(br)
+	decoder.CharsetReader = charset.NewReaderLabel
+	decoder.IgnoreDeclaredEncoding = bom != "" && bom != "UTF-8"
+
+	type frame struct {
+		name   xml.Name
+		states []int             // number of steps matched, see advance
+		ns     map[string]string // namespaces declared by the element
+	}
+	stack := []frame{{states: []int{0}}}
+	lookup := func(prefix string) string {
+		switch prefix {
+		case "xml":
+			return "http://www.w3.org/XML/1998/namespace"
+		case "xmlns":
+			return "xmlns"
+		}
+		for i := len(stack) - 1; i >= 0; i-- {
+			if uri, ok := stack[i].ns[prefix]; ok {
+				return uri
+			}
+		}
+		return ""
+	}
+	var (
+		doc     *Node // document of the element being built
+		current *Node // innermost open node of doc
+		depth   int   // open elements of doc
+	)
+	for {
+		tok, err := decoder.RawToken()
+		if err == io.EOF {
+			if len(stack) > 1 {
+				return fmt.Errorf("xmlquery: unexpected EOF, <%s> is not closed", rawName(stack[len(stack)-1].name))
+			}
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		switch tok := tok.(type) {
+		case xml.StartElement:
+			f := frame{name: tok.Name}
+			for _, attr := range tok.Attr {
+				switch {
+				case attr.Name.Space == "xmlns":
+					if f.ns == nil {
+						f.ns = make(map[string]string)
+					}
+					f.ns[attr.Name.Local] = attr.Value
+				case attr.Name.Space == "" && attr.Name.Local == "xmlns":
+					if f.ns == nil {
+						f.ns = make(map[string]string)
+					}
+					f.ns[""] = attr.Value
+				}
+			}
+			if doc == nil {
+				f.states = m.advance(stack[len(stack)-1].states, &tok)
+			}
+			stack = append(stack, f)
+			if doc == nil && !m.selected(f.states) {
+				break
+			}
+			elem := &Node{
+				Type:         ElementNode,
+				Data:         tok.Name.Local,
+				Prefix:       tok.Name.Space,
+				NamespaceURI: lookup(tok.Name.Space),
+			}
+			for _, attr := range tok.Attr {
+				uri := ""
+				if attr.Name.Space != "" {
+					uri = lookup(attr.Name.Space)
+				}
+				elem.Attr = append(elem.Attr, Attr{Name: attr.Name, Value: attr.Value, NamespaceURI: uri})
+			}
+			if doc == nil {
+				doc = &Node{Type: DocumentNode}
+				current = doc
+			}
+			AddChild(current, elem)
+			current = elem
+			depth++
+		case xml.EndElement:
+			if top := stack[len(stack)-1]; len(stack) == 1 || top.name != tok.Name {
+				return fmt.Errorf("xmlquery: unexpected end element </%s>", rawName(tok.Name))
+			}
+			stack = stack[:len(stack)-1]
+			if doc == nil {
+				break
+			}
+			current = current.Parent
+			if depth--; depth > 0 {
+				break
+			}
+			root := doc.FirstChild
+			setLevel(root, 1)
+			fixNamespaces(root, namespacesInScope(doc))
+			doc, current = nil, nil
+			if err := fn(root); err != nil {
+				return err
+			}
+		case xml.CharData:
+			if doc != nil {
+				AddChild(current, &Node{Type: TextNode, Data: string(tok)})
+				if decoder.CDATA() {
+					current.LastChild.Type = CharDataNode
+				}
+			}
+		case xml.Comment:
+			if doc != nil {
+				AddChild(current, &Node{Type: CommentNode, Data: string(tok)})
+			}
+		case xml.ProcInst:
+			if doc != nil {
+				pi := &Node{Type: DeclarationNode, Data: tok.Target}
+				if strings.TrimSpace(string(tok.Inst)) != "" {
+					pi.SetProcInstData(string(tok.Inst))
+				}
+				AddChild(current, pi)
+			}
+		}
+	}
+}
+
+// advance returns the states of an element whose parent has the given
+// states. A state k means that the first k steps matched the element or
+// one of its ancestors, and that step k is to be tried next: on the
+// children, or on all descendants if it is a descendant step.
+func (m *StreamMatcher) advance(parent []int, start *xml.StartElement) []int {
+	var states []int
+	add := func(k int) {
+		for _, s := range states {
+			if s == k {
+				return
+			}
+		}
+		states = append(states, k)
+	}
+	for _, k := range parent {
+		if k == len(m.steps) {
+			continue
+		}
+		step := &m.steps[k]
+		if step.descendant {
+			add(k)
+		}
+		if step.matches(start) {
+			add(k + 1)
+		}
+	}
+	return states
+}
+
+// selected reports whether states contains a complete match.
+func (m *StreamMatcher) selected(states []int) bool {
+	for _, k := range states {
+		if k == len(m.steps) {
+			return true
+		}
+	}
+	return false
+}
+
+// MatchStream is like NewStreamMatcher followed by Match.
+//
+//	err := xmlquery.MatchStream(f, "//entry[@id='42']", func(n *xmlquery.Node) error {
+//		fmt.Println(n.OutputXML(true))
+//		return nil
+//	})
+func MatchStream(r io.Reader, expr string, fn func(*Node) error) error {
+	m, err := NewStreamMatcher(expr)
+	if err != nil {
+		return err
+	}
+	return m.Match(r, fn)
+}
@@ -0,0 +1,101 @@
+package xmlquery
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+const streamMatchFeed = `<?xml version="1.0"?>
+<feed xmlns="urn:feed" xmlns:m="urn:meta">
+	<entry id="1" type="book"><title>One</title><m:tag>a</m:tag></entry>
+	<group>
+		<entry id="2" type="cd"><title>Two <![CDATA[<live>]]></title></entry>
+		<entry id="3" type="book"><title>Three</title><!--c--><?pi a="1"?></entry>
+	</group>
+	<entry id="4"><entry id="5" type="book"/></entry>
+</feed>`
+
+func matchStreamIDs(t *testing.T, expr string) []string {
+	t.Helper()
+	var ids []string
+	err := MatchStream(strings.NewReader(streamMatchFeed), expr, func(n *Node) error {
+		verifyNodePointers(t, n.Parent)
+		testValue(t, n.Level(), 1)
+		id := n.SelectAttr("id")
+		if title := n.SelectElement("title"); title != nil {
+			id += title.InnerText()
+		}
+		ids = append(ids, id)
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return ids
+}
+
+func TestMatchStream(t *testing.T) {
+	for expr, want := range map[string][]string{
+		"/feed/entry":                              {"1One", "4"},
+		"//entry[@type='book']":                    {"1One", "3Three", "5"},
+		"//group/entry[@type!='book']":             {"2Two <live>"},
+		"/feed//entry[@id and @type='cd']":         {"2Two <live>"},
+		"//*[@id='3']":                             {"3Three"},
+		"//entry[@missing]":                        nil,
+		"/entry":                                   nil,
+		"//entry/entry":                            {"5"},
+		`/feed/group/entry[@id="3"][@type='book']`: {"3Three"},
+	} {
+		testDeepEqual(t, matchStreamIDs(t, expr), want)
+	}
+}
+
+func TestMatchStreamSubtree(t *testing.T) {
+	var got []*Node
+	err := MatchStream(strings.NewReader(streamMatchFeed), "//entry[@id='1' or @id='3']", nil)
+	if err == nil {
+		t.Fatal("expected error for unsupported predicate")
+	}
+	m, err := NewStreamMatcher("//entry[@type='book']")
+	if err != nil {
+		t.Fatal(err)
+	}
+	stop := errors.New("stop")
+	err = m.Match(strings.NewReader(streamMatchFeed), func(n *Node) error {
+		got = append(got, n)
+		if len(got) == 2 {
+			return stop
+		}
+		return nil
+	})
+	testTrue(t, err == stop)
+	testValue(t, len(got), 2)
+	testValue(t, got[0].OutputXML(true), `<entry id="1" type="book" xmlns="urn:feed"><title>One</title><m:tag xmlns:m="urn:meta">a</m:tag></entry>`)
+	testValue(t, got[0].NamespaceURI, "urn:feed")
+	testValue(t, FindOne(got[0], "m:tag").NamespaceURI, "urn:meta")
+	testValue(t, got[1].OutputXML(true), `<entry id="3" type="book" xmlns="urn:feed"><title>Three</title><!--c--><?pi a="1"?></entry>`)
+
+	err = MatchStream(strings.NewReader(streamMatchFeed), "//entry[@id='2']/title", func(n *Node) error {
+		testValue(t, n.LastChild.Type, CharDataNode)
+		testValue(t, n.OutputXMLWithOptions(WithOutputSelf(), WithPreserveSpace()), `<title xmlns="urn:feed">Two <![CDATA[<live>]]></title>`)
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestMatchStreamErrors(t *testing.T) {
+	for _, expr := range []string{"entry", "//entry[1]", "//entry[@id=1]", "//entry/@id", "//entry[", "/child::entry", "//entry/text()", "/"} {
+		if _, err := NewStreamMatcher(expr); err == nil {
+			t.Errorf("NewStreamMatcher(%q) should fail", expr)
+		}
+	}
+	for _, s := range []string{`<a><b></a>`, `<a><b>`} {
+		err := MatchStream(strings.NewReader(s), "//b", func(*Node) error { return nil })
+		if err == nil {
+			t.Errorf("MatchStream(%q) should fail", s)
+		}
+	}
+}
@@ -0,0 +1,261 @@
+package xmlquery
+
+import (
+	"fmt"
+	"io"
+	"reflect"
+	"strings"
+	"text/template"
+)
+
+// TemplateNS is the namespace of the attributes that control a Template.
+const TemplateNS = "https://github.com/suifengpiao14/xmlquery/template"
+
+// A Template fills a template document with data. Text and attribute values
+// of the template may contain text/template actions such as {{.OrderID}}
+// or {{printf "%.2f" .Price}}, which are evaluated with the current data
+// value as dot. The results become text and attribute values and are
+// never parsed as markup; write the output with WithEscapeMode so special
+// characters in attribute values are escaped as well.
+//
+// Elements can be controlled with attributes in the TemplateNS namespace,
+// whose values are field paths like ".Items" or ".Order.Lines", with "."
+// for the current value:
+//
+//   - repeat: the element is repeated for each item of the slice or array,
+//     with the item as the current value for its content.
+//   - if: the element is omitted unless the value is non-zero.
+//
+// These attributes and the declaration of their namespace are removed from
+// the output. For example:
+//
+//	<order xmlns:t="https://github.com/suifengpiao14/xmlquery/template" id="{{.ID}}">
+//	  <line t:repeat=".Lines" sku="{{.SKU}}">{{.Quantity}}</line>
+//	  <note t:if=".Note">{{.Note}}</note>
+//	</order>
+//
+// A Template is safe for concurrent use.
+type Template struct {
+	doc   *Node
+	texts map[*Node]*template.Template
+	attrs map[*Attr]*template.Template
+}
+
+// ParseTemplate parses a template document from r. funcs, which may be
+// nil, are made available to the actions.
+func ParseTemplate(r io.Reader, funcs template.FuncMap) (*Template, error) {
+	doc, err := Parse(r)
+	if err != nil {
+		return nil, err
+	}
+	return NewTemplate(doc, funcs)
+}
+
+// NewTemplate returns a template for the template document doc, which must
+// not be modified afterwards. funcs, which may be nil, are made available
+// to the actions.
+func NewTemplate(doc *Node, funcs template.FuncMap) (*Template, error) {
+	t := &Template{
+		doc:   doc,
+		texts: make(map[*Node]*template.Template),
+		attrs: make(map[*Attr]*template.Template),
+	}
+	compile := func(s string) (*template.Template, error) {
+		return template.New("").Funcs(funcs).Option("missingkey=error").Parse(s)
+	}
+	var walk func(n *Node) error
+	walk = func(n *Node) error {
+		n.Materialize()
+		switch n.Type {
+		case TextNode, CharDataNode:
+			if strings.Contains(n.Data, "{{") {
+				tmpl, err := compile(n.Data)
+				if err != nil {
+					return fmt.Errorf("xmlquery: template %s: %w", n.Path(), err)
+				}
+				t.texts[n] = tmpl
+			}
+		case ElementNode:
+			for i := range n.Attr {
+				attr := &n.Attr[i]
+				if attr.NamespaceURI == TemplateNS {
+					if !strings.HasPrefix(attr.Value, ".") {
+						return fmt.Errorf("xmlquery: template %s: %s must be a field path", n.Path(), attr.Name.Local)
+					}
+					continue
+				}
+				if strings.Contains(attr.Value, "{{") {
+					tmpl, err := compile(attr.Value)
+					if err != nil {
+						return fmt.Errorf("xmlquery: template %s/@%s: %w", n.Path(), attr.Name.Local, err)
+					}
+					t.attrs[attr] = tmpl
+				}
+			}
+		}
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			if err := walk(c); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	if err := walk(doc); err != nil {
+		return nil, err
+	}
+	return t, nil
+}
+
+// Execute returns a new document filled with data.
+func (t *Template) Execute(data interface{}) (*Node, error) {
+	out := &Node{Type: t.doc.Type, Data: t.doc.Data}
+	if err := t.executeChildren(out, t.doc, data); err != nil {
+		return nil, err
+	}
+	setLevel(out, t.doc.level)
+	return out, nil
+}
+
+func (t *Template) executeChildren(parent, n *Node, data interface{}) error {
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		if err := t.execute(parent, c, data); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// execute adds the output of the template node n with data to parent.
+func (t *Template) execute(parent, n *Node, data interface{}) error {
+	if n.Type != ElementNode {
+		out := &Node{Type: n.Type, Data: n.Data, Prefix: n.Prefix, NamespaceURI: n.NamespaceURI, Attr: append([]Attr(nil), n.Attr...)}
+		if tmpl := t.texts[n]; tmpl != nil {
+			s, err := executeString(tmpl, data)
+			if err != nil {
+				return fmt.Errorf("xmlquery: template %s: %w", n.Path(), err)
+			}
+			out.Data = s
+		}
+		AddChild(parent, out)
+		return nil
+	}
+
+	var repeat, cond string
+	for _, attr := range n.Attr {
+		if attr.NamespaceURI == TemplateNS {
+			switch attr.Name.Local {
+			case "repeat":
+				repeat = attr.Value
+			case "if":
+				cond = attr.Value
+			default:
+				return fmt.Errorf("xmlquery: template %s: unknown attribute %s", n.Path(), attr.Name.Local)
+			}
+		}
+	}
+	if cond != "" {
+		v, err := templateValue(data, cond)
+		if err != nil {
+			return fmt.Errorf("xmlquery: template %s: %w", n.Path(), err)
+		}
+		if !v.IsValid() || v.IsZero() {
+			return nil
+		}
+	}
+	if repeat == "" {
+		return t.executeElement(parent, n, data)
+	}
+	v, err := templateValue(data, repeat)
+	if err != nil {
+		return fmt.Errorf("xmlquery: template %s: %w", n.Path(), err)
+	}
+	if !v.IsValid() {
+		return nil
+	}
+	if v.Kind() != reflect.Slice && v.Kind() != reflect.Array {
+		return fmt.Errorf("xmlquery: template %s: can't repeat over %s", n.Path(), v.Type())
+	}
+	for i := 0; i < v.Len(); i++ {
+		if err := t.executeElement(parent, n, v.Index(i).Interface()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (t *Template) executeElement(parent, n *Node, data interface{}) error {
+	out := &Node{Type: ElementNode, Data: n.Data, Prefix: n.Prefix, NamespaceURI: n.NamespaceURI}
+	for i := range n.Attr {
+		attr := n.Attr[i]
+		if attr.NamespaceURI == TemplateNS || isNamespaceDecl(attr) && attr.Value == TemplateNS {
+			continue
+		}
+		if tmpl := t.attrs[&n.Attr[i]]; tmpl != nil {
+			s, err := executeString(tmpl, data)
+			if err != nil {
+				return fmt.Errorf("xmlquery: template %s/@%s: %w", n.Path(), attr.Name.Local, err)
+			}
+			attr.Value = s
+		}
+		out.Attr = append(out.Attr, attr)
+	}
+	AddChild(parent, out)
+	return t.executeChildren(out, n, data)
+}
+
+func isNamespaceDecl(attr Attr) bool {
+	return attr.Name.Space == "xmlns" || attr.Name.Space == "" && attr.Name.Local == "xmlns"
+}
+
+func executeString(tmpl *template.Template, data interface{}) (string, error) {
+	var b strings.Builder
+	if err := tmpl.Execute(&b, data); err != nil {
+		return "", err
+	}
+	return b.String(), nil
+}
+
+// templateValue resolves the field path, such as ".Order.Lines", against
+// data. Path elements are struct fields, map keys or methods without
+// arguments. A nil pointer or interface along the way yields the invalid
+// Value.
+func templateValue(data interface{}, path string) (reflect.Value, error) {
+	v := reflect.ValueOf(data)
+	if path == "." {
+		return v, nil
+	}
+	for _, name := range strings.Split(strings.TrimPrefix(path, "."), ".") {
+		for v.IsValid() && (v.Kind() == reflect.Pointer || v.Kind() == reflect.Interface) {
+			if m := v.MethodByName(name); m.IsValid() {
+				break
+			}
+			if v.IsNil() {
+				return reflect.Value{}, nil
+			}
+			v = v.Elem()
+		}
+		if !v.IsValid() {
+			return v, nil
+		}
+		if m := v.MethodByName(name); m.IsValid() && m.Type().NumIn() == 0 && m.Type().NumOut() >= 1 {
+			v = m.Call(nil)[0]
+			continue
+		}
+		switch v.Kind() {
+		case reflect.Struct:
+			f := v.FieldByName(name)
+			if !f.IsValid() {
+				return reflect.Value{}, fmt.Errorf("%s: no field %s in %s", path, name, v.Type())
+			}
+			v = f
+		case reflect.Map:
+			if v.Type().Key().Kind() != reflect.String {
+				return reflect.Value{}, fmt.Errorf("%s: can't get %s of %s", path, name, v.Type())
+			}
+			v = v.MapIndex(reflect.ValueOf(name).Convert(v.Type().Key()))
+		default:
+			return reflect.Value{}, fmt.Errorf("%s: can't get %s of %s", path, name, v.Type())
+		}
+	}
+	return v, nil
+}
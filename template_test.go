@@ -0,0 +1,89 @@
+package xmlquery
+
+import (
+	"strconv"
+	"strings"
+	"testing"
+	"text/template"
+)
+
+type templateLine struct {
+	SKU      string
+	Quantity int
+	Price    float64
+}
+
+type templateOrder struct {
+	ID    string
+	Note  string
+	Lines []templateLine
+	Meta  map[string]string
+}
+
+func (o templateOrder) Total() float64 {
+	var total float64
+	for _, l := range o.Lines {
+		total += float64(l.Quantity) * l.Price
+	}
+	return total
+}
+
+func TestTemplate(t *testing.T) {
+	s := `<order xmlns:t="https://github.com/suifengpiao14/xmlquery/template" id="{{.ID}}">` +
+		`<line t:repeat=".Lines" sku="{{.SKU}}">{{.Quantity}} x {{money .Price}}</line>` +
+		`<note t:if=".Note">{{.Note}}</note>` +
+		`<total>{{money .Total}}</total>` +
+		`<source t:if=".Meta.source">{{index .Meta "source"}}</source>` +
+		`</order>`
+	tmpl, err := ParseTemplate(strings.NewReader(s), template.FuncMap{
+		"money": func(f float64) string { return strconv.FormatFloat(f, 'f', -1, 64) },
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	doc, err := tmpl.Execute(templateOrder{
+		ID:    "A&1",
+		Lines: []templateLine{{"tea", 2, 1.5}, {"<cake>", 1, 3}},
+		Meta:  map[string]string{"source": "web"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	testValue(t, doc.OutputXMLWithOptions(WithEscapeMode(EscapeNamed)), `<?xml version="1.0"?><order id="A&amp;1"><line sku="tea">2 x 1.5</line><line sku="&lt;cake&gt;">1 x 3</line><total>6</total><source>web</source></order>`)
+	testValue(t, FindOne(doc, "//line[2]").Level(), 2)
+	verifyNodePointers(t, doc)
+
+	// The template can be executed again.
+	doc, err = tmpl.Execute(templateOrder{ID: "2", Note: "fragile"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	testValue(t, doc.OutputXML(false), `<?xml version="1.0"?><order id="2"><note>fragile</note><total>0</total></order>`)
+}
+
+func TestTemplateErrors(t *testing.T) {
+	for _, s := range []string{
+		`<r>{{.X</r>`,
+		`<r a="{{end}}"/>`,
+		`<r xmlns:t="https://github.com/suifengpiao14/xmlquery/template"><x t:repeat="Items"/></r>`,
+	} {
+		if _, err := ParseTemplate(strings.NewReader(s), nil); err == nil {
+			t.Errorf("%s: expected error", s)
+		}
+	}
+
+	tmpl, err := ParseTemplate(strings.NewReader(`<r xmlns:t="https://github.com/suifengpiao14/xmlquery/template"><x t:repeat=".ID"/></r>`), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := tmpl.Execute(templateOrder{ID: "1"}); err == nil {
+		t.Error("expected error for repeat over a string")
+	}
+	tmpl, err = ParseTemplate(strings.NewReader(`<r>{{.Missing}}</r>`), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := tmpl.Execute(templateOrder{}); err == nil {
+		t.Error("expected error for unknown field")
+	}
+}
@@ -0,0 +1,129 @@
+package xmlquery
+
+import (
+	"errors"
+	"time"
+
+	"github.com/antchfx/xpath"
+)
+
+// QueryOptions control the evaluation of a query, see QueryAllWithOptions.
+type QueryOptions struct {
+	// Timeout bounds the time spent evaluating the expression; zero means
+	// no limit. Use it to protect servers from pathological expressions
+	// supplied by users, such as "//*[count(//*) > count(preceding::*)]".
+	Timeout time.Duration
+}
+
+// ErrQueryTimeout is returned when the evaluation of a query took longer
+// than QueryOptions.Timeout.
+var ErrQueryTimeout = errors.New("xmlquery: query timed out")
+
+// QueryAllWithOptions is like QueryAll, but with custom options. If the
+// evaluation times out, it returns the nodes matched so far together with
+// ErrQueryTimeout.
+func QueryAllWithOptions(top *Node, expr string, options QueryOptions) ([]*Node, error) {
+	exp, err := getQueryFor(top, expr)
+	if err != nil {
+		return nil, err
+	}
+	return QuerySelectorAllWithOptions(top, exp, options)
+}
+
+// QuerySelectorAllWithOptions is like QuerySelectorAll, but with custom
+// options. See QueryAllWithOptions.
+func QuerySelectorAllWithOptions(top *Node, selector *xpath.Expr, options QueryOptions) (nodes []*Node, err error) {
+	if options.Timeout <= 0 {
+		return QuerySelectorAll(top, selector), nil
+	}
+	d := &queryDeadline{at: time.Now().Add(options.Timeout)}
+	prof := startProfile(selector)
+	defer prof.end()
+	defer func() {
+		if r := recover(); r != nil {
+			if r != d {
+				panic(r)
+			}
+			err = ErrQueryTimeout
+		}
+	}()
+	nav := selectorNavigator(top, selector)
+	nav.prof = prof
+	t := selector.Select(&deadlineNavigator{NodeNavigator: nav, d: d})
+	for t.MoveNext() {
+		nodes = append(nodes, navigatorNode(t.Current().(*deadlineNavigator).NodeNavigator))
+	}
+	return nodes, nil
+}
+
+// deadlineCheckInterval is the number of navigator moves between two
+// checks of the clock.
+const deadlineCheckInterval = 256
+
+// queryDeadline is the time a query must complete by. Once it is exceeded,
+// the next move of a deadlineNavigator panics with the queryDeadline, which
+// unwinds the evaluation.
+type queryDeadline struct {
+	at    time.Time
+	moves int
+}
+
+func (d *queryDeadline) check() {
+	if d.moves++; d.moves%deadlineCheckInterval == 0 && time.Now().After(d.at) {
+		panic(d)
+	}
+}
+
+// deadlineNavigator is a NodeNavigator that stops the evaluation once its
+// deadline is exceeded.
+type deadlineNavigator struct {
+	*NodeNavigator
+	d *queryDeadline
+}
+
+func (x *deadlineNavigator) Copy() xpath.NodeNavigator {
+	return &deadlineNavigator{NodeNavigator: x.NodeNavigator.Copy().(*NodeNavigator), d: x.d}
+}
+
+func (x *deadlineNavigator) MoveTo(other xpath.NodeNavigator) bool {
+	x.d.check()
+	if o, ok := other.(*deadlineNavigator); ok {
+		other = o.NodeNavigator
+	}
+	return x.NodeNavigator.MoveTo(other)
+}
+
+func (x *deadlineNavigator) MoveToRoot() {
+	x.d.check()
+	x.NodeNavigator.MoveToRoot()
+}
+
+func (x *deadlineNavigator) MoveToParent() bool {
+	x.d.check()
+	return x.NodeNavigator.MoveToParent()
+}
+
+func (x *deadlineNavigator) MoveToNextAttribute() bool {
+	x.d.check()
+	return x.NodeNavigator.MoveToNextAttribute()
+}
+
+func (x *deadlineNavigator) MoveToChild() bool {
+	x.d.check()
+	return x.NodeNavigator.MoveToChild()
+}
+
+func (x *deadlineNavigator) MoveToFirst() bool {
+	x.d.check()
+	return x.NodeNavigator.MoveToFirst()
+}
+
+func (x *deadlineNavigator) MoveToNext() bool {
+	x.d.check()
+	return x.NodeNavigator.MoveToNext()
+}
+
+func (x *deadlineNavigator) MoveToPrevious() bool {
+	x.d.check()
+	return x.NodeNavigator.MoveToPrevious()
+}
@@ -0,0 +1,44 @@
+package xmlquery
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestQueryTimeout(t *testing.T) {
+	var b strings.Builder
+	b.WriteString("<r>")
+	for i := 0; i < 3000; i++ {
+		b.WriteString("<a><b/></a>")
+	}
+	b.WriteString("</r>")
+	doc, err := Parse(strings.NewReader(b.String()))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	nodes, err := QueryAllWithOptions(doc, "//b", QueryOptions{Timeout: time.Minute})
+	if err != nil {
+		t.Fatal(err)
+	}
+	testValue(t, len(nodes), 3000)
+
+	start := time.Now()
+	nodes, err = QueryAllWithOptions(doc, "//*[count(//*) > count(preceding::*)]", QueryOptions{Timeout: 20 * time.Millisecond})
+	if !errors.Is(err, ErrQueryTimeout) {
+		t.Fatalf("got %v, want ErrQueryTimeout", err)
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Fatalf("query ran for %v", elapsed)
+	}
+	testTrue(t, len(nodes) < 6001)
+	for _, n := range nodes {
+		testTrue(t, n.Type == ElementNode)
+	}
+
+	if _, err := QueryAllWithOptions(doc, "//[", QueryOptions{Timeout: time.Second}); err == nil || errors.Is(err, ErrQueryTimeout) {
+		t.Fatalf("got %v, want a syntax error", err)
+	}
+}
@@ -0,0 +1,334 @@
+package xmlquery
+
+import (
+	stdxml "encoding/xml"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/suifengpiao14/xmlquery/xml"
+)
+
+// Tokens returns a reader of the subtree rooted at n as encoding/xml
+// tokens, so that it can be fed to code that consumes tokens, such as an
+// xml.Encoder or, through xml.NewTokenDecoder, a Decoder. Tokens are in
+// the form returned by xml.Decoder.Token: names carry namespace URIs in
+// Space, and xmlns declarations are attributes named xmlns or
+// xmlns:prefix. A document yields the tokens of its children. Text and
+// CDATA sections become CharData, processing instructions ProcInst and
+// doctypes Directive.
+//
+// Tokens are produced as the tree is walked, so the subtree must not be
+// modified until the reader returns io.EOF.
+//
+//	dec := xml.NewTokenDecoder(n.Tokens())
+//	err := dec.Decode(&v)
+func (n *Node) Tokens() stdxml.TokenReader {
+	return &tokenReader{top: n, cur: n}
+}
+
+// ToTokens returns all the tokens of the subtree rooted at n, see Tokens.
+func ToTokens(n *Node) []stdxml.Token {
+	var tokens []stdxml.Token
+	r := n.Tokens()
+	for {
+		tok, err := r.Token()
+		if err != nil {
+			return tokens
+		}
+		tokens = append(tokens, tok)
+	}
+}
+
+// EncodeTo writes the subtree rooted at n through enc and flushes it, so
+// that it can be embedded in a document produced with encoding/xml, for
+// example from a MarshalXML method. Names are written with the prefixes of
+// the subtree, and the namespace declarations an element relies on from its
+// ancestors are added to it; the XML declaration of a document is left out.
+//
+//	enc.EncodeToken(xml.StartElement{Name: xml.Name{Local: "envelope"}})
+//	payload.EncodeTo(enc)
+//	enc.EncodeToken(xml.EndElement{Name: xml.Name{Local: "envelope"}})
+func (n *Node) EncodeTo(enc *stdxml.Encoder) error {
+	top := n
+	if n.Type == ElementNode {
+		top = n.Clone()
+		fixNamespaces(top, namespacesInScope(&Node{Type: DocumentNode}))
+	}
+	r := &tokenReader{top: top, cur: top, raw: true}
+	for {
+		tok, err := r.Token()
+		if err == io.EOF {
+			return enc.Flush()
+		}
+		if pi, ok := tok.(stdxml.ProcInst); ok && pi.Target == "xml" {
+			continue
+		}
+		if err := enc.EncodeToken(tok); err != nil {
+			return err
+		}
+	}
+}
+
+// tokenReader walks a subtree depth first. cur is the node being entered,
+// or left if leaving is set; it is nil at the end. With raw, names are
+// written "prefix:local" in Local, which an xml.Encoder writes as is.
+type tokenReader struct {
+	top, cur *Node
+	leaving  bool
+	raw      bool
+}
+
+func (r *tokenReader) Token() (stdxml.Token, error) {
+	for r.cur != nil {
+		n := r.cur
+		if r.leaving {
+			r.advance()
+			if n.Type == ElementNode {
+				return stdxml.EndElement{Name: r.elementName(n)}, nil
+			}
+			continue
+		}
+		var tok stdxml.Token
+		switch n.Type {
+		case DocumentNode:
+			n.Materialize()
+		case ElementNode:
+			n.Materialize()
+			tok = r.startElement(n)
+		case TextNode, CharDataNode:
+			tok = stdxml.CharData(n.Data)
+		case CommentNode:
+			tok = stdxml.Comment(n.Data)
+		case DeclarationNode:
+			tok = stdxml.ProcInst{Target: n.Data, Inst: []byte(n.ProcInstData())}
+		case NotationNode:
+			tok = stdxml.Directive(n.Data)
+		case AttributeNode:
+			tok = stdxml.CharData(n.InnerText())
+		}
+		if (n.Type == DocumentNode || n.Type == ElementNode) && n.FirstChild != nil {
+			r.cur = n.FirstChild
+		} else {
+			r.leaving = true
+		}
+		if tok != nil {
+			return tok, nil
+		}
+	}
+	return nil, io.EOF
+}
+
+// advance moves past r.cur, which is being left.
+func (r *tokenReader) advance() {
+	n := r.cur
+	switch {
+	case n == r.top:
+		r.cur = nil
+	case n.NextSibling != nil:
+		r.cur, r.leaving = n.NextSibling, false
+	default:
+		r.cur = n.Parent
+	}
+}
+
+// elementName returns the token name of element n.
+func (r *tokenReader) elementName(n *Node) stdxml.Name {
+	if r.raw {
+		return stdxml.Name{Local: qualifiedName(n)}
+	}
+	if n.NamespaceURI == "" {
+		return stdxml.Name{Space: n.Prefix, Local: n.Data} // left as is by the decoder if unbound
+	}
+	return stdxml.Name{Space: n.NamespaceURI, Local: n.Data}
+}
+
+// startElement returns the start element token of element n.
+func (r *tokenReader) startElement(n *Node) stdxml.StartElement {
+	start := stdxml.StartElement{Name: r.elementName(n)}
+	if len(n.Attr) > 0 {
+		start.Attr = make([]stdxml.Attr, len(n.Attr))
+	}
+	for i, attr := range n.Attr {
+		name := stdxml.Name{Space: attr.Name.Space, Local: attr.Name.Local}
+		if r.raw {
+			name = stdxml.Name{Local: qualifiedAttrName(&attr)}
+		} else if name.Space != "" && name.Space != "xmlns" && attr.NamespaceURI != "" {
+			name.Space = attr.NamespaceURI
+		}
+		start.Attr[i] = stdxml.Attr{Name: name, Value: attr.Value}
+	}
+	return start
+}
+
+// FromTokens builds a document from a sequence of encoding/xml tokens,
+// such as those returned by ToTokens or collected from an xml.Decoder,
+// without writing and parsing them again.
+//
+// The Space of a name may hold either a prefix, as returned by
+// Decoder.RawToken, or a namespace URI, as returned by Decoder.Token.
+// A URI is written with a prefix bound to it in scope; if there is none,
+// an element gets a default namespace declaration and an attribute a new
+// ns1, ns2... prefix. FromTokens returns an error if start and end
+// elements don't match.
+func FromTokens(tokens []stdxml.Token) (*Node, error) {
+	doc := &Node{Type: DocumentNode}
+	current := doc
+	scopes := []map[string]string{namespacesInScope(doc)}
+	for _, tok := range tokens {
+		switch tok := tok.(type) {
+		case stdxml.StartElement:
+			scope := make(map[string]string, len(scopes[len(scopes)-1]))
+			for k, v := range scopes[len(scopes)-1] {
+				scope[k] = v
+			}
+			elem := &Node{Type: ElementNode, Data: tok.Name.Local}
+			for _, a := range tok.Attr {
+				switch {
+				case a.Name.Space == "xmlns":
+					elem.Attr = append(elem.Attr, Attr{Name: xml.Name{Space: "xmlns", Local: a.Name.Local}, Value: a.Value, NamespaceURI: "xmlns"})
+				case a.Name.Space == "" && a.Name.Local == "xmlns":
+					elem.Attr = append(elem.Attr, Attr{Name: xml.Name{Local: "xmlns"}, Value: a.Value})
+				}
+			}
+			declareNamespaces(elem, scope)
+			elem.Prefix, elem.NamespaceURI = tokenNamespace(elem, scope, tok.Name.Space, true)
+			for _, a := range tok.Attr {
+				if a.Name.Space == "xmlns" || (a.Name.Space == "" && a.Name.Local == "xmlns") {
+					continue
+				}
+				prefix, uri := tokenNamespace(elem, scope, a.Name.Space, false)
+				elem.Attr = append(elem.Attr, Attr{Name: xml.Name{Space: prefix, Local: a.Name.Local}, Value: a.Value, NamespaceURI: uri})
+			}
+			AddChild(current, elem)
+			current = elem
+			scopes = append(scopes, scope)
+		case stdxml.EndElement:
+			if current == doc {
+				return nil, fmt.Errorf("xmlquery: unexpected end element </%s>", tok.Name.Local)
+			}
+			if tok.Name.Local != current.Data || (tok.Name.Space != current.Prefix && tok.Name.Space != current.NamespaceURI) {
+				return nil, fmt.Errorf("xmlquery: end element </%s> does not match <%s>", tok.Name.Local, qualifiedName(current))
+			}
+			current = current.Parent
+			scopes = scopes[:len(scopes)-1]
+		case stdxml.CharData:
+			AddChild(current, &Node{Type: TextNode, Data: string(tok)})
+		case stdxml.Comment:
+			AddChild(current, &Node{Type: CommentNode, Data: string(tok)})
+		case stdxml.ProcInst:
+			pi := &Node{Type: DeclarationNode, Data: tok.Target}
+			if strings.TrimSpace(string(tok.Inst)) != "" {
+				pi.SetProcInstData(string(tok.Inst))
+			}
+			AddChild(current, pi)
+		case stdxml.Directive:
+			AddChild(current, &Node{Type: NotationNode, Data: string(tok)})
+		default:
+			return nil, fmt.Errorf("xmlquery: unsupported token %T", tok)
+		}
+	}
+	if current != doc {
+		return nil, fmt.Errorf("xmlquery: element <%s> is not closed", qualifiedName(current))
+	}
+	setLevel(doc, 0)
+	return doc, nil
+}
+
+// tokenNamespace returns the prefix and namespace URI of a name of elem
+// whose Space is space, see FromTokens, declaring a namespace on elem if
+// needed.
+func tokenNamespace(elem *Node, scope map[string]string, space string, isElem bool) (prefix, uri string) {
+	if space == "" {
+		if isElem {
+			return "", scope[""]
+		}
+		return "", ""
+	}
+	if uri, ok := scope[space]; ok && space != "" && !strings.Contains(space, ":") {
+		return space, uri
+	}
+	if isElem && scope[""] == space {
+		return "", space
+	}
+	var prefixes []string
+	for p, u := range scope {
+		if p != "" && u == space {
+			prefixes = append(prefixes, p)
+		}
+	}
+	if len(prefixes) > 0 {
+		sort.Strings(prefixes)
+		return prefixes[0], space
+	}
+	if isElem {
+		elem.Attr = append(elem.Attr, Attr{Name: xml.Name{Local: "xmlns"}, Value: space})
+		scope[""] = space
+		return "", space
+	}
+	for i := 1; ; i++ {
+		p := "ns" + strconv.Itoa(i)
+		if _, taken := scope[p]; !taken {
+			elem.Attr = append(elem.Attr, Attr{Name: xml.Name{Space: "xmlns", Local: p}, Value: space, NamespaceURI: "xmlns"})
+			scope[p] = space
+			return p, space
+		}
+	}
+}
+
+// MarshalXML implements xml.Marshaler, so that a *Node field of a struct
+// encoded with encoding/xml is written as the XML it holds, see EncodeTo.
+// An element is written with its own name rather than the one of the
+// field; other nodes, such as text, are written as the text of an element
+// named after the field.
+func (n *Node) MarshalXML(enc *stdxml.Encoder, start stdxml.StartElement) error {
+	switch n.Type {
+	case DocumentNode, ElementNode:
+		return n.EncodeTo(enc)
+	}
+	return enc.EncodeElement(n.InnerText(), start)
+}
+
+// UnmarshalXML implements xml.Unmarshaler, so that a *Node field of a
+// struct decoded with encoding/xml captures the element it is decoded from
+// as it is, attributes and content included, to be queried or written back
+// later. The element has no parent. As encoding/xml reports namespace URIs
+// rather than prefixes, namespaces declared outside the element are
+// declared again on it, see FromTokens.
+func (n *Node) UnmarshalXML(d *stdxml.Decoder, start stdxml.StartElement) error {
+	tokens := []stdxml.Token{start.Copy()}
+	for depth := 1; depth > 0; {
+		tok, err := d.Token()
+		if err != nil {
+			return err
+		}
+		switch tok.(type) {
+		case stdxml.StartElement:
+			depth++
+		case stdxml.EndElement:
+			depth--
+		}
+		tokens = append(tokens, stdxml.CopyToken(tok))
+	}
+	doc, err := FromTokens(tokens)
+	if err != nil {
+		return err
+	}
+	elem := doc.FirstChild
+	*n = Node{
+		Type:         ElementNode,
+		Data:         elem.Data,
+		Prefix:       elem.Prefix,
+		NamespaceURI: elem.NamespaceURI,
+		Attr:         elem.Attr,
+		FirstChild:   elem.FirstChild,
+		LastChild:    elem.LastChild,
+		level:        elem.level,
+	}
+	for child := n.FirstChild; child != nil; child = child.NextSibling {
+		child.Parent = n
+	}
+	return nil
+}
@@ -0,0 +1,275 @@
+package xmlquery
+
+import (
+	stdxml "encoding/xml"
+	"io"
+	"strings"
+	"testing"
+)
+
+const tokensDoc = `<?xml version="1.0"?><!DOCTYPE r><r xmlns="urn:d" xmlns:x="urn:x" x:id="1"><x:a xml:lang="en">t<![CDATA[c]]></x:a><!--n--><?pi data?><b/></r>`
+
+func TestToTokens(t *testing.T) {
+	doc, err := Parse(strings.NewReader(tokensDoc))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var want []stdxml.Token
+	d := stdxml.NewDecoder(strings.NewReader(tokensDoc))
+	for {
+		tok, err := d.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		if cd, ok := tok.(stdxml.CharData); ok && len(want) > 0 {
+			if prev, ok := want[len(want)-1].(stdxml.CharData); ok {
+				want[len(want)-1] = append(prev, cd...)
+				continue
+			}
+		}
+		want = append(want, stdxml.CopyToken(tok))
+	}
+	got := ToTokens(doc)
+	// The parser keeps text and CDATA sections apart.
+	if len(got) != len(want)+1 {
+		t.Fatalf("got %d tokens, want %d: %#v", len(got), len(want)+1, got)
+	}
+	got = append(got[:4], append([]stdxml.Token{append(got[4].(stdxml.CharData), got[5].(stdxml.CharData)...)}, got[6:]...)...)
+	for i := range want {
+		if g, w := tokenString(got[i]), tokenString(want[i]); g != w {
+			t.Errorf("token %d = %s, want %s", i, g, w)
+		}
+	}
+}
+
+func tokenString(tok stdxml.Token) string {
+	switch tok := tok.(type) {
+	case stdxml.StartElement:
+		s := "<{" + tok.Name.Space + "}" + tok.Name.Local
+		for _, a := range tok.Attr {
+			s += " {" + a.Name.Space + "}" + a.Name.Local + "=" + a.Value
+		}
+		return s + ">"
+	case stdxml.EndElement:
+		return "</{" + tok.Name.Space + "}" + tok.Name.Local + ">"
+	case stdxml.CharData:
+		return "text " + string(tok)
+	case stdxml.Comment:
+		return "comment " + string(tok)
+	case stdxml.ProcInst:
+		return "pi " + tok.Target + " " + string(tok.Inst)
+	case stdxml.Directive:
+		return "directive " + string(tok)
+	}
+	return "?"
+}
+
+func TestFromTokens(t *testing.T) {
+	doc, err := Parse(strings.NewReader(tokensDoc))
+	if err != nil {
+		t.Fatal(err)
+	}
+	copied, err := FromTokens(ToTokens(doc))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := strings.Replace(doc.OutputXML(false), "<![CDATA[c]]>", "c", 1)
+	if got := copied.OutputXML(false); got != want {
+		t.Errorf("round trip = %s, want %s", got, want)
+	}
+	verifyNodePointers(t, copied)
+	if n := FindOne(copied, "//b"); n == nil || n.NamespaceURI != "urn:d" || n.level != 2 {
+		t.Errorf("//b = %v", n)
+	}
+
+	// Raw tokens name prefixes instead of URIs.
+	var raw []stdxml.Token
+	d := stdxml.NewDecoder(strings.NewReader(tokensDoc))
+	for {
+		tok, err := d.RawToken()
+		if err != nil {
+			break
+		}
+		raw = append(raw, stdxml.CopyToken(tok))
+	}
+	if copied, err = FromTokens(raw); err != nil {
+		t.Fatal(err)
+	}
+	if a := FindOne(copied, "//x:a"); a == nil || a.NamespaceURI != "urn:x" || a.SelectAttr("xml:lang") != "en" {
+		t.Errorf("//x:a = %v", a)
+	}
+
+	// Namespaces without declarations are declared.
+	built, err := FromTokens([]stdxml.Token{
+		stdxml.StartElement{Name: stdxml.Name{Space: "urn:a", Local: "a"}, Attr: []stdxml.Attr{{Name: stdxml.Name{Space: "urn:b", Local: "k"}, Value: "v"}}},
+		stdxml.EndElement{Name: stdxml.Name{Space: "urn:a", Local: "a"}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	testValue(t, built.OutputXML(false), `<a xmlns="urn:a" xmlns:ns1="urn:b" ns1:k="v"></a>`)
+
+	for _, tokens := range [][]stdxml.Token{
+		{stdxml.StartElement{Name: stdxml.Name{Local: "a"}}},
+		{stdxml.EndElement{Name: stdxml.Name{Local: "a"}}},
+		{stdxml.StartElement{Name: stdxml.Name{Local: "a"}}, stdxml.EndElement{Name: stdxml.Name{Local: "b"}}},
+	} {
+		if _, err := FromTokens(tokens); err == nil {
+			t.Errorf("FromTokens(%v) succeeded", tokens)
+		}
+	}
+}
+
+func TestNodeTokens(t *testing.T) {
+	doc, err := Parse(strings.NewReader(`<r><item id="1"><name>a</name><!--c--></item><item id="2"><name>b</name></item></r>`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var v struct {
+		ID   string `xml:"id,attr"`
+		Name string `xml:"name"`
+	}
+	item := FindOne(doc, "//item[@id='2']")
+	if err := stdxml.NewTokenDecoder(item.Tokens()).Decode(&v); err != nil {
+		t.Fatal(err)
+	}
+	if v.ID != "2" || v.Name != "b" {
+		t.Errorf("decoded %+v", v)
+	}
+
+	// The walk stops at the end of the subtree.
+	r := FindOne(doc, "//item").Tokens()
+	var got []string
+	for {
+		tok, err := r.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		got = append(got, tokenString(tok))
+	}
+	testValue(t, strings.Join(got, ","), "<{}item {}id=1>,<{}name>,text a,</{}name>,comment c,</{}item>")
+	if _, err := r.Token(); err != io.EOF {
+		t.Errorf("Token after EOF = %v", err)
+	}
+
+	var b strings.Builder
+	enc := stdxml.NewEncoder(&b)
+	for r := doc.Tokens(); ; {
+		tok, err := r.Token()
+		if err != nil {
+			break
+		}
+		if err := enc.EncodeToken(tok); err != nil {
+			t.Fatal(err)
+		}
+	}
+	enc.Flush()
+	testValue(t, b.String(), doc.OutputXML(false))
+}
+
+func TestEncodeTo(t *testing.T) {
+	doc, err := Parse(strings.NewReader(`<?xml version="1.0"?><r xmlns="urn:d" xmlns:x="urn:x"><x:a x:id="1">t<![CDATA[<c>]]><b/><!--n--></x:a></r>`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var b strings.Builder
+	enc := stdxml.NewEncoder(&b)
+	if err := enc.EncodeToken(stdxml.StartElement{Name: stdxml.Name{Local: "envelope"}}); err != nil {
+		t.Fatal(err)
+	}
+	if err := FindOne(doc, "//x:a").EncodeTo(enc); err != nil {
+		t.Fatal(err)
+	}
+	if err := doc.EncodeTo(enc); err != nil {
+		t.Fatal(err)
+	}
+	if err := enc.EncodeToken(stdxml.EndElement{Name: stdxml.Name{Local: "envelope"}}); err != nil {
+		t.Fatal(err)
+	}
+	enc.Flush()
+	testValue(t, b.String(), `<envelope>`+
+		`<x:a x:id="1" xmlns:x="urn:x">t&lt;c&gt;<b xmlns="urn:d"></b><!--n--></x:a>`+
+		`<r xmlns="urn:d" xmlns:x="urn:x"><x:a x:id="1">t&lt;c&gt;<b></b><!--n--></x:a></r>`+
+		`</envelope>`)
+	if len(FindOne(doc, "//x:a").Attr) != 1 {
+		t.Error("EncodeTo modified the element")
+	}
+
+	// Inside a MarshalXML method.
+	v := struct {
+		XMLName stdxml.Name `xml:"order"`
+		Ext     marshalNode `xml:"ext"`
+	}{Ext: marshalNode{FindOne(doc, "//b")}}
+	out, err := stdxml.Marshal(v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	testValue(t, string(out), `<order><ext><b xmlns="urn:d"></b></ext></order>`)
+}
+
+type marshalNode struct{ n *Node }
+
+func (m marshalNode) MarshalXML(enc *stdxml.Encoder, start stdxml.StartElement) error {
+	if err := enc.EncodeToken(start); err != nil {
+		return err
+	}
+	if err := m.n.EncodeTo(enc); err != nil {
+		return err
+	}
+	return enc.EncodeToken(start.End())
+}
+
+func TestNodeXMLMarshaler(t *testing.T) {
+	type order struct {
+		XMLName stdxml.Name `xml:"order"`
+		ID      string      `xml:"id,attr"`
+		Ext     *Node       `xml:"ext"`
+		Other   []*Node     `xml:",any"`
+	}
+	const s = `<order id="7" xmlns:x="urn:x"><ext kind="a"><x:keep>1<!--c--></x:keep>t</ext><x:extra x:k="v"/></order>`
+	var v order
+	if err := stdxml.Unmarshal([]byte(s), &v); err != nil {
+		t.Fatal(err)
+	}
+	if v.Ext == nil || v.Ext.Parent != nil || v.Ext.SelectAttr("kind") != "a" {
+		t.Fatalf("Ext = %v", v.Ext)
+	}
+	verifyNodePointers(t, v.Ext)
+	keep := FindOne(v.Ext, "*")
+	if keep == nil || keep.Data != "keep" || keep.NamespaceURI != "urn:x" || keep.Parent != v.Ext {
+		t.Fatalf("keep = %v", keep)
+	}
+	testValue(t, v.Ext.InnerText(), "1t")
+	if len(v.Other) != 1 || v.Other[0].Data != "extra" {
+		t.Fatalf("Other = %v", v.Other)
+	}
+
+	out, err := stdxml.Marshal(v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	testValue(t, string(out), `<order id="7"><ext kind="a"><keep xmlns="urn:x">1<!--c--></keep>t</ext>`+
+		`<extra xmlns="urn:x" xmlns:ns1="urn:x" ns1:k="v"></extra></order>`)
+
+	// Round trip through the marshaled form.
+	var again order
+	if err := stdxml.Unmarshal(out, &again); err != nil {
+		t.Fatal(err)
+	}
+	testValue(t, again.Ext.OutputXMLWithOptions(WithOutputSelf()), v.Ext.OutputXMLWithOptions(WithOutputSelf()))
+
+	text := struct {
+		XMLName stdxml.Name `xml:"r"`
+		Name    *Node       `xml:"name"`
+	}{Name: &Node{Type: TextNode, Data: "a<b"}}
+	if out, err = stdxml.Marshal(text); err != nil {
+		t.Fatal(err)
+	}
+	testValue(t, string(out), `<r><name>a&lt;b</name></r>`)
+}
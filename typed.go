@@ -0,0 +1,211 @@
+package xmlquery
+
+import (
+	"encoding"
+	"errors"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ErrNoMatch is returned by FindAs and the related functions when the
+// expression selects no node.
+var ErrNoMatch = errors.New("xmlquery: no match")
+
+// A ValueError reports a value that could not be converted by FindAs, the
+// related functions, or the AsInt family of methods.
+type ValueError struct {
+	Expr  string // the XPath expression, if any
+	Path  string // the path of the node holding the value, if any
+	Value string // the value
+	Type  string // the Go type it was converted to
+	Err   error  // the conversion error
+}
+
+func (e *ValueError) Error() string {
+	at := ""
+	if e.Path != "" {
+		at = " at " + e.Path
+	}
+	if e.Expr == "" {
+		return fmt.Sprintf("xmlquery: cannot convert %q%s to %s: %v", e.Value, at, e.Type, e.Err)
+	}
+	return fmt.Sprintf("xmlquery: %s: cannot convert %q%s to %s: %v", e.Expr, e.Value, at, e.Type, e.Err)
+}
+
+func (e *ValueError) Unwrap() error { return e.Err }
+
+// FindAs evaluates the XPath expr against top and converts the text of the
+// first selected node, or the value of an expression like count(//item),
+// to T. It returns ErrNoMatch if nothing is selected and a *ValueError if
+// the value can't be converted.
+//
+// T may be a string, bool, integer or floating-point type,
+// time.Duration, time.Time (RFC 3339) or a type whose pointer implements
+// encoding.TextUnmarshaler. Surrounding whitespace is ignored, except for
+// strings. T may also be a pointer to one of these types, which is nil for
+// an element marked with xsi:nil="true", see IsNil.
+func FindAs[T any](top *Node, expr string) (T, error) {
+	var v T
+	s, n, err := firstText(top, expr)
+	if err != nil {
+		return v, err
+	}
+	if n != nil {
+		return v, convertNode(n, expr, &v)
+	}
+	if err := convertText(s, &v); err != nil {
+		return v, &ValueError{Expr: expr, Value: s, Type: reflect.TypeOf(&v).Elem().String(), Err: err}
+	}
+	return v, nil
+}
+
+// FindAllAs is like FindAs, but converts the text of every selected node.
+// It returns an empty slice if nothing is selected.
+func FindAllAs[T any](top *Node, expr string) ([]T, error) {
+	nodes, err := QueryAll(top, expr)
+	if err != nil {
+		return nil, err
+	}
+	values := make([]T, len(nodes))
+	for i, n := range nodes {
+		if err := convertNode(n, expr, &values[i]); err != nil {
+			return nil, err
+		}
+	}
+	return values, nil
+}
+
+// FindString returns the text of the first node selected by expr.
+// See FindAs.
+func FindString(top *Node, expr string) (string, error) {
+	return FindAs[string](top, expr)
+}
+
+// FindInt returns the text of the first node selected by expr as an int.
+// See FindAs.
+func FindInt(top *Node, expr string) (int, error) {
+	return FindAs[int](top, expr)
+}
+
+// FindFloat returns the text of the first node selected by expr as a
+// float64. See FindAs.
+func FindFloat(top *Node, expr string) (float64, error) {
+	return FindAs[float64](top, expr)
+}
+
+// FindBool returns the text of the first node selected by expr as a bool,
+// as parsed by strconv.ParseBool. See FindAs.
+func FindBool(top *Node, expr string) (bool, error) {
+	return FindAs[bool](top, expr)
+}
+
+// FindTime returns the text of the first node selected by expr parsed as a
+// time with the given layout, as in time.Parse. See FindAs.
+func FindTime(top *Node, expr, layout string) (time.Time, error) {
+	s, n, err := firstText(top, expr)
+	if err != nil {
+		return time.Time{}, err
+	}
+	t, err := time.Parse(layout, strings.TrimSpace(s))
+	if err != nil {
+		path := ""
+		if n != nil {
+			path = n.Path()
+		}
+		return time.Time{}, &ValueError{Expr: expr, Path: path, Value: s, Type: "time.Time", Err: err}
+	}
+	return t, nil
+}
+
+// firstText returns the text of the first node selected by expr and the
+// node, or the string value of an expression that doesn't select nodes.
+func firstText(top *Node, expr string) (string, *Node, error) {
+	r, err := Evaluate(top, expr)
+	if err != nil {
+		return "", nil, err
+	}
+	if r.Type != NodeSetResult {
+		return r.String(), nil, nil
+	}
+	if len(r.nodes) == 0 {
+		return "", nil, ErrNoMatch
+	}
+	return r.nodes[0].InnerText(), r.nodes[0], nil
+}
+
+func convertNode[T any](n *Node, expr string, v *T) error {
+	if n.IsNil() && reflect.TypeOf(v).Elem().Kind() == reflect.Ptr {
+		return nil
+	}
+	s := n.InnerText()
+	if err := convertText(s, v); err != nil {
+		return &ValueError{Expr: expr, Path: n.Path(), Value: s, Type: reflect.TypeOf(v).Elem().String(), Err: err}
+	}
+	return nil
+}
+
+// convertText converts s to the value v points to.
+func convertText(s string, v interface{}) error {
+	if u, ok := v.(encoding.TextUnmarshaler); ok {
+		return u.UnmarshalText([]byte(strings.TrimSpace(s)))
+	}
+	switch p := v.(type) {
+	case *string:
+		*p = s
+		return nil
+	case *time.Time:
+		t, err := time.Parse(time.RFC3339Nano, strings.TrimSpace(s))
+		*p = t
+		return err
+	case *time.Duration:
+		d, err := time.ParseDuration(strings.TrimSpace(s))
+		*p = d
+		return err
+	}
+	rv := reflect.ValueOf(v).Elem()
+	switch rv.Kind() {
+	case reflect.String:
+		rv.SetString(s)
+		return nil
+	case reflect.Ptr:
+		elem := reflect.New(rv.Type().Elem())
+		if err := convertText(s, elem.Interface()); err != nil {
+			return err
+		}
+		rv.Set(elem)
+		return nil
+	}
+	s = strings.TrimSpace(s)
+	switch rv.Kind() {
+	case reflect.Bool:
+		b, err := strconv.ParseBool(s)
+		if err != nil {
+			return err
+		}
+		rv.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		i, err := strconv.ParseInt(s, 10, rv.Type().Bits())
+		if err != nil {
+			return err
+		}
+		rv.SetInt(i)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		u, err := strconv.ParseUint(s, 10, rv.Type().Bits())
+		if err != nil {
+			return err
+		}
+		rv.SetUint(u)
+	case reflect.Float32, reflect.Float64:
+		f, err := strconv.ParseFloat(s, rv.Type().Bits())
+		if err != nil {
+			return err
+		}
+		rv.SetFloat(f)
+	default:
+		return fmt.Errorf("unsupported type %s", rv.Type())
+	}
+	return nil
+}
@@ -0,0 +1,86 @@
+package xmlquery
+
+import (
+	"errors"
+	"net/netip"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestFindAs(t *testing.T) {
+	doc, err := Parse(strings.NewReader(`<r>
+  <name> Tea </name>
+  <qty>
+    12
+  </qty>
+  <price>3.25</price>
+  <small>300</small>
+  <ok>true</ok>
+  <at>2024-05-01T10:00:00+02:00</at>
+  <day>01/05/2024</day>
+  <wait>1m30s</wait>
+  <ip>10.0.0.1</ip>
+  <n>1</n><n>2</n><n>x</n>
+</r>`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	s, err := FindString(doc, "//name")
+	testValue(t, err, nil)
+	testValue(t, s, " Tea ")
+	i, err := FindInt(doc, "//qty")
+	testValue(t, err, nil)
+	testValue(t, i, 12)
+	f, err := FindFloat(doc, "//price")
+	testValue(t, err, nil)
+	testValue(t, f, 3.25)
+	b, err := FindBool(doc, "//ok")
+	testValue(t, err, nil)
+	testValue(t, b, true)
+	count, err := FindInt(doc, "count(//n)")
+	testValue(t, err, nil)
+	testValue(t, count, 3)
+
+	at, err := FindAs[time.Time](doc, "//at")
+	testValue(t, err, nil)
+	testTrue(t, at.Equal(time.Date(2024, 5, 1, 8, 0, 0, 0, time.UTC)))
+	day, err := FindTime(doc, "//day", "02/01/2006")
+	testValue(t, err, nil)
+	testTrue(t, day.Equal(time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)))
+	wait, err := FindAs[time.Duration](doc, "//wait")
+	testValue(t, err, nil)
+	testValue(t, wait, 90*time.Second)
+	ip, err := FindAs[netip.Addr](doc, "//ip")
+	testValue(t, err, nil)
+	testValue(t, ip.String(), "10.0.0.1")
+
+	values, err := FindAllAs[int](doc, "//n[position()<3]")
+	testValue(t, err, nil)
+	testValue(t, len(values), 2)
+	testValue(t, values[1], 2)
+
+	if _, err := FindInt(doc, "//missing"); err != ErrNoMatch {
+		t.Fatalf("expected ErrNoMatch, got %v", err)
+	}
+	_, err = FindAs[int8](doc, "//small")
+	var verr *ValueError
+	if !errors.As(err, &verr) {
+		t.Fatalf("expected *ValueError, got %v", err)
+	}
+	testValue(t, verr.Path, "/r[1]/small[1]")
+	testValue(t, verr.Type, "int8")
+	testTrue(t, errors.Is(err, strconv.ErrRange))
+	testValue(t, err.Error(), `xmlquery: //small: cannot convert "300" at /r[1]/small[1] to int8: strconv.ParseInt: parsing "300": value out of range`)
+
+	if _, err := FindAllAs[int](doc, "//n"); !errors.As(err, &verr) || verr.Path != "/r[1]/n[3]" {
+		t.Fatalf("expected *ValueError for third n, got %v", err)
+	}
+	if _, err := FindTime(doc, "//name", time.RFC3339); !errors.As(err, &verr) {
+		t.Fatalf("expected *ValueError, got %v", err)
+	}
+	if _, err := FindAs[[]int](doc, "//n"); !errors.As(err, &verr) {
+		t.Fatalf("expected *ValueError for unsupported type, got %v", err)
+	}
+}
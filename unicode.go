@@ -0,0 +1,47 @@
+package xmlquery
+
+import (
+	"golang.org/x/text/unicode/norm"
+)
+
+// A NormalizationForm is a Unicode normalization form applied to text and
+// attribute values, see ParserOptions.Normalization and
+// WithUnicodeNormalization. Producers may write the same characters in
+// different ways, such as "é" as a single code point or as "e" followed by
+// a combining accent; normalizing makes comparisons, queries and hashes
+// agree on them.
+type NormalizationForm int
+
+const (
+	// NoNormalization keeps strings as they are.
+	NoNormalization NormalizationForm = iota
+	// NFC composes characters, the form recommended for XML documents.
+	NFC
+	// NFD decomposes characters.
+	NFD
+)
+
+// normalize returns s in form f.
+func (f NormalizationForm) normalize(s string) string {
+	var form norm.Form
+	switch f {
+	case NFC:
+		form = norm.NFC
+	case NFD:
+		form = norm.NFD
+	default:
+		return s
+	}
+	if form.IsNormalString(s) {
+		return s
+	}
+	return form.String(s)
+}
+
+// WithUnicodeNormalization writes text, CDATA sections and attribute values
+// in the given normalization form.
+func WithUnicodeNormalization(form NormalizationForm) OutputOption {
+	return func(oc *outputConfiguration) {
+		oc.normalization = form
+	}
+}
@@ -0,0 +1,43 @@
+package xmlquery
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestUnicodeNormalization(t *testing.T) {
+	const (
+		composed   = "caf\u00e9"
+		decomposed = "cafe\u0301"
+	)
+	s := `<r a="` + decomposed + `"><t>` + decomposed + `</t><t>plain</t><![CDATA[` + decomposed + `]]></r>`
+
+	doc, err := ParseWithOptions(strings.NewReader(s), ParserOptions{Normalization: NFC})
+	if err != nil {
+		t.Fatal(err)
+	}
+	testValue(t, FindOne(doc, "/r").SelectAttr("a"), composed)
+	testValue(t, FindOne(doc, "//t").InnerText(), composed)
+	testValue(t, FindOne(doc, "/r").LastChild.Data, composed)
+	testValue(t, len(Find(doc, "//t[. = '"+composed+"']")), 1)
+
+	// Without the option, text is kept as written.
+	doc, err = Parse(strings.NewReader(s))
+	if err != nil {
+		t.Fatal(err)
+	}
+	testValue(t, FindOne(doc, "//t").InnerText(), decomposed)
+	testValue(t, len(Find(doc, "//t[. = '"+composed+"']")), 0)
+	r := FindOne(doc, "/r")
+	testValue(t, r.OutputXMLWithOptions(WithOutputSelf(), WithUnicodeNormalization(NFC)),
+		`<r a="`+composed+`"><t>`+composed+`</t><t>plain</t><![CDATA[`+composed+`]]></r>`)
+	testValue(t, r.OutputXMLWithOptions(WithOutputSelf(), WithUnicodeNormalization(NFD)), s)
+
+	// With RoundTrip, only the nodes that changed lose their source.
+	in := `<r  a="` + decomposed + `"><t  x='1'>` + decomposed + `</t></r>`
+	doc, err = ParseWithOptions(strings.NewReader(in), ParserOptions{RoundTrip: true, Normalization: NFC})
+	if err != nil {
+		t.Fatal(err)
+	}
+	testValue(t, FindOne(doc, "/r").OutputXML(true), `<r a="`+composed+`"><t  x='1'>`+composed+`</t></r>`)
+}
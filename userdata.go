@@ -0,0 +1,34 @@
+package xmlquery
+
+// userData holds the values attached to a node with SetUserData.
+type userData map[interface{}]interface{}
+
+// SetUserData attaches value to n under key, so that analysis passes can
+// annotate nodes, with validation results or provenance for instance,
+// without maintaining maps keyed by node. A nil value removes the entry.
+// As with context.WithValue, key should be of an unexported type of the
+// package that uses it, so that keys of different packages don't collide:
+//
+//	type checkKey struct{}
+//
+//	n.SetUserData(checkKey{}, "invalid price")
+//	msg, _ := n.UserData(checkKey{}).(string)
+//
+// User data is kept by the node only: Clone and the output functions
+// ignore it.
+func (n *Node) SetUserData(key, value interface{}) {
+	if value == nil {
+		delete(n.data, key)
+		return
+	}
+	if n.data == nil {
+		n.data = make(userData)
+	}
+	n.data[key] = value
+}
+
+// UserData returns the value attached to n under key, or nil, see
+// SetUserData.
+func (n *Node) UserData(key interface{}) interface{} {
+	return n.data[key]
+}
@@ -0,0 +1,28 @@
+package xmlquery
+
+import (
+	"strings"
+	"testing"
+)
+
+type testKey struct{ name string }
+
+func TestUserData(t *testing.T) {
+	doc, err := Parse(strings.NewReader(`<r><a/></r>`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	a := FindOne(doc, "//a")
+	testTrue(t, a.UserData(testKey{"check"}) == nil)
+
+	a.SetUserData(testKey{"check"}, "invalid")
+	a.SetUserData(testKey{"line"}, 3)
+	testValue(t, a.UserData(testKey{"check"}), "invalid")
+	testValue(t, a.UserData(testKey{"line"}), 3)
+	testTrue(t, FindOne(doc, "/r").UserData(testKey{"check"}) == nil)
+	testTrue(t, a.Clone().UserData(testKey{"check"}) == nil)
+
+	a.SetUserData(testKey{"check"}, nil)
+	testTrue(t, a.UserData(testKey{"check"}) == nil)
+	testValue(t, a.UserData(testKey{"line"}), 3)
+}
@@ -0,0 +1,50 @@
+package xmlquery
+
+// A Validator checks a subtree, such as the records of a StreamParser.
+//
+// The package has no XML Schema support: it can't compile an XSD, and no
+// Validator checks records against one. A schema validator from another
+// package, or checks written with queries, are attached through this
+// interface.
+type Validator interface {
+	Validate(n *Node) error
+}
+
+// ValidatorFunc adapts a function to the Validator interface.
+type ValidatorFunc func(n *Node) error
+
+// Validate calls f(n).
+func (f ValidatorFunc) Validate(n *Node) error {
+	return f(n)
+}
+
+// A RecordError is returned by StreamParser.Read, together with the record,
+// when the record is not valid, see StreamParser.SetValidator.
+type RecordError struct {
+	Node *Node // the invalid record
+	Err  error // the error returned by the Validator
+}
+
+func (e *RecordError) Error() string {
+	return "xmlquery: invalid record <" + qualifiedName(e.Node) + ">: " + e.Err.Error()
+}
+
+func (e *RecordError) Unwrap() error {
+	return e.Err
+}
+
+// SetValidator makes Read validate each record with v once it has been
+// read entirely, so that documents too large to hold in memory are
+// validated and processed in one pass. An invalid record is returned with
+// a *RecordError; unlike other errors, Read can be called again to
+// continue with the next record. A nil v stops validating.
+//
+//	sp.SetValidator(xmlquery.ValidatorFunc(func(n *xmlquery.Node) error {
+//		if n.SelectAttr("id") == "" {
+//			return errors.New("missing id")
+//		}
+//		return nil
+//	}))
+func (sp *StreamParser) SetValidator(v Validator) {
+	sp.validator = v
+}
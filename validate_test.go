@@ -0,0 +1,46 @@
+package xmlquery
+
+import (
+	"errors"
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestStreamParserValidator(t *testing.T) {
+	s := `<list><item id="1"/><item/><item id="3"/></list>`
+	sp, err := CreateStreamParser(strings.NewReader(s), "/list/item")
+	if err != nil {
+		t.Fatal(err)
+	}
+	errMissingID := errors.New("missing id")
+	sp.SetValidator(ValidatorFunc(func(n *Node) error {
+		if n.SelectAttr("id") == "" {
+			return errMissingID
+		}
+		return nil
+	}))
+
+	var valid int
+	var invalid []*RecordError
+	for {
+		n, err := sp.Read()
+		if err == io.EOF {
+			break
+		}
+		var rerr *RecordError
+		if errors.As(err, &rerr) {
+			testTrue(t, rerr.Node == n)
+			invalid = append(invalid, rerr)
+			continue
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		valid++
+	}
+	testValue(t, valid, 2)
+	testValue(t, len(invalid), 1)
+	testTrue(t, errors.Is(invalid[0], errMissingID))
+	testValue(t, invalid[0].Error(), "xmlquery: invalid record <item>: missing id")
+}
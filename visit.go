@@ -0,0 +1,83 @@
+package xmlquery
+
+import (
+	"errors"
+)
+
+// SkipChildren is returned by a Handler.StartElement callback to skip the
+// content of the element, like fs.SkipDir. EndElement is still called.
+var SkipChildren = errors.New("xmlquery: skip children")
+
+// A Handler receives the events of Node.Visit. Nil callbacks are skipped.
+// A callback returning an error stops the visit, which returns the error.
+type Handler struct {
+	StartElement func(n *Node) error
+	EndElement   func(n *Node) error
+	// Text receives text nodes and CDATA sections.
+	Text    func(n *Node) error
+	Comment func(n *Node) error
+	// ProcInst receives the XML declaration and processing instructions.
+	ProcInst func(n *Node) error
+	// Directive receives DOCTYPE and other directives.
+	Directive func(n *Node) error
+}
+
+// Visit calls the callbacks of h for the nodes of the subtree rooted at n,
+// in document order, as a SAX parser would while reading it: StartElement
+// before the content of an element and EndElement after it. A document
+// itself has no event. The attributes of an element are those of the node
+// passed to StartElement.
+//
+//	depth := 0
+//	err := doc.Visit(xmlquery.Handler{
+//		StartElement: func(n *xmlquery.Node) error { depth++; return nil },
+//		EndElement:   func(n *xmlquery.Node) error { depth--; return nil },
+//	})
+//
+// The subtree must not be modified during the visit.
+func (n *Node) Visit(h Handler) error {
+	call := func(fn func(*Node) error, n *Node) error {
+		if fn == nil {
+			return nil
+		}
+		return fn(n)
+	}
+	var visit func(n *Node) error
+	visit = func(n *Node) error {
+		switch n.Type {
+		case DocumentNode:
+			n.Materialize()
+			for child := n.FirstChild; child != nil; child = child.NextSibling {
+				if err := visit(child); err != nil {
+					return err
+				}
+			}
+			return nil
+		case ElementNode:
+			err := call(h.StartElement, n)
+			if err == SkipChildren {
+				return call(h.EndElement, n)
+			}
+			if err != nil {
+				return err
+			}
+			n.Materialize()
+			for child := n.FirstChild; child != nil; child = child.NextSibling {
+				if err := visit(child); err != nil {
+					return err
+				}
+			}
+			return call(h.EndElement, n)
+		case TextNode, CharDataNode:
+			return call(h.Text, n)
+		case CommentNode:
+			return call(h.Comment, n)
+		case DeclarationNode:
+			return call(h.ProcInst, n)
+		case NotationNode:
+			return call(h.Directive, n)
+		}
+		return nil
+	}
+	return visit(n)
+}
@@ -0,0 +1,58 @@
+package xmlquery
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestVisit(t *testing.T) {
+	s := `<?xml version="1.0"?><!DOCTYPE r><r a="1"><!--c--><x>t<![CDATA[d]]></x><?pi v?><skip><y/></skip></r>`
+	doc, err := Parse(strings.NewReader(s))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var events []string
+	event := func(kind string) func(*Node) error {
+		return func(n *Node) error {
+			events = append(events, kind+" "+n.Data)
+			if kind == "start" && n.Data == "skip" {
+				return SkipChildren
+			}
+			return nil
+		}
+	}
+	h := Handler{
+		StartElement: event("start"),
+		EndElement:   event("end"),
+		Text:         event("text"),
+		Comment:      event("comment"),
+		ProcInst:     event("pi"),
+		Directive:    event("directive"),
+	}
+	if err := doc.Visit(h); err != nil {
+		t.Fatal(err)
+	}
+	testValue(t, strings.Join(events, ", "),
+		"pi xml, directive DOCTYPE r, start r, comment c, start x, text t, text d, end x, pi pi, start skip, end skip, end r")
+
+	// Only the callbacks that are set are called, from the given node.
+	events = nil
+	if err := FindOne(doc, "//x").Visit(Handler{Text: event("text")}); err != nil {
+		t.Fatal(err)
+	}
+	testValue(t, strings.Join(events, ", "), "text t, text d")
+
+	// An error stops the visit.
+	errStop := errors.New("stop")
+	events = nil
+	err = doc.Visit(Handler{StartElement: func(n *Node) error {
+		events = append(events, n.Data)
+		if n.Data == "x" {
+			return errStop
+		}
+		return nil
+	}})
+	testTrue(t, err == errStop)
+	testValue(t, strings.Join(events, ", "), "r, x")
+}
@@ -0,0 +1,170 @@
+package xmlquery
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"os"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// WatchOptions control how WatchFileWithOptions watches a file.
+type WatchOptions struct {
+	// Parser are the options used to parse the file.
+	Parser ParserOptions
+	// Interval is how often the file is checked for changes; the default
+	// is one second.
+	Interval time.Duration
+	// OnError is called, if not nil, when the changed file can't be read or
+	// parsed, for example because it is being written. The current
+	// document is kept until the file changes again and parses.
+	OnError func(error)
+}
+
+// A FileWatcher holds the document parsed from a file and parses it again
+// when the file changes, see WatchFile.
+type FileWatcher struct {
+	path    string
+	options WatchOptions
+	// onReload is called with each new document.
+	onReload func(*Node)
+
+	doc  atomic.Pointer[Node]
+	stat os.FileInfo       // of the loaded file, or of the last failed attempt
+	sum  [sha256.Size]byte // of the content of the loaded file
+
+	stop chan struct{}
+	done chan struct{}
+	once sync.Once
+}
+
+// WatchFile loads the XML file at path, like LoadFile, and checks it for
+// changes every second until the watcher is closed. When the file has
+// changed, it is parsed again, in the background, and the new document
+// replaces the current one atomically before onReload, if not nil, is
+// called with it. A file that can't be parsed, such as one that is being
+// written, leaves the current document in place. The document is shared by
+// the readers, which must not modify it:
+//
+//	w, err := xmlquery.WatchFile("config.xml", func(doc *xmlquery.Node) {
+//		log.Print("configuration reloaded")
+//	})
+//	if err != nil {
+//		return err
+//	}
+//	defer w.Close()
+//	timeout := xmlquery.FindOne(w.Document(), "//timeout")
+//
+// Changes are found by polling the size, modification time and identity of
+// the file, so that files replaced by a rename, as done by SaveFile, are
+// noticed too. An error is returned if the file can't be loaded at first.
+func WatchFile(path string, onReload func(*Node)) (*FileWatcher, error) {
+	return WatchFileWithOptions(path, onReload, WatchOptions{})
+}
+
+// WatchFileWithOptions is like WatchFile, but with custom options.
+func WatchFileWithOptions(path string, onReload func(*Node), options WatchOptions) (*FileWatcher, error) {
+	if options.Interval <= 0 {
+		options.Interval = time.Second
+	}
+	w := &FileWatcher{
+		path:     path,
+		options:  options,
+		onReload: onReload,
+		stop:     make(chan struct{}),
+		done:     make(chan struct{}),
+	}
+	stat, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	doc, err := w.load(stat)
+	if err != nil {
+		return nil, err
+	}
+	w.doc.Store(doc)
+	go w.watch()
+	return w, nil
+}
+
+// Document returns the document parsed last.
+func (w *FileWatcher) Document() *Node {
+	return w.doc.Load()
+}
+
+// Close stops watching the file. It waits for a reload in progress, and
+// its call of onReload, to finish. The document remains available.
+func (w *FileWatcher) Close() error {
+	w.once.Do(func() { close(w.stop) })
+	<-w.done
+	return nil
+}
+
+func (w *FileWatcher) watch() {
+	defer close(w.done)
+	ticker := time.NewTicker(w.options.Interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-w.stop:
+			return
+		case <-ticker.C:
+			w.check()
+		}
+	}
+}
+
+// check reloads the file if it has changed since the last attempt.
+func (w *FileWatcher) check() {
+	stat, err := os.Stat(w.path)
+	if err != nil {
+		// Report a missing file once, not at every check.
+		if w.stat != nil {
+			w.stat = nil
+			w.error(err)
+		}
+		return
+	}
+	if w.stat != nil && os.SameFile(stat, w.stat) && stat.Size() == w.stat.Size() && stat.ModTime().Equal(w.stat.ModTime()) {
+		return
+	}
+	doc, err := w.load(stat)
+	if err != nil {
+		w.error(fmt.Errorf("xmlquery: reloading %s: %w", w.path, err))
+		return
+	}
+	if doc == nil {
+		return // same content
+	}
+	w.doc.Store(doc)
+	if w.onReload != nil {
+		w.onReload(doc)
+	}
+}
+
+// load reads and parses the file, whose state is stat. It returns nil if
+// the content hasn't changed.
+func (w *FileWatcher) load(stat os.FileInfo) (*Node, error) {
+	w.stat = stat
+	data, err := os.ReadFile(w.path)
+	if err != nil {
+		return nil, err
+	}
+	sum := sha256.Sum256(data)
+	if w.doc.Load() != nil && sum == w.sum {
+		return nil, nil
+	}
+	doc, err := parseFileData(w.path, data, w.options.Parser)
+	if err != nil {
+		return nil, err
+	}
+	w.sum = sum
+	return doc, nil
+}
+
+func (w *FileWatcher) error(err error) {
+	if w.options.OnError != nil {
+		w.options.OnError(err)
+	}
+}
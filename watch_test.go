@@ -0,0 +1,75 @@
+package xmlquery
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestWatchFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.xml")
+	write := func(s string, age time.Duration) {
+		t.Helper()
+		if err := os.WriteFile(path, []byte(s), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		// Don't depend on the resolution of modification times.
+		mtime := time.Now().Add(-age)
+		if err := os.Chtimes(path, mtime, mtime); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write(`<config><timeout>5</timeout></config>`, 3*time.Hour)
+
+	reloaded := make(chan *Node, 1)
+	errs := make(chan error, 1)
+	w, err := WatchFileWithOptions(path, func(doc *Node) { reloaded <- doc }, WatchOptions{
+		Interval: 5 * time.Millisecond,
+		OnError:  func(err error) { errs <- err },
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer w.Close()
+	testValue(t, FindOne(w.Document(), "//timeout").InnerText(), "5")
+
+	write(`<config><timeout>10</timeout></config>`, 2*time.Hour)
+	select {
+	case doc := <-reloaded:
+		testValue(t, FindOne(doc, "//timeout").InnerText(), "10")
+		testTrue(t, w.Document() == doc)
+	case err := <-errs:
+		t.Fatal(err)
+	case <-time.After(5 * time.Second):
+		t.Fatal("file not reloaded")
+	}
+
+	write(`<config><timeout>`, time.Hour)
+	select {
+	case doc := <-reloaded:
+		t.Fatalf("invalid file reloaded as %s", doc.OutputXML(false))
+	case err := <-errs:
+		testTrue(t, strings.Contains(err.Error(), "config.xml"))
+	case <-time.After(5 * time.Second):
+		t.Fatal("no error reported")
+	}
+	testValue(t, FindOne(w.Document(), "//timeout").InnerText(), "10")
+
+	write(`<config><timeout>20</timeout></config>`, 0)
+	select {
+	case doc := <-reloaded:
+		testValue(t, FindOne(doc, "//timeout").InnerText(), "20")
+	case err := <-errs:
+		t.Fatal(err)
+	case <-time.After(5 * time.Second):
+		t.Fatal("file not reloaded")
+	}
+	testTrue(t, w.Close() == nil)
+	testTrue(t, w.Close() == nil)
+
+	if _, err := WatchFile(filepath.Join(t.TempDir(), "missing.xml"), nil); err == nil {
+		t.Error("WatchFile of a missing file returned no error")
+	}
+}
@@ -0,0 +1,110 @@
+package xmlquery
+
+import (
+	"bytes"
+	"strings"
+
+	"github.com/suifengpiao14/xmlquery/xml"
+)
+
+// violation reports a well-formedness error found by the parser rather than
+// the decoder, at the current position. It returns the error to fail with,
+// or nil if the parser recovers from errors, in which case the error is
+// added to the diagnostics.
+func (p *parser) violation(msg string) error {
+	line, _ := p.decoder.InputPos()
+	err := p.parseError(&xml.SyntaxError{Msg: msg, Line: line})
+	if !p.recover {
+		return err
+	}
+	p.diagnostics = append(p.diagnostics, err.(*ParseError))
+	return nil
+}
+
+// checkStartElement checks the constraints on a start element that the
+// decoder doesn't, see ParserOptions.WellFormed. It is called after the
+// namespaces the element declares have been added to p.space2prefix.
+func (p *parser) checkStartElement(tok *xml.StartElement) error {
+	if p.level <= 1 {
+		if p.rootSeen {
+			if err := p.violation("more than one root element"); err != nil {
+				return err
+			}
+		}
+		p.rootSeen = true
+	}
+	if space := tok.Name.Space; space != "" {
+		if _, found := p.space2prefix[space]; !found {
+			if err := p.violation("namespace prefix " + space + " is not declared"); err != nil {
+				return err
+			}
+		}
+	}
+	for _, attr := range tok.Attr {
+		var msg string
+		switch {
+		case attr.Name.Space == "xmlns" && attr.Name.Local == "xmlns":
+			msg = "the xmlns prefix cannot be declared"
+		case attr.Name.Space == "xmlns" && attr.Value == "":
+			msg = "namespace prefix " + attr.Name.Local + " cannot be undeclared"
+		case attr.Name.Space == "xmlns" || attr.Name.Space == "":
+			continue
+		default:
+			if _, found := p.space2prefix[attr.Name.Space]; !found {
+				msg = "namespace prefix " + attr.Name.Space + " is not declared"
+			}
+		}
+		if msg != "" {
+			if err := p.violation(msg); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// checkProlog checks the constraints on character data, processing
+// instructions and document type declarations outside the root element,
+// see ParserOptions.WellFormed. start is the offset of tok in the input.
+func (p *parser) checkProlog(tok xml.Token, start int64) error {
+	switch tok := tok.(type) {
+	case xml.CharData:
+		if start == 0 {
+			tok = bytes.TrimPrefix(tok, utf8BOM)
+		}
+		if p.level <= 1 && !isWhitespace(tok) {
+			return p.violation("text outside the root element")
+		}
+	case xml.ProcInst:
+		if !strings.EqualFold(tok.Target, "xml") {
+			break
+		}
+		if tok.Target != "xml" {
+			return p.violation("processing instruction target " + tok.Target + " is reserved")
+		}
+		if start > 0 && !(start == int64(len(utf8BOM)) && p.doc.BOM() == "UTF-8") {
+			return p.violation("XML declaration allowed only at the start of the document")
+		}
+	case xml.Directive:
+		if !strings.HasPrefix(string(tok), "DOCTYPE") {
+			break
+		}
+		switch {
+		case p.level > 1 || p.rootSeen:
+			return p.violation("DOCTYPE allowed only before the root element")
+		case p.doctypeSeen:
+			return p.violation("more than one DOCTYPE")
+		}
+		p.doctypeSeen = true
+	}
+	return nil
+}
+
+// checkEnd checks that the document had a root element, see
+// ParserOptions.WellFormed.
+func (p *parser) checkEnd() error {
+	if !p.rootSeen {
+		return p.violation("no root element")
+	}
+	return nil
+}
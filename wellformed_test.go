@@ -0,0 +1,68 @@
+package xmlquery
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestWellFormed(t *testing.T) {
+	for _, test := range []struct {
+		input, want string
+	}{
+		{"<a/><b/>", "line 1, column 5: more than one root element"},
+		{"x<a/>", "line 1, column 1: text outside the root element"},
+		{"<a/>\n x", "line 1, column 5: text outside the root element"},
+		{"", "line 1, column 1: no root element"},
+		{"<!-- c -->", "line 1, column 11: no root element"},
+		{"<a><?xml version='1.0'?></a>", "line 1, column 4: XML declaration allowed only at the start of the document"},
+		{"<?XML version='1.0'?><a/>", "line 1, column 1: processing instruction target XML is reserved"},
+		{"<a p:x='1'/>", "line 1, column 1: namespace prefix p is not declared"},
+		{"<p:a/>", "line 1, column 1: namespace prefix p is not declared"},
+		{"<a xmlns:p=''/>", "line 1, column 1: namespace prefix p cannot be undeclared"},
+		{"<a x='1' x='2'/>", "line 1, column 1: duplicate attribute x"},
+		{"<a/><!DOCTYPE a>", "line 1, column 5: DOCTYPE allowed only before the root element"},
+		{"<!DOCTYPE a><!DOCTYPE a><a/>", "line 1, column 13: more than one DOCTYPE"},
+		{"<a>&foo;</a>", "line 1, column 4: invalid character entity &foo;"},
+		{"<a></b>", "line 1, column 4: element <a> closed by </b>"},
+	} {
+		_, err := ParseWithOptions(strings.NewReader(test.input), ParserOptions{WellFormed: true})
+		var perr *ParseError
+		if !errors.As(err, &perr) {
+			t.Errorf("%q: got %v, want a *ParseError", test.input, err)
+			continue
+		}
+		if got := "line " + strings.TrimPrefix(strings.SplitN(err.Error(), " (near", 2)[0], "xmlquery: line "); got != test.want {
+			t.Errorf("%q: got %q, want %q", test.input, got, test.want)
+		}
+	}
+
+	for _, s := range []string{
+		"<a/>",
+		"\uFEFF<?xml version='1.0'?>\n<!DOCTYPE a>\n<!-- c -->\n<a xmlns:p='urn:p' p:x='1'><p:b/></a>\n<?pi?>\n",
+	} {
+		if _, err := ParseWithOptions(strings.NewReader(s), ParserOptions{WellFormed: true}); err != nil {
+			t.Errorf("%q: %v", s, err)
+		}
+	}
+}
+
+func TestWellFormedRecover(t *testing.T) {
+	const s = "<?xml version='1.0'?>\n<a x='1' x='2'>\n  <b>&nbsp;</b>\n  <p:c/>\n  <c></d></a>\n<e/>"
+	_, diags, err := ParseRecover(strings.NewReader(s), ParserOptions{WellFormed: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, d := range diags {
+		got = append(got, strings.SplitN(d.Error(), " (near", 2)[0])
+	}
+	testValue(t, strings.Join(got, "\n"), strings.Join([]string{
+		"xmlquery: line 2, column 1: duplicate attribute x",
+		"xmlquery: line 3, column 6: invalid character entity &nbsp;",
+		"xmlquery: line 4, column 3: namespace prefix p is not declared",
+		"xmlquery: line 5, column 6: unexpected end element </d>",
+		"xmlquery: line 5, column 10: element <c> closed by </a>",
+		"xmlquery: line 6, column 1: more than one root element",
+	}, "\n"))
+}
@@ -174,6 +174,21 @@ type Decoder struct {
 	// of whether an end element is present.
 	AutoClose []string
 
+	// StrayEndTags makes an end tag that doesn't match any open element
+	// a syntax error, instead of closing all open elements when Strict ==
+	// false. With ClearError, the end tag is then skipped. When Strict ==
+	// true, an end tag that matches an open element other than the
+	// innermost one is a syntax error too; once it has been cleared, the
+	// end tag also closes the elements in between, as when Strict ==
+	// false.
+	StrayEndTags bool
+
+	// ReplaceInvalidUTF8 makes the decoder replace each byte that is not
+	// part of a valid UTF-8 sequence in character data, attribute values,
+	// comments and processing instructions with U+FFFD, instead of
+	// returning a syntax error. Names must still be valid UTF-8.
+	ReplaceInvalidUTF8 bool
+
 	// Entity can be used to map non-standard entity names to string replacements.
 	// The parser behaves as if these standard mappings are present in the map,
 	// regardless of the actual map content:
@@ -185,6 +200,19 @@ type Decoder struct {
 	//	"quot": `"`,
 	Entity map[string]string
 
+	// DefaultEntity, if non-nil, is called with the entity names that are
+	// neither standard nor in Entity, and returns their replacement text
+	// and true if it knows them, so that large tables, such as the named
+	// character references of HTML5, can be used without copying them.
+	DefaultEntity func(name string) (string, bool)
+
+	// UndefinedEntity, if non-nil, is called when Strict == false with each
+	// entity reference that is kept as text because it is undefined or
+	// malformed, such as "&bogus;" or "&" followed by a space, right after
+	// the reference has been read. Strict mode returns a syntax error for
+	// such references instead.
+	UndefinedEntity func(ref string)
+
 	// CharsetReader, if non-nil, defines a function to generate
 	// charset-conversion readers, converting from the provided
 	// non-UTF-8 charset into UTF-8. If CharsetReader is nil or
@@ -192,6 +220,11 @@ type Decoder struct {
 	// CharsetReader's result values must be non-nil.
 	CharsetReader func(charset string, input io.Reader) (io.Reader, error)
 
+	// IgnoreDeclaredEncoding makes the decoder disregard the encoding
+	// named in the XML declaration, for input that has already been
+	// converted to UTF-8, such as after detecting a byte order mark.
+	IgnoreDeclaredEncoding bool
+
 	// DefaultSpace sets the default name space used for unadorned tags,
 	// as if the entire XML stream were wrapped in an element containing
 	// the attribute xmlns="DefaultSpace".
@@ -207,6 +240,9 @@ type Decoder struct {
 	toClose        Name
 	nextToken      Token
 	nextByte       int
+	emptyTagEnd    bool  // readName stopped at the '/' of "/>"
+	cdata          bool  // the last token read was a CDATA section
+	closeReported  bool  // the end tag in nextToken closing several elements has been reported, see StrayEndTags
 	ns             map[string]string
 	err            error
 	line           int
@@ -473,14 +509,29 @@ func (d *Decoder) syntaxError(msg string) error {
 // the stack to restore the name translations that existed
 // before we saw this element.
 func (d *Decoder) popElement(t *EndElement) bool {
-	s := d.pop()
 	name := t.Name
+	if d.StrayEndTags && !d.isOpen(name.Local) {
+		d.err = d.syntaxError("unexpected end element </" + name.Local + ">")
+		return false
+	}
+	s := d.pop()
 	switch {
 	case s == nil || s.kind != stkStart:
 		d.err = d.syntaxError("unexpected end element </" + name.Local + ">")
 		return false
 	case s.name.Local != name.Local:
-		if !d.Strict {
+		if d.Strict && d.StrayEndTags && !d.closeReported {
+			// Report the error while the end tag is the last token
+			// read. Once it has been cleared, the end tag is processed
+			// again and closes the open elements.
+			d.stk, d.free, s.next = s, s.next, d.stk
+			d.nextToken = EndElement{name}
+			d.closeReported = true
+			d.err = d.syntaxError("element <" + s.name.Local + "> closed by </" + name.Local + ">")
+			return false
+		}
+		if !d.Strict || d.StrayEndTags {
+			d.closeReported = false
 			d.needClose = true
 			d.toClose = t.Name
 			t.Name = s.name
@@ -510,6 +561,16 @@ func (d *Decoder) popElement(t *EndElement) bool {
 	return true
 }
 
+// isOpen reports whether an element with the given local name is open.
+func (d *Decoder) isOpen(local string) bool {
+	for s := d.stk; s != nil; s = s.next {
+		if s.kind == stkStart && s.name.Local == local {
+			return true
+		}
+	}
+	return false
+}
+
 // If the top element on the stack is autoclosing and
 // t is not the end tag, invent the end tag.
 func (d *Decoder) autoClose(t Token) (Token, bool) {
@@ -542,6 +603,7 @@ func (d *Decoder) RawToken() (Token, error) {
 }
 
 func (d *Decoder) rawToken() (Token, error) {
+	d.cdata = false
 	if d.t != nil {
 		return d.t.Token()
 	}
@@ -617,7 +679,10 @@ func (d *Decoder) rawToken() (Token, error) {
 			b0 = b
 		}
 		data := d.buf.Bytes()
-		data = data[0 : len(data)-2] // chop ?>
+		data = d.checkUTF8(data[0 : len(data)-2]) // chop ?>
+		if data == nil {
+			return nil, d.err
+		}
 
 		if target == "xml" {
 			content := string(data)
@@ -627,7 +692,7 @@ func (d *Decoder) rawToken() (Token, error) {
 				return nil, d.err
 			}
 			enc := procInst("encoding", content)
-			if enc != "" && enc != "utf-8" && enc != "UTF-8" && !strings.EqualFold(enc, "utf-8") {
+			if enc != "" && enc != "utf-8" && enc != "UTF-8" && !strings.EqualFold(enc, "utf-8") && !d.IgnoreDeclaredEncoding {
 				if d.CharsetReader == nil {
 					d.err = fmt.Errorf("xml: encoding %q declared but Decoder.CharsetReader is nil", enc)
 					return nil, d.err
@@ -679,7 +744,10 @@ func (d *Decoder) rawToken() (Token, error) {
 				b0, b1 = b1, b
 			}
 			data := d.buf.Bytes()
-			data = data[0 : len(data)-3] // chop -->
+			data = d.checkUTF8(data[0 : len(data)-3]) // chop -->
+			if data == nil {
+				return nil, d.err
+			}
 			return Comment(data), nil
 
 		case '[': // <![
@@ -698,6 +766,7 @@ func (d *Decoder) rawToken() (Token, error) {
 			if data == nil {
 				return nil, d.err
 			}
+			d.cdata = true
 			return CharData(data), nil
 		}
 
@@ -788,9 +857,15 @@ func (d *Decoder) rawToken() (Token, error) {
 
 	attr = []Attr{}
 	for {
-		d.space()
-		if b, ok = d.mustgetc(); !ok {
-			return nil, d.err
+		if d.emptyTagEnd {
+			// readName already consumed the '/' of "/>".
+			d.emptyTagEnd = false
+			b = '/'
+		} else {
+			d.space()
+			if b, ok = d.mustgetc(); !ok {
+				return nil, d.err
+			}
 		}
 		if b == '/' {
 			empty = true
@@ -921,6 +996,14 @@ func (d *Decoder) getc() (b byte, ok bool) {
 	return b, true
 }
 
+// ClearError forgets the syntax error the decoder stopped at, so that the
+// next call to Token resumes reading after the input consumed so far. The
+// element stack is kept, so a document with a stray character or a bad end
+// tag can be decoded to its end, with the offending input skipped.
+func (d *Decoder) ClearError() {
+	d.err = nil
+}
+
 // InputOffset returns the input stream byte offset of the current decoder position.
 // The offset gives the location of the end of the most recently returned token
 // and the beginning of the next token.
@@ -928,6 +1011,12 @@ func (d *Decoder) InputOffset() int64 {
 	return d.offset
 }
 
+// CDATA reports whether the CharData most recently returned by Token or
+// RawToken was read from a <![CDATA[...]]> section.
+func (d *Decoder) CDATA() bool {
+	return d.cdata
+}
+
 // InputPos returns the line of the current decoder position and the 1 based
 // input position of the line. The position gives the location of the end of the
 // most recently returned token.
@@ -1089,6 +1178,9 @@ Input:
 						} else if d.Entity != nil {
 							text, haveText = d.Entity[s]
 						}
+						if !haveText && d.DefaultEntity != nil {
+							text, haveText = d.DefaultEntity(s)
+						}
 					}
 				}
 			}
@@ -1100,6 +1192,9 @@ Input:
 				continue Input
 			}
 			if !d.Strict {
+				if d.UndefinedEntity != nil {
+					d.UndefinedEntity(string(d.buf.Bytes()[before:]))
+				}
 				b0, b1 = 0, 0
 				continue Input
 			}
@@ -1125,6 +1220,10 @@ Input:
 	data := d.buf.Bytes()
 	data = data[0 : len(data)-trunc]
 
+	if d.ReplaceInvalidUTF8 && !utf8.Valid(data) {
+		data = replaceInvalidUTF8(data)
+	}
+
 	// Inspect each rune for being a disallowed character.
 	buf := data
 	for len(buf) > 0 {
@@ -1143,6 +1242,36 @@ Input:
 	return data
 }
 
+// checkUTF8 returns data, with invalid UTF-8 replaced if
+// d.ReplaceInvalidUTF8 is set. Otherwise it sets d.err and returns nil if
+// data is not valid UTF-8.
+func (d *Decoder) checkUTF8(data []byte) []byte {
+	if utf8.Valid(data) {
+		return data
+	}
+	if d.ReplaceInvalidUTF8 {
+		return replaceInvalidUTF8(data)
+	}
+	d.err = d.syntaxError("invalid UTF-8")
+	return nil
+}
+
+// replaceInvalidUTF8 returns a copy of data in which each byte that is not
+// part of a valid UTF-8 sequence is replaced with U+FFFD.
+func replaceInvalidUTF8(data []byte) []byte {
+	out := make([]byte, 0, len(data)+8)
+	for len(data) > 0 {
+		r, size := utf8.DecodeRune(data)
+		if r == utf8.RuneError && size == 1 {
+			out = utf8.AppendRune(out, utf8.RuneError)
+		} else {
+			out = append(out, data[:size]...)
+		}
+		data = data[size:]
+	}
+	return out
+}
+
 // Decide whether the given rune is in the XML Character Range, per
 // the Char production of https://www.xml.com/axml/testaxml.htm,
 // Section 2.2 Characters.
@@ -1195,6 +1324,7 @@ func (d *Decoder) name() (s string, ok bool) {
 // The name is delimited by any single-byte character not valid in names.
 // All multi-byte characters are accepted; the caller must check their validity.
 func (d *Decoder) readName() (ok bool) {
+	d.emptyTagEnd = false
 	var b byte
 	if b, ok = d.mustgetc(); !ok {
 		return
@@ -1209,6 +1339,19 @@ func (d *Decoder) readName() (ok bool) {
 		if b, ok = d.mustgetc(); !ok {
 			return
 		}
+		if b == '/' {
+			// A '/' directly followed by '>' ends an empty element tag
+			// like <a/>, it isn't part of the name.
+			var next byte
+			if next, ok = d.mustgetc(); !ok {
+				return
+			}
+			d.ungetc(next)
+			if next == '>' {
+				d.emptyTagEnd = true
+				break
+			}
+		}
 		if b < utf8.RuneSelf && !isNameByte(b) {
 			d.ungetc(b)
 			break
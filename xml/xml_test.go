@@ -325,6 +325,26 @@ var nonStrictTokens = []Token{
 	CharData("\n"),
 }
 
+func TestDecoderCDATA(t *testing.T) {
+	d := NewDecoder(strings.NewReader(`<a>x<![CDATA[y]]>z</a>`))
+	var got []bool
+	for {
+		tok, err := d.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, ok := tok.(CharData); ok {
+			got = append(got, d.CDATA())
+		}
+	}
+	if want := []bool{false, true, false}; !reflect.DeepEqual(got, want) {
+		t.Errorf("CDATA() = %v, want %v", got, want)
+	}
+}
+
 func TestNonStrictRawToken(t *testing.T) {
 	d := NewDecoder(strings.NewReader(nonStrictInput))
 	d.Strict = false
@@ -1421,3 +1441,60 @@ func TestHTMLAutoClose(t *testing.T) {
 		}
 	}
 }
+
+func TestEmptyElementTagEnd(t *testing.T) {
+	// '/' is accepted in names for template placeholders like {{/name}},
+	// but a '/' directly followed by '>' ends an empty element tag.
+	d := NewDecoder(strings.NewReader(`<r><a/><b x="1"/><c{{/x}}></c{{/x}}></r>`))
+	var have []Token
+	for {
+		tok, err := d.RawToken()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		have = append(have, CopyToken(tok))
+	}
+	want := []Token{
+		StartElement{Name: Name{Local: "r"}, Attr: []Attr{}},
+		StartElement{Name: Name{Local: "a"}, Attr: []Attr{}},
+		EndElement{Name: Name{Local: "a"}},
+		StartElement{Name: Name{Local: "b"}, Attr: []Attr{{Name: Name{Local: "x"}, Value: "1"}}},
+		EndElement{Name: Name{Local: "b"}},
+		StartElement{Name: Name{Local: "c{{/x}}"}, Attr: []Attr{}},
+		EndElement{Name: Name{Local: "c{{/x}}"}},
+		EndElement{Name: Name{Local: "r"}},
+	}
+	if !reflect.DeepEqual(have, want) {
+		t.Errorf("tokens mismatch:\nhave: %#v\nwant: %#v", have, want)
+	}
+}
+
+func TestStrayEndTags(t *testing.T) {
+	d := NewDecoder(strings.NewReader(`<a><b></c></b></a>`))
+	d.Strict = false
+	d.StrayEndTags = true
+	var got []string
+	for {
+		tok, err := d.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			got = append(got, "error")
+			d.ClearError()
+			continue
+		}
+		switch tok := tok.(type) {
+		case StartElement:
+			got = append(got, "<"+tok.Name.Local+">")
+		case EndElement:
+			got = append(got, "</"+tok.Name.Local+">")
+		}
+	}
+	if want := "<a> <b> error </b> </a>"; strings.Join(got, " ") != want {
+		t.Errorf("got %q, want %q", strings.Join(got, " "), want)
+	}
+}
@@ -0,0 +1,309 @@
+package xmlquery
+
+import (
+	"crypto"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/rsa"
+	_ "crypto/sha1" // for RSA-OAEP digests
+	"crypto/sha256"
+	_ "crypto/sha512"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// Identifiers used in XML Encryption.
+const (
+	// XMLEncNS is the namespace of XML Encryption elements.
+	XMLEncNS = "http://www.w3.org/2001/04/xmlenc#"
+	// XMLEnc11NS is the namespace of XML Encryption 1.1 elements.
+	XMLEnc11NS = "http://www.w3.org/2009/xmlenc11#"
+
+	EncryptionTypeElement = "http://www.w3.org/2001/04/xmlenc#Element"
+	EncryptionTypeContent = "http://www.w3.org/2001/04/xmlenc#Content"
+
+	EncryptionAES128GCM = "http://www.w3.org/2009/xmlenc11#aes128-gcm"
+	EncryptionAES192GCM = "http://www.w3.org/2009/xmlenc11#aes192-gcm"
+	EncryptionAES256GCM = "http://www.w3.org/2009/xmlenc11#aes256-gcm"
+
+	KeyTransportRSAOAEP      = "http://www.w3.org/2009/xmlenc11#rsa-oaep"
+	KeyTransportRSAOAEPMGF1P = "http://www.w3.org/2001/04/xmlenc#rsa-oaep-mgf1p"
+)
+
+// ErrDecryption is returned, possibly wrapped, by DecryptElement when the
+// encrypted data can't be decrypted.
+var ErrDecryption = errors.New("xmlquery: decryption failed")
+
+// EncryptElement replaces the element n with an xenc:EncryptedData element
+// holding n, its attributes and descendants, encrypted with a random
+// AES-256-GCM key. The key is encrypted for key with RSA-OAEP (SHA-256) and
+// included as an EncryptedKey. It returns the EncryptedData element.
+//
+// n must have a parent. The plaintext doesn't declare the namespaces n
+// inherits, as they are in scope again where it is decrypted.
+func EncryptElement(n *Node, key *rsa.PublicKey) (*Node, error) {
+	if n.Type != ElementNode || n.Parent == nil {
+		return nil, errors.New("xmlquery: can only encrypt an element in a tree")
+	}
+	plaintext := n.OutputXMLWithOptions(WithOutputSelf(), WithPreserveSpace(), WithoutNamespaceFixup())
+
+	cek := make([]byte, 32)
+	if _, err := rand.Read(cek); err != nil {
+		return nil, err
+	}
+	block, _ := aes.NewCipher(cek)
+	gcm, _ := cipher.NewGCM(block)
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+	// The nonce precedes the ciphertext and tag, as in XML Encryption 1.1.
+	data := gcm.Seal(nonce, nonce, []byte(plaintext), nil)
+	encryptedKey, err := rsa.EncryptOAEP(sha256.New(), rand.Reader, key, cek, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	newElement := func(parent *Node, prefix, ns, name string) *Node {
+		elem := &Node{Type: ElementNode, Data: name, Prefix: prefix, NamespaceURI: ns}
+		if parent != nil {
+			AddChild(parent, elem)
+		}
+		return elem
+	}
+	newCipherData := func(parent *Node, value []byte) {
+		v := newElement(newElement(parent, "xenc", XMLEncNS, "CipherData"), "xenc", XMLEncNS, "CipherValue")
+		AddChild(v, &Node{Type: TextNode, Data: base64.StdEncoding.EncodeToString(value)})
+	}
+	enc := newElement(nil, "xenc", XMLEncNS, "EncryptedData")
+	enc.SetAttr("xmlns:xenc", XMLEncNS)
+	enc.SetAttr("Type", EncryptionTypeElement)
+	newElement(enc, "xenc", XMLEncNS, "EncryptionMethod").SetAttr("Algorithm", EncryptionAES256GCM)
+	keyInfo := newElement(enc, "ds", DSigNS, "KeyInfo")
+	keyInfo.SetAttr("xmlns:ds", DSigNS)
+	ek := newElement(keyInfo, "xenc", XMLEncNS, "EncryptedKey")
+	method := newElement(ek, "xenc", XMLEncNS, "EncryptionMethod")
+	method.SetAttr("Algorithm", KeyTransportRSAOAEP)
+	newElement(method, "ds", DSigNS, "DigestMethod").SetAttr("Algorithm", DigestSHA256)
+	mgf := newElement(method, "xenc11", XMLEnc11NS, "MGF")
+	mgf.SetAttr("xmlns:xenc11", XMLEnc11NS)
+	mgf.SetAttr("Algorithm", XMLEnc11NS+"mgf1sha256")
+	newCipherData(ek, encryptedKey)
+	newCipherData(enc, data)
+
+	setLevel(enc, n.level)
+	replaceNode(n, enc)
+	return enc, nil
+}
+
+// DecryptElement decrypts the xenc:EncryptedData element n with key and
+// replaces it with the decrypted nodes, which it returns: the encrypted
+// element for data of type Element, or the encrypted children for type
+// Content.
+//
+// The content must be encrypted with AES-GCM and the content key included
+// as an EncryptedKey encrypted with RSA-OAEP. key is typically an
+// *rsa.PrivateKey.
+func DecryptElement(n *Node, key crypto.Decrypter) ([]*Node, error) {
+	if n.Type != ElementNode || n.NamespaceURI != XMLEncNS || n.Data != "EncryptedData" {
+		return nil, errors.New("xmlquery: not an EncryptedData element")
+	}
+	if n.Parent == nil {
+		return nil, errors.New("xmlquery: EncryptedData element has no parent")
+	}
+	failed := func(format string, args ...interface{}) error {
+		return fmt.Errorf("%w: %s", ErrDecryption, fmt.Sprintf(format, args...))
+	}
+	typ := n.SelectAttr("Type")
+	if typ != EncryptionTypeElement && typ != EncryptionTypeContent {
+		return nil, failed("unsupported type %q", typ)
+	}
+	var keySize int
+	switch alg := encryptionAlgorithm(n); alg {
+	case EncryptionAES128GCM:
+		keySize = 16
+	case EncryptionAES192GCM:
+		keySize = 24
+	case EncryptionAES256GCM:
+		keySize = 32
+	default:
+		return nil, failed("unsupported encryption method %q", alg)
+	}
+
+	var ek *Node
+	if keyInfo := childElementNS(n, DSigNS, "KeyInfo"); keyInfo != nil {
+		ek = childElementNS(keyInfo, XMLEncNS, "EncryptedKey")
+	}
+	if ek == nil {
+		return nil, failed("missing EncryptedKey")
+	}
+	encryptedKey, err := cipherValue(ek)
+	if err != nil {
+		return nil, failed("bad EncryptedKey: %v", err)
+	}
+	opts, err := oaepOptions(childElementNS(ek, XMLEncNS, "EncryptionMethod"))
+	if err != nil {
+		return nil, failed("%v", err)
+	}
+	cek, err := key.Decrypt(rand.Reader, encryptedKey, opts)
+	if err != nil {
+		return nil, failed("%v", err)
+	}
+	if len(cek) != keySize {
+		return nil, failed("content key has %d bytes, want %d", len(cek), keySize)
+	}
+
+	data, err := cipherValue(n)
+	if err != nil {
+		return nil, failed("bad CipherData: %v", err)
+	}
+	block, _ := aes.NewCipher(cek)
+	gcm, _ := cipher.NewGCM(block)
+	if len(data) < gcm.NonceSize()+gcm.Overhead() {
+		return nil, failed("ciphertext too short")
+	}
+	plaintext, err := gcm.Open(nil, data[:gcm.NonceSize()], data[gcm.NonceSize():], nil)
+	if err != nil {
+		return nil, failed("%v", err)
+	}
+
+	nodes, err := parseFragment(n.Parent, string(plaintext))
+	if err != nil {
+		return nil, failed("%v", err)
+	}
+	if typ == EncryptionTypeElement {
+		elems := 0
+		for _, c := range nodes {
+			if c.Type == ElementNode {
+				elems++
+			}
+		}
+		if elems != 1 {
+			return nil, failed("decrypted data is not an element")
+		}
+	}
+	replaceNode(n, nodes...)
+	return nodes, nil
+}
+
+// encryptionAlgorithm returns the Algorithm of the EncryptionMethod of n.
+func encryptionAlgorithm(n *Node) string {
+	if m := childElementNS(n, XMLEncNS, "EncryptionMethod"); m != nil {
+		return m.SelectAttr("Algorithm")
+	}
+	return ""
+}
+
+// cipherValue returns the decoded CipherData/CipherValue of n.
+func cipherValue(n *Node) ([]byte, error) {
+	data := childElementNS(n, XMLEncNS, "CipherData")
+	if data == nil {
+		return nil, errors.New("missing CipherData")
+	}
+	return decodeBase64Text(childElementNS(data, XMLEncNS, "CipherValue"))
+}
+
+// oaepOptions returns the options of the RSA-OAEP key transport method m.
+func oaepOptions(m *Node) (*rsa.OAEPOptions, error) {
+	if m == nil {
+		return nil, errors.New("missing key EncryptionMethod")
+	}
+	opts := &rsa.OAEPOptions{Hash: crypto.SHA1, MGFHash: crypto.SHA1}
+	alg := m.SelectAttr("Algorithm")
+	if alg != KeyTransportRSAOAEP && alg != KeyTransportRSAOAEPMGF1P {
+		return nil, fmt.Errorf("unsupported key transport %q", alg)
+	}
+	if d := childElementNS(m, DSigNS, "DigestMethod"); d != nil {
+		h, ok := oaepHash(d.SelectAttr("Algorithm"))
+		if !ok {
+			return nil, fmt.Errorf("unsupported digest %q", d.SelectAttr("Algorithm"))
+		}
+		opts.Hash = h
+	}
+	if mgf := childElementNS(m, XMLEnc11NS, "MGF"); mgf != nil && alg == KeyTransportRSAOAEP {
+		h, ok := oaepHash(strings.Replace(mgf.SelectAttr("Algorithm"), "mgf1", "", 1))
+		if !ok {
+			return nil, fmt.Errorf("unsupported MGF %q", mgf.SelectAttr("Algorithm"))
+		}
+		opts.MGFHash = h
+	}
+	if p := childElementNS(m, XMLEncNS, "OAEPparams"); p != nil {
+		label, err := base64.StdEncoding.DecodeString(strings.TrimSpace(p.InnerText()))
+		if err != nil {
+			return nil, err
+		}
+		opts.Label = label
+	}
+	return opts, nil
+}
+
+// oaepHash maps the digest and MGF1 identifiers of XML Encryption to hashes.
+// MGF identifiers are passed with "mgf1" removed.
+func oaepHash(alg string) (crypto.Hash, bool) {
+	switch alg {
+	case "http://www.w3.org/2000/09/xmldsig#sha1", XMLEnc11NS + "sha1":
+		return crypto.SHA1, true
+	case DigestSHA256, XMLEnc11NS + "sha256", "http://www.w3.org/2000/09/xmldsig#sha256":
+		return crypto.SHA256, true
+	case "http://www.w3.org/2001/04/xmlenc#sha512", XMLEnc11NS + "sha512":
+		return crypto.SHA512, true
+	}
+	return 0, false
+}
+
+// parseFragment parses the XML fragment s as content of parent, with the
+// namespaces in scope at parent, and returns the resulting top-level nodes,
+// detached and with levels for parent.
+func parseFragment(parent *Node, s string) ([]*Node, error) {
+	var b strings.Builder
+	b.WriteString("<xmlquery-fragment")
+	for prefix, uri := range namespacesInScope(parent) {
+		switch prefix {
+		case "xml":
+		case "":
+			fmt.Fprintf(&b, ` xmlns="%s"`, escapeAttrValue(uri))
+		default:
+			fmt.Fprintf(&b, ` xmlns:%s="%s"`, prefix, escapeAttrValue(uri))
+		}
+	}
+	b.WriteString(">")
+	b.WriteString(s)
+	b.WriteString("</xmlquery-fragment>")
+	doc, err := Parse(strings.NewReader(b.String()))
+	if err != nil {
+		return nil, err
+	}
+	var nodes []*Node
+	for c := firstChildElement(doc).FirstChild; c != nil; {
+		next := c.NextSibling
+		RemoveFromTree(c)
+		setLevel(c, parent.level+1)
+		nodes = append(nodes, c)
+		c = next
+	}
+	return nodes, nil
+}
+
+// replaceNode replaces old with nodes, in order.
+func replaceNode(old *Node, nodes ...*Node) {
+	parent, prev, next := old.Parent, old.PrevSibling, old.NextSibling
+	RemoveFromTree(old)
+	for _, n := range nodes {
+		n.Parent, n.PrevSibling, n.NextSibling = parent, prev, next
+		if prev == nil {
+			parent.FirstChild = n
+		} else {
+			prev.NextSibling = n
+		}
+		if next == nil {
+			parent.LastChild = n
+		} else {
+			next.PrevSibling = n
+		}
+		prev = n
+		notify(parent, Mutation{Type: NodeInserted, Target: parent, Node: n})
+	}
+}
@@ -0,0 +1,116 @@
+package xmlquery
+
+import (
+	"crypto"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha1"
+	"encoding/base64"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestEncryptElement(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := `<order xmlns="urn:shop" xmlns:p="urn:pay"><item>Widget</item><p:card number="4111">J &amp; Doe<p:cvv>123</p:cvv></p:card><total>5</total></order>`
+	doc, err := Parse(strings.NewReader(s))
+	if err != nil {
+		t.Fatal(err)
+	}
+	card := FindOne(doc, "//p:card")
+	enc, err := EncryptElement(card, &key.PublicKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	testValue(t, enc.Parent, FindOne(doc, "/order"))
+	testValue(t, enc.Level(), 2)
+	testTrue(t, FindOne(doc, "//p:card") == nil)
+	testTrue(t, !strings.Contains(doc.OutputXML(false), "4111"))
+	verifyNodePointers(t, doc)
+
+	// Decrypt a copy that went through serialization.
+	again, err := Parse(strings.NewReader(doc.OutputXML(false)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	nodes, err := DecryptElement(FindOne(again, "//xenc:EncryptedData"), key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	testValue(t, len(nodes), 1)
+	testValue(t, nodes[0].NamespaceURI, "urn:pay")
+	testValue(t, nodes[0].Level(), 2)
+	testValue(t, again.OutputXML(false), `<?xml version="1.0"?>`+s)
+	testValue(t, FindOne(again, "//p:cvv").InnerText(), "123")
+	verifyNodePointers(t, again)
+
+	// The wrong key and tampered data are rejected.
+	other, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := DecryptElement(enc, other); !errors.Is(err, ErrDecryption) {
+		t.Fatalf("expected ErrDecryption, got %v", err)
+	}
+	value := FindOne(enc, "xenc:CipherData/xenc:CipherValue")
+	data, _ := base64.StdEncoding.DecodeString(value.InnerText())
+	data[len(data)-1] ^= 1
+	value.FirstChild.Data = base64.StdEncoding.EncodeToString(data)
+	if _, err := DecryptElement(enc, key); !errors.Is(err, ErrDecryption) {
+		t.Fatalf("expected ErrDecryption, got %v", err)
+	}
+
+	if _, err := DecryptElement(FindOne(doc, "//item"), key); err == nil {
+		t.Fatal("expected error for element that isn't EncryptedData")
+	}
+}
+
+func TestDecryptElementContent(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// AES-128-GCM content encrypted with a key transported with
+	// rsa-oaep-mgf1p, as many SAML identity providers do.
+	cek := make([]byte, 16)
+	rand.Read(cek)
+	block, _ := aes.NewCipher(cek)
+	gcm, _ := cipher.NewGCM(block)
+	nonce := make([]byte, gcm.NonceSize())
+	rand.Read(nonce)
+	data := gcm.Seal(nonce, nonce, []byte(`<a:x>1</a:x>text`), nil)
+	ek, err := rsa.EncryptOAEP(sha1.New(), rand.Reader, &key.PublicKey, cek, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := `<r xmlns:a="urn:a"><e:EncryptedData xmlns:e="http://www.w3.org/2001/04/xmlenc#" Type="http://www.w3.org/2001/04/xmlenc#Content">
+  <e:EncryptionMethod Algorithm="http://www.w3.org/2009/xmlenc11#aes128-gcm"/>
+  <KeyInfo xmlns="http://www.w3.org/2000/09/xmldsig#">
+    <e:EncryptedKey>
+      <e:EncryptionMethod Algorithm="http://www.w3.org/2001/04/xmlenc#rsa-oaep-mgf1p"/>
+      <e:CipherData><e:CipherValue>` + base64.StdEncoding.EncodeToString(ek) + `</e:CipherValue></e:CipherData>
+    </e:EncryptedKey>
+  </KeyInfo>
+  <e:CipherData><e:CipherValue>
+    ` + base64.StdEncoding.EncodeToString(data) + `
+  </e:CipherValue></e:CipherData>
+</e:EncryptedData></r>`
+	doc, err := Parse(strings.NewReader(s))
+	if err != nil {
+		t.Fatal(err)
+	}
+	nodes, err := DecryptElement(FindOne(doc, "//*[local-name()='EncryptedData']"), crypto.Decrypter(key))
+	if err != nil {
+		t.Fatal(err)
+	}
+	testValue(t, len(nodes), 2)
+	testValue(t, doc.OutputXML(false), `<?xml version="1.0"?><r xmlns:a="urn:a"><a:x>1</a:x>text</r>`)
+	testValue(t, FindOne(doc, "//a:x").NamespaceURI, "urn:a")
+	verifyNodePointers(t, doc)
+}
@@ -0,0 +1,73 @@
+package xmlquery
+
+import (
+	"strings"
+
+	"github.com/suifengpiao14/xmlquery/xml"
+)
+
+// XSINamespace is the XML Schema instance namespace of the xsi:type and
+// xsi:nil attributes.
+const XSINamespace = "http://www.w3.org/2001/XMLSchema-instance"
+
+// xsiAttr returns the value of the attribute of n in the XML Schema
+// instance namespace with the given local name. An xsi prefix that isn't
+// bound to any namespace, as in trees built by hand, is accepted too.
+func xsiAttr(n *Node, local string) (string, bool) {
+	for _, attr := range n.Attr {
+		if attr.Name.Local != local {
+			continue
+		}
+		if attr.NamespaceURI == XSINamespace || (attr.NamespaceURI == "" && attr.Name.Space == "xsi") {
+			return attr.Value, true
+		}
+	}
+	return "", false
+}
+
+// IsNil reports whether n is an element marked with xsi:nil="true", which
+// stands for a null value rather than an empty one.
+func (n *Node) IsNil() bool {
+	if n == nil || n.Type != ElementNode {
+		return false
+	}
+	v, ok := xsiAttr(n, "nil")
+	v = strings.TrimSpace(v)
+	return ok && (v == "true" || v == "1")
+}
+
+// XSIType returns the type named by the xsi:type attribute of n, with its
+// prefix resolved to a namespace URI in Space. ok is false if n has no
+// xsi:type or its prefix isn't bound.
+//
+//	// <value xsi:type="xs:int">42</value>
+//	t, _ := n.XSIType() // {http://www.w3.org/2001/XMLSchema int}
+func (n *Node) XSIType() (name xml.Name, ok bool) {
+	if n == nil || n.Type != ElementNode {
+		return xml.Name{}, false
+	}
+	v, ok := xsiAttr(n, "type")
+	if !ok {
+		return xml.Name{}, false
+	}
+	return n.ResolveQName(v)
+}
+
+// ResolveQName resolves a qualified name used in the content of n, such as
+// the value of xsi:type, with the namespaces in scope at n. An unprefixed
+// name is in the default namespace. ok is false if the prefix isn't bound.
+func (n *Node) ResolveQName(qname string) (name xml.Name, ok bool) {
+	qname = strings.TrimSpace(qname)
+	prefix, local, prefixed := strings.Cut(qname, ":")
+	if !prefixed {
+		prefix, local = "", qname
+	}
+	if local == "" {
+		return xml.Name{}, false
+	}
+	uri, bound := namespacesInScope(n)[prefix]
+	if !bound && prefixed {
+		return xml.Name{}, false
+	}
+	return xml.Name{Space: uri, Local: local}, true
+}
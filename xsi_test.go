@@ -0,0 +1,63 @@
+package xmlquery
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestXSIHelpers(t *testing.T) {
+	doc, err := Parse(strings.NewReader(`<r xmlns="urn:d" xmlns:xsi="http://www.w3.org/2001/XMLSchema-instance" xmlns:xs="http://www.w3.org/2001/XMLSchema">
+  <a xsi:nil="true"/>
+  <b xsi:nil="1"></b>
+  <c xsi:nil="false">7</c>
+  <d xsi:type="xs:int">42</d>
+  <e xsi:type="q:t"/>
+  <f xsi:type="local"/>
+  <g>5</g>
+</r>`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	find := func(name string) *Node {
+		return FindOne(doc, "//*[local-name()='"+name+"']")
+	}
+	for name, want := range map[string]bool{"a": true, "b": true, "c": false, "g": false} {
+		if got := find(name).IsNil(); got != want {
+			t.Errorf("%s.IsNil() = %v, want %v", name, got, want)
+		}
+	}
+	if doc.IsNil() {
+		t.Error("document IsNil() = true")
+	}
+
+	name, ok := find("d").XSIType()
+	if !ok || name.Space != "http://www.w3.org/2001/XMLSchema" || name.Local != "int" {
+		t.Errorf("d.XSIType() = %v, %v", name, ok)
+	}
+	if name, ok := find("e").XSIType(); ok {
+		t.Errorf("XSIType() with unbound prefix = %v, want false", name)
+	}
+	if name, ok := find("f").XSIType(); !ok || name.Space != "urn:d" || name.Local != "local" {
+		t.Errorf("f.XSIType() = %v, %v", name, ok)
+	}
+	if _, ok := find("g").XSIType(); ok {
+		t.Error("g.XSIType() = true, want false")
+	}
+
+	v, err := FindAs[*int](doc, "//*[local-name()='a']")
+	if err != nil || v != nil {
+		t.Errorf("FindAs[*int] on nil element = %v, %v", v, err)
+	}
+	v, err = FindAs[*int](doc, "//*[local-name()='c']")
+	if err != nil || v == nil || *v != 7 {
+		t.Errorf("FindAs[*int] = %v, %v", v, err)
+	}
+	s, err := FindAs[string](doc, "//*[local-name()='a']")
+	if err != nil || s != "" {
+		t.Errorf("FindAs[string] on nil element = %q, %v", s, err)
+	}
+	all, err := FindAllAs[*string](doc, "/*/*[local-name()='a' or local-name()='g']")
+	if err != nil || len(all) != 2 || all[0] != nil || all[1] == nil || *all[1] != "5" {
+		t.Errorf("FindAllAs[*string] = %v, %v", all, err)
+	}
+}
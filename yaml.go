@@ -0,0 +1,732 @@
+package xmlquery
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// ToYAML returns the YAML encoding of n with the same mapping as JSON, see
+// JSONOptions. Mappings and sequences are written in block style and
+// strings are quoted when they would otherwise be read back as another
+// value, such as a number or a boolean, or lose surrounding whitespace.
+// The output only uses the subset of YAML that FromYAML reads.
+//
+// Unlike JSON, the text of an element without child elements keeps its
+// surrounding whitespace, as attribute values do, so that FromYAML gives
+// back the same text. PreserveSpace applies to the text of elements with
+// child elements, which is mostly indentation.
+//
+//	<order id="7"><item>a</item><item>b</item></order>
+//
+// becomes
+//
+//	order:
+//	  "@id": "7"
+//	  item:
+//	    - a
+//	    - b
+func ToYAML(n *Node, opts JSONOptions) ([]byte, error) {
+	opts = opts.withDefaults()
+	var b bytes.Buffer
+	if n == nil {
+		b.WriteString("null\n")
+		return b.Bytes(), nil
+	}
+	switch n.Type {
+	case DocumentNode:
+		root := firstChildElement(n)
+		if root == nil {
+			b.WriteString("null\n")
+			return b.Bytes(), nil
+		}
+		n = root
+		fallthrough
+	case ElementNode:
+		writeYAMLScalar(&b, qualifiedName(n))
+		b.WriteByte(':')
+		writeYAMLValue(&b, yamlElement(n, &opts), 2, true)
+	default:
+		writeYAMLScalar(&b, n.InnerText())
+		b.WriteByte('\n')
+	}
+	return b.Bytes(), nil
+}
+
+// FromYAML returns a document node built from the YAML data, the reverse
+// of ToYAML: the data must be a mapping with a single key, the name of the
+// root element. Keys starting with opts.AttrPrefix become attributes,
+// opts.TextKey the text of the element and other keys child elements, one
+// per item if the value is a sequence. Scalars are kept as written, so
+// that 7 and "7" both become the text 7.
+//
+// FromYAML reads a subset of YAML 1.2, that written by ToYAML and most
+// hand-written configuration:
+//
+//   - block mappings with plain or quoted keys, and block sequences,
+//     including "- key: value" items
+//   - plain scalars, single-quoted and double-quoted scalars on one line,
+//     and literal (|) and folded (>) block scalars with chomping indicators
+//   - the empty flow collections {} and []
+//   - comments, directives and a "---" marker before the document, and a
+//     "..." marker after it
+//
+// Anything else, such as anchors (&a), aliases (*a), tags (!!str),
+// explicit keys (?), other flow collections, plain or quoted scalars
+// continued on the next line, and a second document, is rejected with an
+// error naming the construct and its line.
+func FromYAML(data []byte, opts JSONOptions) (*Node, error) {
+	opts = opts.withDefaults()
+	p := &yamlParser{lines: strings.Split(strings.TrimPrefix(string(data), "\ufeff"), "\n")}
+	var v *yamlValue
+	if indent, _, ok := p.peek(); ok {
+		var err error
+		if v, err = p.parseBlock(indent); err != nil {
+			return nil, err
+		}
+	}
+	if indent, content, ok := p.peek(); ok {
+		return nil, p.unsupported(indent, content)
+	}
+	if v == nil || v.kind != yamlMapping || len(v.keys) != 1 {
+		return nil, errors.New("xmlquery: YAML document must be a mapping with a single key")
+	}
+	doc := &Node{Type: DocumentNode}
+	if err := fromYAMLElement(doc, v.keys[0], v.values[0], &opts, namespacesInScope(doc)); err != nil {
+		return nil, err
+	}
+	setLevel(doc, 0)
+	return doc, nil
+}
+
+type yamlKind int
+
+const (
+	yamlScalar yamlKind = iota
+	yamlMapping
+	yamlSequence
+)
+
+// yamlValue is a parsed or to be written YAML value.
+type yamlValue struct {
+	kind   yamlKind
+	scalar string
+	keys   []string     // of a mapping
+	values []*yamlValue // of a mapping, or the items of a sequence
+}
+
+// yamlElement returns the value element n is mapped to, see JSONOptions.
+func yamlElement(n *Node, opts *JSONOptions) *yamlValue {
+	leaf := *opts
+	leaf.PreserveSpace = true
+	attrs, names, groups, text := jsonFields(n, &leaf)
+	if len(names) > 0 && !opts.PreserveSpace {
+		text = strings.TrimSpace(text)
+	}
+	if len(attrs) == 0 && len(names) == 0 {
+		return &yamlValue{scalar: text}
+	}
+	m := &yamlValue{kind: yamlMapping}
+	add := func(key string, v *yamlValue) {
+		m.keys = append(m.keys, key)
+		m.values = append(m.values, v)
+	}
+	for _, attr := range attrs {
+		add(opts.AttrPrefix+qualifiedAttrName(attr), &yamlValue{scalar: attr.Value})
+	}
+	for _, name := range names {
+		group := groups[name]
+		if len(group) == 1 {
+			add(name, yamlElement(group[0], opts))
+			continue
+		}
+		seq := &yamlValue{kind: yamlSequence}
+		for _, child := range group {
+			seq.values = append(seq.values, yamlElement(child, opts))
+		}
+		add(name, seq)
+	}
+	if text != "" {
+		add(opts.TextKey, &yamlValue{scalar: text})
+	}
+	return m
+}
+
+// writeYAMLValue writes v, which follows a key if afterKey is set or a
+// sequence indicator otherwise. The entries of a mapping and the items of a
+// sequence are indented by indent.
+func writeYAMLValue(b *bytes.Buffer, v *yamlValue, indent int, afterKey bool) {
+	pad := strings.Repeat(" ", indent)
+	switch {
+	case v.kind == yamlScalar:
+		if afterKey {
+			b.WriteByte(' ')
+		}
+		writeYAMLScalar(b, v.scalar)
+		b.WriteByte('\n')
+	case len(v.values) == 0:
+		if afterKey {
+			b.WriteByte(' ')
+		}
+		if v.kind == yamlMapping {
+			b.WriteString("{}\n")
+		} else {
+			b.WriteString("[]\n")
+		}
+	case v.kind == yamlMapping:
+		if afterKey {
+			b.WriteByte('\n')
+		}
+		for i, key := range v.keys {
+			if i > 0 || afterKey {
+				b.WriteString(pad)
+			}
+			writeYAMLScalar(b, key)
+			b.WriteByte(':')
+			writeYAMLValue(b, v.values[i], indent+2, true)
+		}
+	default:
+		if afterKey {
+			b.WriteByte('\n')
+		}
+		for i, item := range v.values {
+			if i > 0 || afterKey {
+				b.WriteString(pad)
+			}
+			b.WriteString("- ")
+			writeYAMLValue(b, item, indent+2, false)
+		}
+	}
+}
+
+// writeYAMLScalar writes s as a plain scalar if it is read back as the same
+// string, or as a double-quoted one otherwise.
+func writeYAMLScalar(b *bytes.Buffer, s string) {
+	if yamlPlainSafe(s) {
+		b.WriteString(s)
+		return
+	}
+	writeJSONString(b, s) // a JSON string is a valid double-quoted scalar
+}
+
+// yamlPlainSafe reports whether s can be written as a plain scalar.
+func yamlPlainSafe(s string) bool {
+	if s == "" || s != strings.TrimSpace(s) || strings.ContainsAny(s[:1], "-?:,[]{}#&*!|>'\"%@`") {
+		return false
+	}
+	if strings.Contains(s, ": ") || strings.Contains(s, " #") || strings.HasSuffix(s, ":") {
+		return false
+	}
+	for _, r := range s {
+		if r < ' ' || r == 0x7f || r == utf8.RuneError || r == '\u00a0' || r == '\u2028' || r == '\u2029' || r == '\ufeff' {
+			return false
+		}
+	}
+	switch strings.ToLower(s) {
+	case "~", "null", "true", "false", "yes", "no", "on", "off", "y", "n", ".inf", "-.inf", "+.inf", ".nan":
+		return false
+	}
+	if _, err := strconv.ParseFloat(strings.ReplaceAll(s, "_", ""), 64); err == nil {
+		return false
+	}
+	if _, err := strconv.ParseInt(s, 0, 64); err == nil {
+		return false
+	}
+	// Dates and sexagesimal numbers of YAML 1.1.
+	if s[0] >= '0' && s[0] <= '9' && strings.ContainsAny(s, "-:") {
+		return false
+	}
+	return true
+}
+
+// fromYAMLElement adds the element named name that v is mapped to, see
+// FromYAML, to parent.
+func fromYAMLElement(parent *Node, name string, v *yamlValue, opts *JSONOptions, scope map[string]string) error {
+	xname := newXMLName(name)
+	if xname.Local == "" {
+		return fmt.Errorf("xmlquery: invalid element name %q in YAML", name)
+	}
+	elem := &Node{Type: ElementNode, Data: xname.Local, Prefix: xname.Space}
+	switch v.kind {
+	case yamlSequence:
+		return fmt.Errorf("xmlquery: nested sequence in YAML element %s", name)
+	case yamlScalar:
+		elem.NamespaceURI = scope[elem.Prefix]
+		if v.scalar != "" {
+			AddChild(elem, &Node{Type: TextNode, Data: v.scalar})
+		}
+		AddChild(parent, elem)
+		return nil
+	}
+	isAttr := func(key string) bool {
+		return opts.AttrPrefix != "" && key != opts.TextKey && strings.HasPrefix(key, opts.AttrPrefix)
+	}
+	for i, key := range v.keys {
+		if !isAttr(key) {
+			continue
+		}
+		if v.values[i].kind != yamlScalar {
+			return fmt.Errorf("xmlquery: YAML attribute %s of %s is not a scalar", key, name)
+		}
+		attr := Attr{Name: newXMLName(key[len(opts.AttrPrefix):]), Value: v.values[i].scalar}
+		if attr.Name.Space == "xmlns" {
+			attr.NamespaceURI = "xmlns"
+		}
+		elem.Attr = append(elem.Attr, attr)
+	}
+	local := scope
+	if hasNamespaceDecls(elem) {
+		local = make(map[string]string, len(scope))
+		for k, v := range scope {
+			local[k] = v
+		}
+		declareNamespaces(elem, local)
+	}
+	elem.NamespaceURI = local[elem.Prefix]
+	for i := range elem.Attr {
+		if space := elem.Attr[i].Name.Space; space != "" && space != "xmlns" {
+			elem.Attr[i].NamespaceURI = local[space]
+		}
+	}
+	AddChild(parent, elem)
+	for i, key := range v.keys {
+		value := v.values[i]
+		switch {
+		case isAttr(key):
+		case key == opts.TextKey:
+			if value.kind != yamlScalar {
+				return fmt.Errorf("xmlquery: YAML text of %s is not a scalar", name)
+			}
+			if value.scalar != "" {
+				AddChild(elem, &Node{Type: TextNode, Data: value.scalar})
+			}
+		case value.kind == yamlSequence:
+			for _, item := range value.values {
+				if err := fromYAMLElement(elem, key, item, opts, local); err != nil {
+					return err
+				}
+			}
+		default:
+			if err := fromYAMLElement(elem, key, value, opts, local); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// yamlParser parses the subset of YAML described in FromYAML, line by line.
+type yamlParser struct {
+	lines []string
+	pos   int
+	// override replaces the content of the current line once, after a
+	// sequence indicator followed by the item on the same line.
+	override       string
+	overrideIndent int
+	overridden     bool
+	started        bool // after the first content or document marker
+}
+
+func (p *yamlParser) errorf(format string, args ...interface{}) error {
+	return fmt.Errorf("xmlquery: YAML line %d: %s", p.pos+1, fmt.Sprintf(format, args...))
+}
+
+// peek returns the indentation and content of the next line with content,
+// skipping blank lines, comments, directives and the marker starting the
+// document. A marker starting another document is returned as content,
+// which is then rejected.
+func (p *yamlParser) peek() (indent int, content string, ok bool) {
+	if p.overridden {
+		return p.overrideIndent, p.override, true
+	}
+	for ; p.pos < len(p.lines); p.pos++ {
+		line := strings.TrimRight(p.lines[p.pos], " \t\r")
+		content = strings.TrimLeft(line, " ")
+		switch {
+		case content == "" || content[0] == '#':
+			continue
+		case !p.started && (content[0] == '%' && len(content) == len(line)):
+			continue
+		case !p.started && (content == "---" || strings.HasPrefix(content, "--- #")):
+			p.started = true
+			continue
+		case content == "...":
+			p.pos = len(p.lines)
+			return 0, "", false
+		}
+		p.started = true
+		return len(line) - len(content), content, true
+	}
+	return 0, "", false
+}
+
+// advance moves past the line returned by peek.
+func (p *yamlParser) advance() {
+	if p.overridden {
+		p.overridden = false
+	}
+	p.pos++
+}
+
+// parseBlock parses the block node starting at the next line, indented by
+// indent.
+func (p *yamlParser) parseBlock(indent int) (*yamlValue, error) {
+	_, content, _ := p.peek()
+	if content == "-" || strings.HasPrefix(content, "- ") {
+		return p.parseSequence(indent)
+	}
+	if _, _, ok := splitYAMLKey(content); ok {
+		return p.parseMapping(indent)
+	}
+	line := p.pos
+	s, err := p.parseScalar(content, indent)
+	if err != nil {
+		return nil, err
+	}
+	if p.pos == line {
+		p.advance()
+	}
+	return s, nil
+}
+
+func (p *yamlParser) parseSequence(indent int) (*yamlValue, error) {
+	seq := &yamlValue{kind: yamlSequence}
+	for {
+		i, content, ok := p.peek()
+		if !ok || i != indent || !(content == "-" || strings.HasPrefix(content, "- ")) {
+			break
+		}
+		rest := strings.TrimLeft(content[1:], " ")
+		if rest == "" || rest[0] == '#' {
+			p.advance()
+			item := &yamlValue{}
+			if next, _, ok := p.peek(); ok && next > indent {
+				var err error
+				if item, err = p.parseBlock(next); err != nil {
+					return nil, err
+				}
+			}
+			seq.values = append(seq.values, item)
+			continue
+		}
+		if rest[0] == '|' || rest[0] == '>' {
+			item, err := p.parseBlockScalar(rest, indent)
+			if err != nil {
+				return nil, err
+			}
+			seq.values = append(seq.values, item)
+			continue
+		}
+		// Parse the item as if it started on its own line, at the column
+		// it is written at.
+		p.overridden = true
+		p.override = rest
+		p.overrideIndent = i + len(content) - len(rest)
+		item, err := p.parseBlock(p.overrideIndent)
+		if err != nil {
+			return nil, err
+		}
+		seq.values = append(seq.values, item)
+	}
+	return seq, nil
+}
+
+func (p *yamlParser) parseMapping(indent int) (*yamlValue, error) {
+	m := &yamlValue{kind: yamlMapping}
+	seen := make(map[string]bool)
+	for {
+		i, content, ok := p.peek()
+		if !ok || i != indent {
+			break
+		}
+		key, rest, ok := splitYAMLKey(content)
+		if !ok {
+			return nil, p.unsupported(i, content)
+		}
+		if err := p.checkIndicator(key); err != nil {
+			return nil, err
+		}
+		k := &yamlValue{scalar: key}
+		var err error
+		if key[0] == '"' || key[0] == '\'' {
+			if k, err = p.parseScalar(key, indent); err != nil {
+				return nil, err
+			}
+		}
+		if seen[k.scalar] {
+			return nil, p.errorf("duplicate key %q", k.scalar)
+		}
+		seen[k.scalar] = true
+		var value *yamlValue
+		if rest == "" || rest[0] == '#' {
+			p.advance()
+			value = &yamlValue{}
+			if next, c, ok := p.peek(); ok && (next > indent || (next == indent && (c == "-" || strings.HasPrefix(c, "- ")))) {
+				if value, err = p.parseBlock(next); err != nil {
+					return nil, err
+				}
+			}
+		} else {
+			line := p.pos
+			if value, err = p.parseScalar(rest, indent); err != nil {
+				return nil, err
+			}
+			if p.pos == line {
+				p.advance()
+			}
+		}
+		m.keys = append(m.keys, k.scalar)
+		m.values = append(m.values, value)
+	}
+	return m, nil
+}
+
+// splitYAMLKey splits the mapping entry s at the separator after its key.
+func splitYAMLKey(s string) (key, rest string, ok bool) {
+	end := -1
+	switch s[0] {
+	case '"', '\'':
+		quote := s[0]
+		for i := 1; i < len(s); i++ {
+			if s[i] == '\\' && quote == '"' {
+				i++
+			} else if s[i] == quote {
+				if quote == '\'' && i+1 < len(s) && s[i+1] == '\'' {
+					i++
+					continue
+				}
+				end = i + 1
+				break
+			}
+		}
+		if end < 0 || end >= len(s) || s[end] != ':' || (end+1 < len(s) && s[end+1] != ' ') {
+			return "", "", false
+		}
+	default:
+		for i := 0; i < len(s); i++ {
+			if s[i] == ':' && (i+1 == len(s) || s[i+1] == ' ') {
+				end = i
+				break
+			}
+			if s[i] == '#' && i > 0 && s[i-1] == ' ' {
+				return "", "", false
+			}
+		}
+		if end <= 0 {
+			return "", "", false
+		}
+	}
+	return strings.TrimRight(s[:end], " "), strings.TrimLeft(s[end+1:], " "), true
+}
+
+// parseScalar parses the scalar s, written on the current line of a node
+// indented by indent. Block scalars consume the lines that follow.
+func (p *yamlParser) parseScalar(s string, indent int) (*yamlValue, error) {
+	switch s[0] {
+	case '"':
+		return p.parseDoubleQuoted(s)
+	case '\'':
+		end := -1
+		var b strings.Builder
+		for i := 1; i < len(s); i++ {
+			if s[i] == '\'' {
+				if i+1 < len(s) && s[i+1] == '\'' {
+					b.WriteByte('\'')
+					i++
+					continue
+				}
+				end = i + 1
+				break
+			}
+			b.WriteByte(s[i])
+		}
+		if end < 0 {
+			return nil, p.errorf("unterminated single-quoted scalar")
+		}
+		if err := p.checkTrailing(s[end:]); err != nil {
+			return nil, err
+		}
+		return &yamlValue{scalar: b.String()}, nil
+	case '|', '>':
+		return p.parseBlockScalar(s, indent)
+	case '{', '[':
+		if v := strings.TrimSpace(stripYAMLComment(s)); v == "{}" {
+			return &yamlValue{kind: yamlMapping}, nil
+		} else if v == "[]" {
+			return &yamlValue{kind: yamlSequence}, nil
+		}
+	}
+	if err := p.checkIndicator(s); err != nil {
+		return nil, err
+	}
+	v := strings.TrimSpace(stripYAMLComment(s))
+	switch v {
+	case "~", "null", "Null", "NULL":
+		v = ""
+	}
+	return &yamlValue{scalar: v}, nil
+}
+
+// stripYAMLComment removes the comment that ends the plain scalar s.
+func stripYAMLComment(s string) string {
+	if i := strings.Index(s, " #"); i >= 0 {
+		return s[:i]
+	}
+	return s
+}
+
+// yamlIndicators names the constructs starting with the indicators that
+// FromYAML doesn't support.
+var yamlIndicators = map[byte]string{
+	'&': "anchors", '*': "aliases", '!': "tags", '?': "explicit keys",
+	'{': "flow mappings", '[': "flow sequences", '@': "reserved indicators", '`': "reserved indicators",
+}
+
+// checkIndicator returns an error if the key or scalar s starts with an
+// unsupported construct.
+func (p *yamlParser) checkIndicator(s string) error {
+	if what, ok := yamlIndicators[s[0]]; ok && (s[0] != '?' || len(s) == 1 || s[1] == ' ') {
+		return p.errorf("%s (%q) are not supported", what, s[:1])
+	}
+	return nil
+}
+
+// unsupported returns the error for the line indented by indent with the
+// given content, which can't be parsed where it is.
+func (p *yamlParser) unsupported(indent int, content string) error {
+	switch {
+	case content == "---" || strings.HasPrefix(content, "--- "):
+		return p.errorf("several documents are not supported")
+	case p.pos > 0 && indent > 0:
+		if err := p.checkIndicator(content); err != nil {
+			return err
+		}
+		return p.errorf("unexpected %q: wrong indentation, or a scalar continued on the next line, which is not supported", content)
+	}
+	if err := p.checkIndicator(content); err != nil {
+		return err
+	}
+	return p.errorf("unexpected %q, expected a mapping key", content)
+}
+
+func (p *yamlParser) checkTrailing(s string) error {
+	if s = strings.TrimLeft(s, " "); s != "" && s[0] != '#' {
+		return p.errorf("unexpected %q after quoted scalar", s)
+	}
+	return nil
+}
+
+var yamlEscapes = map[byte]string{
+	'0': "\x00", 'a': "\a", 'b': "\b", 't': "\t", '\t': "\t", 'n': "\n", 'v': "\v", 'f': "\f", 'r': "\r",
+	'e': "\x1b", ' ': " ", '"': "\"", '/': "/", '\\': "\\", 'N': "\u0085", '_': "\u00a0", 'L': "\u2028", 'P': "\u2029",
+}
+
+func (p *yamlParser) parseDoubleQuoted(s string) (*yamlValue, error) {
+	var b strings.Builder
+	for i := 1; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case c == '"':
+			if err := p.checkTrailing(s[i+1:]); err != nil {
+				return nil, err
+			}
+			return &yamlValue{scalar: b.String()}, nil
+		case c != '\\':
+			b.WriteByte(c)
+		case i+1 == len(s):
+			return nil, p.errorf("unterminated double-quoted scalar")
+		default:
+			i++
+			if esc, ok := yamlEscapes[s[i]]; ok {
+				b.WriteString(esc)
+				continue
+			}
+			size := map[byte]int{'x': 2, 'u': 4, 'U': 8}[s[i]]
+			if size == 0 || i+size >= len(s) {
+				return nil, p.errorf("invalid escape sequence in double-quoted scalar")
+			}
+			r, err := strconv.ParseUint(s[i+1:i+1+size], 16, 32)
+			if err != nil {
+				return nil, p.errorf("invalid escape sequence in double-quoted scalar")
+			}
+			i += size
+			// A surrogate pair, as written by encoding/json.
+			if r >= 0xd800 && r < 0xdc00 && strings.HasPrefix(s[i+1:], `\u`) && i+7 <= len(s) {
+				if lo, err := strconv.ParseUint(s[i+3:i+7], 16, 32); err == nil && lo >= 0xdc00 && lo < 0xe000 {
+					r = 0x10000 + (r-0xd800)<<10 + (lo - 0xdc00)
+					i += 6
+				}
+			}
+			b.WriteRune(rune(r))
+		}
+	}
+	return nil, p.errorf("unterminated double-quoted scalar")
+}
+
+// parseBlockScalar parses the literal (|) or folded (>) block scalar
+// introduced by header, whose content is on the lines that follow.
+func (p *yamlParser) parseBlockScalar(header string, indent int) (*yamlValue, error) {
+	h := strings.TrimSpace(stripYAMLComment(header))
+	folded := h[0] == '>'
+	chomp := byte(0)
+	for _, c := range []byte(h[1:]) {
+		switch {
+		case c == '-' || c == '+':
+			chomp = c
+		case c >= '1' && c <= '9':
+			return nil, p.errorf("explicit indentation of block scalars is not supported")
+		default:
+			return nil, p.errorf("invalid block scalar header %q", h)
+		}
+	}
+	p.overridden = false
+	p.pos++
+	var lines []string
+	width := -1
+	for ; p.pos < len(p.lines); p.pos++ {
+		line := strings.TrimRight(p.lines[p.pos], "\r")
+		content := strings.TrimLeft(line, " ")
+		if strings.TrimSpace(line) == "" {
+			lines = append(lines, "")
+			continue
+		}
+		col := len(line) - len(content)
+		if width < 0 {
+			if col <= indent {
+				break
+			}
+			width = col
+		}
+		if col < width {
+			break
+		}
+		lines = append(lines, line[width:])
+	}
+	trailing := 0
+	for trailing < len(lines) && lines[len(lines)-1-trailing] == "" {
+		trailing++
+	}
+	body := lines[:len(lines)-trailing]
+	var b strings.Builder
+	for i, line := range body {
+		switch {
+		case i == 0:
+		case !folded || line == "" || body[i-1] == "" || line[0] == ' ' || body[i-1][0] == ' ':
+			b.WriteByte('\n')
+		default:
+			b.WriteByte(' ')
+		}
+		b.WriteString(line)
+	}
+	switch {
+	case len(body) == 0 || chomp == '-':
+	case chomp == '+':
+		b.WriteString(strings.Repeat("\n", trailing+1))
+	default:
+		b.WriteByte('\n')
+	}
+	return &yamlValue{scalar: b.String()}, nil
+}
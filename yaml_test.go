@@ -0,0 +1,172 @@
+package xmlquery
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestToYAML(t *testing.T) {
+	doc, err := Parse(strings.NewReader(`<?xml version="1.0"?>
+<order id="7" xmlns:x="urn:x">
+	<item>a</item>
+	<item sku="b">b <![CDATA[&]]></item>
+	<x:note> n: 1 </x:note>
+	<qty>12</qty>
+	<flag>yes</flag>
+	<empty/>
+	<!--c-->
+	tail
+</order>`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := ToYAML(doc, DefaultJSONOptions)
+	if err != nil {
+		t.Fatal(err)
+	}
+	testValue(t, string(data), `order:
+  "@id": "7"
+  item:
+    - a
+    - "@sku": b
+      "#text": b &
+  x:note: " n: 1 "
+  qty: "12"
+  flag: "yes"
+  empty: ""
+  "#text": tail
+`)
+
+	data, err = ToYAML(doc, JSONOptions{AttrPrefix: "-", TextKey: "_", NamespaceDecls: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	testTrue(t, strings.Contains(string(data), `  "-xmlns:x": urn:x`+"\n"))
+
+	back, err := FromYAML(data, JSONOptions{AttrPrefix: "-", TextKey: "_"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	testValue(t, back.OutputXML(false), `<order id="7" xmlns:x="urn:x"><item>a</item><item sku="b">b &amp;</item><x:note>n: 1</x:note><qty>12</qty><flag>yes</flag><empty></empty>tail</order>`)
+	testValue(t, FindOne(back, "//x:note").NamespaceURI, "urn:x")
+	verifyNodePointers(t, back)
+
+	data, err = ToYAML(&Node{Type: DocumentNode}, DefaultJSONOptions)
+	if err != nil {
+		t.Fatal(err)
+	}
+	testValue(t, string(data), "null\n")
+}
+
+func TestYAMLWhitespace(t *testing.T) {
+	doc, err := Parse(strings.NewReader(`<r a=" x "><t> lead</t><u>trail </u><w>  </w><m k="1"> both </m>
+</r>`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Empty options take the defaults.
+	data, err := ToYAML(doc, JSONOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	testValue(t, string(data), `r:
+  "@a": " x "
+  t: " lead"
+  u: "trail "
+  w: "  "
+  m:
+    "@k": "1"
+    "#text": " both "
+`)
+	back, err := FromYAML(data, JSONOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"t", "u", "w", "m"} {
+		testValue(t, FindOne(back, "//"+name).InnerText(), FindOne(doc, "//"+name).InnerText())
+	}
+	testValue(t, FindOne(back, "/r").SelectAttr("a"), " x ")
+	testValue(t, FindOne(back, "/r/m").SelectAttr("k"), "1")
+}
+
+func TestFromYAML(t *testing.T) {
+	doc, err := FromYAML([]byte(`# feed settings
+---
+feed:
+  '@version': 2  # inline comment
+  source:
+  - name: north
+    url: "https://example.com/n?a=1&b=\u00e9"
+  - name: south
+    tags: []
+  note: |
+    line one
+    line two
+  summary: >-
+    folded
+    text
+  nested:
+    - - ignored
+`), DefaultJSONOptions)
+	testTrue(t, err != nil && strings.Contains(err.Error(), "nested sequence"))
+
+	doc, err = FromYAML([]byte(`feed:
+  '@version': 2  # inline comment
+  source:
+  - name: north
+    url: "https://example.com/n?a=1&b=\u00e9"
+  - name: 'it''s south'
+    tags: []
+  note: |
+    line one
+    line two
+  summary: >-
+    folded
+    text
+  empty: ~
+`), DefaultJSONOptions)
+	if err != nil {
+		t.Fatal(err)
+	}
+	verifyNodePointers(t, doc)
+	testValue(t, FindOne(doc, "/feed/@version").InnerText(), "2")
+	testValue(t, len(Find(doc, "/feed/source")), 2)
+	testValue(t, FindOne(doc, "/feed/source[1]/url").InnerText(), "https://example.com/n?a=1&b=\u00e9")
+	testValue(t, FindOne(doc, "/feed/source[2]/name").InnerText(), "it's south")
+	testValue(t, FindOne(doc, "/feed/note").InnerText(), "line one\nline two\n")
+	testValue(t, FindOne(doc, "/feed/summary").InnerText(), "folded text")
+	testTrue(t, FindOne(doc, "/feed/empty").FirstChild == nil)
+
+	for _, s := range []string{
+		"",
+		"a: 1\nb: 2\n",
+		"- a\n",
+		"a: {b: 1}\n",
+		"a:\n  b: 1\n  b: 2\n",
+		"a:\n  b: \"open\n",
+		"a: 1\n---\nb: 2\n",
+		"a:\n  \"@x\":\n    b: 1\n",
+	} {
+		if _, err := FromYAML([]byte(s), DefaultJSONOptions); err == nil {
+			t.Errorf("FromYAML(%q) returned no error", s)
+		}
+	}
+
+	for s, msg := range map[string]string{
+		"a:\n  b: &x 1\n":         `line 2: anchors ("&") are not supported`,
+		"a:\n  b: *x\n":           `line 2: aliases ("*") are not supported`,
+		"a: !!str 1\n":            `line 1: tags ("!") are not supported`,
+		"a:\n  ? b\n  : 1\n":      `line 2: explicit keys ("?") are not supported`,
+		"a: {b: 1}\n":             `line 1: flow mappings ("{") are not supported`,
+		"a:\n  - [1, 2]\n":        `line 2: flow sequences ("[") are not supported`,
+		"a:\n  &x b: 1\n":         `line 2: anchors ("&") are not supported`,
+		"a: 1\n---\nb: 2\n":       `line 2: several documents are not supported`,
+		"a:\n  b: one\n    two\n": `line 3: unexpected "two": wrong indentation, or a scalar continued on the next line`,
+		"a: 1\nb: 2\n":            `must be a mapping with a single key`,
+	} {
+		_, err := FromYAML([]byte(s), DefaultJSONOptions)
+		if err == nil || !strings.Contains(err.Error(), msg) {
+			t.Errorf("FromYAML(%q) = %v, want an error containing %q", s, err, msg)
+		}
+	}
+}
@@ -0,0 +1,85 @@
+package xmlquery
+
+import (
+	"archive/zip"
+	"io/fs"
+	"path"
+	"sort"
+	"strings"
+)
+
+// OpenZipPart parses the XML part named partName of a ZIP archive, such as
+// "word/document.xml" of a .docx file or "content.xml" of an .odt file.
+// A leading slash, as in the part names of [Content_Types].xml, is
+// ignored, and so is the case of partName if no part has the exact name.
+// An error wrapping fs.ErrNotExist is returned if there is no such part.
+//
+//	r, err := zip.OpenReader("report.docx")
+//	if err != nil {
+//		return err
+//	}
+//	defer r.Close()
+//	doc, err := xmlquery.OpenZipPart(&r.Reader, "word/document.xml")
+func OpenZipPart(archive *zip.Reader, partName string) (*Node, error) {
+	return OpenZipPartWithOptions(archive, partName, ParserOptions{})
+}
+
+// OpenZipPartWithOptions is like OpenZipPart, but with custom options.
+func OpenZipPartWithOptions(archive *zip.Reader, partName string, options ParserOptions) (*Node, error) {
+	name := strings.TrimPrefix(partName, "/")
+	f := zipPart(archive, name)
+	if f == nil {
+		return nil, &fs.PathError{Op: "open", Path: partName, Err: fs.ErrNotExist}
+	}
+	rc, err := f.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer rc.Close()
+	return ParseWithOptions(rc, options)
+}
+
+// zipPart returns the file named name of archive, or nil.
+func zipPart(archive *zip.Reader, name string) *zip.File {
+	var folded *zip.File
+	for _, f := range archive.File {
+		if f.Name == name {
+			return f
+		}
+		if folded == nil && strings.EqualFold(f.Name, name) {
+			folded = f
+		}
+	}
+	return folded
+}
+
+// ZipXMLParts returns the names of the XML parts of a ZIP archive, sorted:
+// the files named *.xml or *.rels, and for Office Open XML packages the
+// parts [Content_Types].xml gives an XML content type.
+func ZipXMLParts(archive *zip.Reader) []string {
+	xmlExts := map[string]bool{".xml": true, ".rels": true}
+	xmlParts := make(map[string]bool)
+	if doc, err := OpenZipPart(archive, "[Content_Types].xml"); err == nil {
+		for _, n := range Find(doc, "/*[local-name()='Types']/*[local-name()='Default']") {
+			if xmlMIMERegex.MatchString(n.SelectAttr("ContentType")) {
+				xmlExts["."+strings.ToLower(n.SelectAttr("Extension"))] = true
+			}
+		}
+		for _, n := range Find(doc, "/*[local-name()='Types']/*[local-name()='Override']") {
+			if xmlMIMERegex.MatchString(n.SelectAttr("ContentType")) {
+				xmlParts[strings.ToLower(strings.TrimPrefix(n.SelectAttr("PartName"), "/"))] = true
+			}
+		}
+	}
+	var names []string
+	for _, f := range archive.File {
+		if f.FileInfo().IsDir() {
+			continue
+		}
+		if xmlExts[strings.ToLower(path.Ext(f.Name))] || xmlParts[strings.ToLower(f.Name)] {
+			names = append(names, f.Name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
@@ -0,0 +1,53 @@
+package xmlquery
+
+import (
+	"archive/zip"
+	"bytes"
+	"errors"
+	"io/fs"
+	"strings"
+	"testing"
+)
+
+func TestZipParts(t *testing.T) {
+	var buf bytes.Buffer
+	w := zip.NewWriter(&buf)
+	for _, f := range []struct{ name, content string }{
+		{"[Content_Types].xml", `<Types xmlns="http://schemas.openxmlformats.org/package/2006/content-types">
+	<Default Extension="rels" ContentType="application/vnd.openxmlformats-package.relationships+xml"/>
+	<Default Extension="png" ContentType="image/png"/>
+	<Override PartName="/word/document.bin" ContentType="application/vnd.openxmlformats-officedocument.wordprocessingml.document.main+xml"/>
+</Types>`},
+		{"_rels/.rels", `<Relationships/>`},
+		{"word/document.bin", `<w:document xmlns:w="urn:w"><w:body><w:p>Hello</w:p></w:body></w:document>`},
+		{"word/media/image1.png", "\x89PNG"},
+		{"docProps/core.xml", `<coreProperties/>`},
+	} {
+		fw, err := w.Create(f.name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		fw.Write([]byte(f.content))
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	archive, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	testValue(t, strings.Join(ZipXMLParts(archive), ","), "[Content_Types].xml,_rels/.rels,docProps/core.xml,word/document.bin")
+
+	doc, err := OpenZipPart(archive, "/Word/Document.bin")
+	if err != nil {
+		t.Fatal(err)
+	}
+	testValue(t, FindOne(doc, "//w:p").InnerText(), "Hello")
+
+	_, err = OpenZipPart(archive, "word/missing.xml")
+	testTrue(t, errors.Is(err, fs.ErrNotExist))
+
+	_, err = OpenZipPart(archive, "word/media/image1.png")
+	testTrue(t, err != nil)
+}